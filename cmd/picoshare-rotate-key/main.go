@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/postgres"
+	"github.com/mtlynch/picoshare/store/sqlite"
+)
+
+type keyRotator interface {
+	RotateEncryptionKey(context.Context, encryption.MasterKey) (int, error)
+}
+
+// picoshare-rotate-key re-wraps the per-entry data keys in a PicoShare database
+// so that a new master key protects them. Run it while PicoShare is stopped,
+// then restart PicoShare with the new key. If PS_POSTGRES_URL is set, it
+// rotates the key of that PostgreSQL database instead of the SQLite database.
+func main() {
+	log.SetFlags(log.LstdFlags | log.Llongfile)
+
+	dbPath := flag.String("db", "data/store.db", "path to database")
+	oldKeyPath := flag.String("old-key-file", "", "path to file containing the current encryption key")
+	newKeyPath := flag.String("new-key-file", "", "path to file containing the new encryption key")
+	flag.Parse()
+
+	if *oldKeyPath == "" || *newKeyPath == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	oldKey, err := readKeyFile(*oldKeyPath)
+	if err != nil {
+		log.Fatalf("failed to read current encryption key: %v", err)
+	}
+
+	newKey, err := readKeyFile(*newKeyPath)
+	if err != nil {
+		log.Fatalf("failed to read new encryption key: %v", err)
+	}
+
+	var store keyRotator
+	if postgresURL := os.Getenv("PS_POSTGRES_URL"); postgresURL != "" {
+		store = postgres.NewWithEncryption(postgresURL, oldKey)
+	} else {
+		if _, err := os.Stat(*dbPath); err != nil {
+			log.Fatalf("failed to open database: %v", err)
+		}
+		store = sqlite.NewWithEncryption(*dbPath, oldKey, false)
+	}
+
+	n, err := store.RotateEncryptionKey(context.Background(), newKey)
+	if err != nil {
+		log.Fatalf("failed to rotate encryption key: %v", err)
+	}
+
+	fmt.Printf("re-wrapped data keys for %d blobs\n", n)
+}
+
+func readKeyFile(path string) (encryption.MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return encryption.MasterKey{}, err
+	}
+	return encryption.ParseMasterKey(string(data))
+}
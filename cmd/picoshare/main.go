@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -17,11 +18,27 @@ import (
 
 	"github.com/mtlynch/picoshare/garbagecollect"
 	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
 	"github.com/mtlynch/picoshare/handlers/auth/shared_secret"
+	"github.com/mtlynch/picoshare/notify"
+	"github.com/mtlynch/picoshare/scrub"
 	"github.com/mtlynch/picoshare/space"
+	"github.com/mtlynch/picoshare/space/checkers"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/postgres"
 	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/webhook"
 )
 
+type appStore interface {
+	handlers.Store
+	garbagecollect.DatabasePurger
+	garbagecollect.EvictionStore
+	scrub.EntryVerifier
+	checkers.DatabaseMetadataReader
+	webhook.DeliveryStore
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Llongfile)
 	log.Print("starting picoshare server")
@@ -42,17 +59,77 @@ func main() {
 
 	ensureDirExists(dbDir)
 
-	store := sqlite.New(*dbPath, isLitestreamEnabled())
+	masterKey, err := encryptionKeyFromEnv()
+	if err != nil {
+		log.Fatalf("failed to read encryption key: %v", err)
+	}
+
+	if masterKey != nil {
+		log.Printf("encrypting new uploads at rest")
+	}
 
-	spaceChecker := space.NewChecker(*dbPath, &store)
+	var store appStore
+	var spaceChecker space.Checker
+	if postgresURL := os.Getenv("PS_POSTGRES_URL"); postgresURL != "" {
+		var s postgres.Store
+		if masterKey != nil {
+			s = postgres.NewWithEncryption(postgresURL, *masterKey)
+		} else {
+			s = postgres.New(postgresURL)
+		}
+		store = s
+		spaceChecker = space.NewCheckerForRemoteDatabase(dbDir, store)
+	} else {
+		var s sqlite.Store
+		if masterKey != nil {
+			s = sqlite.NewWithEncryption(*dbPath, *masterKey, isLitestreamEnabled())
+		} else {
+			s = sqlite.New(*dbPath, isLitestreamEnabled())
+		}
+		store = &s
+		spaceChecker = space.NewChecker(*dbPath, store)
+	}
 
-	collector := garbagecollect.NewCollector(store)
+	storageLimits, err := storageLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("invalid storage limits: %v", err)
+	}
+
+	// Report entries that the garbage collector deletes to the event webhook.
+	purger := webhook.NewReportingPurger(store)
+	var collector garbagecollect.Collector
+	if storageLimits.EvictionPolicy != space.EvictNothing {
+		log.Printf("evicting entries by policy %q when stored data exceeds %d bytes", storageLimits.EvictionPolicy, storageLimits.MaxStoredBytes)
+		evictor := garbagecollect.NewEvictor(webhook.NewReportingEvictionStore(store), storageLimits.MaxStoredBytes, storageLimits.EvictionPolicy)
+		collector = garbagecollect.NewCollectorWithEvictor(purger, evictor)
+	} else {
+		collector = garbagecollect.NewCollector(purger)
+	}
 	gc := garbagecollect.NewScheduler(&collector, 7*time.Hour)
 	gc.StartAsync()
 
+	scrubber := scrub.NewScrubber(store, os.Getenv("PS_QUARANTINE_CORRUPT_FILES") != "")
+	scrubs := scrub.NewScheduler(&scrubber, 24*time.Hour)
+	scrubs.StartAsync()
+
+	webhookSender := webhook.NewSender(store)
+	webhooks := webhook.NewScheduler(&webhookSender, 5*time.Second)
+	webhooks.StartAsync()
+
 	clock := handlers.NewClock()
 
-	server := handlers.New(authenticator, &store, spaceChecker, &collector, &clock)
+	notifier := notify.NewDispatcher()
+
+	server := handlers.New(handlers.Options{
+		Authenticator:     authenticator,
+		Store:             store,
+		SpaceChecker:      spaceChecker,
+		StorageLimits:     storageLimits,
+		Collector:         &collector,
+		Notifier:          notifier,
+		Clock:             &clock,
+		GuestLinkUnlocker: guest_passphrase.NewFromSharedSecret(secret),
+	})
 
 	h := gorilla.LoggingHandler(os.Stdout, server.Router())
 	if os.Getenv("PS_BEHIND_PROXY") != "" {
@@ -77,6 +154,8 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Give notifications about the final requests a chance to go out.
+	notifier.Wait()
 }
 
 func sharedSecretFromEnv() (string, error) {
@@ -94,6 +173,59 @@ func sharedSecretFromEnv() (string, error) {
 	return secret, nil
 }
 
+// encryptionKeyFromEnv reads the master key for encryption at rest. If the
+// environment specifies no key, it returns nil.
+func encryptionKeyFromEnv() (*encryption.MasterKey, error) {
+	var raw string
+	if path := os.Getenv("PS_ENCRYPTION_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading PS_ENCRYPTION_KEY_FILE: %w", err)
+		}
+		raw = string(data)
+	} else if raw = os.Getenv("PS_ENCRYPTION_KEY"); raw == "" {
+		return nil, nil
+	}
+
+	mk, err := encryption.ParseMasterKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &mk, nil
+}
+
+// storageLimitsFromEnv reads the limits on how much disk space uploads may
+// consume. Limits that the environment doesn't specify are zero, meaning
+// PicoShare doesn't enforce them.
+func storageLimitsFromEnv() (space.Limits, error) {
+	var limits space.Limits
+	if raw := os.Getenv("PS_STORAGE_LIMIT"); raw != "" {
+		maxStoredBytes, err := space.ParseSize(raw)
+		if err != nil {
+			return space.Limits{}, fmt.Errorf("PS_STORAGE_LIMIT: %w", err)
+		}
+		limits.MaxStoredBytes = maxStoredBytes
+	}
+	if raw := os.Getenv("PS_MIN_FREE_SPACE"); raw != "" {
+		minFreeBytes, err := space.ParseSize(raw)
+		if err != nil {
+			return space.Limits{}, fmt.Errorf("PS_MIN_FREE_SPACE: %w", err)
+		}
+		limits.MinFreeBytes = minFreeBytes
+	}
+	if raw := os.Getenv("PS_EVICTION_POLICY"); raw != "" {
+		policy, err := space.ParseEvictionPolicy(raw)
+		if err != nil {
+			return space.Limits{}, fmt.Errorf("PS_EVICTION_POLICY: %w", err)
+		}
+		if limits.MaxStoredBytes == 0 {
+			return space.Limits{}, errors.New("PS_EVICTION_POLICY requires PS_STORAGE_LIMIT")
+		}
+		limits.EvictionPolicy = policy
+	}
+	return limits, nil
+}
+
 func ensureDirExists(dir string) {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		if err := os.Mkdir(dir, os.ModePerm); err != nil {
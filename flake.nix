@@ -70,7 +70,7 @@
         fontDirectories = [nodepkgs.dejavu_fonts];
       };
 
-      goVendorHash = "sha256-X2vrEhgEnKKNXRyLCtT+wBbunFHgkcyWZh6DMpQieQ0=";
+      goVendorHash = "sha256-Z5gfNDzzaL8C4OfzePDS3mzMCnQHES7GaB7TQnZRpW4=";
 
       npmDepsHash = "sha256-7z4Fdtl0WqriTyh9g1sUlNyoc/vyp5akeP0b/JDzheQ=";
 
@@ -1,17 +1,26 @@
 package garbagecollect
 
 import (
+	"context"
 	"sync"
+
+	"github.com/mtlynch/picoshare/picoshare"
 )
 
 type (
 	DatabasePurger interface {
-		Purge() error
+		Purge(context.Context) error
+	}
+
+	StorageEvictor interface {
+		Evict(context.Context) error
+		EvictExcept(context.Context, picoshare.EntryID) error
 	}
 
 	Collector struct {
-		purger DatabasePurger
-		mu     sync.Mutex
+		purger  DatabasePurger
+		evictor StorageEvictor
+		mu      sync.Mutex
 	}
 )
 
@@ -21,13 +30,51 @@ func NewCollector(purger DatabasePurger) Collector {
 	}
 }
 
-func (c *Collector) Collect() error {
+// NewCollectorWithEvictor creates a collector that also evicts entries after
+// it purges expired data.
+func NewCollectorWithEvictor(purger DatabasePurger, evictor StorageEvictor) Collector {
+	return Collector{
+		purger:  purger,
+		evictor: evictor,
+	}
+}
+
+func (c *Collector) Collect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.purger.Purge(ctx); err != nil {
+		return err
+	}
+
+	// Evict only after purging, as deleting expired entries might be enough to
+	// get back under the storage limit.
+	if c.evictor != nil {
+		if err := c.evictor.Evict(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CollectExcept collects garbage like Collect, but it never evicts the entry
+// with the given ID. If PicoShare can't get back under its storage limit
+// without evicting that entry, CollectExcept returns a
+// StorageLimitExceededError.
+func (c *Collector) CollectExcept(ctx context.Context, keep picoshare.EntryID) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if err := c.purger.Purge(); err != nil {
+	if err := c.purger.Purge(ctx); err != nil {
 		return err
 	}
 
+	if c.evictor != nil {
+		if err := c.evictor.EvictExcept(ctx, keep); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -1,221 +1,178 @@
 package garbagecollect_test
 
 import (
-	"reflect"
-	"sort"
-	"strings"
+	"context"
+	"errors"
+	"sync"
 	"testing"
-	"time"
-
-	"github.com/go-test/deep"
 
 	"github.com/mtlynch/picoshare/garbagecollect"
 	"github.com/mtlynch/picoshare/picoshare"
-	"github.com/mtlynch/picoshare/store/test_sqlite"
 )
 
-func TestCollectDoesNothingWhenStoreIsEmpty(t *testing.T) {
-	dataStore := test_sqlite.New()
-	c := garbagecollect.NewCollector(dataStore)
-	err := c.Collect()
-	if err != nil {
-		t.Fatalf("garbage collection failed: %v", err)
-	}
+type mockPurger struct {
+	err     error
+	mu      sync.Mutex
+	active  int
+	overlap bool
+	calls   int
+}
 
-	remaining, err := dataStore.GetEntriesMetadata()
-	if err != nil {
-		t.Fatalf("retrieving datastore metadata failed: %v", err)
+func (p *mockPurger) Purge(context.Context) error {
+	p.mu.Lock()
+	p.calls++
+	p.active++
+	if p.active > 1 {
+		p.overlap = true
 	}
+	p.mu.Unlock()
 
-	expected := []picoshare.UploadMetadata{}
-	if !reflect.DeepEqual(expected, remaining) {
-		t.Fatalf("unexpected results in datastore: got %+v, want %+v", remaining, expected)
-	}
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+
+	return p.err
 }
 
-func TestCollectExpiredFile(t *testing.T) {
-	dataStore := test_sqlite.New()
-	d := "dummy data"
-	expireInFiveMins := makeRelativeExpirationTime(5 * time.Minute)
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("AAAAAAAAAAAA"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("2024-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
-		})
-	dataStore.InsertEntryDownload(
-		picoshare.EntryID("AAAAAAAAAAAA"),
-		picoshare.DownloadRecord{
-			Time:      mustParseTime("2023-06-01T12:00:00Z"),
-			ClientIP:  "192.168.1.1",
-			UserAgent: "test-agent",
-		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("BBBBBBBBBBBB"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("3000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
-		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("CCCCCCCCCCCC"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  picoshare.NeverExpire,
-			Size:     mustParseFileSize(len(d)),
-		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("DDDDDDDDDDDD"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  makeRelativeExpirationTime(-1 * time.Second),
-			Size:     mustParseFileSize(len(d)),
-		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("EEEEEEEEEEEE"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  expireInFiveMins,
-			Size:     mustParseFileSize(len(d)),
-		})
+type mockEvictor struct {
+	err    error
+	purger *mockPurger
+	// purgedFirst records whether the collector purged the database before it
+	// evicted entries.
+	purgedFirst bool
+	calls       int
+	kept        picoshare.EntryID
+}
 
-	c := garbagecollect.NewCollector(dataStore)
-	err := c.Collect()
-	if err != nil {
-		t.Fatalf("garbage collection failed: %v", err)
-	}
+func (e *mockEvictor) Evict(context.Context) error {
+	e.calls++
+	e.purgedFirst = e.purger.calls > 0
+	return e.err
+}
 
-	remaining, err := dataStore.GetEntriesMetadata()
-	if err != nil {
-		t.Fatalf("retrieving datastore metadata failed: %v", err)
-	}
+func (e *mockEvictor) EvictExcept(_ context.Context, keep picoshare.EntryID) error {
+	e.kept = keep
+	return e.Evict(context.Background())
+}
 
-	expected := []picoshare.UploadMetadata{
-		{
-			ID:       picoshare.EntryID("BBBBBBBBBBBB"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("3000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
-		},
+func TestCollect(t *testing.T) {
+	purgeErr := errors.New("dummy purge error")
+	for _, tt := range []struct {
+		description string
+		purgeErr    error
+		want        error
+	}{
 		{
-			ID:       picoshare.EntryID("CCCCCCCCCCCC"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  picoshare.NeverExpire,
-			Size:     mustParseFileSize(len(d)),
+			description: "succeeds when purge succeeds",
+			purgeErr:    nil,
+			want:        nil,
 		},
 		{
-			ID:       picoshare.EntryID("EEEEEEEEEEEE"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  expireInFiveMins,
-			Size:     mustParseFileSize(len(d)),
+			description: "returns error when purge fails",
+			purgeErr:    purgeErr,
+			want:        purgeErr,
 		},
-	}
-	if diff := deep.Equal(expected, remaining); diff != nil {
-		t.Errorf("unexpected results in datastore: got %v, want %v, diff = %v", remaining, expected, diff)
-		t.Errorf("got=%+v", remaining)
-		t.Errorf("want=%+v", expected)
-		t.Errorf("diff=%+v", diff)
-		t.FailNow()
-	}
-}
-
-func TestCollectDoesNothingWhenNoFilesAreExpired(t *testing.T) {
-	dataStore := test_sqlite.New()
-	d := "dummy data"
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("AAAAAAAAAAAA"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("4000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
-		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("BBBBBBBBBBBB"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("3000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			purger := mockPurger{err: tt.purgeErr}
+			c := garbagecollect.NewCollector(&purger)
+
+			if got, want := c.Collect(context.Background()), tt.want; got != want {
+				t.Errorf("err=%v, want=%v", got, want)
+			}
+
+			if got, want := purger.calls, 1; got != want {
+				t.Errorf("purge calls=%d, want=%d", got, want)
+			}
 		})
-	dataStore.InsertEntry(strings.NewReader(d),
-		picoshare.UploadMetadata{
-			ID:       picoshare.EntryID("CCCCCCCCCCCC"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  picoshare.NeverExpire,
-			Size:     mustParseFileSize(len(d)),
-		})
-
-	c := garbagecollect.NewCollector(dataStore)
-	err := c.Collect()
-	if err != nil {
-		t.Fatalf("garbage collection failed: %v", err)
 	}
+}
 
-	remaining, err := dataStore.GetEntriesMetadata()
-	if err != nil {
-		t.Fatalf("retrieving datastore metadata failed: %v", err)
-	}
-
-	// Sort the elements so they have a consistent ordering.
-	sort.Slice(remaining, func(i, j int) bool {
-		return (time.Time(remaining[i].Expires)).After(time.Time(remaining[j].Expires))
-	})
-
-	expected := []picoshare.UploadMetadata{
+func TestCollectWithEvictor(t *testing.T) {
+	purgeErr := errors.New("dummy purge error")
+	evictErr := errors.New("dummy evict error")
+	for _, tt := range []struct {
+		description    string
+		purgeErr       error
+		evictErr       error
+		want           error
+		wantEvictCalls int
+	}{
 		{
-			ID:       picoshare.EntryID("AAAAAAAAAAAA"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("4000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
+			description:    "evicts after purging",
+			want:           nil,
+			wantEvictCalls: 1,
 		},
 		{
-			ID:       picoshare.EntryID("BBBBBBBBBBBB"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  mustParseExpirationTime("3000-01-01T00:00:00Z"),
-			Size:     mustParseFileSize(len(d)),
+			description:    "returns error when eviction fails",
+			evictErr:       evictErr,
+			want:           evictErr,
+			wantEvictCalls: 1,
 		},
 		{
-			ID:       picoshare.EntryID("CCCCCCCCCCCC"),
-			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
-			Expires:  picoshare.NeverExpire,
-			Size:     mustParseFileSize(len(d)),
+			description:    "skips eviction when purge fails",
+			purgeErr:       purgeErr,
+			want:           purgeErr,
+			wantEvictCalls: 0,
 		},
-	}
-
-	if diff := deep.Equal(expected, remaining); diff != nil {
-		t.Errorf("unexpected results in datastore: got %v, want %v, diff = %v", remaining, expected, diff)
-		t.Errorf("got=%+v", remaining)
-		t.Errorf("want=%+v", expected)
-		t.Errorf("diff=%+v", diff)
-		t.FailNow()
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			purger := mockPurger{err: tt.purgeErr}
+			evictor := mockEvictor{err: tt.evictErr, purger: &purger}
+			c := garbagecollect.NewCollectorWithEvictor(&purger, &evictor)
+
+			if got, want := c.Collect(context.Background()), tt.want; got != want {
+				t.Errorf("err=%v, want=%v", got, want)
+			}
+
+			if got, want := evictor.calls, tt.wantEvictCalls; got != want {
+				t.Fatalf("evict calls=%d, want=%d", got, want)
+			}
+
+			if evictor.calls > 0 && !evictor.purgedFirst {
+				t.Errorf("collector evicted entries before purging expired entries")
+			}
+		})
 	}
 }
 
-func mustParseTime(s string) time.Time {
-	t, err := time.Parse(time.RFC3339, s)
-	if err != nil {
-		panic(err)
+func TestCollectExceptKeepsEntry(t *testing.T) {
+	purger := mockPurger{}
+	evictor := mockEvictor{purger: &purger}
+	c := garbagecollect.NewCollectorWithEvictor(&purger, &evictor)
+
+	if err := c.CollectExcept(context.Background(), picoshare.EntryID("new-upload")); err != nil {
+		t.Fatalf("garbage collection failed: %v", err)
 	}
-	return t
-}
 
-func mustParseExpirationTime(s string) picoshare.ExpirationTime {
-	et, err := time.Parse(time.RFC3339, s)
-	if err != nil {
-		panic(err)
+	if got, want := evictor.kept, picoshare.EntryID("new-upload"); got != want {
+		t.Errorf("kept=%v, want=%v", got, want)
+	}
+	if !evictor.purgedFirst {
+		t.Errorf("collector evicted entries before purging expired entries")
 	}
-	return picoshare.ExpirationTime(et)
 }
 
-func makeRelativeExpirationTime(delta time.Duration) picoshare.ExpirationTime {
-	return picoshare.ExpirationTime(time.Now().UTC().Add(delta).Truncate(time.Second))
-}
+func TestCollectDoesNotPurgeConcurrently(t *testing.T) {
+	purger := mockPurger{}
+	c := garbagecollect.NewCollector(&purger)
 
-func mustParseFileSize(val int) picoshare.FileSize {
-	fileSize, err := picoshare.FileSizeFromInt(val)
-	if err != nil {
-		panic(err)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if err := c.Collect(context.Background()); err != nil {
+				t.Errorf("garbage collection failed: %v", err)
+			}
+		})
 	}
+	wg.Wait()
 
-	return fileSize
+	if got, want := purger.calls, 10; got != want {
+		t.Errorf("purge calls=%d, want=%d", got, want)
+	}
+	if purger.overlap {
+		t.Errorf("collector purged the database from multiple goroutines at once")
+	}
 }
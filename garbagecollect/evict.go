@@ -0,0 +1,187 @@
+package garbagecollect
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space"
+)
+
+type (
+	EvictionStore interface {
+		GetEntriesMetadata(context.Context) ([]picoshare.UploadMetadata, error)
+		GetLatestDownloadTimes(context.Context) (map[picoshare.EntryID]time.Time, error)
+		GetStoredDataSize(context.Context) (uint64, error)
+		DeleteEntry(context.Context, picoshare.EntryID) error
+		InsertEvictedEntry(context.Context, picoshare.EvictedEntry) error
+	}
+
+	// Evictor deletes entries when PicoShare's stored data exceeds its storage
+	// limit.
+	Evictor struct {
+		store          EvictionStore
+		maxStoredBytes uint64
+		policy         space.EvictionPolicy
+	}
+
+	// StorageLimitExceededError occurs when the evictor can't get PicoShare's
+	// stored data back under the storage limit without evicting an entry that
+	// it must keep.
+	StorageLimitExceededError struct {
+		StoredBytes    uint64
+		MaxStoredBytes uint64
+	}
+)
+
+func (e *StorageLimitExceededError) Error() string {
+	return fmt.Sprintf("stored data (%d bytes) exceeds storage limit (%d bytes)", e.StoredBytes, e.MaxStoredBytes)
+}
+
+func NewEvictor(store EvictionStore, maxStoredBytes uint64, policy space.EvictionPolicy) Evictor {
+	return Evictor{
+		store:          store,
+		maxStoredBytes: maxStoredBytes,
+		policy:         policy,
+	}
+}
+
+// Evict deletes entries in the order that the eviction policy chooses until
+// PicoShare's stored data is back under the storage limit. It never evicts
+// entries that are set to never expire.
+func (e Evictor) Evict(ctx context.Context) error {
+	storedBytes, err := e.evict(ctx, picoshare.EntryID(""))
+	if err != nil {
+		return err
+	}
+
+	if storedBytes > e.maxStoredBytes {
+		log.Printf("stored data (%d bytes) still exceeds storage limit (%d bytes) because all remaining entries are set to never expire", storedBytes, e.maxStoredBytes)
+	}
+
+	return nil
+}
+
+// EvictExcept deletes entries like Evict, but it never evicts the entry with
+// the given ID, such as an upload that just took PicoShare over its storage
+// limit. If PicoShare's stored data is still over the limit once no other
+// entries remain to evict, EvictExcept returns a StorageLimitExceededError.
+func (e Evictor) EvictExcept(ctx context.Context, keep picoshare.EntryID) error {
+	storedBytes, err := e.evict(ctx, keep)
+	if err != nil {
+		return err
+	}
+
+	if storedBytes > e.maxStoredBytes {
+		return &StorageLimitExceededError{
+			StoredBytes:    storedBytes,
+			MaxStoredBytes: e.maxStoredBytes,
+		}
+	}
+
+	return nil
+}
+
+// evict deletes eviction candidates other than the entry with ID keep until
+// PicoShare's stored data is under the storage limit or no candidates remain.
+// It returns the size of the stored data once it's done.
+func (e Evictor) evict(ctx context.Context, keep picoshare.EntryID) (uint64, error) {
+	storedBytes, err := e.store.GetStoredDataSize(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if storedBytes <= e.maxStoredBytes {
+		return storedBytes, nil
+	}
+
+	candidates, err := e.evictionCandidates(ctx, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("stored data (%d bytes) exceeds storage limit (%d bytes), evicting entries by policy %q", storedBytes, e.maxStoredBytes, e.policy)
+
+	for _, entry := range candidates {
+		if storedBytes <= e.maxStoredBytes {
+			break
+		}
+
+		if err := e.store.DeleteEntry(ctx, entry.ID); err != nil {
+			return 0, err
+		}
+
+		if err := e.store.InsertEvictedEntry(ctx, picoshare.EvictedEntry{
+			ID:       entry.ID,
+			Filename: entry.Filename,
+			Size:     entry.Size,
+			Uploaded: entry.Uploaded,
+			Evicted:  time.Now(),
+			Policy:   e.policy.String(),
+		}); err != nil {
+			return 0, err
+		}
+
+		// Identical entries share their data, so deleting an entry doesn't
+		// necessarily free as many bytes as the entry's size. Measure again
+		// rather than guess.
+		storedBytes, err = e.store.GetStoredDataSize(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return storedBytes, nil
+}
+
+// evictionCandidates returns the entries other than keep that the evictor may
+// delete, in the order that it should delete them.
+func (e Evictor) evictionCandidates(ctx context.Context, keep picoshare.EntryID) ([]picoshare.UploadMetadata, error) {
+	entries, err := e.store.GetEntriesMetadata(ctx)
+	if err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+
+	candidates := []picoshare.UploadMetadata{}
+	for _, entry := range entries {
+		if entry.Expires == picoshare.NeverExpire || entry.ID == keep {
+			continue
+		}
+		candidates = append(candidates, entry)
+	}
+
+	var evictFirst func(a, b picoshare.UploadMetadata) bool
+	switch e.policy {
+	case space.EvictLeastRecentlyDownloadedFirst:
+		latestDownloads, err := e.store.GetLatestDownloadTimes(ctx)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+		lastUsed := func(entry picoshare.UploadMetadata) time.Time {
+			if t, ok := latestDownloads[entry.ID]; ok {
+				return t
+			}
+			return entry.Uploaded
+		}
+		evictFirst = func(a, b picoshare.UploadMetadata) bool {
+			return lastUsed(a).Before(lastUsed(b))
+		}
+	case space.EvictLargestFirst:
+		evictFirst = func(a, b picoshare.UploadMetadata) bool {
+			return a.Size.UInt64() > b.Size.UInt64()
+		}
+	default:
+		evictFirst = func(a, b picoshare.UploadMetadata) bool {
+			return a.Uploaded.Before(b.Uploaded)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return evictFirst(candidates[i], candidates[j])
+	})
+
+	return candidates, nil
+}
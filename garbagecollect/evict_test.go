@@ -0,0 +1,310 @@
+package garbagecollect_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space"
+)
+
+type mockEvictionStore struct {
+	entries         []picoshare.UploadMetadata
+	latestDownloads map[picoshare.EntryID]time.Time
+	deleteErr       error
+	evicted         []picoshare.EvictedEntry
+}
+
+func (s *mockEvictionStore) GetEntriesMetadata(context.Context) ([]picoshare.UploadMetadata, error) {
+	return s.entries, nil
+}
+
+func (s *mockEvictionStore) GetLatestDownloadTimes(context.Context) (map[picoshare.EntryID]time.Time, error) {
+	return s.latestDownloads, nil
+}
+
+// GetStoredDataSize sums the sizes of the remaining entries. Entries with the
+// same filename stand in for entries that share their data.
+func (s *mockEvictionStore) GetStoredDataSize(context.Context) (uint64, error) {
+	counted := map[picoshare.Filename]bool{}
+	var total uint64
+	for _, entry := range s.entries {
+		if counted[entry.Filename] {
+			continue
+		}
+		counted[entry.Filename] = true
+		total += entry.Size.UInt64()
+	}
+	return total, nil
+}
+
+func (s *mockEvictionStore) DeleteEntry(_ context.Context, id picoshare.EntryID) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	remaining := []picoshare.UploadMetadata{}
+	for _, entry := range s.entries {
+		if entry.ID != id {
+			remaining = append(remaining, entry)
+		}
+	}
+	s.entries = remaining
+	return nil
+}
+
+func (s *mockEvictionStore) InsertEvictedEntry(_ context.Context, entry picoshare.EvictedEntry) error {
+	s.evicted = append(s.evicted, entry)
+	return nil
+}
+
+func TestEvict(t *testing.T) {
+	entries := []picoshare.UploadMetadata{
+		{
+			ID:       picoshare.EntryID("old-small"),
+			Filename: picoshare.Filename("old-small.txt"),
+			Uploaded: mustParseTime("2025-01-01T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(100),
+		},
+		{
+			ID:       picoshare.EntryID("middle-large"),
+			Filename: picoshare.Filename("middle-large.txt"),
+			Uploaded: mustParseTime("2025-02-01T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(500),
+		},
+		{
+			ID:       picoshare.EntryID("new-medium"),
+			Filename: picoshare.Filename("new-medium.txt"),
+			Uploaded: mustParseTime("2025-03-01T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(300),
+		},
+		{
+			ID:       picoshare.EntryID("oldest-never-expires"),
+			Filename: picoshare.Filename("oldest-never-expires.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.NeverExpire,
+			Size:     mustParseFileSize(1000),
+		},
+	}
+	latestDownloads := map[picoshare.EntryID]time.Time{
+		"old-small":    mustParseTime("2025-06-01T00:00:00Z"),
+		"middle-large": mustParseTime("2025-04-01T00:00:00Z"),
+	}
+
+	for _, tt := range []struct {
+		description    string
+		entries        []picoshare.UploadMetadata
+		maxStoredBytes uint64
+		policy         space.EvictionPolicy
+		want           []picoshare.EntryID
+	}{
+		{
+			description:    "evicts nothing when under the limit",
+			entries:        entries,
+			maxStoredBytes: 1900,
+			policy:         space.EvictOldestFirst,
+			want:           []picoshare.EntryID{},
+		},
+		{
+			description:    "evicts oldest entries first",
+			entries:        entries,
+			maxStoredBytes: 1500,
+			policy:         space.EvictOldestFirst,
+			want:           []picoshare.EntryID{"old-small", "middle-large"},
+		},
+		{
+			description:    "evicts largest entries first",
+			entries:        entries,
+			maxStoredBytes: 1500,
+			policy:         space.EvictLargestFirst,
+			want:           []picoshare.EntryID{"middle-large"},
+		},
+		{
+			description:    "evicts least recently downloaded entries first",
+			entries:        entries,
+			maxStoredBytes: 1500,
+			policy:         space.EvictLeastRecentlyDownloadedFirst,
+			// Nobody has downloaded new-medium, so its last use is its upload
+			// time, which is earlier than the latest download of middle-large.
+			want: []picoshare.EntryID{"new-medium", "middle-large"},
+		},
+		{
+			description:    "never evicts entries that never expire",
+			entries:        entries,
+			maxStoredBytes: 100,
+			policy:         space.EvictOldestFirst,
+			want:           []picoshare.EntryID{"old-small", "middle-large", "new-medium"},
+		},
+		{
+			description: "keeps evicting while entries share data",
+			entries: []picoshare.UploadMetadata{
+				{
+					ID:       picoshare.EntryID("copy-1"),
+					Filename: picoshare.Filename("shared.txt"),
+					Uploaded: mustParseTime("2025-01-01T00:00:00Z"),
+					Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+					Size:     mustParseFileSize(500),
+				},
+				{
+					ID:       picoshare.EntryID("copy-2"),
+					Filename: picoshare.Filename("shared.txt"),
+					Uploaded: mustParseTime("2025-02-01T00:00:00Z"),
+					Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+					Size:     mustParseFileSize(500),
+				},
+				{
+					ID:       picoshare.EntryID("unique"),
+					Filename: picoshare.Filename("unique.txt"),
+					Uploaded: mustParseTime("2025-03-01T00:00:00Z"),
+					Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+					Size:     mustParseFileSize(300),
+				},
+			},
+			maxStoredBytes: 400,
+			policy:         space.EvictOldestFirst,
+			want:           []picoshare.EntryID{"copy-1", "copy-2"},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			store := mockEvictionStore{
+				entries:         tt.entries,
+				latestDownloads: latestDownloads,
+			}
+			e := garbagecollect.NewEvictor(&store, tt.maxStoredBytes, tt.policy)
+
+			if err := e.Evict(context.Background()); err != nil {
+				t.Fatalf("eviction failed: %v", err)
+			}
+
+			evictedIDs := []picoshare.EntryID{}
+			for _, entry := range store.evicted {
+				evictedIDs = append(evictedIDs, entry.ID)
+
+				if got, want := entry.Policy, tt.policy.String(); got != want {
+					t.Errorf("policy=%s, want=%s", got, want)
+				}
+				if entry.Evicted.IsZero() {
+					t.Errorf("eviction time of %v is unset", entry.ID)
+				}
+			}
+
+			if got, want := evictedIDs, tt.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("evicted=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestEvictReturnsDeleteError(t *testing.T) {
+	deleteErr := errors.New("dummy delete error")
+	store := mockEvictionStore{
+		entries: []picoshare.UploadMetadata{
+			{
+				ID:       picoshare.EntryID("dummy-entry"),
+				Filename: picoshare.Filename("dummy.txt"),
+				Uploaded: mustParseTime("2025-01-01T00:00:00Z"),
+				Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+				Size:     mustParseFileSize(500),
+			},
+		},
+		deleteErr: deleteErr,
+	}
+	e := garbagecollect.NewEvictor(&store, 100, space.EvictOldestFirst)
+
+	if got, want := e.Evict(context.Background()), deleteErr; got != want {
+		t.Errorf("err=%v, want=%v", got, want)
+	}
+
+	if got, want := len(store.evicted), 0; got != want {
+		t.Errorf("eviction records=%d, want=%d", got, want)
+	}
+}
+
+func TestEvictExcept(t *testing.T) {
+	entries := []picoshare.UploadMetadata{
+		{
+			ID:       picoshare.EntryID("old-small"),
+			Filename: picoshare.Filename("old-small.txt"),
+			Uploaded: mustParseTime("2025-01-01T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(100),
+		},
+		{
+			ID:       picoshare.EntryID("new-upload"),
+			Filename: picoshare.Filename("new-upload.txt"),
+			Uploaded: mustParseTime("2025-03-01T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2030-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(500),
+		},
+	}
+
+	for _, tt := range []struct {
+		description    string
+		maxStoredBytes uint64
+		policy         space.EvictionPolicy
+		wantEvicted    []picoshare.EntryID
+		wantErr        bool
+	}{
+		{
+			description:    "evicts other entries to make room",
+			maxStoredBytes: 500,
+			policy:         space.EvictLargestFirst,
+			wantEvicted:    []picoshare.EntryID{"old-small"},
+			wantErr:        false,
+		},
+		{
+			description:    "fails when only the kept entry could make room",
+			maxStoredBytes: 400,
+			policy:         space.EvictLargestFirst,
+			wantEvicted:    []picoshare.EntryID{"old-small"},
+			wantErr:        true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			store := mockEvictionStore{
+				entries: entries,
+			}
+			e := garbagecollect.NewEvictor(&store, tt.maxStoredBytes, tt.policy)
+
+			err := e.EvictExcept(context.Background(), picoshare.EntryID("new-upload"))
+			_, isLimitErr := errors.AsType[*garbagecollect.StorageLimitExceededError](err)
+			if got, want := isLimitErr, tt.wantErr; got != want {
+				t.Fatalf("err=%v, wantErr=%v", err, want)
+			}
+
+			evictedIDs := []picoshare.EntryID{}
+			for _, entry := range store.evicted {
+				evictedIDs = append(evictedIDs, entry.ID)
+			}
+			if got, want := evictedIDs, tt.wantEvicted; !reflect.DeepEqual(got, want) {
+				t.Errorf("evicted=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustParseExpirationTime(s string) picoshare.ExpirationTime {
+	return picoshare.ExpirationTime(mustParseTime(s))
+}
+
+func mustParseFileSize(val int) picoshare.FileSize {
+	fileSize, err := picoshare.FileSizeFromInt(val)
+	if err != nil {
+		panic(err)
+	}
+	return fileSize
+}
@@ -1,6 +1,7 @@
 package garbagecollect
 
 import (
+	"context"
 	"log"
 	"time"
 )
@@ -21,7 +22,7 @@ func (s *Scheduler) StartAsync() {
 	go func() {
 		for range s.ticker.C {
 			log.Printf("performing database maintenance")
-			if err := s.collector.Collect(); err != nil {
+			if err := s.collector.Collect(context.Background()); err != nil {
 				log.Printf("database maintenance failed: %v", err)
 			}
 		}
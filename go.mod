@@ -4,11 +4,13 @@ go 1.26
 
 require (
 	codeberg.org/mtlynch/go-evolutionary-migrate v0.0.1
-	github.com/go-test/deep v1.1.1
 	github.com/gorilla/mux v1.8.0
+	github.com/klauspost/compress v1.18.0
+	github.com/lib/pq v1.12.3
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/mileusna/useragent v1.3.3
 	github.com/mtlynch/gorilla-handlers v1.5.2
+	github.com/yuin/goldmark v1.8.2
 	golang.org/x/crypto v0.0.0-20220331220935-ae2d96664a29
 	golang.org/x/sys v0.29.0
 )
@@ -0,0 +1,112 @@
+// Package guest_passphrase restricts passphrase-protected guest links to guests
+// who know the passphrase.
+package guest_passphrase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// HeaderName is the HTTP header through which command-line clients can supply
+// a guest link's passphrase instead of unlocking the link in a browser.
+const HeaderName = "X-Guest-Link-Passphrase"
+
+const cookieNamePrefix = "guestLink-"
+
+// Hash creates a hash of a guest link passphrase suitable for storage.
+func Hash(passphrase string) (picoshare.GuestLinkPassphraseHash, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passphrase), bcrypt.DefaultCost)
+	if err != nil {
+		return picoshare.GuestLinkPassphraseHash(""), err
+	}
+	return picoshare.GuestLinkPassphraseHash(hash), nil
+}
+
+// Verify returns true if the passphrase matches the guest link's passphrase.
+func Verify(gl picoshare.GuestLink, passphrase string) bool {
+	if !gl.IsPassphraseProtected() {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(gl.PassphraseHash), []byte(passphrase)) == nil
+}
+
+// Unlocker remembers which guest links a client has unlocked by signing a
+// cookie with a key that only the server knows.
+type Unlocker struct {
+	key []byte
+}
+
+// New creates an Unlocker that signs its cookies with key.
+func New(key []byte) Unlocker {
+	return Unlocker{key: key}
+}
+
+// NewFromSharedSecret creates an Unlocker with a key that it derives from
+// PicoShare's shared secret, so that cookies remain valid across restarts and
+// across instances that share the same secret.
+func NewFromSharedSecret(sharedSecret string) Unlocker {
+	mac := hmac.New(sha256.New, []byte(sharedSecret))
+	mac.Write([]byte("picoshare guest link unlock cookie"))
+	return New(mac.Sum(nil))
+}
+
+// IsZero returns true if the Unlocker has no key.
+func (u Unlocker) IsZero() bool {
+	return len(u.key) == 0
+}
+
+// StartSession sets a cookie that lets the client use the guest link without
+// entering its passphrase again.
+func (u Unlocker) StartSession(w http.ResponseWriter, gl picoshare.GuestLink) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName(gl),
+		Value:    u.sign(gl),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   30 * 24 * 60 * 60, // 30 days in seconds
+	})
+}
+
+// Authenticate returns true if the request may use the guest link, either
+// because the link has no passphrase, the request includes the passphrase in
+// its headers, or the client previously unlocked the link.
+func (u Unlocker) Authenticate(r *http.Request, gl picoshare.GuestLink) bool {
+	if !gl.IsPassphraseProtected() {
+		return true
+	}
+
+	if passphrase := r.Header.Get(HeaderName); passphrase != "" {
+		return Verify(gl, passphrase)
+	}
+
+	cookie, err := r.Cookie(cookieName(gl))
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(cookie.Value), []byte(u.sign(gl)))
+}
+
+func cookieName(gl picoshare.GuestLink) string {
+	return cookieNamePrefix + gl.ID.String()
+}
+
+// sign derives the session cookie's value from the guest link's ID and
+// passphrase hash. The server's key keeps anyone who can read the database from
+// forging cookies, and signing the passphrase hash means that changing the
+// passphrase invalidates any existing sessions.
+func (u Unlocker) sign(gl picoshare.GuestLink) string {
+	mac := hmac.New(sha256.New, u.key)
+	mac.Write([]byte(gl.ID))
+	// Separate the fields so that no other ID and hash produce the same message.
+	mac.Write([]byte{0})
+	mac.Write([]byte(gl.PassphraseHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package guest_passphrase_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestVerify(t *testing.T) {
+	gl := mustMakeProtectedGuestLink("mysecret")
+
+	for _, tt := range []struct {
+		description string
+		guestLink   picoshare.GuestLink
+		passphrase  string
+		want        bool
+	}{
+		{"accept correct passphrase", gl, "mysecret", true},
+		{"reject incorrect passphrase", gl, "wrongsecret", false},
+		{"reject empty passphrase", gl, "", false},
+		{"reject any passphrase for an unprotected link", picoshare.GuestLink{ID: gl.ID}, "mysecret", false},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if got, want := guest_passphrase.Verify(tt.guestLink, tt.passphrase), tt.want; got != want {
+				t.Errorf("verified=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	gl := mustMakeProtectedGuestLink("mysecret")
+	unlocker := guest_passphrase.NewFromSharedSecret("dummy-shared-secret")
+
+	w := httptest.NewRecorder()
+	unlocker.StartSession(w, gl)
+	sessionCookies := w.Result().Cookies()
+	if got, want := len(sessionCookies), 1; got != want {
+		t.Fatalf("cookie count=%d, want=%d", got, want)
+	}
+	sessionCookie := sessionCookies[0]
+
+	for _, tt := range []struct {
+		description string
+		guestLink   picoshare.GuestLink
+		header      string
+		cookie      *http.Cookie
+		want        bool
+	}{
+		{
+			description: "allow any request to an unprotected link",
+			guestLink:   picoshare.GuestLink{ID: gl.ID},
+			want:        true,
+		},
+		{
+			description: "reject request without credentials",
+			guestLink:   gl,
+			want:        false,
+		},
+		{
+			description: "accept correct passphrase in header",
+			guestLink:   gl,
+			header:      "mysecret",
+			want:        true,
+		},
+		{
+			description: "reject incorrect passphrase in header",
+			guestLink:   gl,
+			header:      "wrongsecret",
+			want:        false,
+		},
+		{
+			description: "accept session cookie",
+			guestLink:   gl,
+			cookie:      sessionCookie,
+			want:        true,
+		},
+		{
+			description: "reject forged session cookie",
+			guestLink:   gl,
+			cookie: &http.Cookie{
+				Name:  sessionCookie.Name,
+				Value: "forged-value",
+			},
+			want: false,
+		},
+		{
+			description: "reject session cookie signed with a different key",
+			guestLink:   gl,
+			cookie:      mustMakeSessionCookie(guest_passphrase.NewFromSharedSecret("other-shared-secret"), gl),
+			want:        false,
+		},
+		{
+			// Someone who can read the database knows the guest link's ID and
+			// passphrase hash, but that must not be enough to unlock the link.
+			description: "reject session cookie built from database contents",
+			guestLink:   gl,
+			cookie: &http.Cookie{
+				Name:  sessionCookie.Name,
+				Value: signWithKey([]byte(gl.PassphraseHash), []byte(gl.ID)),
+			},
+			want: false,
+		},
+		{
+			description: "reject session cookie signed with an empty key",
+			guestLink:   gl,
+			cookie:      mustMakeSessionCookie(guest_passphrase.New(nil), gl),
+			want:        false,
+		},
+		{
+			description: "reject session cookie after passphrase changes",
+			guestLink:   mustMakeProtectedGuestLink("mysecret"),
+			cookie:      sessionCookie,
+			want:        false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/g/"+tt.guestLink.ID.String(), nil)
+			if tt.header != "" {
+				req.Header.Set(guest_passphrase.HeaderName, tt.header)
+			}
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+
+			if got, want := unlocker.Authenticate(req, tt.guestLink), tt.want; got != want {
+				t.Errorf("authenticated=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func mustMakeProtectedGuestLink(passphrase string) picoshare.GuestLink {
+	hash, err := guest_passphrase.Hash(passphrase)
+	if err != nil {
+		panic(err)
+	}
+	return picoshare.GuestLink{
+		ID:             picoshare.GuestLinkID("abcdefgh23456789"),
+		PassphraseHash: hash,
+	}
+}
+
+func mustMakeSessionCookie(unlocker guest_passphrase.Unlocker, gl picoshare.GuestLink) *http.Cookie {
+	w := httptest.NewRecorder()
+	unlocker.StartSession(w, gl)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		panic("expected exactly one session cookie")
+	}
+	return cookies[0]
+}
+
+func signWithKey(key, message []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,73 @@
+// Package guest_session recognizes the browser session in which a guest
+// uploaded files through a guest link so that PicoShare can show guests their
+// own uploads.
+package guest_session
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/random"
+)
+
+const (
+	cookieNamePrefix = "guestUploads-"
+
+	// tokenBytes is the number of random bytes in a session token.
+	tokenBytes = 32
+)
+
+// Start returns the key of the client's session on the guest link. If the
+// client doesn't have a session yet, Start creates one and sets a cookie that
+// lasts until the client closes their browser.
+func Start(w http.ResponseWriter, r *http.Request, gl picoshare.GuestLink) picoshare.GuestSessionKey {
+	if key, ok := FromRequest(r, gl); ok {
+		return key
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(random.Bytes(tokenBytes))
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName(gl),
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return keyFromToken(token)
+}
+
+// FromRequest returns the key of the client's session on the guest link, if
+// the client has one.
+func FromRequest(r *http.Request, gl picoshare.GuestLink) (picoshare.GuestSessionKey, bool) {
+	cookie, err := r.Cookie(cookieName(gl))
+	if err != nil || cookie.Value == "" {
+		return picoshare.GuestSessionKey(""), false
+	}
+	return keyFromToken(cookie.Value), true
+}
+
+// Owns returns true if the client uploaded the entry during its current
+// session on the guest link.
+func Owns(r *http.Request, gl picoshare.GuestLink, entry picoshare.UploadMetadata) bool {
+	if entry.GuestSession.Empty() {
+		return false
+	}
+	key, ok := FromRequest(r, gl)
+	return ok && key == entry.GuestSession
+}
+
+func cookieName(gl picoshare.GuestLink) string {
+	return cookieNamePrefix + gl.ID.String()
+}
+
+// keyFromToken derives the session key that PicoShare stores from the token in
+// the client's cookie. Storing a hash means that someone who reads the
+// database can't impersonate a guest's session.
+func keyFromToken(token string) picoshare.GuestSessionKey {
+	sum := sha256.Sum256([]byte(token))
+	return picoshare.GuestSessionKey(hex.EncodeToString(sum[:]))
+}
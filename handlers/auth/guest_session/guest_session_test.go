@@ -0,0 +1,112 @@
+package guest_session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/auth/guest_session"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestStart(t *testing.T) {
+	gl := picoshare.GuestLink{ID: picoshare.GuestLinkID("abcdefgh23456789")}
+
+	w := httptest.NewRecorder()
+	key := guest_session.Start(w, httptest.NewRequest(http.MethodPost, "/", nil), gl)
+	if key.Empty() {
+		t.Fatalf("session key is empty")
+	}
+	cookies := w.Result().Cookies()
+	if got, want := len(cookies), 1; got != want {
+		t.Fatalf("cookie count=%d, want=%d", got, want)
+	}
+	if got, want := cookies[0].HttpOnly, true; got != want {
+		t.Errorf("HttpOnly=%v, want=%v", got, want)
+	}
+	if got, want := cookies[0].MaxAge, 0; got != want {
+		t.Errorf("MaxAge=%d, want=%d", got, want)
+	}
+	if cookies[0].Value == key.String() {
+		t.Errorf("cookie value matches stored session key")
+	}
+
+	// A client that already has a session keeps it.
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	if got, want := guest_session.Start(w, r, gl), key; got != want {
+		t.Errorf("resumed session key=%v, want=%v", got, want)
+	}
+	if got, want := len(w.Result().Cookies()), 0; got != want {
+		t.Errorf("cookie count when resuming session=%d, want=%d", got, want)
+	}
+
+	// A second client gets a different session.
+	w = httptest.NewRecorder()
+	if other := guest_session.Start(w, httptest.NewRequest(http.MethodPost, "/", nil), gl); other == key {
+		t.Errorf("second client's session key matches first client's")
+	}
+}
+
+func TestOwns(t *testing.T) {
+	gl := picoshare.GuestLink{ID: picoshare.GuestLinkID("abcdefgh23456789")}
+	otherLink := picoshare.GuestLink{ID: picoshare.GuestLinkID("zyxwvuts98765432")}
+
+	w := httptest.NewRecorder()
+	key := guest_session.Start(w, httptest.NewRequest(http.MethodPost, "/", nil), gl)
+	sessionCookie := w.Result().Cookies()[0]
+
+	for _, tt := range []struct {
+		description string
+		guestLink   picoshare.GuestLink
+		cookie      *http.Cookie
+		entry       picoshare.UploadMetadata
+		want        bool
+	}{
+		{
+			description: "client owns entry from its session",
+			guestLink:   gl,
+			cookie:      sessionCookie,
+			entry:       picoshare.UploadMetadata{GuestSession: key},
+			want:        true,
+		},
+		{
+			description: "client doesn't own entry from another session",
+			guestLink:   gl,
+			cookie:      sessionCookie,
+			entry:       picoshare.UploadMetadata{GuestSession: picoshare.GuestSessionKey("other")},
+			want:        false,
+		},
+		{
+			description: "client doesn't own entry without a session",
+			guestLink:   gl,
+			cookie:      sessionCookie,
+			entry:       picoshare.UploadMetadata{},
+			want:        false,
+		},
+		{
+			description: "client without a cookie owns nothing",
+			guestLink:   gl,
+			entry:       picoshare.UploadMetadata{GuestSession: key},
+			want:        false,
+		},
+		{
+			description: "session cookie doesn't apply to other guest links",
+			guestLink:   otherLink,
+			cookie:      sessionCookie,
+			entry:       picoshare.UploadMetadata{GuestSession: key},
+			want:        false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			if got, want := guest_session.Owns(r, tt.guestLink, tt.entry), tt.want; got != want {
+				t.Errorf("owns=%v, want=%v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+var contextKeyBranding = new(contextKey{name: "branding"})
+
+// loadBranding adds the instance's branding to the request context so that
+// every page can show it.
+func (s Server) loadBranding(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		branding, err := s.getDB(r).ReadBrandingSettings(r.Context())
+		if err != nil {
+			// Pages still work without branding, so fall back to the defaults
+			// rather than fail the request.
+			log.Printf("failed to read branding settings: %v", err)
+			branding = picoshare.BrandingSettings{}
+		}
+		ctx := context.WithValue(r.Context(), contextKeyBranding, branding)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func brandingFromContext(ctx context.Context) picoshare.BrandingSettings {
+	b, ok := ctx.Value(contextKeyBranding).(picoshare.BrandingSettings)
+	if !ok {
+		return picoshare.BrandingSettings{}
+	}
+	return b
+}
+
+// brandedTitle replaces the default instance name in a page title with the
+// instance's name.
+func brandedTitle(title string, b picoshare.BrandingSettings) string {
+	if b.Name == "" {
+		return title
+	}
+	return strings.Replace(title, "PicoShare", b.Name, 1)
+}
+
+func (s Server) brandingLogoGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logo, err := s.getDB(r).ReadBrandingLogo(r.Context())
+		if err != nil {
+			log.Printf("failed to read branding logo: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to read branding logo: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if logo.Empty() {
+			http.Error(w, "No logo", http.StatusNotFound)
+			return
+		}
+
+		// The logo can change at any time, so browsers must check that their
+		// cached copy is still current.
+		sum := sha256.Sum256(logo.Data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Content-Type", logo.ContentType.String())
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(logo.Data))
+	}
+}
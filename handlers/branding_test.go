@@ -0,0 +1,166 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+)
+
+// dummyPNG is the signature and header of a PNG image, which is enough for
+// content type detection.
+var dummyPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newStoreWithBrandingLogo(t *testing.T) sqlite.Store {
+	dataStore := test_sqlite.New()
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		Branding: picoshare.BrandingSettings{
+			Name: "Acme Files",
+			Logo: picoshare.BrandingLogo{
+				ContentType: picoshare.ContentType("image/png"),
+				Data:        dummyPNG,
+			},
+			AccentColor: "#1a73e8",
+		},
+	}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+	return dataStore
+}
+
+func TestBrandingLogoGet(t *testing.T) {
+	t.Run("returns 404 when there's no logo", func(t *testing.T) {
+		dataStore := test_sqlite.New()
+		s := handlers.New(handlers.Options{
+			Authenticator: mockAuthenticator{},
+			Store:         &dataStore,
+		})
+
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/branding/logo", nil))
+
+		if got, want := rec.Result().StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("status=%d, want=%d", got, want)
+		}
+	})
+
+	t.Run("serves the logo and revalidates cached copies", func(t *testing.T) {
+		dataStore := newStoreWithBrandingLogo(t)
+		s := handlers.New(handlers.Options{
+			Authenticator: mockAuthenticator{},
+			Store:         &dataStore,
+		})
+
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/branding/logo", nil))
+		res := rec.Result()
+
+		if got, want := res.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("status=%d, want=%d", got, want)
+		}
+		if got, want := res.Header.Get("Content-Type"), "image/png"; got != want {
+			t.Errorf("Content-Type=%v, want=%v", got, want)
+		}
+		if got, want := rec.Body.String(), string(dummyPNG); got != want {
+			t.Errorf("body=%q, want=%q", got, want)
+		}
+		etag := res.Header.Get("ETag")
+		if etag == "" {
+			t.Fatalf("response has no ETag")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/branding/logo", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, req)
+		if got, want := rec.Result().StatusCode, http.StatusNotModified; got != want {
+			t.Errorf("revalidation status=%d, want=%d", got, want)
+		}
+	})
+}
+
+func TestBrandingAppearsOnPages(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		branded     bool
+		path        string
+		want        []string
+		wantMissing []string
+	}{
+		{
+			description: "owner pages show the default branding",
+			branded:     false,
+			path:        "/",
+			want:        []string{"<title>PicoShare - Upload</title>"},
+			wantMissing: []string{"/branding/logo", "--accent-color", `class="branded"`},
+		},
+		{
+			description: "owner pages show custom branding",
+			branded:     true,
+			path:        "/",
+			want: []string{
+				"<title>Acme Files - Upload</title>",
+				`src="/branding/logo"`,
+				"--accent-color: #1a73e8;",
+				`class="branded"`,
+			},
+			wantMissing: []string{"<title>PicoShare - Upload</title>"},
+		},
+		{
+			description: "settings page offers to remove the current logo",
+			branded:     true,
+			path:        "/settings",
+			want: []string{
+				`id="brand-logo-preview"`,
+				`id="remove-brand-logo"`,
+			},
+		},
+		{
+			description: "guest pages show custom branding",
+			branded:     true,
+			path:        "/g/" + dummyGuestLinkID.String(),
+			want: []string{
+				`src="/branding/logo"`,
+				"Acme Files",
+				"--accent-color: #1a73e8;",
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if tt.branded {
+				dataStore = newStoreWithBrandingLogo(t)
+			}
+			mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{})
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("page doesn't contain %q", want)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("page unexpectedly contains %q", missing)
+				}
+			}
+		})
+	}
+}
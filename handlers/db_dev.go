@@ -75,7 +75,7 @@ func dbPerSessionPost() http.HandlerFunc {
 // performs this action on a regular schedule.
 func (s *Server) cleanupPost() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := s.collector.Collect(); err != nil {
+		if err := s.collector.Collect(r.Context()); err != nil {
 			log.Printf("garbage collection failed: %v", err)
 			http.Error(w, fmt.Sprintf("garbage collection failed: %v", err), http.StatusInternalServerError)
 			return
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_session"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/webhook"
 )
 
 func (s Server) entryDelete() http.HandlerFunc {
@@ -17,11 +22,89 @@ func (s Server) entryDelete() http.HandlerFunc {
 			return
 		}
 
-		err = s.getDB(r).DeleteEntry(id)
+		// Read the entry before we delete it so that the webhook can describe it.
+		entry, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
+		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
+			entry = picoshare.UploadMetadata{}
+		} else if err != nil {
+			log.Printf("failed to read metadata of entry %v: %v", id, err)
+			http.Error(w, "failed to delete entry", http.StatusInternalServerError)
+			return
+		}
+
+		err = s.getDB(r).DeleteEntry(r.Context(), id)
+		if err != nil {
+			log.Printf("failed to delete entry %v: %v", id, err)
+			http.Error(w, "failed to delete entry", http.StatusInternalServerError)
+			return
+		}
+
+		if entry.ID != "" {
+			s.queueWebhook(r, webhook.EntryEvent(picoshare.WebhookEventEntryDeleted, entry, s.clock.Now()))
+		}
+	}
+}
+
+func (s Server) guestEntryDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guestLinkID, err := parseGuestLinkID(mux.Vars(r)["guestLinkID"])
 		if err != nil {
+			log.Printf("error parsing guest link ID: %v", err)
+			http.Error(w, fmt.Sprintf("Invalid guest link ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		id, err := parseEntryID(mux.Vars(r)["id"])
+		if err != nil {
+			log.Printf("error parsing ID: %v", err)
+			http.Error(w, fmt.Sprintf("bad entry ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), guestLinkID)
+		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+			http.Error(w, "Invalid guest link ID", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("error retrieving guest link with ID %v: %v", guestLinkID, err)
+			http.Error(w, "Failed to retrieve guest link", http.StatusInternalServerError)
+			return
+		}
+
+		if !s.guestLinkUnlocker.Authenticate(r, gl) {
+			http.Error(w, "Guest link requires a passphrase", http.StatusUnauthorized)
+			return
+		}
+
+		if !gl.CanDeleteUploads() {
+			http.Error(w, "Guest link doesn't allow guests to delete files", http.StatusForbidden)
+			return
+		}
+
+		entry, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
+		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
+			http.Error(w, "entry not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("failed to read metadata of entry %v: %v", id, err)
+			http.Error(w, "failed to delete entry", http.StatusInternalServerError)
+			return
+		}
+
+		// Respond as if the entry doesn't exist so that guests can't probe for
+		// files that they didn't upload.
+		if entry.GuestLink.ID != gl.ID || !guest_session.Owns(r, gl, entry) {
+			http.Error(w, "entry not found", http.StatusNotFound)
+			return
+		}
+
+		if err := s.getDB(r).DeleteEntry(r.Context(), id); err != nil {
 			log.Printf("failed to delete entry %v: %v", id, err)
 			http.Error(w, "failed to delete entry", http.StatusInternalServerError)
 			return
 		}
+
+		s.queueWebhook(r, webhook.EntryEvent(picoshare.WebhookEventEntryDeleted, entry, s.clock.Now()))
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -1,32 +1,32 @@
 package handlers_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
-	"github.com/mtlynch/picoshare/garbagecollect"
 	"github.com/mtlynch/picoshare/handlers"
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/store"
 	"github.com/mtlynch/picoshare/store/test_sqlite"
 )
 
-var nilSpaceChecker handlers.SpaceChecker
-var nilGarbageCollector *garbagecollect.Collector
-
 func TestDeleteExistingFile(t *testing.T) {
 	dataStore := test_sqlite.New()
 	fileContents := "dummy data"
-	dataStore.InsertEntry(strings.NewReader(fileContents),
+	dataStore.InsertEntry(context.Background(), strings.NewReader(fileContents),
 		picoshare.UploadMetadata{
 			ID:       picoshare.EntryID("hR87apiUCj"),
 			Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
 			Expires:  mustParseExpirationTime("2024-01-01T00:00:00Z"),
 			Size:     mustParseFileSize(len(fileContents)),
 		})
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/entry/hR87apiUCj", nil)
 
@@ -39,7 +39,7 @@ func TestDeleteExistingFile(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	_, err := dataStore.GetEntryMetadata(picoshare.EntryID("hR87apiUCj"))
+	_, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("hR87apiUCj"))
 	if _, ok := err.(store.EntryNotFoundError); !ok {
 		t.Fatalf("expected entry %v to be deleted", picoshare.EntryID("hR87apiUCj"))
 	}
@@ -47,7 +47,10 @@ func TestDeleteExistingFile(t *testing.T) {
 
 func TestDeleteNonExistentFile(t *testing.T) {
 	dataStore := test_sqlite.New()
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/entry/hR87apiUCj", nil)
 
@@ -64,7 +67,10 @@ func TestDeleteNonExistentFile(t *testing.T) {
 
 func TestDeleteInvalidEntryID(t *testing.T) {
 	dataStore := test_sqlite.New()
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/entry/invalid-entry-id", nil)
 
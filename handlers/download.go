@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -14,18 +17,33 @@ import (
 
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/webhook"
 )
 
 func (s Server) entryGet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := parseEntryID(mux.Vars(r)["id"])
 		if err != nil {
-			log.Printf("error parsing ID: %v", err)
-			http.Error(w, fmt.Sprintf("bad entry ID: %v", err), http.StatusBadRequest)
-			return
+			// Entries can also have a custom slug in place of their ID.
+			slug, slugErr := parseEntrySlug(mux.Vars(r)["id"])
+			if slugErr != nil || slug.Empty() {
+				log.Printf("error parsing ID: %v", err)
+				http.Error(w, fmt.Sprintf("bad entry ID: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			id, err = s.getDB(r).GetEntryIDBySlug(r.Context(), slug)
+			if _, ok := errors.AsType[store.EntrySlugNotFoundError](err); ok {
+				http.Error(w, "entry not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				log.Printf("error retrieving entry with slug %v: %v", slug, err)
+				http.Error(w, "failed to retrieve entry", http.StatusInternalServerError)
+				return
+			}
 		}
 
-		entry, err := s.getDB(r).GetEntryMetadata(id)
+		entry, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
 		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 			http.Error(w, "entry not found", http.StatusNotFound)
 			return
@@ -47,8 +65,19 @@ func (s Server) entryGet() http.HandlerFunc {
 		}
 		w.Header().Set("Content-Type", contentType.String())
 
-		entryFile, err := s.getDB(r).ReadEntryFile(id)
-		if err != nil {
+		if !entry.SHA256.Empty() {
+			if err := setDigestHeaders(w, entry.SHA256); err != nil {
+				log.Printf("invalid checksum for entry %v: %v", id, err)
+			}
+		}
+
+		entryFile, err := s.getDB(r).ReadEntryFile(r.Context(), id)
+		if _, ok := errors.AsType[store.EntryQuarantinedError](err); ok {
+			// Refuse to serve the file rather than send the client a damaged copy.
+			log.Printf("refusing to serve quarantined entry %v", id)
+			http.Error(w, "file is unavailable because its data is corrupted", http.StatusInternalServerError)
+			return
+		} else if err != nil {
 			log.Printf("error retrieving entry data with id %v: %v", id, err)
 			http.Error(w, "failed to retrieve entry", http.StatusInternalServerError)
 			return
@@ -56,12 +85,34 @@ func (s Server) entryGet() http.HandlerFunc {
 
 		http.ServeContent(w, r, entry.Filename.String(), entry.Uploaded, entryFile)
 
-		if err := recordDownload(s.getDB(r), entry.ID, s.clock.Now(), r.RemoteAddr, r.Header.Get("User-Agent")); err != nil {
+		// Finish recording the download even if the client disconnects as soon as
+		// it receives the file.
+		r = r.WithContext(context.WithoutCancel(r.Context()))
+
+		if download, err := recordDownload(r.Context(), s.getDB(r), entry.ID, s.clock.Now(), r.RemoteAddr, r.Header.Get("User-Agent")); err != nil {
 			log.Printf("failed to record download of file %s: %v", id.String(), err)
+		} else {
+			s.queueWebhook(r, webhook.DownloadEvent(entry, download))
 		}
+
+		s.notifyIfFirstDownload(r, entry)
 	}
 }
 
+// setDigestHeaders advertises the checksum of the full file so that clients can
+// verify their download. We send both the current Repr-Digest header (RFC 9530)
+// and the older Digest header (RFC 3230) that it replaced.
+func setDigestHeaders(w http.ResponseWriter, checksum picoshare.SHA256Checksum) error {
+	raw, err := hex.DecodeString(checksum.String())
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	w.Header().Set("Repr-Digest", fmt.Sprintf("sha-256=:%s:", encoded))
+	w.Header().Set("Digest", fmt.Sprintf("SHA-256=%s", encoded))
+	return nil
+}
+
 func inferContentTypeFromFilename(f picoshare.Filename) (picoshare.ContentType, error) {
 	// For files that modern browser can play natively, infer the content type if
 	// none was specified at upload time.
@@ -71,15 +122,20 @@ func inferContentTypeFromFilename(f picoshare.Filename) (picoshare.ContentType,
 	return picoshare.ContentType(""), errors.New("could not infer content type from filename")
 }
 
-func recordDownload(db Store, id picoshare.EntryID, t time.Time, remoteAddr, userAgent string) error {
+func recordDownload(ctx context.Context, db Store, id picoshare.EntryID, t time.Time, remoteAddr, userAgent string) (picoshare.DownloadRecord, error) {
 	ip, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
 		ip = remoteAddr
 	}
 
-	return db.InsertEntryDownload(id, picoshare.DownloadRecord{
+	download := picoshare.DownloadRecord{
 		Time:      t,
 		ClientIP:  ip,
 		UserAgent: userAgent,
-	})
+	}
+	if err := db.InsertEntryDownload(ctx, id, download); err != nil {
+		return picoshare.DownloadRecord{}, err
+	}
+
+	return download, nil
 }
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +14,7 @@ import (
 
 type mockEntry struct {
 	ID          picoshare.EntryID
+	Slug        picoshare.EntrySlug
 	Filename    picoshare.Filename
 	ContentType picoshare.ContentType
 }
@@ -20,6 +22,7 @@ type mockEntry struct {
 var (
 	dummyTextEntry = mockEntry{
 		ID:          "TTTTTTTTTT",
+		Slug:        "test-notes",
 		Filename:    picoshare.Filename("test.txt"),
 		ContentType: picoshare.ContentType("text/plain;charset=utf-8"),
 	}
@@ -106,6 +109,40 @@ func TestEntryGet(t *testing.T) {
 			expectedContentType:        "text/html",
 			expectedCSP:                "sandbox",
 		},
+		{
+			description:                "retrieves entry by its slug",
+			requestRoute:               "/-test-notes",
+			expectedStatus:             http.StatusOK,
+			expectedContentDisposition: `filename="test.txt"`,
+			expectedContentType:        "text/plain;charset=utf-8",
+			expectedCSP:                "sandbox",
+		},
+		{
+			description:                "retrieves entry by its slug regardless of case",
+			requestRoute:               "/-Test-Notes",
+			expectedStatus:             http.StatusOK,
+			expectedContentDisposition: `filename="test.txt"`,
+			expectedContentType:        "text/plain;charset=utf-8",
+			expectedCSP:                "sandbox",
+		},
+		{
+			description:                "retrieves entry by its slug and filename",
+			requestRoute:               "/-test-notes/test.txt",
+			expectedStatus:             http.StatusOK,
+			expectedContentDisposition: `filename="test.txt"`,
+			expectedContentType:        "text/plain;charset=utf-8",
+			expectedCSP:                "sandbox",
+		},
+		{
+			description:    "request for non-existent slug returns 404",
+			requestRoute:   "/-missing-notes",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description:    "request for invalid entry ID returns 400",
+			requestRoute:   "/-bad_id",
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			description:    "request for non-existent entry returns 404",
 			requestRoute:   "/-ZZZZZZZZZZ",
@@ -127,6 +164,7 @@ func TestEntryGet(t *testing.T) {
 				entry := picoshare.UploadEntry{
 					UploadMetadata: picoshare.UploadMetadata{
 						ID:          mockEntry.ID,
+						Slug:        mockEntry.Slug,
 						Filename:    mockEntry.Filename,
 						ContentType: mockEntry.ContentType,
 						Uploaded:    mustParseTime("2023-01-01T00:00:00Z"),
@@ -135,12 +173,15 @@ func TestEntryGet(t *testing.T) {
 					},
 					Reader: strings.NewReader(data),
 				}
-				if err := dataStore.InsertEntry(entry.Reader, entry.UploadMetadata); err != nil {
+				if err := dataStore.InsertEntry(context.Background(), entry.Reader, entry.UploadMetadata); err != nil {
 					panic(err)
 				}
 			}
 
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
 
 			req := httptest.NewRequest(http.MethodGet, tt.requestRoute, nil)
 
@@ -168,6 +209,106 @@ func TestEntryGet(t *testing.T) {
 			if got, want := res.Header.Get("Content-Security-Policy"), tt.expectedCSP; got != want {
 				t.Errorf("Content-Security-Policy=%s, want=%s", got, want)
 			}
+
+			// Every entry has the same contents, so they all have the same digest.
+			if got, want := res.Header.Get("Repr-Digest"), "sha-256=:eXuwq/95jXIAr3aF3KeQHt/8Ur8mUA1b2XKCZY7iQVI=:"; got != want {
+				t.Errorf("Repr-Digest=%s, want=%s", got, want)
+			}
+
+			if got, want := res.Header.Get("Digest"), "SHA-256=eXuwq/95jXIAr3aF3KeQHt/8Ur8mUA1b2XKCZY7iQVI="; got != want {
+				t.Errorf("Digest=%s, want=%s", got, want)
+			}
 		})
 	}
 }
+
+func TestEntryGetRefusesQuarantinedEntry(t *testing.T) {
+	dataStore := test_sqlite.New()
+
+	data := "dummy data"
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), picoshare.UploadMetadata{
+		ID:       dummyTextEntry.ID,
+		Filename: dummyTextEntry.Filename,
+		Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:  picoshare.NeverExpire,
+		Size:     mustParseFileSize(len(data)),
+	}); err != nil {
+		panic(err)
+	}
+	if err := dataStore.InsertCorruptEntry(context.Background(), picoshare.CorruptEntry{
+		ID:          dummyTextEntry.ID,
+		Detected:    mustParseTime("2024-01-01T00:00:00Z"),
+		Reason:      "chunk 1 is missing",
+		Quarantined: true,
+	}); err != nil {
+		panic(err)
+	}
+
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/-TTTTTTTTTT", nil)
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusInternalServerError; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+}
+
+// disconnectingRecorder simulates a client that disconnects as soon as it
+// receives the response body.
+type disconnectingRecorder struct {
+	*httptest.ResponseRecorder
+	disconnect context.CancelFunc
+}
+
+func (r disconnectingRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(b)
+	r.disconnect()
+	return n, err
+}
+
+func TestEntryGetRecordsDownloadAfterClientDisconnects(t *testing.T) {
+	dataStore := test_sqlite.New()
+
+	data := "dummy data"
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), picoshare.UploadMetadata{
+		ID:       dummyTextEntry.ID,
+		Filename: dummyTextEntry.Filename,
+		Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:  picoshare.NeverExpire,
+		Size:     mustParseFileSize(len(data)),
+	}); err != nil {
+		panic(err)
+	}
+
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/-TTTTTTTTTT", nil)
+	rec := disconnectingRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		disconnect:       cancel,
+	}
+	s.Router().ServeHTTP(rec, req)
+
+	if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+
+	downloads, err := dataStore.GetEntryDownloads(context.Background(), dummyTextEntry.ID)
+	if err != nil {
+		t.Fatalf("failed to retrieve downloads: %v", err)
+	}
+	if got, want := len(downloads), 1; got != want {
+		t.Errorf("downloads=%d, want=%d", got, want)
+	}
+}
@@ -1,17 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
 	"github.com/mtlynch/picoshare/handlers/parse"
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/random"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/webhook"
 )
 
 const (
@@ -28,7 +33,7 @@ var guestLinkIDCharacters = []rune("abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTU
 
 func (s Server) guestLinksPost() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		gl, err := s.guestLinkFromRequest(r)
+		gl, err := s.guestLinkFromRequest(r, picoshare.GuestLink{})
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 			return
@@ -37,12 +42,14 @@ func (s Server) guestLinksPost() http.HandlerFunc {
 		gl.ID = generateGuestLinkID()
 		gl.Created = s.clock.Now()
 
-		if err := s.getDB(r).InsertGuestLink(gl); err != nil {
+		if err := s.getDB(r).InsertGuestLink(r.Context(), gl); err != nil {
 			log.Printf("failed to save guest link: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to save guest link: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		s.queueWebhook(r, webhook.GuestLinkEvent(picoshare.WebhookEventGuestLinkCreated, gl, s.clock.Now()))
+
 		respondJSON(w, GuestLinkPostResponse{ID: gl.ID.String()})
 	}
 }
@@ -56,7 +63,7 @@ func (s Server) guestLinksDelete() http.HandlerFunc {
 			return
 		}
 
-		if err := s.getDB(r).DeleteGuestLink(id); err != nil {
+		if err := s.getDB(r).DeleteGuestLink(r.Context(), id); err != nil {
 			log.Printf("failed to delete guest link: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to delete guest link: %v", err), http.StatusInternalServerError)
 			return
@@ -73,37 +80,109 @@ func (s *Server) guestLinksEnableDisable() http.HandlerFunc {
 			return
 		}
 
-		if _, err := s.getDB(r).GetGuestLink(id); err != nil {
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), id)
+		if err != nil {
 			log.Printf("failed to get guest link ID %s: %v", mux.Vars(r)["id"], err)
 			http.Error(w, fmt.Sprintf("Guest link with ID %s not found: %v", mux.Vars(r)["id"], err), http.StatusNotFound)
 			return
 		}
 
 		// Determine if client is enabling or disabling link.
-		var dbFn func(picoshare.GuestLinkID) error
-		if strings.HasSuffix(r.URL.Path, "/enable") {
+		var dbFn func(context.Context, picoshare.GuestLinkID) error
+		enable := strings.HasSuffix(r.URL.Path, "/enable")
+		if enable {
 			dbFn = s.getDB(r).EnableGuestLink
 		} else {
 			dbFn = s.getDB(r).DisableGuestLink
 		}
 
-		if err := dbFn(id); err != nil {
+		if err := dbFn(r.Context(), id); err != nil {
 			log.Printf("failed to change guest link enabled state: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to change guest link enabled state: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		if !enable && !gl.IsDisabled {
+			gl.IsDisabled = true
+			s.queueWebhook(r, webhook.GuestLinkEvent(picoshare.WebhookEventGuestLinkDisabled, gl, s.clock.Now()))
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func (s Server) guestLinkFromRequest(r *http.Request) (picoshare.GuestLink, error) {
+func (s Server) guestLinksPut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseGuestLinkID(mux.Vars(r)["id"])
+		if err != nil {
+			log.Printf("failed to parse guest link ID %s: %v", mux.Vars(r)["id"], err)
+			http.Error(w, fmt.Sprintf("Invalid guest link ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		current, err := s.getDB(r).GetGuestLink(r.Context(), id)
+		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+			http.Error(w, "Invalid guest link ID", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("failed to get guest link ID %s: %v", id, err)
+			http.Error(w, "Failed to retrieve guest link", http.StatusInternalServerError)
+			return
+		}
+
+		gl, err := s.guestLinkFromRequest(r, current)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := checkGuestLinkLimitsAllowUploads(gl, current); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		gl.LastModified = s.clock.Now()
+
+		if err := s.getDB(r).UpdateGuestLink(r.Context(), id, gl); err != nil {
+			if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+				http.Error(w, "Invalid guest link ID", http.StatusNotFound)
+				return
+			}
+			log.Printf("failed to update guest link: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to update guest link: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// checkGuestLinkLimitsAllowUploads verifies that an edited guest link's limits
+// still accommodate the files that guests have already uploaded through it.
+func checkGuestLinkLimitsAllowUploads(edited, current picoshare.GuestLink) error {
+	if edited.MaxFileUploads != picoshare.GuestUploadUnlimitedFileUploads && *edited.MaxFileUploads < current.FilesUploaded {
+		return fmt.Errorf("upload limit (%d) is lower than the number of files guests have already uploaded (%d)", *edited.MaxFileUploads, current.FilesUploaded)
+	}
+	if edited.MaxTotalBytes != picoshare.GuestUploadUnlimitedTotalBytes && *edited.MaxTotalBytes < current.BytesUploaded {
+		return fmt.Errorf("storage quota (%d bytes) is lower than the amount guests have already uploaded (%d bytes)", *edited.MaxTotalBytes, current.BytesUploaded)
+	}
+	return nil
+}
+
+// guestLinkFromRequest parses a guest link from the request body. If current is
+// non-empty, the request is an edit to that existing guest link.
+func (s Server) guestLinkFromRequest(r *http.Request, current picoshare.GuestLink) (picoshare.GuestLink, error) {
 	var payload struct {
-		Label          string  `json:"label"`
-		UrlExpiration  string  `json:"urlExpirationTime"`
-		FileExpiration string  `json:"fileLifetime"`
-		MaxFileBytes   *uint64 `json:"maxFileBytes"`
-		MaxFileUploads *int    `json:"maxFileUploads"`
+		Label              string   `json:"label"`
+		UrlExpiration      string   `json:"urlExpirationTime"`
+		FileExpiration     string   `json:"fileLifetime"`
+		MaxFileBytes       *uint64  `json:"maxFileBytes"`
+		MaxFileUploads     *int     `json:"maxFileUploads"`
+		MaxTotalBytes      *uint64  `json:"maxTotalBytes"`
+		Passphrase         *string  `json:"passphrase"`
+		AllowedFileTypes   []string `json:"allowedFileTypes"`
+		AllowSenderDetails bool     `json:"allowSenderDetails"`
+		UploadListing      string   `json:"uploadListing"`
+		Instructions       string   `json:"instructions"`
+		UploadWindow       string   `json:"uploadWindow"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
@@ -117,6 +196,12 @@ func (s Server) guestLinkFromRequest(r *http.Request) (picoshare.GuestLink, erro
 	}
 
 	urlExpiration, err := parse.Expiration(payload.UrlExpiration, s.clock.Now())
+	if errors.Is(err, parse.ErrExpirationTooSoon) && !current.Empty() && isSameExpiration(payload.UrlExpiration, current.UrlExpires) {
+		// Let admins keep a link's current expiration time, even if it's
+		// imminent or has passed, so that they can edit the link's other
+		// settings without extending it.
+		urlExpiration, err = current.UrlExpires, nil
+	}
 	if err != nil {
 		return picoshare.GuestLink{}, err
 	}
@@ -136,15 +221,57 @@ func (s Server) guestLinkFromRequest(r *http.Request) (picoshare.GuestLink, erro
 		return picoshare.GuestLink{}, err
 	}
 
+	maxTotalBytes, err := parseMaxTotalBytes(payload.MaxTotalBytes)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	passphraseHash, err := parsePassphrase(payload.Passphrase, current.PassphraseHash)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	allowedFileTypes, err := parse.GuestUploadFileTypes(payload.AllowedFileTypes)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	uploadListing, err := parse.GuestUploadListing(payload.UploadListing)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	instructions, err := parse.GuestLinkInstructions(payload.Instructions)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	uploadWindow, err := parse.GuestLinkUploadWindow(payload.UploadWindow)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
 	return picoshare.GuestLink{
-		Label:           label,
-		UrlExpires:      urlExpiration,
-		MaxFileLifetime: fileExpiration,
-		MaxFileBytes:    maxFileBytes,
-		MaxFileUploads:  maxFileUploads,
+		Label:              label,
+		UrlExpires:         urlExpiration,
+		MaxFileLifetime:    fileExpiration,
+		MaxFileBytes:       maxFileBytes,
+		MaxFileUploads:     maxFileUploads,
+		MaxTotalBytes:      maxTotalBytes,
+		PassphraseHash:     passphraseHash,
+		AllowedFileTypes:   allowedFileTypes,
+		AllowSenderDetails: payload.AllowSenderDetails,
+		UploadListing:      uploadListing,
+		Instructions:       instructions,
+		UploadWindow:       uploadWindow,
 	}, nil
 }
 
+func isSameExpiration(raw string, et picoshare.ExpirationTime) bool {
+	t, err := time.Parse(time.RFC3339, raw)
+	return err == nil && t.Equal(et.Time())
+}
+
 func parseMaxFileBytes(limitRaw *uint64) (picoshare.GuestUploadMaxFileBytes, error) {
 	if limitRaw == nil {
 		return picoshare.GuestUploadUnlimitedFileSize, nil
@@ -156,6 +283,35 @@ func parseMaxFileBytes(limitRaw *uint64) (picoshare.GuestUploadMaxFileBytes, err
 	return picoshare.GuestUploadMaxFileBytes(limitRaw), nil
 }
 
+func parseMaxTotalBytes(limitRaw *uint64) (picoshare.GuestUploadMaxTotalBytes, error) {
+	if limitRaw == nil {
+		return picoshare.GuestUploadUnlimitedTotalBytes, nil
+	}
+	if *limitRaw < GuestLinkByteLimitMinimum {
+		return nil, fmt.Errorf("guest storage quota must be at least %d bytes", GuestLinkByteLimitMinimum)
+	}
+
+	return picoshare.GuestUploadMaxTotalBytes(limitRaw), nil
+}
+
+// parsePassphrase hashes a new guest link passphrase. A nil passphrase keeps
+// the current one, and an empty passphrase removes it.
+func parsePassphrase(passphraseRaw *string, current picoshare.GuestLinkPassphraseHash) (picoshare.GuestLinkPassphraseHash, error) {
+	if passphraseRaw == nil {
+		return current, nil
+	}
+	if *passphraseRaw == "" {
+		return picoshare.GuestLinkPassphraseHash(""), nil
+	}
+
+	passphrase, err := parse.GuestLinkPassphrase(*passphraseRaw)
+	if err != nil {
+		return picoshare.GuestLinkPassphraseHash(""), err
+	}
+
+	return guest_passphrase.Hash(passphrase)
+}
+
 func parseUploadCountLimit(limitRaw *int) (picoshare.GuestUploadCountLimit, error) {
 	if limitRaw == nil {
 		return picoshare.GuestUploadUnlimitedFileUploads, nil
@@ -167,6 +323,49 @@ func parseUploadCountLimit(limitRaw *int) (picoshare.GuestUploadCountLimit, erro
 	return picoshare.GuestUploadCountLimit(limitRaw), nil
 }
 
+func (s Server) guestLinkUnlockPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guestLinkID, err := parseGuestLinkID(mux.Vars(r)["guestLinkID"])
+		if err != nil {
+			log.Printf("error parsing guest link ID: %v", err)
+			http.Error(w, fmt.Sprintf("Invalid guest link ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), guestLinkID)
+		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+			http.Error(w, "Invalid guest link ID", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("error retrieving guest link with ID %v: %v", guestLinkID, err)
+			http.Error(w, "Failed to retrieve guest link", http.StatusInternalServerError)
+			return
+		}
+
+		var payload struct {
+			Passphrase string `json:"passphrase"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("failed to decode JSON request: %v", err)
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if !gl.IsPassphraseProtected() {
+			http.Error(w, "Guest link does not require a passphrase", http.StatusBadRequest)
+			return
+		}
+
+		if !guest_passphrase.Verify(gl, payload.Passphrase) {
+			http.Error(w, "Incorrect passphrase", http.StatusUnauthorized)
+			return
+		}
+
+		s.guestLinkUnlocker.StartSession(w, gl)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func generateGuestLinkID() picoshare.GuestLinkID {
 	return picoshare.GuestLinkID(random.String(GuestLinkIDLength, guestLinkIDCharacters))
 }
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/store"
 	"github.com/mtlynch/picoshare/store/test_sqlite"
@@ -105,6 +107,28 @@ func TestGuestLinksPost(t *testing.T) {
 			},
 			status: http.StatusOK,
 		},
+		{
+			description: "guest link with a storage quota",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"maxTotalBytes": 52428800
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				Label:           picoshare.GuestLinkLabel(""),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				MaxTotalBytes:   makeGuestUploadMaxTotalBytes(52428800),
+			},
+			status: http.StatusOK,
+		},
 		{
 			description: "empty string",
 			payload:     "",
@@ -194,6 +218,19 @@ func TestGuestLinksPost(t *testing.T) {
 				}`,
 			status: http.StatusBadRequest,
 		},
+		{
+			description: "maxTotalBytes field below minimum",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"maxTotalBytes": 1024
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			status:      http.StatusBadRequest,
+		},
 		{
 			description: "negative maxFileUploads field",
 			payload: `{
@@ -214,6 +251,152 @@ func TestGuestLinksPost(t *testing.T) {
 				}`,
 			status: http.StatusBadRequest,
 		},
+		{
+			description: "request with allowed file types",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"allowedFileTypes": [".PDF", " application/pdf ", "", ".pdf"]
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:          mustParseTime("2024-01-01T00:00:00Z"),
+				Label:            picoshare.GuestLinkLabel(""),
+				UrlExpires:       mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime:  picoshare.FileLifetimeInfinite,
+				MaxFileBytes:     picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:   picoshare.GuestUploadUnlimitedFileUploads,
+				AllowedFileTypes: picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "request that allows sender details",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"allowSenderDetails": true
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:            mustParseTime("2024-01-01T00:00:00Z"),
+				Label:              picoshare.GuestLinkLabel(""),
+				UrlExpires:         mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime:    picoshare.FileLifetimeInfinite,
+				MaxFileBytes:       picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:     picoshare.GuestUploadUnlimitedFileUploads,
+				AllowSenderDetails: true,
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "request that lists uploads from the guest's session",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"uploadListing": "session"
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				Label:           picoshare.GuestLinkLabel(""),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				UploadListing:   picoshare.GuestUploadListingSession,
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "request with instructions for guests",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"instructions": "  Please upload your **tax forms**.\n"
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				Label:           picoshare.GuestLinkLabel(""),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				Instructions:    picoshare.GuestLinkInstructions("Please upload your **tax forms**."),
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "request with upload window",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"uploadWindow": "24h0m0s"
+				}`,
+			currentTime: mustParseTime("2024-01-01T00:00:00Z"),
+			expected: picoshare.GuestLink{
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				Label:           picoshare.GuestLinkLabel(""),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				UploadWindow:    picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "upload window shorter than an hour",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"uploadWindow": "5m"
+				}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			description: "invalid upload listing",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"uploadListing": "everyone"
+				}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			description: "invalid allowed file type",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"allowedFileTypes": ["pdf"]
+				}`,
+			status: http.StatusBadRequest,
+		},
 		{
 			description: "zero maxFileUploads field",
 			payload: `{
@@ -228,7 +411,11 @@ func TestGuestLinksPost(t *testing.T) {
 		t.Run(tt.description, func(t *testing.T) {
 			dataStore := test_sqlite.New()
 			c := mockClock{tt.currentTime}
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, c)
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
 
 			req := httptest.NewRequest(
 				http.MethodPost,
@@ -260,7 +447,7 @@ func TestGuestLinksPost(t *testing.T) {
 				t.Fatalf("response is not valid JSON: %v", body)
 			}
 
-			gl, err := dataStore.GetGuestLink(picoshare.GuestLinkID(response.ID))
+			gl, err := dataStore.GetGuestLink(context.Background(), picoshare.GuestLinkID(response.ID))
 			if err != nil {
 				t.Fatalf("failed to retrieve guest link from datastore: %v", err)
 			}
@@ -279,18 +466,25 @@ func makeGuestUploadMaxFileBytes(i uint64) picoshare.GuestUploadMaxFileBytes {
 	return picoshare.GuestUploadMaxFileBytes(&i)
 }
 
+func makeGuestUploadMaxTotalBytes(i uint64) picoshare.GuestUploadMaxTotalBytes {
+	return picoshare.GuestUploadMaxTotalBytes(&i)
+}
+
 func makeGuestUploadCountLimit(i int) picoshare.GuestUploadCountLimit {
 	return picoshare.GuestUploadCountLimit(&i)
 }
 
 func TestDeleteExistingGuestLink(t *testing.T) {
 	dataStore := test_sqlite.New()
-	dataStore.InsertGuestLink(picoshare.GuestLink{
+	dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
 		ID:         picoshare.GuestLinkID("abcdefgh23456789"),
 		Created:    mustParseTime("2025-05-25T00:00:00Z"),
 		UrlExpires: mustParseExpirationTime("2030-01-02T03:04:25Z"),
 	})
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -306,7 +500,7 @@ func TestDeleteExistingGuestLink(t *testing.T) {
 		t.Fatalf("status=%d, want=%d", got, want)
 	}
 
-	_, err := dataStore.GetGuestLink(picoshare.GuestLinkID("dummy-guest-link-id"))
+	_, err := dataStore.GetGuestLink(context.Background(), picoshare.GuestLinkID("dummy-guest-link-id"))
 	if _, ok := err.(store.GuestLinkNotFoundError); !ok {
 		t.Fatalf("expected entry %v to be deleted, got: %v", picoshare.EntryID("abcdefgh23456789"), err)
 	}
@@ -314,7 +508,10 @@ func TestDeleteExistingGuestLink(t *testing.T) {
 
 func TestDeleteNonExistentGuestLink(t *testing.T) {
 	dataStore := test_sqlite.New()
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -334,7 +531,10 @@ func TestDeleteNonExistentGuestLink(t *testing.T) {
 
 func TestDeleteInvalidGuestLink(t *testing.T) {
 	dataStore := test_sqlite.New()
-	s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -475,12 +675,15 @@ func TestEnableDisableGuestLink(t *testing.T) {
 			dataStore := test_sqlite.New()
 
 			if !tt.guestLinkInStore.Empty() {
-				if err := dataStore.InsertGuestLink(tt.guestLinkInStore); err != nil {
+				if err := dataStore.InsertGuestLink(context.Background(), tt.guestLinkInStore); err != nil {
 					t.Fatalf("failed to insert dummy guest link: %v", err)
 				}
 			}
 
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
 
 			req := httptest.NewRequest(http.MethodPut, tt.requestRoute, nil)
 			rec := httptest.NewRecorder()
@@ -495,7 +698,7 @@ func TestEnableDisableGuestLink(t *testing.T) {
 				return
 			}
 
-			gl, err := dataStore.GetGuestLink(tt.guestLinkInStore.ID)
+			gl, err := dataStore.GetGuestLink(context.Background(), tt.guestLinkInStore.ID)
 			if err != nil {
 				t.Fatalf("failed to getGuestLink : %s", tt.guestLinkInStore.ID)
 			}
@@ -506,3 +709,573 @@ func TestEnableDisableGuestLink(t *testing.T) {
 		})
 	}
 }
+
+func TestGuestLinksPut(t *testing.T) {
+	largeUpload := strings.Repeat("A", 2*1024*1024)
+	smallUpload := "dummy upload"
+	bytesUploaded := uint64(len(largeUpload) + len(smallUpload))
+
+	originalLink := picoshare.GuestLink{
+		ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+		Label:           picoshare.GuestLinkLabel("For Joe"),
+		Created:         mustParseTime("2024-01-01T00:00:00Z"),
+		UrlExpires:      mustParseExpirationTime("2024-06-01T00:00:00Z"),
+		MaxFileLifetime: picoshare.FileLifetimeInfinite,
+		MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+		MaxFileUploads:  makeGuestUploadCountLimit(5),
+		MaxTotalBytes:   picoshare.GuestUploadUnlimitedTotalBytes,
+	}
+
+	for _, tt := range []struct {
+		description string
+		route       string
+		payload     string
+		expected    picoshare.GuestLink
+		status      int
+	}{
+		{
+			description: "updates every editable field",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": "For Jane",
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"168h0m0s",
+					"maxFileBytes": 5242880,
+					"maxFileUploads": 10,
+					"maxTotalBytes": 10485760,
+					"instructions": "Upload your *signed* contract.",
+					"uploadWindow": "168h0m0s"
+				}`,
+			expected: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Label:           picoshare.GuestLinkLabel("For Jane"),
+				Instructions:    picoshare.GuestLinkInstructions("Upload your *signed* contract."),
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				MaxFileBytes:    makeGuestUploadMaxFileBytes(5242880),
+				MaxFileUploads:  makeGuestUploadCountLimit(10),
+				MaxTotalBytes:   makeGuestUploadMaxTotalBytes(10485760),
+				UploadWindow:    picoshare.GuestLinkUploadWindow(7 * 24 * time.Hour),
+				FilesUploaded:   2,
+				BytesUploaded:   bytesUploaded,
+				FirstUpload:     mustParseTime("2024-02-01T00:00:00Z"),
+				LastModified:    mustParseTime("2025-01-01T00:00:00Z"),
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "keeps an expiration time that has already passed if it's unchanged",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": "For Jane",
+					"urlExpirationTime":"2024-06-01T00:00:00Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null
+				}`,
+			expected: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Label:           picoshare.GuestLinkLabel("For Jane"),
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:      mustParseExpirationTime("2024-06-01T00:00:00Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				MaxTotalBytes:   picoshare.GuestUploadUnlimitedTotalBytes,
+				FilesUploaded:   2,
+				BytesUploaded:   bytesUploaded,
+				FirstUpload:     mustParseTime("2024-02-01T00:00:00Z"),
+				LastModified:    mustParseTime("2025-01-01T00:00:00Z"),
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "allows an upload limit that equals the number of files uploaded",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": 2
+				}`,
+			expected: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  makeGuestUploadCountLimit(2),
+				MaxTotalBytes:   picoshare.GuestUploadUnlimitedTotalBytes,
+				FilesUploaded:   2,
+				BytesUploaded:   bytesUploaded,
+				FirstUpload:     mustParseTime("2024-02-01T00:00:00Z"),
+				LastModified:    mustParseTime("2025-01-01T00:00:00Z"),
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "rejects an upload limit below the number of files uploaded",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": 1
+				}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			description: "rejects a storage quota below the bytes uploaded",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"maxTotalBytes": 1048576
+				}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			description: "rejects a new expiration time in the past",
+			route:       "/api/guest-links/abcdefgh23456789",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2024-07-01T00:00:00Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null
+				}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			description: "rejects a non-existent guest link",
+			route:       "/api/guest-links/abcdefgh2345678X",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null
+				}`,
+			status: http.StatusNotFound,
+		},
+		{
+			description: "rejects an invalid guest link ID",
+			route:       "/api/guest-links/i-am-an-invalid-link",
+			payload: `{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null
+				}`,
+			status: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.InsertGuestLink(context.Background(), originalLink); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+			for i, contents := range []string{largeUpload, smallUpload} {
+				if err := dataStore.InsertEntry(context.Background(), strings.NewReader(contents), picoshare.UploadMetadata{
+					ID:        picoshare.EntryID(fmt.Sprintf("AAAAAAAAA%d", i)),
+					Filename:  picoshare.Filename(fmt.Sprintf("upload-%d.txt", i)),
+					Uploaded:  mustParseTime("2024-02-01T00:00:00Z"),
+					Expires:   picoshare.NeverExpire,
+					Size:      mustParseFileSize(len(contents)),
+					GuestLink: originalLink,
+				}); err != nil {
+					t.Fatalf("failed to insert dummy entry: %v", err)
+				}
+			}
+
+			c := mockClock{mustParseTime("2025-01-01T00:00:00Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			req := httptest.NewRequest(http.MethodPut, tt.route, strings.NewReader(tt.payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if tt.status != http.StatusOK {
+				gl, err := dataStore.GetGuestLink(context.Background(), originalLink.ID)
+				if err != nil {
+					t.Fatalf("failed to retrieve guest link from datastore: %v", err)
+				}
+				if !gl.LastModified.IsZero() {
+					t.Errorf("rejected edit modified guest link at %v", gl.LastModified)
+				}
+				return
+			}
+
+			gl, err := dataStore.GetGuestLink(context.Background(), originalLink.ID)
+			if err != nil {
+				t.Fatalf("failed to retrieve guest link from datastore: %v", err)
+			}
+
+			if got, want := gl, tt.expected; !reflect.DeepEqual(got, want) {
+				t.Errorf("guestLink=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkPassphraseProtectsUploads(t *testing.T) {
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	passphraseHash, err := guest_passphrase.Hash("mysecret")
+	if err != nil {
+		t.Fatalf("failed to hash passphrase: %v", err)
+	}
+
+	dataStore := test_sqlite.New()
+	if err := dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
+		ID:              guestLinkID,
+		Created:         mustParseTime("2024-01-01T00:00:00Z"),
+		UrlExpires:      picoshare.NeverExpire,
+		MaxFileLifetime: picoshare.FileLifetimeInfinite,
+		PassphraseHash:  passphraseHash,
+	}); err != nil {
+		t.Fatalf("failed to insert dummy guest link: %v", err)
+	}
+
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	upload := func(header string, cookies ...*http.Cookie) int {
+		formData, contentType := createMultipartFormBody("dummy.txt", "", strings.NewReader("dummy upload"))
+		req := httptest.NewRequest(http.MethodPost, "/api/guest/"+guestLinkID.String(), formData)
+		req.Header.Add("Content-Type", contentType)
+		req.Header.Add("Accept", "application/json")
+		if header != "" {
+			req.Header.Add(guest_passphrase.HeaderName, header)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, req)
+		return rec.Result().StatusCode
+	}
+
+	unlock := func(payload string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/guest/"+guestLinkID.String()+"/unlock", strings.NewReader(payload))
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	if got, want := upload(""), http.StatusUnauthorized; got != want {
+		t.Errorf("upload without passphrase: status=%d, want=%d", got, want)
+	}
+	if got, want := upload("wrongsecret"), http.StatusUnauthorized; got != want {
+		t.Errorf("upload with incorrect passphrase header: status=%d, want=%d", got, want)
+	}
+	if got, want := upload("mysecret"), http.StatusOK; got != want {
+		t.Errorf("upload with passphrase header: status=%d, want=%d", got, want)
+	}
+
+	if got, want := unlock(`{"passphrase": "wrongsecret"}`).StatusCode, http.StatusUnauthorized; got != want {
+		t.Errorf("unlock with incorrect passphrase: status=%d, want=%d", got, want)
+	}
+	if got, want := unlock(`{malformed`).StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("unlock with malformed request: status=%d, want=%d", got, want)
+	}
+
+	res := unlock(`{"passphrase": "mysecret"}`)
+	if got, want := res.StatusCode, http.StatusNoContent; got != want {
+		t.Fatalf("unlock with correct passphrase: status=%d, want=%d", got, want)
+	}
+	if got, want := upload("", res.Cookies()...), http.StatusOK; got != want {
+		t.Errorf("upload with session cookie: status=%d, want=%d", got, want)
+	}
+
+	gl, err := dataStore.GetGuestLink(context.Background(), guestLinkID)
+	if err != nil {
+		t.Fatalf("failed to retrieve guest link from datastore: %v", err)
+	}
+	if got, want := gl.FilesUploaded, 2; got != want {
+		t.Errorf("files uploaded=%d, want=%d", got, want)
+	}
+}
+
+func TestGuestLinksPassphrase(t *testing.T) {
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	originalHash, err := guest_passphrase.Hash("original")
+	if err != nil {
+		t.Fatalf("failed to hash passphrase: %v", err)
+	}
+
+	for _, tt := range []struct {
+		description    string
+		method         string
+		passphraseJSON string
+		status         int
+		wantPassphrase string
+	}{
+		{
+			description:    "create link without a passphrase",
+			method:         http.MethodPost,
+			passphraseJSON: `null`,
+			status:         http.StatusOK,
+		},
+		{
+			description:    "create link with a passphrase",
+			method:         http.MethodPost,
+			passphraseJSON: `"new secret"`,
+			status:         http.StatusOK,
+			wantPassphrase: "new secret",
+		},
+		{
+			description:    "reject a passphrase of only whitespace",
+			method:         http.MethodPost,
+			passphraseJSON: `"   "`,
+			status:         http.StatusBadRequest,
+		},
+		{
+			description:    "reject a passphrase that's too long",
+			method:         http.MethodPost,
+			passphraseJSON: fmt.Sprintf(`"%s"`, strings.Repeat("A", 73)),
+			status:         http.StatusBadRequest,
+		},
+		{
+			description:    "edit keeps the current passphrase when the field is null",
+			method:         http.MethodPut,
+			passphraseJSON: `null`,
+			status:         http.StatusOK,
+			wantPassphrase: "original",
+		},
+		{
+			description:    "edit replaces the passphrase",
+			method:         http.MethodPut,
+			passphraseJSON: `"new secret"`,
+			status:         http.StatusOK,
+			wantPassphrase: "new secret",
+		},
+		{
+			description:    "edit removes the passphrase when the field is empty",
+			method:         http.MethodPut,
+			passphraseJSON: `""`,
+			status:         http.StatusOK,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
+				ID:              guestLinkID,
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:      picoshare.NeverExpire,
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				PassphraseHash:  originalHash,
+			}); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+
+			c := mockClock{mustParseTime("2024-01-01T00:00:00Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			route := "/api/guest-links"
+			if tt.method == http.MethodPut {
+				route += "/" + guestLinkID.String()
+			}
+			payload := fmt.Sprintf(`{
+					"label": null,
+					"urlExpirationTime":"2030-01-02T03:04:25Z",
+					"fileLifetime":"876000h0m0s",
+					"maxFileBytes": null,
+					"maxFileUploads": null,
+					"passphrase": %s
+				}`, tt.passphraseJSON)
+			req := httptest.NewRequest(tt.method, route, strings.NewReader(payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			id := guestLinkID
+			if tt.method == http.MethodPost {
+				var response handlers.GuestLinkPostResponse
+				if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+					t.Fatalf("response is not valid JSON: %v", err)
+				}
+				id = picoshare.GuestLinkID(response.ID)
+			}
+
+			gl, err := dataStore.GetGuestLink(context.Background(), id)
+			if err != nil {
+				t.Fatalf("failed to retrieve guest link from datastore: %v", err)
+			}
+
+			if got, want := gl.IsPassphraseProtected(), tt.wantPassphrase != ""; got != want {
+				t.Fatalf("passphrase protected=%v, want=%v", got, want)
+			}
+			if tt.wantPassphrase == "" {
+				return
+			}
+			if !guest_passphrase.Verify(gl, tt.wantPassphrase) {
+				t.Errorf("stored passphrase doesn't match %q", tt.wantPassphrase)
+			}
+		})
+	}
+}
+
+func TestGuestLinksNewGet(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		route       string
+		status      int
+		want        []string
+		wantMissing []string
+	}{
+		{
+			description: "new guest link page starts with the defaults",
+			route:       "/guest-links/new",
+			status:      http.StatusOK,
+			want:        []string{`value="Client documents"`, `data-max-file-size="20"`},
+			wantMissing: []string{"Copying settings from", `value="For Jane"`},
+		},
+		{
+			description: "duplicating a guest link copies its settings",
+			route:       "/guest-links/new?from=abcdefgh23456789",
+			status:      http.StatusOK,
+			want: []string{
+				"Copying settings from",
+				`value="For Jane"`,
+				"Upload your *signed* contract.",
+				`value="5"`,
+				`value="10"`,
+				`value=".pdf"`,
+				"doesn't include the original link's passphrase",
+				"30 days (same as original)",
+			},
+		},
+		{
+			description: "duplicating a guest link that doesn't exist fails",
+			route:       "/guest-links/new?from=zyxwvuts98765432",
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "duplicating a guest link with an invalid ID fails",
+			route:       "/guest-links/new?from=i-am-an-invalid-link",
+			status:      http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				GuestLinkPresets: []picoshare.GuestLinkPreset{
+					{
+						Name:            "Client documents",
+						MaxFileLifetime: picoshare.NewFileLifetimeInDays(30),
+						MaxFileBytes:    makeGuestUploadMaxFileBytes(20 * 1024 * 1024),
+						MaxFileUploads:  makeGuestUploadCountLimit(3),
+					},
+				},
+			}); err != nil {
+				t.Fatalf("failed to save settings: %v", err)
+			}
+			if err := dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
+				ID:               picoshare.GuestLinkID("abcdefgh23456789"),
+				Label:            picoshare.GuestLinkLabel("For Jane"),
+				Created:          mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:       mustParseExpirationTime("2024-01-31T00:00:00Z"),
+				MaxFileLifetime:  picoshare.NewFileLifetimeInDays(7),
+				MaxFileBytes:     makeGuestUploadMaxFileBytes(5 * 1024 * 1024),
+				MaxFileUploads:   makeGuestUploadCountLimit(10),
+				PassphraseHash:   picoshare.GuestLinkPassphraseHash("dummy-passphrase-hash"),
+				AllowedFileTypes: picoshare.GuestUploadFileTypes{".pdf"},
+				Instructions:     picoshare.GuestLinkInstructions("Upload your *signed* contract."),
+			}); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         mockClock{mustParseTime("2025-01-01T00:00:00Z")},
+			})
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.route, nil))
+
+			if got, want := rec.Result().StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("page doesn't contain %q", want)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("page unexpectedly contains %q", missing)
+				}
+			}
+		})
+	}
+}
+
+func TestGuestLinkIndexShowsUploadWindow(t *testing.T) {
+	dataStore := test_sqlite.New()
+	mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+		UploadWindow: picoshare.GuestLinkUploadWindow(24 * time.Hour),
+	})
+	c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+		Clock:         c,
+	})
+
+	getIndex := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/guest-links", nil)
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, req)
+		if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+			t.Fatalf("status=%d, want=%d", got, want)
+		}
+		return rec.Body.String()
+	}
+
+	if got, want := getIndex(), "Closes 1 day after first upload"; !strings.Contains(got, want) {
+		t.Errorf("guest link index before first upload doesn't contain %q", want)
+	}
+
+	mustGuestUpload(t, s, "dummy.txt")
+
+	if got, want := getIndex(), "Window started"; !strings.Contains(got, want) {
+		t.Errorf("guest link index after first upload doesn't contain %q", want)
+	}
+}
@@ -0,0 +1,430 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+)
+
+const dummyGuestLinkID = picoshare.GuestLinkID("abcdefgh23456789")
+
+func mustInsertListingGuestLink(t *testing.T, dataStore sqlite.Store, gl picoshare.GuestLink) {
+	t.Helper()
+	gl.ID = dummyGuestLinkID
+	gl.Created = mustParseTime("2024-01-01T00:00:00Z")
+	if time.Time(gl.UrlExpires).IsZero() {
+		gl.UrlExpires = picoshare.NeverExpire
+	}
+	gl.MaxFileLifetime = picoshare.FileLifetimeInfinite
+	if err := dataStore.InsertGuestLink(context.Background(), gl); err != nil {
+		t.Fatalf("failed to insert dummy guest link: %v", err)
+	}
+}
+
+// mustGuestUpload uploads a file through the dummy guest link and returns the
+// new entry's ID along with the session cookie the guest received.
+func mustGuestUpload(t *testing.T, s handlers.Server, filename string, cookies ...*http.Cookie) (picoshare.EntryID, []*http.Cookie) {
+	t.Helper()
+	body, contentType := createMultipartFormBody(filename, "", strings.NewReader("dummy upload"))
+	req := httptest.NewRequest(http.MethodPost, "/api/guest/"+dummyGuestLinkID.String(), body)
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("Accept", "application/json")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("upload status=%d, want=%d", got, want)
+	}
+
+	var response handlers.EntryPostResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	return picoshare.EntryID(response.ID), res.Cookies()
+}
+
+func getGuestUploadPage(t *testing.T, s handlers.Server, cookies []*http.Cookie) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/g/"+dummyGuestLinkID.String(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("guest page status=%d, want=%d", got, want)
+	}
+	return rec.Body.String()
+}
+
+func TestGuestUploadPageListsUploads(t *testing.T) {
+	for _, tt := range []struct {
+		description       string
+		listing           picoshare.GuestUploadListing
+		ownerSeesFile     bool
+		strangerSeesFile  bool
+		ownerCanDeleteBtn bool
+	}{
+		{
+			description:      "link without a listing shows no uploads",
+			listing:          picoshare.GuestUploadListingNone,
+			ownerSeesFile:    false,
+			strangerSeesFile: false,
+		},
+		{
+			description:       "session listing shows only the guest's own uploads",
+			listing:           picoshare.GuestUploadListingSession,
+			ownerSeesFile:     true,
+			strangerSeesFile:  false,
+			ownerCanDeleteBtn: true,
+		},
+		{
+			description:       "full listing shows every upload",
+			listing:           picoshare.GuestUploadListingAll,
+			ownerSeesFile:     true,
+			strangerSeesFile:  true,
+			ownerCanDeleteBtn: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+				UploadListing: tt.listing,
+			})
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			_, cookies := mustGuestUpload(t, s, "mistake.txt")
+			if got, want := len(cookies), 1; got != want {
+				t.Fatalf("cookie count after upload=%d, want=%d", got, want)
+			}
+
+			ownerPage := getGuestUploadPage(t, s, cookies)
+			if got, want := strings.Contains(ownerPage, "mistake.txt"), tt.ownerSeesFile; got != want {
+				t.Errorf("uploader sees file=%v, want=%v", got, want)
+			}
+			if got, want := strings.Contains(ownerPage, `data-filename="mistake.txt"`), tt.ownerCanDeleteBtn; got != want {
+				t.Errorf("uploader sees delete button=%v, want=%v", got, want)
+			}
+
+			strangerPage := getGuestUploadPage(t, s, nil)
+			if got, want := strings.Contains(strangerPage, "mistake.txt"), tt.strangerSeesFile; got != want {
+				t.Errorf("other guest sees file=%v, want=%v", got, want)
+			}
+			if strings.Contains(strangerPage, `data-filename="mistake.txt"`) {
+				t.Errorf("other guest sees delete button for a file they didn't upload")
+			}
+		})
+	}
+}
+
+func TestInactiveGuestLinkPageListsUploads(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		expireLink  bool
+		disableLink bool
+		wantListing bool
+	}{
+		{
+			description: "lists uploads when guests reached the upload limit",
+			wantListing: true,
+		},
+		{
+			description: "lists no uploads after the link expires",
+			expireLink:  true,
+			wantListing: false,
+		},
+		{
+			description: "lists no uploads after the owner disables the link",
+			disableLink: true,
+			wantListing: false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+				UploadListing:  picoshare.GuestUploadListingSession,
+				MaxFileUploads: makeGuestUploadCountLimit(1),
+			})
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			_, cookies := mustGuestUpload(t, s, "mistake.txt")
+
+			if tt.expireLink {
+				gl, err := dataStore.GetGuestLink(context.Background(), dummyGuestLinkID)
+				if err != nil {
+					t.Fatalf("failed to get guest link: %v", err)
+				}
+				gl.UrlExpires = picoshare.ExpirationTime(mustParseTime("2024-01-02T00:00:00Z"))
+				gl.LastModified = mustParseTime("2024-01-02T00:00:00Z")
+				if err := dataStore.UpdateGuestLink(context.Background(), dummyGuestLinkID, gl); err != nil {
+					t.Fatalf("failed to expire guest link: %v", err)
+				}
+			}
+			if tt.disableLink {
+				if err := dataStore.DisableGuestLink(context.Background(), dummyGuestLinkID); err != nil {
+					t.Fatalf("failed to disable guest link: %v", err)
+				}
+			}
+
+			page := getGuestUploadPage(t, s, cookies)
+			if !strings.Contains(page, "Guest Link Inactive") {
+				t.Fatalf("guest page doesn't say that the link is inactive")
+			}
+			if got, want := strings.Contains(page, "mistake.txt"), tt.wantListing; got != want {
+				t.Errorf("uploader sees file=%v, want=%v", got, want)
+			}
+			if got, want := strings.Contains(page, `data-filename="mistake.txt"`), tt.wantListing; got != want {
+				t.Errorf("uploader sees delete button=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestUploadsShareSession(t *testing.T) {
+	dataStore := test_sqlite.New()
+	mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+		UploadListing: picoshare.GuestUploadListingSession,
+	})
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	firstID, cookies := mustGuestUpload(t, s, "first.txt")
+	secondID, newCookies := mustGuestUpload(t, s, "second.txt", cookies...)
+	if got, want := len(newCookies), 0; got != want {
+		t.Errorf("cookie count on second upload=%d, want=%d", got, want)
+	}
+
+	first, err := dataStore.GetEntryMetadata(context.Background(), firstID)
+	if err != nil {
+		t.Fatalf("failed to get first entry: %v", err)
+	}
+	second, err := dataStore.GetEntryMetadata(context.Background(), secondID)
+	if err != nil {
+		t.Fatalf("failed to get second entry: %v", err)
+	}
+	if first.GuestSession.Empty() {
+		t.Fatalf("guest upload has no session")
+	}
+	if got, want := second.GuestSession, first.GuestSession; got != want {
+		t.Errorf("second upload session=%v, want=%v", got, want)
+	}
+}
+
+func TestGuestEntryDelete(t *testing.T) {
+	for _, tt := range []struct {
+		description   string
+		guestLink     picoshare.GuestLink
+		useOwnSession bool
+		disableLink   bool
+		status        int
+		deleted       bool
+	}{
+		{
+			description: "guest deletes their own upload",
+			guestLink: picoshare.GuestLink{
+				UploadListing: picoshare.GuestUploadListingSession,
+			},
+			useOwnSession: true,
+			status:        http.StatusNoContent,
+			deleted:       true,
+		},
+		{
+			description: "guest deletes their own upload after reaching the upload limit",
+			guestLink: picoshare.GuestLink{
+				UploadListing:  picoshare.GuestUploadListingAll,
+				MaxFileUploads: makeGuestUploadCountLimit(1),
+			},
+			useOwnSession: true,
+			status:        http.StatusNoContent,
+			deleted:       true,
+		},
+		{
+			description: "another guest can't delete the upload",
+			guestLink: picoshare.GuestLink{
+				UploadListing: picoshare.GuestUploadListingAll,
+			},
+			useOwnSession: false,
+			status:        http.StatusNotFound,
+		},
+		{
+			description:   "guest can't delete uploads when the link doesn't list them",
+			guestLink:     picoshare.GuestLink{},
+			useOwnSession: true,
+			status:        http.StatusForbidden,
+		},
+		{
+			description: "guest can't delete uploads after the owner disables the link",
+			guestLink: picoshare.GuestLink{
+				UploadListing: picoshare.GuestUploadListingSession,
+			},
+			useOwnSession: true,
+			disableLink:   true,
+			status:        http.StatusForbidden,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newStoreWithEventWebhook(t)
+			mustInsertListingGuestLink(t, dataStore, tt.guestLink)
+			c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			entryID, cookies := mustGuestUpload(t, s, "mistake.txt")
+			if tt.disableLink {
+				if err := dataStore.DisableGuestLink(context.Background(), dummyGuestLinkID); err != nil {
+					t.Fatalf("failed to disable guest link: %v", err)
+				}
+			}
+			deliveriesBefore := len(mustReadWebhookPayloads(t, dataStore))
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/guest/"+dummyGuestLinkID.String()+"/entries/"+entryID.String(), nil)
+			if tt.useOwnSession {
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+			}
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			_, err := dataStore.GetEntryMetadata(context.Background(), entryID)
+			_, notFound := errors.AsType[store.EntryNotFoundError](err)
+			if got, want := notFound, tt.deleted; got != want {
+				t.Errorf("deleted=%v, want=%v", got, want)
+			}
+
+			payloads := mustReadWebhookPayloads(t, dataStore)
+			if !tt.deleted {
+				if got, want := len(payloads), deliveriesBefore; got != want {
+					t.Errorf("webhook deliveries=%d, want=%d", got, want)
+				}
+				return
+			}
+			if got, want := len(payloads), deliveriesBefore+1; got != want {
+				t.Fatalf("webhook deliveries=%d, want=%d", got, want)
+			}
+			if got, want := payloads[len(payloads)-1].Type, picoshare.WebhookEventEntryDeleted; got != want {
+				t.Errorf("event type=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestEntryDeleteRejectsEntryFromAnotherLink(t *testing.T) {
+	dataStore := test_sqlite.New()
+	mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+		UploadListing: picoshare.GuestUploadListingSession,
+	})
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	_, cookies := mustGuestUpload(t, s, "mine.txt")
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader("dummy data"), picoshare.UploadMetadata{
+		ID:       dummyTextEntry.ID,
+		Filename: dummyTextEntry.Filename,
+		Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:  picoshare.NeverExpire,
+		Size:     mustParseFileSize(len("dummy data")),
+	}); err != nil {
+		t.Fatalf("failed to insert owner entry: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/guest/"+dummyGuestLinkID.String()+"/entries/"+dummyTextEntry.ID.String(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+
+	if got, want := rec.Result().StatusCode, http.StatusNotFound; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+	if _, err := dataStore.GetEntryMetadata(context.Background(), dummyTextEntry.ID); err != nil {
+		t.Errorf("owner entry is missing after rejected guest delete: %v", err)
+	}
+}
+
+func TestGuestUploadPageInstructions(t *testing.T) {
+	for _, tt := range []struct {
+		description  string
+		instructions picoshare.GuestLinkInstructions
+		want         []string
+		wantMissing  []string
+	}{
+		{
+			description:  "link without instructions shows none",
+			instructions: "",
+			wantMissing:  []string{`id="guest-instructions"`},
+		},
+		{
+			description:  "renders Markdown",
+			instructions: "Please upload your **tax forms**.\n\n- W-2\n- 1099",
+			want: []string{
+				`id="guest-instructions"`,
+				"<strong>tax forms</strong>",
+				"<li>W-2</li>",
+			},
+		},
+		{
+			description:  "omits raw HTML and unsafe links",
+			instructions: "<script>alert(1)</script>\n\n[click](javascript:alert(1))",
+			want:         []string{`id="guest-instructions"`},
+			wantMissing:  []string{"<script>alert(1)", "javascript:alert"},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			mustInsertListingGuestLink(t, dataStore, picoshare.GuestLink{
+				Instructions: tt.instructions,
+			})
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			body := getGuestUploadPage(t, s, nil)
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("page doesn't contain %q", want)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("page unexpectedly contains %q", missing)
+				}
+			}
+		})
+	}
+}
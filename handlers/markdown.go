@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// markdown converts the Markdown that owners write for guests into HTML. By
+// default, goldmark omits raw HTML and drops links with dangerous schemes,
+// such as javascript: URLs, so the output never needs the Content Security
+// Policy to allow inline scripts or styles.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Linkify, extension.Strikethrough),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+func renderMarkdown(s string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(s), &buf); err != nil {
+		return template.HTML(""), err
+	}
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/mtlynch/picoshare/notify"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// notifyOwner reports an event to the PicoShare owner if they've subscribed to
+// events of that kind. Delivery happens in the background, so notifyOwner
+// never delays or fails the request that triggered the event.
+func (s Server) notifyOwner(r *http.Request, ev notify.Event) {
+	settings, err := s.getDB(r).ReadSettings(r.Context())
+	if err != nil {
+		log.Printf("failed to read notification settings: %v", err)
+		return
+	}
+
+	ev.Time = s.clock.Now()
+	if ev.Entry.ID != "" {
+		ev.FileURL = fmt.Sprintf("%s/files/%s/info", baseURLFromRequest(r), ev.Entry.ID)
+	}
+
+	s.notifier.Notify(settings.Notifications, ev)
+}
+
+// notifyIfFirstDownload reports the download of an entry to the owner if it's
+// the entry's first download. The store marks the entry as downloaded with a
+// conditional write, so only one of several concurrent downloads triggers the
+// notification.
+func (s Server) notifyIfFirstDownload(r *http.Request, entry picoshare.UploadMetadata) {
+	first, err := s.getDB(r).MarkEntryDownloaded(r.Context(), entry.ID)
+	if err != nil {
+		log.Printf("failed to mark file %v as downloaded: %v", entry.ID, err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	settings, err := s.getDB(r).ReadSettings(r.Context())
+	if err != nil {
+		log.Printf("failed to read notification settings: %v", err)
+		return
+	}
+	if !settings.Notifications.OnFirstDownload || !settings.Notifications.HasChannels() {
+		return
+	}
+
+	s.notifyOwner(r, notify.Event{
+		Kind:  notify.EventFirstDownload,
+		Entry: entry,
+	})
+}
@@ -0,0 +1,320 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/notify"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+)
+
+type mockNotifier struct {
+	mu       sync.Mutex
+	notified []notify.Event
+	sent     []notify.Event
+	settings []picoshare.NotificationSettings
+	sendErr  error
+}
+
+func (n *mockNotifier) Notify(settings picoshare.NotificationSettings, ev notify.Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notified = append(n.notified, ev)
+	n.settings = append(n.settings, settings)
+}
+
+func (n *mockNotifier) Send(_ context.Context, settings picoshare.NotificationSettings, ev notify.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, ev)
+	n.settings = append(n.settings, settings)
+	return n.sendErr
+}
+
+var dummyNotificationSettings = picoshare.NotificationSettings{
+	OnGuestUpload:   true,
+	OnFirstDownload: true,
+	Webhook: picoshare.WebhookNotificationSettings{
+		URL: "https://hooks.example.com/picoshare",
+	},
+}
+
+func TestGuestUploadNotifiesOwner(t *testing.T) {
+	dataStore := test_sqlite.New()
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		Notifications:       dummyNotificationSettings,
+	}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+	guestLink := picoshare.GuestLink{
+		ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+		Label:           picoshare.GuestLinkLabel("For Jane"),
+		Created:         mustParseTime("2024-01-01T00:00:00Z"),
+		UrlExpires:      picoshare.NeverExpire,
+		MaxFileLifetime: picoshare.FileLifetimeInfinite,
+	}
+	if err := dataStore.InsertGuestLink(context.Background(), guestLink); err != nil {
+		t.Fatalf("failed to insert dummy guest link: %v", err)
+	}
+
+	notifier := mockNotifier{}
+	c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+		Notifier:      &notifier,
+		Clock:         c,
+	})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	f, err := mw.CreateFormFile("file", "forms.txt")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := f.Write([]byte("dummy upload")); err != nil {
+		t.Fatalf("failed to write form file: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/guest/abcdefgh23456789", &body)
+	req.Header.Add("Content-Type", mw.FormDataContentType())
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+
+	if got, want := len(notifier.notified), 1; got != want {
+		t.Fatalf("notifications=%d, want=%d", got, want)
+	}
+	ev := notifier.notified[0]
+	if got, want := ev.Kind, notify.EventGuestUpload; got != want {
+		t.Errorf("kind=%v, want=%v", got, want)
+	}
+	if got, want := ev.Entry.Filename, picoshare.Filename("forms.txt"); got != want {
+		t.Errorf("filename=%v, want=%v", got, want)
+	}
+	if got, want := ev.GuestLink.Label, guestLink.Label; got != want {
+		t.Errorf("guest link label=%v, want=%v", got, want)
+	}
+	if got, want := ev.Time, c.Now(); !got.Equal(want) {
+		t.Errorf("time=%v, want=%v", got, want)
+	}
+	if got, want := ev.FileURL, "http://example.com/files/"+ev.Entry.ID.String()+"/info"; got != want {
+		t.Errorf("file URL=%v, want=%v", got, want)
+	}
+	if got, want := notifier.settings[0], dummyNotificationSettings; got != want {
+		t.Errorf("settings=%+v, want=%+v", got, want)
+	}
+}
+
+func TestFirstDownloadNotifiesOwner(t *testing.T) {
+	for _, tt := range []struct {
+		description       string
+		notifications     picoshare.NotificationSettings
+		downloads         int
+		wantNotifications int
+	}{
+		{
+			description:       "notifies once for repeated downloads",
+			notifications:     dummyNotificationSettings,
+			downloads:         3,
+			wantNotifications: 1,
+		},
+		{
+			description: "doesn't notify when owner hasn't subscribed",
+			notifications: picoshare.NotificationSettings{
+				OnGuestUpload: true,
+				Webhook:       dummyNotificationSettings.Webhook,
+			},
+			downloads:         1,
+			wantNotifications: 0,
+		},
+		{
+			description: "doesn't notify when no channels are configured",
+			notifications: picoshare.NotificationSettings{
+				OnFirstDownload: true,
+			},
+			downloads:         1,
+			wantNotifications: 0,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				Notifications:       tt.notifications,
+			}); err != nil {
+				t.Fatalf("failed to save settings: %v", err)
+			}
+			data := "dummy data"
+			if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), picoshare.UploadMetadata{
+				ID:       dummyTextEntry.ID,
+				Filename: dummyTextEntry.Filename,
+				Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+				Expires:  picoshare.NeverExpire,
+				Size:     mustParseFileSize(len(data)),
+			}); err != nil {
+				t.Fatalf("failed to insert dummy entry: %v", err)
+			}
+
+			notifier := mockNotifier{}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Notifier:      &notifier,
+			})
+
+			for range tt.downloads {
+				req := httptest.NewRequest(http.MethodGet, "/-TTTTTTTTTT", nil)
+				rec := httptest.NewRecorder()
+				s.Router().ServeHTTP(rec, req)
+				if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+					t.Fatalf("status=%d, want=%d", got, want)
+				}
+			}
+
+			if got, want := len(notifier.notified), tt.wantNotifications; got != want {
+				t.Fatalf("notifications=%d, want=%d", got, want)
+			}
+			if tt.wantNotifications == 0 {
+				return
+			}
+			if got, want := notifier.notified[0].Kind, notify.EventFirstDownload; got != want {
+				t.Errorf("kind=%v, want=%v", got, want)
+			}
+			if got, want := notifier.notified[0].Entry.ID, dummyTextEntry.ID; got != want {
+				t.Errorf("entry ID=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestSettingsNotificationsTestPost(t *testing.T) {
+	for _, tt := range []struct {
+		description  string
+		saved        picoshare.NotificationSettings
+		payload      string
+		sendErr      error
+		status       int
+		wantSettings picoshare.NotificationSettings
+	}{
+		{
+			description: "sends test through the channels in the request",
+			payload: `{
+					"webhookUrl": "https://hooks.example.com/picoshare"
+				}`,
+			status: http.StatusNoContent,
+			wantSettings: picoshare.NotificationSettings{
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "https://hooks.example.com/picoshare",
+				},
+			},
+		},
+		{
+			description: "uses saved password when request omits it",
+			saved: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Username: "picoshare",
+					Password: "hunter2",
+					To:       "owner@example.com",
+				},
+			},
+			payload: `{
+					"smtpHost": "smtp.example.com",
+					"smtpPort": 587,
+					"smtpUsername": "picoshare",
+					"smtpTo": "owner@example.com"
+				}`,
+			status: http.StatusNoContent,
+			wantSettings: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Port:     587,
+					Username: "picoshare",
+					Password: "hunter2",
+					To:       "owner@example.com",
+				},
+			},
+		},
+		{
+			description: "reports delivery failure",
+			payload: `{
+					"ntfyTopicUrl": "https://ntfy.example.com/picoshare"
+				}`,
+			sendErr: errors.New("ntfy: 403 Forbidden"),
+			status:  http.StatusBadGateway,
+		},
+		{
+			description: "rejects request without channels",
+			payload:     `{}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			description: "rejects invalid webhook URL",
+			payload: `{
+					"webhookUrl": "javascript:alert(1)"
+				}`,
+			status: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				Notifications:       tt.saved,
+			}); err != nil {
+				t.Fatalf("failed to save settings: %v", err)
+			}
+
+			notifier := mockNotifier{sendErr: tt.sendErr}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Notifier:      &notifier,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/settings/notifications/test", strings.NewReader(tt.payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if tt.status != http.StatusNoContent {
+				return
+			}
+
+			if got, want := len(notifier.sent), 1; got != want {
+				t.Fatalf("sent=%d, want=%d", got, want)
+			}
+			if got, want := notifier.sent[0].Kind, notify.EventTest; got != want {
+				t.Errorf("kind=%v, want=%v", got, want)
+			}
+			if got, want := notifier.settings[0], tt.wantSettings; got != want {
+				t.Errorf("settings=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
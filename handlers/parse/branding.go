@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// MaxInstanceNameLength is the maximum number of characters allowed in the
+// instance name, so that it fits in the navigation bar.
+const MaxInstanceNameLength = 50
+
+// MaxBrandingLogoBytes is the maximum size of the logo image. PicoShare keeps
+// the logo in its settings, so the logo should be a small image.
+const MaxBrandingLogoBytes = 256 * 1024
+
+var ErrInstanceNameTooLong = fmt.Errorf("instance name too long - limit %d characters", MaxInstanceNameLength)
+var ErrInstanceNameInvalid = errors.New("instance name must not contain line breaks")
+var ErrAccentColorInvalid = errors.New("accent color must be a hex color such as #1a73e8")
+var ErrBrandingLogoInvalid = errors.New("logo must be a base64-encoded data URL")
+var ErrBrandingLogoTooLarge = fmt.Errorf("logo is too large - limit %d KiB", MaxBrandingLogoBytes/1024)
+var ErrBrandingLogoType = errors.New("logo must be a PNG, JPEG, GIF, or WebP image")
+
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// brandingLogoTypes are the image formats that PicoShare accepts for the
+// logo. PicoShare doesn't accept SVG images because they can contain scripts.
+var brandingLogoTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+}
+
+// BrandingSettings checks that branding settings are safe to show on every
+// page. It trims surrounding whitespace from the name and normalizes the
+// accent color to lowercase.
+func BrandingSettings(bs picoshare.BrandingSettings) (picoshare.BrandingSettings, error) {
+	name := strings.TrimSpace(bs.Name)
+	if len([]rune(name)) > MaxInstanceNameLength {
+		return picoshare.BrandingSettings{}, ErrInstanceNameTooLong
+	}
+	if strings.ContainsAny(name, "\r\n") {
+		return picoshare.BrandingSettings{}, ErrInstanceNameInvalid
+	}
+
+	accentColor := strings.ToLower(strings.TrimSpace(bs.AccentColor))
+	if accentColor != "" && !accentColorPattern.MatchString(accentColor) {
+		return picoshare.BrandingSettings{}, ErrAccentColorInvalid
+	}
+
+	logo, err := brandingLogo(bs.Logo.Data)
+	if err != nil {
+		return picoshare.BrandingSettings{}, err
+	}
+
+	return picoshare.BrandingSettings{
+		Name:        name,
+		Logo:        logo,
+		AccentColor: accentColor,
+	}, nil
+}
+
+// BrandingLogoDataURL decodes a logo from a base64-encoded data URL, such as
+// the one that a browser's FileReader produces. An empty string means no
+// logo.
+func BrandingLogoDataURL(dataURL string) (picoshare.BrandingLogo, error) {
+	if dataURL == "" {
+		return picoshare.BrandingLogo{}, nil
+	}
+
+	header, encoded, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return picoshare.BrandingLogo{}, ErrBrandingLogoInvalid
+	}
+	if base64.StdEncoding.DecodedLen(len(encoded)) > MaxBrandingLogoBytes+3 {
+		return picoshare.BrandingLogo{}, ErrBrandingLogoTooLarge
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return picoshare.BrandingLogo{}, ErrBrandingLogoInvalid
+	}
+
+	return brandingLogo(data)
+}
+
+// brandingLogo checks the logo's size and format. PicoShare ignores the media
+// type that the client claims and detects the format from the image itself.
+func brandingLogo(data []byte) (picoshare.BrandingLogo, error) {
+	if len(data) == 0 {
+		return picoshare.BrandingLogo{}, nil
+	}
+	if len(data) > MaxBrandingLogoBytes {
+		return picoshare.BrandingLogo{}, ErrBrandingLogoTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(brandingLogoTypes, contentType) {
+		return picoshare.BrandingLogo{}, ErrBrandingLogoType
+	}
+
+	return picoshare.BrandingLogo{
+		ContentType: picoshare.ContentType(contentType),
+		Data:        data,
+	}, nil
+}
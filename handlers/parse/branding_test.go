@@ -0,0 +1,171 @@
+package parse_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// dummyPNG is the signature and header of a PNG image, which is enough for
+// content type detection.
+var dummyPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+
+func TestBrandingSettings(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       picoshare.BrandingSettings
+		output      picoshare.BrandingSettings
+		err         error
+	}{
+		{
+			description: "accept empty settings",
+			input:       picoshare.BrandingSettings{},
+			output:      picoshare.BrandingSettings{},
+			err:         nil,
+		},
+		{
+			description: "accept name, logo, and accent color",
+			input: picoshare.BrandingSettings{
+				Name:        " Acme Legal ",
+				Logo:        picoshare.BrandingLogo{Data: dummyPNG},
+				AccentColor: "#1A73E8",
+			},
+			output: picoshare.BrandingSettings{
+				Name: "Acme Legal",
+				Logo: picoshare.BrandingLogo{
+					ContentType: picoshare.ContentType("image/png"),
+					Data:        dummyPNG,
+				},
+				AccentColor: "#1a73e8",
+			},
+			err: nil,
+		},
+		{
+			description: "reject name that's too long",
+			input: picoshare.BrandingSettings{
+				Name: strings.Repeat("A", parse.MaxInstanceNameLength+1),
+			},
+			err: parse.ErrInstanceNameTooLong,
+		},
+		{
+			description: "reject name with a line break",
+			input: picoshare.BrandingSettings{
+				Name: "Acme\nLegal",
+			},
+			err: parse.ErrInstanceNameInvalid,
+		},
+		{
+			description: "reject named accent color",
+			input: picoshare.BrandingSettings{
+				AccentColor: "red",
+			},
+			err: parse.ErrAccentColorInvalid,
+		},
+		{
+			description: "reject accent color that tries to escape CSS",
+			input: picoshare.BrandingSettings{
+				AccentColor: "#fff;}body{display:none",
+			},
+			err: parse.ErrAccentColorInvalid,
+		},
+		{
+			description: "reject SVG logo",
+			input: picoshare.BrandingSettings{
+				Logo: picoshare.BrandingLogo{
+					Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+				},
+			},
+			err: parse.ErrBrandingLogoType,
+		},
+		{
+			description: "reject logo that's too large",
+			input: picoshare.BrandingSettings{
+				Logo: picoshare.BrandingLogo{
+					Data: append(bytes.Clone(dummyPNG), make([]byte, parse.MaxBrandingLogoBytes)...),
+				},
+			},
+			err: parse.ErrBrandingLogoTooLarge,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			bs, err := parse.BrandingSettings(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", got, want)
+			}
+			if got, want := bs.Name, tt.output.Name; got != want {
+				t.Errorf("name=%v, want=%v", got, want)
+			}
+			if got, want := bs.AccentColor, tt.output.AccentColor; got != want {
+				t.Errorf("accent color=%v, want=%v", got, want)
+			}
+			if got, want := bs.Logo.ContentType, tt.output.Logo.ContentType; got != want {
+				t.Errorf("logo content type=%v, want=%v", got, want)
+			}
+			if got, want := bs.Logo.Data, tt.output.Logo.Data; !bytes.Equal(got, want) {
+				t.Errorf("logo data=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestBrandingLogoDataURL(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      []byte
+		err         error
+	}{
+		{
+			description: "accept empty data URL",
+			input:       "",
+			output:      nil,
+			err:         nil,
+		},
+		{
+			description: "accept PNG data URL",
+			input:       "data:image/png;base64," + base64.StdEncoding.EncodeToString(dummyPNG),
+			output:      dummyPNG,
+			err:         nil,
+		},
+		{
+			description: "detect format regardless of declared media type",
+			input:       "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(dummyPNG),
+			output:      dummyPNG,
+			err:         nil,
+		},
+		{
+			description: "reject URL that isn't a data URL",
+			input:       "https://example.com/logo.png",
+			err:         parse.ErrBrandingLogoInvalid,
+		},
+		{
+			description: "reject data URL that isn't base64-encoded",
+			input:       "data:image/png,hello",
+			err:         parse.ErrBrandingLogoInvalid,
+		},
+		{
+			description: "reject invalid base64",
+			input:       "data:image/png;base64,!!!",
+			err:         parse.ErrBrandingLogoInvalid,
+		},
+		{
+			description: "reject data URL that's too large",
+			input:       "data:image/png;base64," + strings.Repeat("A", parse.MaxBrandingLogoBytes*2),
+			err:         parse.ErrBrandingLogoTooLarge,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			logo, err := parse.BrandingLogoDataURL(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", got, want)
+			}
+			if got, want := logo.Data, tt.output; !bytes.Equal(got, want) {
+				t.Errorf("logo data=%v, want=%v", got, want)
+			}
+		})
+	}
+}
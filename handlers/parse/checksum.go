@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+var ErrSHA256ChecksumInvalid = errors.New("SHA-256 checksum must be 64 hexadecimal characters")
+
+// SHA256Checksum parses a hex-encoded SHA-256 checksum. An empty string means
+// that the client didn't specify a checksum, so it's valid and produces an
+// empty checksum.
+func SHA256Checksum(s string) (picoshare.SHA256Checksum, error) {
+	if s == "" {
+		return picoshare.SHA256Checksum(""), nil
+	}
+
+	if len(s) != hex.EncodedLen(sha256.Size) {
+		return picoshare.SHA256Checksum(""), ErrSHA256ChecksumInvalid
+	}
+
+	if _, err := hex.DecodeString(s); err != nil {
+		return picoshare.SHA256Checksum(""), ErrSHA256ChecksumInvalid
+	}
+
+	return picoshare.SHA256Checksum(strings.ToLower(s)), nil
+}
@@ -0,0 +1,63 @@
+package parse_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestSHA256Checksum(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.SHA256Checksum
+		err         error
+	}{
+		{
+			description: "accept valid checksum",
+			input:       "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b",
+			output:      picoshare.SHA256Checksum("09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"),
+			err:         nil,
+		},
+		{
+			description: "convert uppercase checksum to lowercase",
+			input:       "09CA7E4EAA6E8AE9C7D261167129184883644D07DFBA7CBFBC4C8A2E08360D5B",
+			output:      picoshare.SHA256Checksum("09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"),
+			err:         nil,
+		},
+		{
+			description: "accept empty checksum",
+			input:       "",
+			output:      picoshare.SHA256Checksum(""),
+			err:         nil,
+		},
+		{
+			description: "reject checksum that's too short",
+			input:       "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d",
+			err:         parse.ErrSHA256ChecksumInvalid,
+		},
+		{
+			description: "reject checksum that's too long",
+			input:       "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b00",
+			err:         parse.ErrSHA256ChecksumInvalid,
+		},
+		{
+			description: "reject checksum with non-hex characters",
+			input:       strings.Repeat("z", 64),
+			err:         parse.ErrSHA256ChecksumInvalid,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s [%s]", tt.description, tt.input), func(t *testing.T) {
+			checksum, err := parse.SHA256Checksum(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", err, want)
+			}
+			if got, want := checksum, tt.output; got != want {
+				t.Errorf("checksum=%v, want=%v", got, want)
+			}
+		})
+	}
+}
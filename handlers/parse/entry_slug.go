@@ -0,0 +1,48 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// MinEntrySlugLength is the minimum length of a custom download link, so that
+// slugs stay distinctive.
+const MinEntrySlugLength = 3
+
+// Arbitrary limit to keep custom download links short enough to share.
+const MaxEntrySlugLength = 64
+
+var ErrEntrySlugTooShort = fmt.Errorf("custom link too short - minimum %d characters", MinEntrySlugLength)
+var ErrEntrySlugTooLong = fmt.Errorf("custom link too long - limit %d characters", MaxEntrySlugLength)
+var ErrEntrySlugInvalid = errors.New("custom link can contain only letters, numbers, and single hyphens between them, except for the look-alike characters l, 0, and 1")
+
+// entrySlugCharacters are the lowercase characters of the entry ID alphabet,
+// which leaves out characters that readers easily confuse (l, 0, and 1).
+const entrySlugCharacters = "abcdefghijkmnopqrstuvwxyz23456789"
+
+var entrySlugPattern = regexp.MustCompile(fmt.Sprintf(`^[%[1]s]+(-[%[1]s]+)*$`, entrySlugCharacters))
+
+// EntrySlug parses a custom alias for an entry's download link. Slugs are
+// case-insensitive, so EntrySlug converts them to lowercase. An empty string
+// means that the entry has no slug.
+func EntrySlug(raw string) (picoshare.EntrySlug, error) {
+	slug := strings.ToLower(strings.TrimSpace(raw))
+	if slug == "" {
+		return picoshare.EntrySlug(""), nil
+	}
+	if len(slug) < MinEntrySlugLength {
+		return picoshare.EntrySlug(""), ErrEntrySlugTooShort
+	}
+	if len(slug) > MaxEntrySlugLength {
+		return picoshare.EntrySlug(""), ErrEntrySlugTooLong
+	}
+	if !entrySlugPattern.MatchString(slug) {
+		return picoshare.EntrySlug(""), ErrEntrySlugInvalid
+	}
+
+	return picoshare.EntrySlug(slug), nil
+}
@@ -0,0 +1,119 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestEntrySlug(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.EntrySlug
+		err         error
+	}{
+		{
+			description: "accept slug with hyphens",
+			input:       "q3-report",
+			output:      picoshare.EntrySlug("q3-report"),
+			err:         nil,
+		},
+		{
+			description: "convert slug to lowercase and trim whitespace",
+			input:       "  Q3-Report ",
+			output:      picoshare.EntrySlug("q3-report"),
+			err:         nil,
+		},
+		{
+			description: "treat empty string as no slug",
+			input:       "",
+			output:      picoshare.EntrySlug(""),
+			err:         nil,
+		},
+		{
+			description: "accept slug of maximum length",
+			input:       strings.Repeat("a", parse.MaxEntrySlugLength),
+			output:      picoshare.EntrySlug(strings.Repeat("a", parse.MaxEntrySlugLength)),
+			err:         nil,
+		},
+		{
+			description: "reject slug that's too short",
+			input:       "q3",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugTooShort,
+		},
+		{
+			description: "reject slug that's too long",
+			input:       strings.Repeat("a", parse.MaxEntrySlugLength+1),
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugTooLong,
+		},
+		{
+			description: "reject slug with a slash",
+			input:       "reports/q3",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with a space",
+			input:       "q3 report",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with consecutive hyphens",
+			input:       "q3--report",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug that starts with a hyphen",
+			input:       "-q3-report",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with non-ASCII letters",
+			input:       "übersicht",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with a lowercase l",
+			input:       "sales-q3",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with an uppercase L",
+			input:       "SALES-Q3",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with a zero",
+			input:       "q3-2024",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+		{
+			description: "reject slug with a one",
+			input:       "q1-report",
+			output:      picoshare.EntrySlug(""),
+			err:         parse.ErrEntrySlugInvalid,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			slug, err := parse.EntrySlug(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", err, want)
+			}
+			if got, want := slug, tt.output; got != want {
+				t.Errorf("slug=%v, want=%v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// MaxEventWebhookSecretBytes is the maximum number of bytes allowed in the
+// secret that PicoShare signs event webhooks with.
+const MaxEventWebhookSecretBytes = 256
+
+var ErrEventWebhookSecretTooLong = errors.New("event webhook secret is too long")
+
+// EventWebhookSettings checks that the event webhook settings point to a URL
+// that PicoShare can send requests to. It trims surrounding whitespace from
+// each field.
+func EventWebhookSettings(ws picoshare.EventWebhookSettings) (picoshare.EventWebhookSettings, error) {
+	url, err := notificationURL(ws.URL)
+	if err != nil {
+		return picoshare.EventWebhookSettings{}, fmt.Errorf("event webhook: %w", err)
+	}
+	if url == "" {
+		// Without a URL, there's nothing to sign.
+		return picoshare.EventWebhookSettings{}, nil
+	}
+
+	secret := strings.TrimSpace(ws.Secret)
+	if len(secret) > MaxEventWebhookSecretBytes {
+		return picoshare.EventWebhookSettings{}, ErrEventWebhookSecretTooLong
+	}
+
+	return picoshare.EventWebhookSettings{
+		URL:    url,
+		Secret: secret,
+	}, nil
+}
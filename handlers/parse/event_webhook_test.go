@@ -0,0 +1,92 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestEventWebhookSettings(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       picoshare.EventWebhookSettings
+		output      picoshare.EventWebhookSettings
+		valid       bool
+	}{
+		{
+			description: "accept empty settings",
+			input:       picoshare.EventWebhookSettings{},
+			output:      picoshare.EventWebhookSettings{},
+			valid:       true,
+		},
+		{
+			description: "accept URL and secret",
+			input: picoshare.EventWebhookSettings{
+				URL:    "https://tickets.example.com/hooks/picoshare",
+				Secret: "s3cr3t",
+			},
+			output: picoshare.EventWebhookSettings{
+				URL:    "https://tickets.example.com/hooks/picoshare",
+				Secret: "s3cr3t",
+			},
+			valid: true,
+		},
+		{
+			description: "accept URL without a secret",
+			input: picoshare.EventWebhookSettings{
+				URL: "http://localhost:9000/hook",
+			},
+			output: picoshare.EventWebhookSettings{
+				URL: "http://localhost:9000/hook",
+			},
+			valid: true,
+		},
+		{
+			description: "trim surrounding whitespace",
+			input: picoshare.EventWebhookSettings{
+				URL:    " https://tickets.example.com/hook ",
+				Secret: " s3cr3t\n",
+			},
+			output: picoshare.EventWebhookSettings{
+				URL:    "https://tickets.example.com/hook",
+				Secret: "s3cr3t",
+			},
+			valid: true,
+		},
+		{
+			description: "drop secret without a URL",
+			input: picoshare.EventWebhookSettings{
+				Secret: "s3cr3t",
+			},
+			output: picoshare.EventWebhookSettings{},
+			valid:  true,
+		},
+		{
+			description: "reject non-HTTP URL",
+			input: picoshare.EventWebhookSettings{
+				URL: "file:///etc/passwd",
+			},
+			valid: false,
+		},
+		{
+			description: "reject secret that's too long",
+			input: picoshare.EventWebhookSettings{
+				URL:    "https://tickets.example.com/hook",
+				Secret: strings.Repeat("A", parse.MaxEventWebhookSecretBytes+1),
+			},
+			valid: false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			settings, err := parse.EventWebhookSettings(tt.input)
+			if tt.valid != (err == nil) {
+				t.Fatalf("err=%v, want valid=%v", err, tt.valid)
+			}
+			if got, want := settings, tt.output; got != want {
+				t.Errorf("settings=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
@@ -10,6 +10,10 @@ import (
 // MaxFileNoteBytes is the maximum number of bytes allowed in a file note.
 const MaxFileNoteBytes = 500
 
+// MaxGuestFileNoteBytes is the maximum number of bytes allowed in a note that a
+// guest attaches to an upload.
+const MaxGuestFileNoteBytes = 250
+
 // illegalNoteTagPattern matches tags we don't allow in file notes. We have
 // other protections in place to prevent XSS and escaping HTML encoding, but
 // this is just defense in depth to catch highly suspicious strings that we
@@ -17,19 +21,39 @@ const MaxFileNoteBytes = 500
 var illegalNoteTagPattern = regexp.MustCompile(`<\s*/?((script)|(iframe))\s*>`)
 
 func FileNote(s string) (picoshare.FileNote, error) {
+	return parseNote(s, MaxFileNoteBytes)
+}
+
+// GuestFileNote parses a note that a guest attached to an upload. Guest notes
+// get the same checks as notes from the PicoShare owner, but they have a lower
+// length limit.
+func GuestFileNote(s string) (picoshare.FileNote, error) {
+	return parseNote(s, MaxGuestFileNoteBytes)
+}
+
+func parseNote(s string, maxBytes int) (picoshare.FileNote, error) {
 	if s == "" {
 		return picoshare.FileNote{}, nil
 	}
-	if len(s) > MaxFileNoteBytes {
+	if len(s) > maxBytes {
 		return picoshare.FileNote{}, errors.New("note is too long")
 	}
-	if err := checkJavaScriptNullOrUndefined(s); err != nil {
+	if err := checkNoteText(s); err != nil {
 		return picoshare.FileNote{}, err
 	}
+	return picoshare.FileNote{Value: &s}, nil
+}
+
+// checkNoteText rejects free-form text that's likely a client error or that
+// contains suspicious HTML.
+func checkNoteText(s string) error {
+	if err := checkJavaScriptNullOrUndefined(s); err != nil {
+		return err
+	}
 	if illegalNoteTagPattern.MatchString(s) {
-		return picoshare.FileNote{}, errors.New("note must not contain HTML tags")
+		return errors.New("note must not contain HTML tags")
 	}
-	return picoshare.FileNote{Value: &s}, nil
+	return nil
 }
 
 // If the client sent a value of 'null' or 'undefined', it's likely a JS error
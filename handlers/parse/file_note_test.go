@@ -85,6 +85,42 @@ func TestFileNote(t *testing.T) {
 	}
 }
 
+func TestGuestFileNote(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		valid       bool
+	}{
+		{
+			description: "valid message",
+			input:       "Here are the signed forms",
+			valid:       true,
+		},
+		{
+			description: "message of maximum bytes",
+			input:       strings.Repeat("A", parse.MaxGuestFileNoteBytes),
+			valid:       true,
+		},
+		{
+			description: "message that's too long for a guest but not for the owner",
+			input:       strings.Repeat("A", parse.MaxGuestFileNoteBytes+1),
+			valid:       false,
+		},
+		{
+			description: "contains a <script> tag",
+			input:       "<script>alert(1)</script>",
+			valid:       false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			_, err := parse.GuestFileNote(tt.input)
+			if got, want := err == nil, tt.valid; got != want {
+				t.Errorf("valid=%v, want=%v (err=%v)", got, want, err)
+			}
+		})
+	}
+}
+
 func makeFileNote(s string) picoshare.FileNote {
 	return picoshare.FileNote{Value: &s}
 }
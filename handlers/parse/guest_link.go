@@ -1,7 +1,13 @@
 package parse
 
 import (
+	"errors"
 	"fmt"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/mtlynch/picoshare/picoshare"
 )
@@ -9,7 +15,30 @@ import (
 // Arbitrary limit to prevent too-long labels in the UI.
 const MaxGuestLinkLabelLength = 200
 
+// bcrypt ignores everything past the first 72 bytes of a passphrase.
+const MaxGuestLinkPassphraseLength = 72
+
+// Arbitrary limit to keep the allowed file types legible in the UI.
+const MaxGuestUploadFileTypes = 20
+
+// Arbitrary limit to keep instructions short enough for guests to read.
+const MaxGuestLinkInstructionsBytes = 5000
+
+// Upload windows shorter than an hour would close before most guests finish
+// uploading, and longer windows are better served by an expiration time.
+const MinGuestLinkUploadWindow = time.Hour
+const MaxGuestLinkUploadWindow = 365 * 24 * time.Hour
+
 var ErrGuestLinkLabelTooLong = fmt.Errorf("label too long - limit %d characters", MaxGuestLinkLabelLength)
+var ErrGuestLinkPassphraseEmpty = errors.New("passphrase must not be empty")
+var ErrGuestLinkPassphraseTooLong = fmt.Errorf("passphrase too long - limit %d bytes", MaxGuestLinkPassphraseLength)
+var ErrGuestUploadFileTypesTooMany = fmt.Errorf("too many allowed file types - limit %d", MaxGuestUploadFileTypes)
+var ErrGuestLinkInstructionsTooLong = fmt.Errorf("instructions too long - limit %d bytes", MaxGuestLinkInstructionsBytes)
+var ErrGuestLinkUploadWindowInvalid = errors.New("upload window must be a duration such as 24h0m0s")
+var ErrGuestLinkUploadWindowTooShort = fmt.Errorf("upload window must be at least %v", MinGuestLinkUploadWindow)
+var ErrGuestLinkUploadWindowTooLong = fmt.Errorf("upload window must be at most %d days", MaxGuestLinkUploadWindow/(24*time.Hour))
+
+var fileExtensionPattern = regexp.MustCompile(`^(\.[a-z0-9_+-]+)+$`)
 
 func GuestLinkLabel(label string) (picoshare.GuestLinkLabel, error) {
 	if len(label) > MaxGuestLinkLabelLength {
@@ -18,3 +47,112 @@ func GuestLinkLabel(label string) (picoshare.GuestLinkLabel, error) {
 
 	return picoshare.GuestLinkLabel(label), nil
 }
+
+// GuestLinkInstructions validates Markdown-formatted instructions for guests.
+// It trims surrounding whitespace so that blank instructions count as none.
+func GuestLinkInstructions(instructions string) (picoshare.GuestLinkInstructions, error) {
+	instructions = strings.TrimSpace(instructions)
+	if len(instructions) > MaxGuestLinkInstructionsBytes {
+		return picoshare.GuestLinkInstructions(""), ErrGuestLinkInstructionsTooLong
+	}
+
+	return picoshare.GuestLinkInstructions(instructions), nil
+}
+
+// GuestLinkUploadWindow parses how long a guest link works after its first
+// upload. An empty string means that the link has no upload window.
+func GuestLinkUploadWindow(raw string) (picoshare.GuestLinkUploadWindow, error) {
+	if raw == "" {
+		return picoshare.NoUploadWindow, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return picoshare.NoUploadWindow, ErrGuestLinkUploadWindowInvalid
+	}
+	if d < MinGuestLinkUploadWindow {
+		return picoshare.NoUploadWindow, ErrGuestLinkUploadWindowTooShort
+	}
+	if d > MaxGuestLinkUploadWindow {
+		return picoshare.NoUploadWindow, ErrGuestLinkUploadWindowTooLong
+	}
+
+	return picoshare.GuestLinkUploadWindow(d), nil
+}
+
+// GuestLinkPassphrase validates a passphrase that guests must enter to use a
+// guest link.
+func GuestLinkPassphrase(passphrase string) (string, error) {
+	if strings.TrimSpace(passphrase) == "" {
+		return "", ErrGuestLinkPassphraseEmpty
+	}
+
+	if len(passphrase) > MaxGuestLinkPassphraseLength {
+		return "", ErrGuestLinkPassphraseTooLong
+	}
+
+	return passphrase, nil
+}
+
+// GuestUploadFileTypes validates and normalizes a list of the file extensions
+// and media types that guests may upload through a guest link.
+func GuestUploadFileTypes(raw []string) (picoshare.GuestUploadFileTypes, error) {
+	fileTypes := picoshare.GuestUploadFileTypes{}
+	for _, r := range raw {
+		fileType := strings.ToLower(strings.TrimSpace(r))
+		if fileType == "" {
+			continue
+		}
+
+		if strings.HasPrefix(fileType, ".") {
+			if !fileExtensionPattern.MatchString(fileType) {
+				return picoshare.GuestUploadFileTypes{}, fmt.Errorf("invalid file extension: %s", r)
+			}
+		} else if err := checkMediaTypePattern(fileType); err != nil {
+			return picoshare.GuestUploadFileTypes{}, fmt.Errorf("invalid media type %s: %w", r, err)
+		}
+
+		if slices.Contains(fileTypes, fileType) {
+			continue
+		}
+		fileTypes = append(fileTypes, fileType)
+	}
+
+	if len(fileTypes) > MaxGuestUploadFileTypes {
+		return picoshare.GuestUploadFileTypes{}, ErrGuestUploadFileTypesTooMany
+	}
+
+	return fileTypes, nil
+}
+
+// GuestUploadListing parses the setting that controls which uploaded files a
+// guest link's upload page lists. An empty string or "none" means that the page
+// lists no files.
+func GuestUploadListing(raw string) (picoshare.GuestUploadListing, error) {
+	switch raw {
+	case "", "none":
+		return picoshare.GuestUploadListingNone, nil
+	case picoshare.GuestUploadListingSession.String():
+		return picoshare.GuestUploadListingSession, nil
+	case picoshare.GuestUploadListingAll.String():
+		return picoshare.GuestUploadListingAll, nil
+	}
+	return picoshare.GuestUploadListingNone, fmt.Errorf("invalid upload listing: %s", raw)
+}
+
+func checkMediaTypePattern(s string) error {
+	mediaType, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return err
+	}
+	if len(params) > 0 || mediaType != s {
+		return errors.New("media type must not have parameters")
+	}
+
+	typ, subtype, ok := strings.Cut(mediaType, "/")
+	if !ok || typ == "*" || subtype == "" {
+		return errors.New("media type must have the form type/subtype or type/*")
+	}
+
+	return nil
+}
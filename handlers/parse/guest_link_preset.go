@@ -0,0 +1,50 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// Arbitrary limit to keep preset names short enough for a dropdown.
+const MaxGuestLinkPresetNameLength = 50
+
+// Arbitrary limit to keep the list of presets short enough to choose from.
+const MaxGuestLinkPresets = 20
+
+var ErrGuestLinkPresetNameEmpty = errors.New("preset name must not be empty")
+var ErrGuestLinkPresetNameTooLong = fmt.Errorf("preset name too long - limit %d characters", MaxGuestLinkPresetNameLength)
+var ErrGuestLinkPresetNameDuplicate = errors.New("preset names must be unique")
+var ErrGuestLinkPresetsTooMany = fmt.Errorf("too many guest link presets - limit %d", MaxGuestLinkPresets)
+
+// GuestLinkPresets validates the names of the owner's guest link presets. It
+// trims surrounding whitespace from each name and rejects names that differ
+// only in case, as they'd be indistinguishable in a list.
+func GuestLinkPresets(presets []picoshare.GuestLinkPreset) ([]picoshare.GuestLinkPreset, error) {
+	if len(presets) > MaxGuestLinkPresets {
+		return nil, ErrGuestLinkPresetsTooMany
+	}
+
+	var parsed []picoshare.GuestLinkPreset
+	seen := map[string]bool{}
+	for _, preset := range presets {
+		name := strings.TrimSpace(preset.Name)
+		if name == "" {
+			return nil, ErrGuestLinkPresetNameEmpty
+		}
+		if len([]rune(name)) > MaxGuestLinkPresetNameLength {
+			return nil, ErrGuestLinkPresetNameTooLong
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, ErrGuestLinkPresetNameDuplicate
+		}
+		seen[strings.ToLower(name)] = true
+
+		preset.Name = name
+		parsed = append(parsed, preset)
+	}
+
+	return parsed, nil
+}
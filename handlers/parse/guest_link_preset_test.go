@@ -0,0 +1,75 @@
+package parse_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestGuestLinkPresets(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       []picoshare.GuestLinkPreset
+		output      []picoshare.GuestLinkPreset
+		err         error
+	}{
+		{
+			description: "accept valid presets",
+			input: []picoshare.GuestLinkPreset{
+				{Name: " Client docs ", MaxFileLifetime: picoshare.NewFileLifetimeInDays(30)},
+				{Name: "Photos", MaxFileLifetime: picoshare.FileLifetimeInfinite},
+			},
+			output: []picoshare.GuestLinkPreset{
+				{Name: "Client docs", MaxFileLifetime: picoshare.NewFileLifetimeInDays(30)},
+				{Name: "Photos", MaxFileLifetime: picoshare.FileLifetimeInfinite},
+			},
+			err: nil,
+		},
+		{
+			description: "accept no presets",
+			input:       nil,
+			output:      nil,
+			err:         nil,
+		},
+		{
+			description: "reject preset without a name",
+			input: []picoshare.GuestLinkPreset{
+				{Name: "  "},
+			},
+			err: parse.ErrGuestLinkPresetNameEmpty,
+		},
+		{
+			description: "reject preset name that's too long",
+			input: []picoshare.GuestLinkPreset{
+				{Name: strings.Repeat("A", parse.MaxGuestLinkPresetNameLength+1)},
+			},
+			err: parse.ErrGuestLinkPresetNameTooLong,
+		},
+		{
+			description: "reject presets whose names differ only in case",
+			input: []picoshare.GuestLinkPreset{
+				{Name: "Photos"},
+				{Name: "photos"},
+			},
+			err: parse.ErrGuestLinkPresetNameDuplicate,
+		},
+		{
+			description: "reject too many presets",
+			input:       make([]picoshare.GuestLinkPreset, parse.MaxGuestLinkPresets+1),
+			err:         parse.ErrGuestLinkPresetsTooMany,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			presets, err := parse.GuestLinkPresets(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", got, want)
+			}
+			if got, want := presets, tt.output; !reflect.DeepEqual(got, want) {
+				t.Errorf("presets=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
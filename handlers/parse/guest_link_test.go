@@ -2,8 +2,10 @@ package parse_test
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mtlynch/picoshare/handlers/parse"
 	"github.com/mtlynch/picoshare/picoshare"
@@ -46,3 +48,257 @@ func TestGuestLinkLabel(t *testing.T) {
 		})
 	}
 }
+
+func TestGuestLinkInstructions(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.GuestLinkInstructions
+		err         error
+	}{
+		{
+			description: "accept Markdown instructions",
+			input:       "Please upload your **signed contract** here.",
+			output:      picoshare.GuestLinkInstructions("Please upload your **signed contract** here."),
+			err:         nil,
+		},
+		{
+			description: "allow empty instructions",
+			input:       "",
+			output:      picoshare.GuestLinkInstructions(""),
+			err:         nil,
+		},
+		{
+			description: "treat whitespace as empty instructions",
+			input:       " \n\t ",
+			output:      picoshare.GuestLinkInstructions(""),
+			err:         nil,
+		},
+		{
+			description: "reject instructions that are too long",
+			input:       strings.Repeat("A", parse.MaxGuestLinkInstructionsBytes+1),
+			output:      picoshare.GuestLinkInstructions(""),
+			err:         parse.ErrGuestLinkInstructionsTooLong,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			instructions, err := parse.GuestLinkInstructions(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", err, want)
+			}
+			if got, want := instructions, tt.output; got != want {
+				t.Errorf("instructions=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkUploadWindow(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.GuestLinkUploadWindow
+		err         error
+	}{
+		{
+			description: "accept 24-hour window",
+			input:       "24h0m0s",
+			output:      picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			err:         nil,
+		},
+		{
+			description: "treat empty string as no window",
+			input:       "",
+			output:      picoshare.NoUploadWindow,
+			err:         nil,
+		},
+		{
+			description: "reject window that isn't a duration",
+			input:       "1 day",
+			output:      picoshare.NoUploadWindow,
+			err:         parse.ErrGuestLinkUploadWindowInvalid,
+		},
+		{
+			description: "reject window shorter than the minimum",
+			input:       "59m",
+			output:      picoshare.NoUploadWindow,
+			err:         parse.ErrGuestLinkUploadWindowTooShort,
+		},
+		{
+			description: "reject negative window",
+			input:       "-24h",
+			output:      picoshare.NoUploadWindow,
+			err:         parse.ErrGuestLinkUploadWindowTooShort,
+		},
+		{
+			description: "reject window longer than the maximum",
+			input:       "8761h",
+			output:      picoshare.NoUploadWindow,
+			err:         parse.ErrGuestLinkUploadWindowTooLong,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			window, err := parse.GuestLinkUploadWindow(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", err, want)
+			}
+			if got, want := window, tt.output; got != want {
+				t.Errorf("window=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkPassphrase(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      string
+		err         error
+	}{
+		{
+			description: "accept valid passphrase",
+			input:       "correct horse battery staple",
+			output:      "correct horse battery staple",
+			err:         nil,
+		},
+		{
+			description: "accept passphrase at the length limit",
+			input:       strings.Repeat("A", parse.MaxGuestLinkPassphraseLength),
+			output:      strings.Repeat("A", parse.MaxGuestLinkPassphraseLength),
+			err:         nil,
+		},
+		{
+			description: "reject empty passphrase",
+			input:       "",
+			output:      "",
+			err:         parse.ErrGuestLinkPassphraseEmpty,
+		},
+		{
+			description: "reject passphrase of only whitespace",
+			input:       "   ",
+			output:      "",
+			err:         parse.ErrGuestLinkPassphraseEmpty,
+		},
+		{
+			description: "reject passphrases that are too long",
+			input:       strings.Repeat("A", parse.MaxGuestLinkPassphraseLength+1),
+			output:      "",
+			err:         parse.ErrGuestLinkPassphraseTooLong,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			passphrase, err := parse.GuestLinkPassphrase(tt.input)
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", err, want)
+			}
+			if got, want := passphrase, tt.output; got != want {
+				t.Errorf("passphrase=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestUploadFileTypes(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       []string
+		output      picoshare.GuestUploadFileTypes
+		wantErr     bool
+	}{
+		{
+			description: "nil list allows any file",
+			input:       nil,
+			output:      picoshare.GuestUploadFileTypes{},
+		},
+		{
+			description: "accept extensions and media types",
+			input:       []string{".pdf", "application/pdf", "image/*", ".tar.gz"},
+			output:      picoshare.GuestUploadFileTypes{".pdf", "application/pdf", "image/*", ".tar.gz"},
+		},
+		{
+			description: "normalize case and whitespace",
+			input:       []string{" .PDF ", "Application/PDF"},
+			output:      picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+		},
+		{
+			description: "skip empty entries and duplicates",
+			input:       []string{".pdf", "", "  ", ".PDF"},
+			output:      picoshare.GuestUploadFileTypes{".pdf"},
+		},
+		{
+			description: "reject extension without a name",
+			input:       []string{"."},
+			wantErr:     true,
+		},
+		{
+			description: "reject extension with invalid characters",
+			input:       []string{".p,df"},
+			wantErr:     true,
+		},
+		{
+			description: "reject bare word",
+			input:       []string{"pdf"},
+			wantErr:     true,
+		},
+		{
+			description: "reject media type with parameters",
+			input:       []string{"text/plain; charset=utf-8"},
+			wantErr:     true,
+		},
+		{
+			description: "reject wildcard for every media type",
+			input:       []string{"*/*"},
+			wantErr:     true,
+		},
+		{
+			description: "reject too many file types",
+			input: func() []string {
+				types := []string{}
+				for i := 0; i <= parse.MaxGuestUploadFileTypes; i++ {
+					types = append(types, fmt.Sprintf(".ext%d", i))
+				}
+				return types
+			}(),
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			fileTypes, err := parse.GuestUploadFileTypes(tt.input)
+			if gotErr, wantErr := err != nil, tt.wantErr; gotErr != wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, want := fileTypes, tt.output; !reflect.DeepEqual(got, want) {
+				t.Errorf("fileTypes=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestUploadListing(t *testing.T) {
+	for _, tt := range []struct {
+		input   string
+		output  picoshare.GuestUploadListing
+		wantErr bool
+	}{
+		{"", picoshare.GuestUploadListingNone, false},
+		{"none", picoshare.GuestUploadListingNone, false},
+		{"session", picoshare.GuestUploadListingSession, false},
+		{"all", picoshare.GuestUploadListingAll, false},
+		{"All", picoshare.GuestUploadListingNone, true},
+		{"everything", picoshare.GuestUploadListingNone, true},
+	} {
+		t.Run(fmt.Sprintf("%q", tt.input), func(t *testing.T) {
+			listing, err := parse.GuestUploadListing(tt.input)
+			if gotErr, wantErr := err != nil, tt.wantErr; gotErr != wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, wantErr)
+			}
+			if got, want := listing, tt.output; got != want {
+				t.Errorf("listing=%q, want=%q", got, want)
+			}
+		})
+	}
+}
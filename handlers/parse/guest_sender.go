@@ -0,0 +1,64 @@
+package parse
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// MaxSenderNameBytes is the maximum number of bytes allowed in the name that a
+// guest provides with an upload.
+const MaxSenderNameBytes = 100
+
+// MaxSenderEmailBytes is the maximum number of bytes allowed in the email
+// address that a guest provides with an upload. It matches the longest email
+// address that SMTP can deliver to.
+const MaxSenderEmailBytes = 254
+
+var ErrSenderNameTooLong = errors.New("sender name is too long")
+var ErrSenderNameIllegalCharacters = errors.New("illegal characters in sender name")
+var ErrSenderEmailTooLong = errors.New("sender email is too long")
+var ErrSenderEmailInvalid = errors.New("sender email is not a valid email address")
+
+// SenderName parses the name that a guest provides with an upload. An empty
+// string means that the guest didn't provide a name.
+func SenderName(s string) (picoshare.SenderName, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return picoshare.SenderName(""), nil
+	}
+	if len(s) > MaxSenderNameBytes {
+		return picoshare.SenderName(""), ErrSenderNameTooLong
+	}
+	if strings.ContainsAny(s, "\a\b\t\n\v\f\r") {
+		return picoshare.SenderName(""), ErrSenderNameIllegalCharacters
+	}
+	if err := checkNoteText(s); err != nil {
+		return picoshare.SenderName(""), err
+	}
+	return picoshare.SenderName(s), nil
+}
+
+// SenderEmail parses the email address that a guest provides with an upload.
+// An empty string means that the guest didn't provide an email address.
+func SenderEmail(s string) (picoshare.SenderEmail, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return picoshare.SenderEmail(""), nil
+	}
+	if len(s) > MaxSenderEmailBytes {
+		return picoshare.SenderEmail(""), ErrSenderEmailTooLong
+	}
+	if err := checkNoteText(s); err != nil {
+		return picoshare.SenderEmail(""), err
+	}
+	// Accept only a bare address, not a display name with an address in angle
+	// brackets.
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return picoshare.SenderEmail(""), ErrSenderEmailInvalid
+	}
+	return picoshare.SenderEmail(s), nil
+}
@@ -0,0 +1,132 @@
+package parse_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestSenderName(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.SenderName
+		valid       bool
+	}{
+		{
+			description: "accept valid name",
+			input:       "Jane Doe",
+			output:      picoshare.SenderName("Jane Doe"),
+			valid:       true,
+		},
+		{
+			description: "allow empty name",
+			input:       "",
+			output:      picoshare.SenderName(""),
+			valid:       true,
+		},
+		{
+			description: "trim surrounding whitespace",
+			input:       "  Jane Doe ",
+			output:      picoshare.SenderName("Jane Doe"),
+			valid:       true,
+		},
+		{
+			description: "accept name of maximum length",
+			input:       strings.Repeat("A", parse.MaxSenderNameBytes),
+			output:      picoshare.SenderName(strings.Repeat("A", parse.MaxSenderNameBytes)),
+			valid:       true,
+		},
+		{
+			description: "reject name that's too long",
+			input:       strings.Repeat("A", parse.MaxSenderNameBytes+1),
+			valid:       false,
+		},
+		{
+			description: "reject name with a line break",
+			input:       "Jane\nDoe",
+			valid:       false,
+		},
+		{
+			description: "reject literal null string",
+			input:       "null",
+			valid:       false,
+		},
+		{
+			description: "reject name with a <script> tag",
+			input:       "<script>alert(1)</script>",
+			valid:       false,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s [%s]", tt.description, tt.input), func(t *testing.T) {
+			name, err := parse.SenderName(tt.input)
+			if tt.valid != (err == nil) {
+				t.Fatalf("err=%v, want valid=%v", err, tt.valid)
+			}
+			if got, want := name, tt.output; got != want {
+				t.Errorf("name=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestSenderEmail(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		output      picoshare.SenderEmail
+		valid       bool
+	}{
+		{
+			description: "accept valid email",
+			input:       "jane@example.com",
+			output:      picoshare.SenderEmail("jane@example.com"),
+			valid:       true,
+		},
+		{
+			description: "allow empty email",
+			input:       "",
+			output:      picoshare.SenderEmail(""),
+			valid:       true,
+		},
+		{
+			description: "trim surrounding whitespace",
+			input:       " jane@example.com ",
+			output:      picoshare.SenderEmail("jane@example.com"),
+			valid:       true,
+		},
+		{
+			description: "reject email without a domain",
+			input:       "jane",
+			valid:       false,
+		},
+		{
+			description: "reject email with a display name",
+			input:       "Jane Doe <jane@example.com>",
+			valid:       false,
+		},
+		{
+			description: "reject multiple addresses",
+			input:       "jane@example.com, joe@example.com",
+			valid:       false,
+		},
+		{
+			description: "reject email that's too long",
+			input:       strings.Repeat("a", parse.MaxSenderEmailBytes) + "@example.com",
+			valid:       false,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s [%s]", tt.description, tt.input), func(t *testing.T) {
+			email, err := parse.SenderEmail(tt.input)
+			if tt.valid != (err == nil) {
+				t.Fatalf("err=%v, want valid=%v", err, tt.valid)
+			}
+			if got, want := email, tt.output; got != want {
+				t.Errorf("email=%v, want=%v", got, want)
+			}
+		})
+	}
+}
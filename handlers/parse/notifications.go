@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// MaxNotificationURLBytes is the maximum number of bytes allowed in a webhook
+// or ntfy topic URL.
+const MaxNotificationURLBytes = 2048
+
+var ErrSMTPHostInvalid = errors.New("SMTP host is not a valid hostname")
+var ErrSMTPRecipientRequired = errors.New("SMTP settings must include a recipient address")
+var ErrNotificationEmailInvalid = errors.New("notification email is not a valid email address")
+var ErrNotificationURLInvalid = errors.New("notification URL must be an absolute http or https URL")
+var ErrNotificationURLTooLong = errors.New("notification URL is too long")
+var ErrNotificationCredentialInvalid = errors.New("notification credentials must not contain line breaks")
+
+// NotificationSettings checks that notification settings describe channels
+// that PicoShare can send to. It trims surrounding whitespace from each field.
+func NotificationSettings(ns picoshare.NotificationSettings) (picoshare.NotificationSettings, error) {
+	email, err := emailNotificationSettings(ns.Email)
+	if err != nil {
+		return picoshare.NotificationSettings{}, err
+	}
+
+	webhookURL, err := notificationURL(ns.Webhook.URL)
+	if err != nil {
+		return picoshare.NotificationSettings{}, fmt.Errorf("webhook: %w", err)
+	}
+
+	topicURL, err := notificationURL(ns.Ntfy.TopicURL)
+	if err != nil {
+		return picoshare.NotificationSettings{}, fmt.Errorf("ntfy: %w", err)
+	}
+	accessToken := strings.TrimSpace(ns.Ntfy.AccessToken)
+	if strings.ContainsAny(accessToken, "\r\n") {
+		return picoshare.NotificationSettings{}, fmt.Errorf("ntfy: %w", ErrNotificationCredentialInvalid)
+	}
+
+	return picoshare.NotificationSettings{
+		OnGuestUpload:   ns.OnGuestUpload,
+		OnFirstDownload: ns.OnFirstDownload,
+		Email:           email,
+		Webhook: picoshare.WebhookNotificationSettings{
+			URL: webhookURL,
+		},
+		Ntfy: picoshare.NtfyNotificationSettings{
+			TopicURL:    topicURL,
+			AccessToken: accessToken,
+		},
+	}, nil
+}
+
+func emailNotificationSettings(es picoshare.EmailNotificationSettings) (picoshare.EmailNotificationSettings, error) {
+	host := strings.TrimSpace(es.Host)
+	if host == "" {
+		// Without a host, the rest of the SMTP settings are meaningless.
+		return picoshare.EmailNotificationSettings{}, nil
+	}
+	if strings.ContainsAny(host, " \t\r\n/:@") {
+		return picoshare.EmailNotificationSettings{}, ErrSMTPHostInvalid
+	}
+
+	to, err := notificationEmail(es.To)
+	if err != nil {
+		return picoshare.EmailNotificationSettings{}, err
+	}
+	if to == "" {
+		return picoshare.EmailNotificationSettings{}, ErrSMTPRecipientRequired
+	}
+
+	from, err := notificationEmail(es.From)
+	if err != nil {
+		return picoshare.EmailNotificationSettings{}, err
+	}
+
+	username := strings.TrimSpace(es.Username)
+	if strings.ContainsAny(username, "\r\n") || strings.ContainsAny(es.Password, "\r\n") {
+		return picoshare.EmailNotificationSettings{}, fmt.Errorf("SMTP: %w", ErrNotificationCredentialInvalid)
+	}
+
+	return picoshare.EmailNotificationSettings{
+		Host:     host,
+		Port:     es.Port,
+		Username: username,
+		Password: es.Password,
+		From:     from,
+		To:       to,
+	}, nil
+}
+
+func notificationEmail(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return "", ErrNotificationEmailInvalid
+	}
+	return s, nil
+}
+
+func notificationURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if len(s) > MaxNotificationURLBytes {
+		return "", ErrNotificationURLTooLong
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrNotificationURLInvalid
+	}
+	return s, nil
+}
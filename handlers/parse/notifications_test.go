@@ -0,0 +1,208 @@
+package parse_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestNotificationSettings(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       picoshare.NotificationSettings
+		output      picoshare.NotificationSettings
+		valid       bool
+	}{
+		{
+			description: "accept empty settings",
+			input:       picoshare.NotificationSettings{},
+			output:      picoshare.NotificationSettings{},
+			valid:       true,
+		},
+		{
+			description: "accept every channel",
+			input: picoshare.NotificationSettings{
+				OnGuestUpload:   true,
+				OnFirstDownload: true,
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Port:     587,
+					Username: "picoshare",
+					Password: "hunter2",
+					From:     "picoshare@example.com",
+					To:       "owner@example.com",
+				},
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "https://hooks.example.com/picoshare",
+				},
+				Ntfy: picoshare.NtfyNotificationSettings{
+					TopicURL:    "https://ntfy.sh/picoshare-uploads",
+					AccessToken: "tk_dummy",
+				},
+			},
+			output: picoshare.NotificationSettings{
+				OnGuestUpload:   true,
+				OnFirstDownload: true,
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Port:     587,
+					Username: "picoshare",
+					Password: "hunter2",
+					From:     "picoshare@example.com",
+					To:       "owner@example.com",
+				},
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "https://hooks.example.com/picoshare",
+				},
+				Ntfy: picoshare.NtfyNotificationSettings{
+					TopicURL:    "https://ntfy.sh/picoshare-uploads",
+					AccessToken: "tk_dummy",
+				},
+			},
+			valid: true,
+		},
+		{
+			description: "trim surrounding whitespace",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: " smtp.example.com ",
+					To:   " owner@example.com",
+				},
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "http://localhost:8080/hook ",
+				},
+			},
+			output: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: "smtp.example.com",
+					To:   "owner@example.com",
+				},
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "http://localhost:8080/hook",
+				},
+			},
+			valid: true,
+		},
+		{
+			description: "drop SMTP settings without a host",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Username: "picoshare",
+					To:       "owner@example.com",
+				},
+			},
+			output: picoshare.NotificationSettings{},
+			valid:  true,
+		},
+		{
+			description: "reject SMTP settings without a recipient",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: "smtp.example.com",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject SMTP host that includes a port",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: "smtp.example.com:587",
+					To:   "owner@example.com",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject recipient with a display name",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: "smtp.example.com",
+					To:   "Owner <owner@example.com>",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject invalid sender address",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host: "smtp.example.com",
+					From: "picoshare",
+					To:   "owner@example.com",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject SMTP password with a line break",
+			input: picoshare.NotificationSettings{
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Password: "hunter2\r\nRCPT TO:<victim@example.com>",
+					To:       "owner@example.com",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject webhook URL with a non-HTTP scheme",
+			input: picoshare.NotificationSettings{
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "ftp://example.com/hook",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject relative webhook URL",
+			input: picoshare.NotificationSettings{
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "/hook",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject webhook URL that's too long",
+			input: picoshare.NotificationSettings{
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "https://example.com/" + strings.Repeat("a", parse.MaxNotificationURLBytes),
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject ntfy topic URL without a host",
+			input: picoshare.NotificationSettings{
+				Ntfy: picoshare.NtfyNotificationSettings{
+					TopicURL: "https:///uploads",
+				},
+			},
+			valid: false,
+		},
+		{
+			description: "reject ntfy access token with a line break",
+			input: picoshare.NotificationSettings{
+				Ntfy: picoshare.NtfyNotificationSettings{
+					TopicURL:    "https://ntfy.sh/uploads",
+					AccessToken: "tk_dummy\nX-Injected: true",
+				},
+			},
+			valid: false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			settings, err := parse.NotificationSettings(tt.input)
+			if tt.valid != (err == nil) {
+				t.Fatalf("err=%v, want valid=%v", err, tt.valid)
+			}
+			if got, want := settings, tt.output; !reflect.DeepEqual(got, want) {
+				t.Errorf("settings=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
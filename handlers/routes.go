@@ -13,13 +13,17 @@ func (s *Server) routes() {
 	authenticatedApis.HandleFunc("/entry/{id}", s.entryPut()).Methods(http.MethodPut)
 	authenticatedApis.HandleFunc("/entry/{id}", s.entryDelete()).Methods(http.MethodDelete)
 	authenticatedApis.HandleFunc("/guest-links", s.guestLinksPost()).Methods(http.MethodPost)
+	authenticatedApis.HandleFunc("/guest-links/{id}", s.guestLinksPut()).Methods(http.MethodPut)
 	authenticatedApis.HandleFunc("/guest-links/{id}", s.guestLinksDelete()).Methods(http.MethodDelete)
 	authenticatedApis.HandleFunc("/guest-links/{id}/enable", s.guestLinksEnableDisable()).Methods(http.MethodPut)
 	authenticatedApis.HandleFunc("/guest-links/{id}/disable", s.guestLinksEnableDisable()).Methods(http.MethodPut)
 	authenticatedApis.HandleFunc("/settings", s.settingsPut()).Methods(http.MethodPut)
+	authenticatedApis.HandleFunc("/settings/notifications/test", s.settingsNotificationsTestPost()).Methods(http.MethodPost)
 
 	publicApis := s.router.PathPrefix("/api").Subrouter()
 	publicApis.HandleFunc("/guest/{guestLinkID}", s.guestEntryPost()).Methods(http.MethodPost)
+	publicApis.HandleFunc("/guest/{guestLinkID}/unlock", s.guestLinkUnlockPost()).Methods(http.MethodPost)
+	publicApis.HandleFunc("/guest/{guestLinkID}/entries/{id}", s.guestEntryDelete()).Methods(http.MethodDelete)
 
 	static := s.router.PathPrefix("/").Subrouter()
 	static.PathPrefix("/css/").HandlerFunc(serveStaticResource()).Methods(http.MethodGet)
@@ -45,6 +49,7 @@ func (s *Server) routes() {
 	authenticatedViews := s.router.PathPrefix("/").Subrouter()
 	authenticatedViews.Use(s.requireAuthentication)
 	authenticatedViews.Use(enforceContentSecurityPolicy)
+	authenticatedViews.Use(s.loadBranding)
 	authenticatedViews.HandleFunc("/information", s.systemInformationGet()).Methods(http.MethodGet)
 	authenticatedViews.HandleFunc("/files", s.fileIndexGet()).Methods(http.MethodGet)
 	authenticatedViews.HandleFunc("/files/{id}/downloads", s.fileDownloadsGet()).Methods(http.MethodGet)
@@ -53,11 +58,14 @@ func (s *Server) routes() {
 	authenticatedViews.HandleFunc("/files/{id}/confirm-delete", s.fileConfirmDeleteGet()).Methods(http.MethodGet)
 	authenticatedViews.HandleFunc("/guest-links", s.guestLinkIndexGet()).Methods(http.MethodGet)
 	authenticatedViews.HandleFunc("/guest-links/new", s.guestLinksNewGet()).Methods(http.MethodGet)
+	authenticatedViews.HandleFunc("/guest-links/{id}/edit", s.guestLinkEditGet()).Methods(http.MethodGet)
 	authenticatedViews.HandleFunc("/settings", s.settingsGet()).Methods(http.MethodGet)
+	authenticatedViews.HandleFunc("/settings/webhook-deliveries", s.webhookDeliveriesGet()).Methods(http.MethodGet)
 
 	views := s.router.PathPrefix("/").Subrouter()
 	views.Use(upgradeToHttps)
 	views.Use(enforceContentSecurityPolicy)
+	views.Use(s.loadBranding)
 	views.HandleFunc("/login", s.authGet()).Methods(http.MethodGet)
 	views.PathPrefix("/g/{guestLinkID}").HandlerFunc(s.guestUploadGet()).Methods(http.MethodGet)
 	views.HandleFunc("/", s.indexGet()).Methods(http.MethodGet)
@@ -72,6 +80,9 @@ func (s *Server) routes() {
 			next.ServeHTTP(w, r)
 		})
 	})
+	// The logo is an image that the owner uploaded, so serve it in the same
+	// sandbox as other uploads.
+	downloadViews.HandleFunc("/branding/logo", s.brandingLogoGet()).Methods(http.MethodGet)
 	downloadViews.PathPrefix("/-{id}").HandlerFunc(s.entryGet()).Methods(http.MethodGet)
 	downloadViews.PathPrefix("/-{id}/{filename}").HandlerFunc(s.entryGet()).Methods(http.MethodGet)
 	// Legacy routes for entries. We stopped using them because the ! has
@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
+	"github.com/mtlynch/picoshare/notify"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/random"
 	"github.com/mtlynch/picoshare/space"
 )
 
 type (
 	SpaceChecker interface {
-		Check() (space.Usage, error)
+		Check(context.Context) (space.Usage, error)
 	}
 
 	Clock interface {
@@ -25,13 +30,42 @@ type (
 		Authenticate(r *http.Request) bool
 	}
 
+	Notifier interface {
+		Notify(picoshare.NotificationSettings, notify.Event)
+		Send(context.Context, picoshare.NotificationSettings, notify.Event) error
+	}
+
+	// Options contains the dependencies of a Server.
+	Options struct {
+		Authenticator Authenticator
+		Store         Store
+		SpaceChecker  SpaceChecker
+		// StorageLimits restricts how much disk space uploads may consume. The
+		// zero value places no restriction on uploads.
+		StorageLimits space.Limits
+		Collector     *garbagecollect.Collector
+		// Notifier reports events to the owner. If it's nil, the server sends
+		// notifications through the channels in the owner's settings.
+		Notifier Notifier
+		// Clock reports the current time. If it's nil, the server uses the
+		// system clock.
+		Clock Clock
+		// GuestLinkUnlocker remembers which passphrase-protected guest links a
+		// client has unlocked. If it's zero, the server uses a random key, so
+		// guest links lock again when the server restarts.
+		GuestLinkUnlocker guest_passphrase.Unlocker
+	}
+
 	Server struct {
-		router        *mux.Router
-		authenticator Authenticator
-		store         Store
-		spaceChecker  SpaceChecker
-		collector     *garbagecollect.Collector
-		clock         Clock
+		router            *mux.Router
+		authenticator     Authenticator
+		store             Store
+		spaceChecker      SpaceChecker
+		storageLimits     space.Limits
+		collector         *garbagecollect.Collector
+		notifier          Notifier
+		clock             Clock
+		guestLinkUnlocker guest_passphrase.Unlocker
 	}
 )
 
@@ -42,14 +76,30 @@ func (s Server) Router() *mux.Router {
 
 // New creates a new server with all the state it needs to satisfy HTTP
 // requests.
-func New(authenticator Authenticator, store Store, spaceChecker SpaceChecker, collector *garbagecollect.Collector, clock Clock) Server {
+func New(opts Options) Server {
+	notifier := opts.Notifier
+	if notifier == nil {
+		notifier = notify.NewDispatcher()
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = NewClock()
+	}
+	guestLinkUnlocker := opts.GuestLinkUnlocker
+	if guestLinkUnlocker.IsZero() {
+		guestLinkUnlocker = guest_passphrase.New(random.Bytes(32))
+	}
+
 	s := Server{
-		router:        mux.NewRouter(),
-		authenticator: authenticator,
-		store:         store,
-		spaceChecker:  spaceChecker,
-		collector:     collector,
-		clock:         clock,
+		router:            mux.NewRouter(),
+		authenticator:     opts.Authenticator,
+		store:             opts.Store,
+		spaceChecker:      opts.SpaceChecker,
+		storageLimits:     opts.StorageLimits,
+		collector:         opts.Collector,
+		notifier:          notifier,
+		clock:             clock,
+		guestLinkUnlocker: guestLinkUnlocker,
 	}
 
 	s.routes()
@@ -7,18 +7,72 @@ import (
 	"net/http"
 
 	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/notify"
 	"github.com/mtlynch/picoshare/picoshare"
 )
 
+// notificationSettingsPayload is the JSON representation of the owner's
+// notification settings. The SMTP password and ntfy access token are
+// pointers because the settings page never displays them, so a missing value
+// means that the owner wants to keep the current secret.
+type notificationSettingsPayload struct {
+	OnGuestUpload   bool    `json:"onGuestUpload"`
+	OnFirstDownload bool    `json:"onFirstDownload"`
+	SMTPHost        string  `json:"smtpHost"`
+	SMTPPort        uint16  `json:"smtpPort"`
+	SMTPUsername    string  `json:"smtpUsername"`
+	SMTPPassword    *string `json:"smtpPassword"`
+	SMTPFrom        string  `json:"smtpFrom"`
+	SMTPTo          string  `json:"smtpTo"`
+	WebhookURL      string  `json:"webhookUrl"`
+	NtfyTopicURL    string  `json:"ntfyTopicUrl"`
+	NtfyAccessToken *string `json:"ntfyAccessToken"`
+}
+
+// eventWebhookSettingsPayload is the JSON representation of the owner's event
+// webhook settings. As with notification credentials, a missing secret means
+// that the owner wants to keep the current one.
+type eventWebhookSettingsPayload struct {
+	URL    string  `json:"url"`
+	Secret *string `json:"secret"`
+}
+
+// brandingSettingsPayload is the JSON representation of the instance's
+// branding. The logo is a base64-encoded data URL. A missing logo means that
+// the owner wants to keep the current logo, and an empty logo means that they
+// want to remove it.
+type brandingSettingsPayload struct {
+	Name        string  `json:"name"`
+	Logo        *string `json:"logo"`
+	AccentColor string  `json:"accentColor"`
+}
+
+// guestLinkPresetPayload is the JSON representation of a guest link preset.
+// Its fields match the limits in a guest link request.
+type guestLinkPresetPayload struct {
+	Name           string  `json:"name"`
+	FileLifetime   string  `json:"fileLifetime"`
+	MaxFileBytes   *uint64 `json:"maxFileBytes"`
+	MaxFileUploads *int    `json:"maxFileUploads"`
+	MaxTotalBytes  *uint64 `json:"maxTotalBytes"`
+}
+
 func (s Server) settingsPut() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		settings, err := settingsFromRequest(r)
+		current, err := s.getDB(r).ReadSettings(r.Context())
+		if err != nil {
+			log.Printf("failed to read settings: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to read settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		settings, err := settingsFromRequest(r, current)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 			return
 		}
 
-		if err := s.getDB(r).UpdateSettings(settings); err != nil {
+		if err := s.getDB(r).UpdateSettings(r.Context(), settings); err != nil {
 			log.Printf("failed to save settings: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to save settings: %v", err), http.StatusInternalServerError)
 			return
@@ -26,10 +80,56 @@ func (s Server) settingsPut() http.HandlerFunc {
 	}
 }
 
-func settingsFromRequest(r *http.Request) (picoshare.Settings, error) {
+// settingsNotificationsTestPost sends a test notification through the
+// channels in the request so that the owner can check their settings before
+// saving them.
+func (s Server) settingsNotificationsTestPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, err := s.getDB(r).ReadSettings(r.Context())
+		if err != nil {
+			log.Printf("failed to read settings: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to read settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		var payload notificationSettingsPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("failed to decode JSON request: %v", err)
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		notifications, err := notificationSettingsFromPayload(payload, current.Notifications)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if !notifications.HasChannels() {
+			http.Error(w, "Invalid request: no notification channels are configured", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.notifier.Send(r.Context(), notifications, notify.Event{
+			Kind: notify.EventTest,
+			Time: s.clock.Now(),
+		}); err != nil {
+			log.Printf("failed to send test notification: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to send test notification: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func settingsFromRequest(r *http.Request, current picoshare.Settings) (picoshare.Settings, error) {
 	var payload struct {
-		DefaultExpirationDays uint16 `json:"defaultExpirationDays"`
-		DefaultNeverExpire    bool   `json:"defaultNeverExpire"`
+		DefaultExpirationDays uint16                      `json:"defaultExpirationDays"`
+		DefaultNeverExpire    bool                        `json:"defaultNeverExpire"`
+		Notifications         notificationSettingsPayload `json:"notifications"`
+		EventWebhook          eventWebhookSettingsPayload `json:"eventWebhook"`
+		Branding              brandingSettingsPayload     `json:"branding"`
+		GuestLinkPresets      []guestLinkPresetPayload    `json:"guestLinkPresets"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
@@ -46,7 +146,114 @@ func settingsFromRequest(r *http.Request) (picoshare.Settings, error) {
 		return picoshare.Settings{}, err
 	}
 
+	notifications, err := notificationSettingsFromPayload(payload.Notifications, current.Notifications)
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
+	eventWebhookSecret := current.EventWebhook.Secret
+	if payload.EventWebhook.Secret != nil {
+		eventWebhookSecret = *payload.EventWebhook.Secret
+	}
+	eventWebhook, err := parse.EventWebhookSettings(picoshare.EventWebhookSettings{
+		URL:    payload.EventWebhook.URL,
+		Secret: eventWebhookSecret,
+	})
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
+	logo := current.Branding.Logo
+	if payload.Branding.Logo != nil {
+		if logo, err = parse.BrandingLogoDataURL(*payload.Branding.Logo); err != nil {
+			return picoshare.Settings{}, err
+		}
+	}
+	branding, err := parse.BrandingSettings(picoshare.BrandingSettings{
+		Name:        payload.Branding.Name,
+		Logo:        logo,
+		AccentColor: payload.Branding.AccentColor,
+	})
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
+	presets, err := guestLinkPresetsFromPayload(payload.GuestLinkPresets)
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
 	return picoshare.Settings{
 		DefaultFileLifetime: defaultLifetime,
+		Notifications:       notifications,
+		EventWebhook:        eventWebhook,
+		Branding:            branding,
+		GuestLinkPresets:    presets,
 	}, nil
 }
+
+func guestLinkPresetsFromPayload(payload []guestLinkPresetPayload) ([]picoshare.GuestLinkPreset, error) {
+	var presets []picoshare.GuestLinkPreset
+	for _, p := range payload {
+		fileLifetime, err := parse.FileLifetimeFromString(p.FileLifetime)
+		if err != nil {
+			return nil, err
+		}
+
+		maxFileBytes, err := parseMaxFileBytes(p.MaxFileBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		maxFileUploads, err := parseUploadCountLimit(p.MaxFileUploads)
+		if err != nil {
+			return nil, err
+		}
+
+		maxTotalBytes, err := parseMaxTotalBytes(p.MaxTotalBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		presets = append(presets, picoshare.GuestLinkPreset{
+			Name:            p.Name,
+			MaxFileLifetime: fileLifetime,
+			MaxFileBytes:    maxFileBytes,
+			MaxFileUploads:  maxFileUploads,
+			MaxTotalBytes:   maxTotalBytes,
+		})
+	}
+
+	return parse.GuestLinkPresets(presets)
+}
+
+func notificationSettingsFromPayload(payload notificationSettingsPayload, current picoshare.NotificationSettings) (picoshare.NotificationSettings, error) {
+	smtpPassword := current.Email.Password
+	if payload.SMTPPassword != nil {
+		smtpPassword = *payload.SMTPPassword
+	}
+	ntfyAccessToken := current.Ntfy.AccessToken
+	if payload.NtfyAccessToken != nil {
+		ntfyAccessToken = *payload.NtfyAccessToken
+	}
+
+	return parse.NotificationSettings(picoshare.NotificationSettings{
+		OnGuestUpload:   payload.OnGuestUpload,
+		OnFirstDownload: payload.OnFirstDownload,
+		Email: picoshare.EmailNotificationSettings{
+			Host:     payload.SMTPHost,
+			Port:     payload.SMTPPort,
+			Username: payload.SMTPUsername,
+			Password: smtpPassword,
+			From:     payload.SMTPFrom,
+			To:       payload.SMTPTo,
+		},
+		Webhook: picoshare.WebhookNotificationSettings{
+			URL: payload.WebhookURL,
+		},
+		Ntfy: picoshare.NtfyNotificationSettings{
+			TopicURL:    payload.NtfyTopicURL,
+			AccessToken: ntfyAccessToken,
+		},
+	})
+}
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -39,6 +40,205 @@ func TestSettingsPut(t *testing.T) {
 			},
 			status: http.StatusOK,
 		},
+		{
+			description: "valid request with notification settings",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"notifications": {
+						"onGuestUpload": true,
+						"smtpHost": "smtp.example.com",
+						"smtpPort": 465,
+						"smtpUsername": "picoshare",
+						"smtpPassword": "hunter2",
+						"smtpTo": "owner@example.com",
+						"ntfyTopicUrl": "https://ntfy.sh/picoshare-uploads"
+					}
+				}`,
+			settings: picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				Notifications: picoshare.NotificationSettings{
+					OnGuestUpload: true,
+					Email: picoshare.EmailNotificationSettings{
+						Host:     "smtp.example.com",
+						Port:     465,
+						Username: "picoshare",
+						Password: "hunter2",
+						To:       "owner@example.com",
+					},
+					Ntfy: picoshare.NtfyNotificationSettings{
+						TopicURL: "https://ntfy.sh/picoshare-uploads",
+					},
+				},
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "rejects invalid notification email address",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"notifications": {
+						"smtpHost": "smtp.example.com",
+						"smtpTo": "not an email"
+					}
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "rejects SMTP port out of range",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"notifications": {
+						"smtpHost": "smtp.example.com",
+						"smtpPort": 70000,
+						"smtpTo": "owner@example.com"
+					}
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "valid request with event webhook",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"eventWebhook": {
+						"url": " https://hooks.example.com/picoshare-events ",
+						"secret": "s3cr3t"
+					}
+				}`,
+			settings: picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				EventWebhook: picoshare.EventWebhookSettings{
+					URL:    "https://hooks.example.com/picoshare-events",
+					Secret: "s3cr3t",
+				},
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "rejects event webhook with invalid URL",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"eventWebhook": {
+						"url": "ftp://hooks.example.com/picoshare-events"
+					}
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "valid request with branding",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"name": " Acme Files ",
+						"accentColor": "#1A73E8"
+					}
+				}`,
+			settings: picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				Branding: picoshare.BrandingSettings{
+					Name:        "Acme Files",
+					AccentColor: "#1a73e8",
+				},
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "rejects branding with invalid accent color",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"accentColor": "red; background: url(x)"
+					}
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "rejects branding with an SVG logo",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"logo": "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciLz4="
+					}
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "valid request with guest link presets",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"guestLinkPresets": [
+						{
+							"name": "Client documents",
+							"fileLifetime": "720h0m0s",
+							"maxFileBytes": 20971520,
+							"maxFileUploads": 3,
+							"maxTotalBytes": null
+						},
+						{
+							"name": " Photos ",
+							"fileLifetime": "876000h0m0s",
+							"maxFileBytes": null,
+							"maxFileUploads": null,
+							"maxTotalBytes": 104857600
+						}
+					]
+				}`,
+			settings: picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				GuestLinkPresets: []picoshare.GuestLinkPreset{
+					{
+						Name:            "Client documents",
+						MaxFileLifetime: picoshare.NewFileLifetimeInDays(30),
+						MaxFileBytes:    makeGuestUploadMaxFileBytes(20971520),
+						MaxFileUploads:  makeGuestUploadCountLimit(3),
+					},
+					{
+						Name:            "Photos",
+						MaxFileLifetime: picoshare.FileLifetimeInfinite,
+						MaxTotalBytes:   makeGuestUploadMaxTotalBytes(104857600),
+					},
+				},
+			},
+			status: http.StatusOK,
+		},
+		{
+			description: "rejects guest link presets with duplicate names",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"guestLinkPresets": [
+						{"name": "Photos", "fileLifetime": "720h0m0s"},
+						{"name": "photos", "fileLifetime": "168h0m0s"}
+					]
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "rejects guest link preset with an invalid file lifetime",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"guestLinkPresets": [
+						{"name": "Photos", "fileLifetime": "banana"}
+					]
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
+		{
+			description: "rejects guest link preset with a zero upload limit",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"guestLinkPresets": [
+						{"name": "Photos", "fileLifetime": "720h0m0s", "maxFileUploads": 0}
+					]
+				}`,
+			settings: picoshare.Settings{},
+			status:   http.StatusBadRequest,
+		},
 		{
 			description: "rejects invalid expiration days (too low)",
 			payload: `{
@@ -66,7 +266,10 @@ func TestSettingsPut(t *testing.T) {
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			dataStore := test_sqlite.New()
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
 
 			req := httptest.NewRequest(
 				http.MethodPut,
@@ -88,7 +291,7 @@ func TestSettingsPut(t *testing.T) {
 				return
 			}
 
-			settings, err := dataStore.ReadSettings()
+			settings, err := dataStore.ReadSettings(context.Background())
 			if err != nil {
 				t.Fatalf("failed to retrieve settings from datastore: %v", err)
 			}
@@ -99,3 +302,296 @@ func TestSettingsPut(t *testing.T) {
 		})
 	}
 }
+
+func TestSettingsPutKeepsNotificationSecrets(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		payload     string
+		email       picoshare.EmailNotificationSettings
+		ntfy        picoshare.NtfyNotificationSettings
+	}{
+		{
+			description: "keeps secrets that the request omits",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"notifications": {
+						"smtpHost": "smtp.example.com",
+						"smtpUsername": "picoshare",
+						"smtpTo": "owner@example.com",
+						"ntfyTopicUrl": "https://ntfy.sh/picoshare-uploads"
+					}
+				}`,
+			email: picoshare.EmailNotificationSettings{
+				Host:     "smtp.example.com",
+				Username: "picoshare",
+				Password: "hunter2",
+				To:       "owner@example.com",
+			},
+			ntfy: picoshare.NtfyNotificationSettings{
+				TopicURL:    "https://ntfy.sh/picoshare-uploads",
+				AccessToken: "tk_dummy",
+			},
+		},
+		{
+			description: "replaces secrets that the request includes",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"notifications": {
+						"smtpHost": "smtp.example.com",
+						"smtpUsername": "picoshare",
+						"smtpPassword": "correct horse",
+						"smtpTo": "owner@example.com",
+						"ntfyTopicUrl": "https://ntfy.sh/picoshare-uploads",
+						"ntfyAccessToken": ""
+					}
+				}`,
+			email: picoshare.EmailNotificationSettings{
+				Host:     "smtp.example.com",
+				Username: "picoshare",
+				Password: "correct horse",
+				To:       "owner@example.com",
+			},
+			ntfy: picoshare.NtfyNotificationSettings{
+				TopicURL: "https://ntfy.sh/picoshare-uploads",
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				Notifications: picoshare.NotificationSettings{
+					Email: picoshare.EmailNotificationSettings{
+						Host:     "smtp.example.com",
+						Username: "picoshare",
+						Password: "hunter2",
+						To:       "owner@example.com",
+					},
+					Ntfy: picoshare.NtfyNotificationSettings{
+						TopicURL:    "https://ntfy.sh/picoshare-uploads",
+						AccessToken: "tk_dummy",
+					},
+				},
+			}); err != nil {
+				t.Fatalf("failed to save settings: %v", err)
+			}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(tt.payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to retrieve settings from datastore: %v", err)
+			}
+			if got, want := settings.Notifications.Email, tt.email; got != want {
+				t.Errorf("email settings=%+v, want=%+v", got, want)
+			}
+			if got, want := settings.Notifications.Ntfy, tt.ntfy; got != want {
+				t.Errorf("ntfy settings=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSettingsPutKeepsEventWebhookSecret(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		payload     string
+		want        picoshare.EventWebhookSettings
+	}{
+		{
+			description: "keeps secret that the request omits",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"eventWebhook": {
+						"url": "https://hooks.example.com/new"
+					}
+				}`,
+			want: picoshare.EventWebhookSettings{
+				URL:    "https://hooks.example.com/new",
+				Secret: "s3cr3t",
+			},
+		},
+		{
+			description: "replaces secret that the request includes",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"eventWebhook": {
+						"url": "https://hooks.example.com/picoshare-events",
+						"secret": "n3w-s3cr3t"
+					}
+				}`,
+			want: picoshare.EventWebhookSettings{
+				URL:    "https://hooks.example.com/picoshare-events",
+				Secret: "n3w-s3cr3t",
+			},
+		},
+		{
+			description: "clears secret along with the URL",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"eventWebhook": {
+						"url": ""
+					}
+				}`,
+			want: picoshare.EventWebhookSettings{},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				EventWebhook: picoshare.EventWebhookSettings{
+					URL:    "https://hooks.example.com/picoshare-events",
+					Secret: "s3cr3t",
+				},
+			}); err != nil {
+				t.Fatalf("failed to save settings: %v", err)
+			}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(tt.payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to retrieve settings from datastore: %v", err)
+			}
+			if got, want := settings.EventWebhook, tt.want; got != want {
+				t.Errorf("event webhook settings=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSettingsPutKeepsBrandingLogo(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		payload     string
+		want        picoshare.BrandingLogo
+	}{
+		{
+			description: "keeps logo that the request omits",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"name": "Acme Files"
+					}
+				}`,
+			want: picoshare.BrandingLogo{
+				ContentType: picoshare.ContentType("image/png"),
+				Data:        dummyPNG,
+			},
+		},
+		{
+			description: "replaces logo that the request includes",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"logo": "data:image/gif;base64,R0lGODlhAQABAAAAACw="
+					}
+				}`,
+			want: picoshare.BrandingLogo{
+				ContentType: picoshare.ContentType("image/gif"),
+				Data:        []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00,"),
+			},
+		},
+		{
+			description: "removes logo when the request sends an empty logo",
+			payload: `{
+					"defaultExpirationDays": 7,
+					"branding": {
+						"logo": ""
+					}
+				}`,
+			want: picoshare.BrandingLogo{},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newStoreWithBrandingLogo(t)
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(tt.payload))
+			req.Header.Add("Content-Type", "text/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to retrieve settings from datastore: %v", err)
+			}
+			if got, want := settings.Branding.Logo, tt.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("logo=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSettingsGetShowsGuestLinkPresets(t *testing.T) {
+	dataStore := test_sqlite.New()
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		GuestLinkPresets: []picoshare.GuestLinkPreset{
+			{
+				Name:            "Client documents",
+				MaxFileLifetime: picoshare.NewFileLifetimeInDays(30),
+				MaxFileBytes:    makeGuestUploadMaxFileBytes(20 * 1024 * 1024),
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/settings", nil))
+
+	if got, want := rec.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`value="Client documents"`,
+		`value="20"`,
+		`id="guest-link-preset-template"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("settings page doesn't contain %q", want)
+		}
+	}
+}
@@ -61,10 +61,16 @@ export async function guestUploadFile(
   file,
   guestLinkID,
   expirationTime,
+  senderDetails,
   progressFn
 ) {
   const formData = new FormData();
   formData.append("file", file);
+  for (const [field, value] of Object.entries(senderDetails || {})) {
+    if (value) {
+      formData.append(field, value);
+    }
+  }
   return uploadFormData(
     `/api/guest/${guestLinkID}?expiration=${encodeURIComponent(
       expirationTime
@@ -74,10 +80,11 @@ export async function guestUploadFile(
   );
 }
 
-export async function editFile(id, filename, expiration, note) {
+export async function editFile(id, filename, expiration, note, slug) {
   let payload = {
     filename,
     note,
+    slug,
   };
   if (expiration) {
     payload.expiration = expiration;
@@ -129,3 +136,27 @@ export async function deleteFile(id) {
       return Promise.reject(error);
     });
 }
+
+export async function guestDeleteFile(guestLinkId, id) {
+  return fetch(`/api/guest/${guestLinkId}/entries/${id}`, {
+    method: "DELETE",
+    credentials: "include",
+  })
+    .then((response) => {
+      if (!response.ok) {
+        return response.text().then((error) => {
+          return Promise.reject(error);
+        });
+      }
+      return Promise.resolve();
+    })
+    .catch((error) => {
+      if (error.message) {
+        return Promise.reject(
+          "Failed to communicate with server" +
+            (error.message ? `: ${error.message}` : ".")
+        );
+      }
+      return Promise.reject(error);
+    });
+}
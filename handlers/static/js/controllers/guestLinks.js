@@ -5,7 +5,14 @@ export async function guestLinkNew(
   urlExpirationTime,
   fileLifetime,
   maxFileBytes,
-  maxFileUploads
+  maxFileUploads,
+  maxTotalBytes,
+  passphrase,
+  allowedFileTypes,
+  allowSenderDetails,
+  uploadListing,
+  instructions,
+  uploadWindow
 ) {
   return fetch("/api/guest-links", {
     method: "POST",
@@ -16,6 +23,93 @@ export async function guestLinkNew(
       fileLifetime,
       maxFileBytes,
       maxFileUploads,
+      maxTotalBytes,
+      passphrase,
+      allowedFileTypes,
+      allowSenderDetails,
+      uploadListing,
+      instructions,
+      uploadWindow,
+    }),
+  })
+    .then((response) => {
+      if (!response.ok) {
+        return response.text().then((error) => {
+          return Promise.reject(error);
+        });
+      }
+      return Promise.resolve();
+    })
+    .catch((error) => {
+      if (error.message) {
+        return Promise.reject(
+          "Failed to communicate with server" +
+            (error.message ? `: ${error.message}` : ".")
+        );
+      }
+      return Promise.reject(error);
+    });
+}
+
+export async function guestLinkUpdate(
+  id,
+  label,
+  urlExpirationTime,
+  fileLifetime,
+  maxFileBytes,
+  maxFileUploads,
+  maxTotalBytes,
+  passphrase,
+  allowedFileTypes,
+  allowSenderDetails,
+  uploadListing,
+  instructions,
+  uploadWindow
+) {
+  return fetch(`/api/guest-links/${id}`, {
+    method: "PUT",
+    credentials: "include",
+    body: JSON.stringify({
+      label,
+      urlExpirationTime,
+      fileLifetime,
+      maxFileBytes,
+      maxFileUploads,
+      maxTotalBytes,
+      passphrase,
+      allowedFileTypes,
+      allowSenderDetails,
+      uploadListing,
+      instructions,
+      uploadWindow,
+    }),
+  })
+    .then((response) => {
+      if (!response.ok) {
+        return response.text().then((error) => {
+          return Promise.reject(error);
+        });
+      }
+      return Promise.resolve();
+    })
+    .catch((error) => {
+      if (error.message) {
+        return Promise.reject(
+          "Failed to communicate with server" +
+            (error.message ? `: ${error.message}` : ".")
+        );
+      }
+      return Promise.reject(error);
+    });
+}
+
+export async function guestLinkUnlock(id, passphrase) {
+  return fetch(`/api/guest/${id}/unlock`, {
+    method: "POST",
+    mode: "same-origin",
+    credentials: "include",
+    body: JSON.stringify({
+      passphrase,
     }),
   })
     .then((response) => {
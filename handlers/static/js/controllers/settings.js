@@ -24,3 +24,28 @@ export async function settingsPut(settings) {
       return Promise.reject(error);
     });
 }
+
+export async function settingsNotificationsTest(notifications) {
+  return fetch("/api/settings/notifications/test", {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(notifications),
+  })
+    .then((response) => {
+      if (!response.ok) {
+        return response.text().then((error) => {
+          return Promise.reject(error);
+        });
+      }
+      return Promise.resolve();
+    })
+    .catch((error) => {
+      if (error.message) {
+        return Promise.reject(
+          "Failed to communicate with server" +
+            (error.message ? `: ${error.message}` : ".")
+        );
+      }
+      return Promise.reject(error);
+    });
+}
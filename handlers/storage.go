@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space"
+)
+
+type (
+	// insufficientStorageError occurs when an upload doesn't fit within
+	// PicoShare's storage limits or within its guest link's quota.
+	insufficientStorageError struct {
+		reason string
+	}
+
+	// uploadLimitReader wraps the body of an upload request and fails as soon as
+	// the client sends more bytes than PicoShare has room to store.
+	uploadLimitReader struct {
+		body      io.ReadCloser
+		remaining uint64
+		reason    string
+		err       error
+	}
+)
+
+func (e *insufficientStorageError) Error() string {
+	return e.reason
+}
+
+// limitUploadToAvailableStorage checks whether an upload can fit within
+// PicoShare's storage limits and within the quota of the guest link that the
+// client is uploading through, if any. If the upload can't fit, it returns an
+// insufficientStorageError. Otherwise, it limits the request body so that the
+// upload fails once the client sends more data than PicoShare has room for.
+//
+// The limit applies to the whole request body, so PicoShare refuses uploads
+// slightly below the limit because the other fields of a multipart request take
+// up a few hundred bytes.
+func (s Server) limitUploadToAvailableStorage(r *http.Request, gl picoshare.GuestLink) error {
+	available := uint64(math.MaxUint64)
+	var reason string
+
+	if !s.storageLimits.IsUnlimited() {
+		usage, err := s.spaceChecker.Check(r.Context())
+		if err != nil {
+			return err
+		}
+		available = s.storageLimits.AvailableBytes(usage)
+		reason = "PicoShare does not have enough storage space left for this upload"
+	}
+
+	if remaining, ok := gl.RemainingBytes(); ok && remaining < available {
+		available = remaining
+		reason = "upload exceeds the guest link's storage quota"
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	if available == 0 || (r.ContentLength > 0 && uint64(r.ContentLength) > available) {
+		return &insufficientStorageError{reason}
+	}
+
+	r.Body = &uploadLimitReader{
+		body:      r.Body,
+		remaining: available,
+		reason:    reason,
+	}
+
+	return nil
+}
+
+// makeRoomForUpload runs the garbage collector if an upload took PicoShare's
+// stored data beyond its storage limit, so that the eviction policy applies
+// right away rather than at the next scheduled collection. The collector never
+// evicts the new upload itself. If PicoShare can't get back under its storage
+// limit without evicting the new upload, makeRoomForUpload deletes the upload
+// and returns an insufficientStorageError.
+func (s Server) makeRoomForUpload(r *http.Request, id picoshare.EntryID) error {
+	if s.storageLimits.MaxStoredBytes == 0 || s.storageLimits.EvictionPolicy == space.EvictNothing {
+		return nil
+	}
+
+	// Finish evicting even if the client disconnects after its upload succeeds.
+	ctx := context.WithoutCancel(r.Context())
+
+	usage, err := s.spaceChecker.Check(ctx)
+	if err != nil {
+		log.Printf("failed to check storage usage after upload: %v", err)
+		return nil
+	}
+	if usage.TotalStoredBytes <= s.storageLimits.MaxStoredBytes {
+		return nil
+	}
+
+	log.Printf("stored data (%d bytes) exceeds storage limit (%d bytes), evicting entries", usage.TotalStoredBytes, s.storageLimits.MaxStoredBytes)
+	err = s.collector.CollectExcept(ctx, id)
+	if _, ok := errors.AsType[*garbagecollect.StorageLimitExceededError](err); ok {
+		log.Printf("deleting upload %v because evicting other entries didn't make room for it: %v", id, err)
+		if err := s.getDB(r).DeleteEntry(ctx, id); err != nil {
+			log.Printf("failed to delete upload %v: %v", id, err)
+		}
+		return &insufficientStorageError{"PicoShare does not have enough storage space left for this upload"}
+	} else if err != nil {
+		log.Printf("failed to evict entries after upload: %v", err)
+	}
+
+	return nil
+}
+
+// respondStorageLimitError responds to an upload that
+// limitUploadToAvailableStorage refused.
+func respondStorageLimitError(w http.ResponseWriter, err error) {
+	if ise, ok := errors.AsType[*insufficientStorageError](err); ok {
+		log.Printf("refusing upload: %v", ise)
+		http.Error(w, ise.Error(), http.StatusInsufficientStorage)
+		return
+	}
+	log.Printf("failed to check available storage: %v", err)
+	http.Error(w, "Failed to check available storage", http.StatusInternalServerError)
+}
+
+func (lr *uploadLimitReader) Read(p []byte) (int, error) {
+	if lr.err != nil {
+		return 0, lr.err
+	}
+
+	// Read one byte beyond the limit so that we can tell whether the body
+	// exceeds the limit or ends exactly at it.
+	if uint64(len(p)) > lr.remaining {
+		p = p[:lr.remaining+1]
+	}
+
+	n, err := lr.body.Read(p)
+	if uint64(n) <= lr.remaining {
+		lr.remaining -= uint64(n)
+		return n, err
+	}
+
+	n = int(lr.remaining)
+	lr.remaining = 0
+	lr.err = &insufficientStorageError{lr.reason}
+	return n, lr.err
+}
+
+func (lr *uploadLimitReader) Close() error {
+	return lr.body.Close()
+}
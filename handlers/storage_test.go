@@ -0,0 +1,415 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+)
+
+type mockSpaceChecker struct {
+	usage space.Usage
+	err   error
+}
+
+func (c mockSpaceChecker) Check(context.Context) (space.Usage, error) {
+	return c.usage, c.err
+}
+
+func TestEntryPostStorageLimits(t *testing.T) {
+	contents := strings.Repeat("A", 4096)
+	for _, tt := range []struct {
+		description      string
+		limits           space.Limits
+		usage            space.Usage
+		checkErr         error
+		knownBodyLength  bool
+		status           int
+		entriesInStore   int
+		expectedBodyText string
+	}{
+		{
+			description:    "no limits",
+			limits:         space.Limits{},
+			checkErr:       errors.New("space checker should not run without limits"),
+			status:         http.StatusOK,
+			entriesInStore: 1,
+		},
+		{
+			description: "upload fits within storage cap",
+			limits:      space.Limits{MaxStoredBytes: 1024 * 1024},
+			usage: space.Usage{
+				TotalStoredBytes:     1024,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			knownBodyLength: true,
+			status:          http.StatusOK,
+			entriesInStore:  1,
+		},
+		{
+			description: "declared upload size exceeds storage cap",
+			limits:      space.Limits{MaxStoredBytes: 3000},
+			usage: space.Usage{
+				TotalStoredBytes:     1000,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			knownBodyLength:  true,
+			status:           http.StatusInsufficientStorage,
+			expectedBodyText: "not have enough storage space",
+		},
+		{
+			description: "streamed upload exceeds storage cap",
+			limits:      space.Limits{MaxStoredBytes: 3000},
+			usage: space.Usage{
+				TotalStoredBytes:     1000,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			knownBodyLength:  false,
+			status:           http.StatusInsufficientStorage,
+			expectedBodyText: "not have enough storage space",
+		},
+		{
+			description: "storage cap is already full",
+			limits:      space.Limits{MaxStoredBytes: 1000},
+			usage: space.Usage{
+				TotalStoredBytes:     1000,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			knownBodyLength: false,
+			status:          http.StatusInsufficientStorage,
+		},
+		{
+			description: "streamed upload would leave too little free space",
+			limits:      space.Limits{MinFreeBytes: 1024 * 1024},
+			usage: space.Usage{
+				TotalStoredBytes:     0,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 + 1024*1024 + 2000,
+			},
+			knownBodyLength: false,
+			status:          http.StatusInsufficientStorage,
+		},
+		{
+			description: "upload leaves enough free space",
+			limits:      space.Limits{MinFreeBytes: 1024 * 1024},
+			usage: space.Usage{
+				TotalStoredBytes:     0,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			knownBodyLength: false,
+			status:          http.StatusOK,
+			entriesInStore:  1,
+		},
+		{
+			description:     "space checker fails",
+			limits:          space.Limits{MaxStoredBytes: 1024 * 1024},
+			checkErr:        errors.New("dummy space checker error"),
+			knownBodyLength: true,
+			status:          http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			spaceChecker := mockSpaceChecker{usage: tt.usage, err: tt.checkErr}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				SpaceChecker:  spaceChecker,
+				StorageLimits: tt.limits,
+			})
+
+			formData, contentType := createMultipartFormBody("dummy.txt", "", strings.NewReader(contents))
+			if tt.knownBodyLength {
+				formData = bytes.NewReader(mustReadAll(formData))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/entry?expiration=2040-01-01T00:00:00Z", formData)
+			req.Header.Add("Content-Type", contentType)
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+
+			if !strings.Contains(string(body), tt.expectedBodyText) {
+				t.Errorf("body=%s, want it to contain %s", body, tt.expectedBodyText)
+			}
+
+			entries, err := dataStore.GetEntriesMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("failed to list entries metadata: %v", err)
+			}
+
+			if got, want := len(entries), tt.entriesInStore; got != want {
+				t.Errorf("entry count=%d, want=%d", got, want)
+			}
+		})
+	}
+}
+
+type mockPurger struct{}
+
+func (mockPurger) Purge(context.Context) error {
+	return nil
+}
+
+type mockEvictor struct {
+	err       error
+	evictions int
+	kept      picoshare.EntryID
+}
+
+func (e *mockEvictor) Evict(context.Context) error {
+	e.evictions++
+	return e.err
+}
+
+func (e *mockEvictor) EvictExcept(_ context.Context, keep picoshare.EntryID) error {
+	e.kept = keep
+	return e.Evict(context.Background())
+}
+
+func TestEntryPostEvictsWhenOverStorageLimit(t *testing.T) {
+	for _, tt := range []struct {
+		description       string
+		limits            space.Limits
+		usage             space.Usage
+		evictErr          error
+		status            int
+		evictionsExpected int
+		entriesInStore    int
+	}{
+		{
+			description: "evicts when upload exceeds storage limit",
+			limits: space.Limits{
+				MaxStoredBytes: 3000,
+				EvictionPolicy: space.EvictOldestFirst,
+			},
+			usage: space.Usage{
+				TotalStoredBytes:     5000,
+				FileSystemUsedBytes:  5000,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			status:            http.StatusOK,
+			evictionsExpected: 1,
+			entriesInStore:    1,
+		},
+		{
+			description: "rejects upload when evicting other entries doesn't make room",
+			limits: space.Limits{
+				MaxStoredBytes: 3000,
+				EvictionPolicy: space.EvictOldestFirst,
+			},
+			usage: space.Usage{
+				TotalStoredBytes:     5000,
+				FileSystemUsedBytes:  5000,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			evictErr:          &garbagecollect.StorageLimitExceededError{StoredBytes: 4000, MaxStoredBytes: 3000},
+			status:            http.StatusInsufficientStorage,
+			evictionsExpected: 1,
+			entriesInStore:    0,
+		},
+		{
+			description: "skips eviction when upload stays within storage limit",
+			limits: space.Limits{
+				MaxStoredBytes: 1024 * 1024,
+				EvictionPolicy: space.EvictOldestFirst,
+			},
+			usage: space.Usage{
+				TotalStoredBytes:     5000,
+				FileSystemUsedBytes:  5000,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			status:            http.StatusOK,
+			evictionsExpected: 0,
+			entriesInStore:    1,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			spaceChecker := mockSpaceChecker{usage: tt.usage}
+			evictor := mockEvictor{err: tt.evictErr}
+			collector := garbagecollect.NewCollectorWithEvictor(mockPurger{}, &evictor)
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				SpaceChecker:  spaceChecker,
+				StorageLimits: tt.limits,
+				Collector:     &collector,
+			})
+
+			formData, contentType := createMultipartFormBody("dummy.txt", "", strings.NewReader("dummy upload"))
+			req := httptest.NewRequest(http.MethodPost, "/api/entry?expiration=2040-01-01T00:00:00Z", formData)
+			req.Header.Add("Content-Type", contentType)
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if got, want := evictor.evictions, tt.evictionsExpected; got != want {
+				t.Errorf("evictions=%d, want=%d", got, want)
+			}
+
+			entries, err := dataStore.GetEntriesMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("failed to get entry metadata: %v", err)
+			}
+			if got, want := len(entries), tt.entriesInStore; got != want {
+				t.Fatalf("entries=%d, want=%d", got, want)
+			}
+
+			// The evictor must never choose the new upload to make room for itself.
+			if evictor.evictions > 0 && len(entries) > 0 {
+				if got, want := evictor.kept, entries[0].ID; got != want {
+					t.Errorf("kept=%v, want=%v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGuestUploadStorageQuota(t *testing.T) {
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	previousUpload := strings.Repeat("B", 1000)
+	for _, tt := range []struct {
+		description     string
+		maxTotalBytes   picoshare.GuestUploadMaxTotalBytes
+		limits          space.Limits
+		usage           space.Usage
+		contents        string
+		knownBodyLength bool
+		status          int
+	}{
+		{
+			description:   "guest link without a quota",
+			maxTotalBytes: picoshare.GuestUploadUnlimitedTotalBytes,
+			contents:      strings.Repeat("A", 4096),
+			status:        http.StatusOK,
+		},
+		{
+			description:   "upload fits within guest link quota",
+			maxTotalBytes: makeGuestUploadMaxTotalBytes(10000),
+			contents:      strings.Repeat("A", 4096),
+			status:        http.StatusOK,
+		},
+		{
+			description:     "declared upload size exceeds remaining guest link quota",
+			maxTotalBytes:   makeGuestUploadMaxTotalBytes(4000),
+			contents:        strings.Repeat("A", 4096),
+			knownBodyLength: true,
+			status:          http.StatusInsufficientStorage,
+		},
+		{
+			description:   "streamed upload exceeds remaining guest link quota",
+			maxTotalBytes: makeGuestUploadMaxTotalBytes(4000),
+			contents:      strings.Repeat("A", 4096),
+			status:        http.StatusInsufficientStorage,
+		},
+		{
+			description:   "guest link has used its entire quota",
+			maxTotalBytes: makeGuestUploadMaxTotalBytes(1000),
+			contents:      "A",
+			status:        http.StatusInsufficientStorage,
+		},
+		{
+			description:   "server storage cap is stricter than guest link quota",
+			maxTotalBytes: makeGuestUploadMaxTotalBytes(1024 * 1024),
+			limits:        space.Limits{MaxStoredBytes: 3000},
+			usage: space.Usage{
+				TotalStoredBytes:     1000,
+				FileSystemUsedBytes:  1024,
+				FileSystemTotalBytes: 1024 * 1024 * 1024,
+			},
+			contents: strings.Repeat("A", 4096),
+			status:   http.StatusInsufficientStorage,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			if err := dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
+				ID:              guestLinkID,
+				Created:         mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:      picoshare.NeverExpire,
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxTotalBytes:   tt.maxTotalBytes,
+			}); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+			if err := dataStore.InsertEntry(context.Background(), strings.NewReader(previousUpload), picoshare.UploadMetadata{
+				ID:        picoshare.EntryID("dummy-id"),
+				GuestLink: picoshare.GuestLink{ID: guestLinkID},
+				Uploaded:  mustParseTime("2024-01-01T00:00:00Z"),
+				Expires:   picoshare.NeverExpire,
+				Size:      mustParseFileSize(len(previousUpload)),
+			}); err != nil {
+				t.Fatalf("failed to insert dummy entry: %v", err)
+			}
+
+			spaceChecker := mockSpaceChecker{usage: tt.usage}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				SpaceChecker:  spaceChecker,
+				StorageLimits: tt.limits,
+			})
+
+			formData, contentType := createMultipartFormBody("dummy.txt", "", strings.NewReader(tt.contents))
+			if tt.knownBodyLength {
+				formData = bytes.NewReader(mustReadAll(formData))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/guest/"+guestLinkID.String(), formData)
+			req.Header.Add("Content-Type", contentType)
+			req.Header.Add("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			gl, err := dataStore.GetGuestLink(context.Background(), guestLinkID)
+			if err != nil {
+				t.Fatalf("failed to get guest link: %v", err)
+			}
+
+			wantFiles := 1
+			if tt.status == http.StatusOK {
+				wantFiles = 2
+			}
+			if got, want := gl.FilesUploaded, wantFiles; got != want {
+				t.Errorf("files uploaded=%d, want=%d", got, want)
+			}
+		})
+	}
+}
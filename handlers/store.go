@@ -1,26 +1,37 @@
 package handlers
 
 import (
+	"context"
 	"io"
 
 	"github.com/mtlynch/picoshare/picoshare"
 )
 
 type Store interface {
-	GetEntriesMetadata() ([]picoshare.UploadMetadata, error)
-	ReadEntryFile(picoshare.EntryID) (io.ReadSeeker, error)
-	GetEntryMetadata(id picoshare.EntryID) (picoshare.UploadMetadata, error)
-	InsertEntry(reader io.Reader, metadata picoshare.UploadMetadata) error
-	UpdateEntryMetadata(id picoshare.EntryID, metadata picoshare.UploadMetadata) error
-	DeleteEntry(id picoshare.EntryID) error
-	GetGuestLink(picoshare.GuestLinkID) (picoshare.GuestLink, error)
-	GetGuestLinks() ([]picoshare.GuestLink, error)
-	InsertGuestLink(picoshare.GuestLink) error
-	DeleteGuestLink(picoshare.GuestLinkID) error
-	DisableGuestLink(picoshare.GuestLinkID) error
-	EnableGuestLink(picoshare.GuestLinkID) error
-	InsertEntryDownload(picoshare.EntryID, picoshare.DownloadRecord) error
-	GetEntryDownloads(id picoshare.EntryID) ([]picoshare.DownloadRecord, error)
-	ReadSettings() (picoshare.Settings, error)
-	UpdateSettings(picoshare.Settings) error
+	GetEntriesMetadata(context.Context) ([]picoshare.UploadMetadata, error)
+	ReadEntryFile(context.Context, picoshare.EntryID) (io.ReadSeeker, error)
+	GetEntryMetadata(ctx context.Context, id picoshare.EntryID) (picoshare.UploadMetadata, error)
+	InsertEntry(ctx context.Context, reader io.Reader, metadata picoshare.UploadMetadata) error
+	UpdateEntryMetadata(ctx context.Context, id picoshare.EntryID, metadata picoshare.UploadMetadata) error
+	GetEntryIDBySlug(ctx context.Context, slug picoshare.EntrySlug) (picoshare.EntryID, error)
+	DeleteEntry(ctx context.Context, id picoshare.EntryID) error
+	GetGuestLinkEntriesMetadata(context.Context, picoshare.GuestLinkID) ([]picoshare.UploadMetadata, error)
+	GetGuestLink(context.Context, picoshare.GuestLinkID) (picoshare.GuestLink, error)
+	GetGuestLinks(context.Context) ([]picoshare.GuestLink, error)
+	InsertGuestLink(context.Context, picoshare.GuestLink) error
+	UpdateGuestLink(context.Context, picoshare.GuestLinkID, picoshare.GuestLink) error
+	DeleteGuestLink(context.Context, picoshare.GuestLinkID) error
+	DisableGuestLink(context.Context, picoshare.GuestLinkID) error
+	EnableGuestLink(context.Context, picoshare.GuestLinkID) error
+	InsertEntryDownload(context.Context, picoshare.EntryID, picoshare.DownloadRecord) error
+	GetEntryDownloads(ctx context.Context, id picoshare.EntryID) ([]picoshare.DownloadRecord, error)
+	MarkEntryDownloaded(ctx context.Context, id picoshare.EntryID) (bool, error)
+	ReadSettings(context.Context) (picoshare.Settings, error)
+	ReadBrandingSettings(context.Context) (picoshare.BrandingSettings, error)
+	ReadBrandingLogo(context.Context) (picoshare.BrandingLogo, error)
+	UpdateSettings(context.Context, picoshare.Settings) error
+	GetCorruptEntries(context.Context) ([]picoshare.CorruptEntry, error)
+	GetEvictedEntries(context.Context) ([]picoshare.EvictedEntry, error)
+	InsertWebhookDelivery(context.Context, picoshare.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, limit int) ([]picoshare.WebhookDelivery, error)
 }
@@ -4,15 +4,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 
 	"github.com/gorilla/mux"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_session"
 	"github.com/mtlynch/picoshare/handlers/parse"
+	"github.com/mtlynch/picoshare/notify"
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/random"
 	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/webhook"
 )
 
 const EntryIDLength = 10
@@ -22,7 +27,8 @@ var entryIDCharacters = []rune("abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXY
 
 type (
 	EntryPostResponse struct {
-		ID string `json:"id"`
+		ID     string `json:"id"`
+		SHA256 string `json:"sha256"`
 	}
 
 	dbError struct {
@@ -47,12 +53,21 @@ func (s Server) entryPost() http.HandlerFunc {
 			return
 		}
 
-		// We're intentionally not limiting the size of the request because we
-		// assume that the uploading user is trusted, so they can upload files of
-		// any size they want.
-		id, err := s.insertFileFromRequest(r, expiration, picoshare.GuestLinkID(""))
+		// We're intentionally not limiting the size of individual files because
+		// we assume that the uploading user is trusted, so they can upload files
+		// of any size they want, as long as they fit in PicoShare's storage
+		// limits.
+		if err := s.limitUploadToAvailableStorage(r, picoshare.GuestLink{}); err != nil {
+			respondStorageLimitError(w, err)
+			return
+		}
+
+		entry, err := s.insertFileFromRequest(r, expiration, picoshare.GuestLink{}, picoshare.GuestSessionKey(""))
 		if err != nil {
-			if _, ok := errors.AsType[*dbError](err); ok {
+			if ise, ok := errors.AsType[*insufficientStorageError](err); ok {
+				log.Printf("refusing upload: %v", ise)
+				http.Error(w, ise.Error(), http.StatusInsufficientStorage)
+			} else if _, ok := errors.AsType[*dbError](err); ok {
 				log.Printf("failed to insert uploaded file into data store: %v", err)
 				http.Error(w, "failed to insert file into database", http.StatusInternalServerError)
 			} else {
@@ -62,7 +77,17 @@ func (s Server) entryPost() http.HandlerFunc {
 			return
 		}
 
-		respondJSON(w, EntryPostResponse{ID: id.String()})
+		if err := s.makeRoomForUpload(r, entry.ID); err != nil {
+			respondStorageLimitError(w, err)
+			return
+		}
+
+		s.queueWebhook(r, webhook.EntryEvent(picoshare.WebhookEventEntryCreated, entry, s.clock.Now()))
+
+		respondJSON(w, EntryPostResponse{
+			ID:     entry.ID.String(),
+			SHA256: entry.SHA256.String(),
+		})
 	}
 }
 
@@ -83,15 +108,25 @@ func (s Server) entryPut() http.HandlerFunc {
 			return
 		}
 
-		if err := s.getDB(r).UpdateEntryMetadata(id, metadata); err != nil {
+		if err := s.getDB(r).UpdateEntryMetadata(r.Context(), id, metadata); err != nil {
 			if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 				http.Error(w, "Invalid entry ID", http.StatusNotFound)
 				return
 			}
+			if _, ok := errors.AsType[store.EntrySlugConflictError](err); ok {
+				http.Error(w, fmt.Sprintf("Bad request: %v", err), http.StatusConflict)
+				return
+			}
 			log.Printf("error saving entry metadata: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to save new entry data: %v", err), http.StatusInternalServerError)
 			return
 		}
+
+		if entry, err := s.getDB(r).GetEntryMetadata(r.Context(), id); err != nil {
+			log.Printf("failed to read metadata of updated entry %v: %v", id, err)
+		} else {
+			s.queueWebhook(r, webhook.EntryEvent(picoshare.WebhookEventEntryUpdated, entry, s.clock.Now()))
+		}
 	}
 }
 
@@ -104,7 +139,7 @@ func (s Server) guestEntryPost() http.HandlerFunc {
 			return
 		}
 
-		gl, err := s.getDB(r).GetGuestLink(guestLinkID)
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), guestLinkID)
 		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
 			http.Error(w, "Invalid guest link ID", http.StatusNotFound)
 			return
@@ -114,6 +149,16 @@ func (s Server) guestEntryPost() http.HandlerFunc {
 			return
 		}
 
+		if !s.guestLinkUnlocker.Authenticate(r, gl) {
+			http.Error(w, "Guest link requires a passphrase", http.StatusUnauthorized)
+			return
+		}
+
+		if gl.IsQuotaExhausted() && !gl.IsDisabled && !gl.IsExpired() {
+			http.Error(w, "Guest link has used its entire storage quota", http.StatusInsufficientStorage)
+			return
+		}
+
 		if !gl.IsActive() {
 			http.Error(w, "Guest link is no longer active", http.StatusUnauthorized)
 			return
@@ -133,9 +178,20 @@ func (s Server) guestEntryPost() http.HandlerFunc {
 			return
 		}
 
-		id, err := s.insertFileFromRequest(r, expiration, guestLinkID)
+		if err := s.limitUploadToAvailableStorage(r, gl); err != nil {
+			respondStorageLimitError(w, err)
+			return
+		}
+
+		entry, err := s.insertFileFromRequest(r, expiration, gl, guest_session.Start(w, r, gl))
 		if err != nil {
-			if _, ok := errors.AsType[*dbError](err); ok {
+			if ise, ok := errors.AsType[*insufficientStorageError](err); ok {
+				log.Printf("refusing guest upload: %v", ise)
+				http.Error(w, ise.Error(), http.StatusInsufficientStorage)
+			} else if dfe, ok := errors.AsType[*disallowedFileTypeError](err); ok {
+				log.Printf("refusing guest upload: %v", dfe)
+				http.Error(w, dfe.Error(), http.StatusUnsupportedMediaType)
+			} else if _, ok := errors.AsType[*dbError](err); ok {
 				log.Printf("failed to insert uploaded file into data store: %v", err)
 				http.Error(w, "failed to insert file into database", http.StatusInternalServerError)
 			} else {
@@ -145,13 +201,28 @@ func (s Server) guestEntryPost() http.HandlerFunc {
 			return
 		}
 
+		if err := s.makeRoomForUpload(r, entry.ID); err != nil {
+			respondStorageLimitError(w, err)
+			return
+		}
+
+		s.notifyOwner(r, notify.Event{
+			Kind:      notify.EventGuestUpload,
+			Entry:     entry,
+			GuestLink: gl,
+		})
+		s.queueWebhook(r, webhook.EntryEvent(picoshare.WebhookEventEntryCreated, entry, s.clock.Now()))
+
 		if clientAcceptsJson(r) {
-			respondJSON(w, EntryPostResponse{ID: id.String()})
+			respondJSON(w, EntryPostResponse{
+				ID:     entry.ID.String(),
+				SHA256: entry.SHA256.String(),
+			})
 		} else {
 			// If client does not accept JSON, assume this is a command-line client
 			// and return plaintext.
 			w.Header().Set("Content-Type", "text/plain")
-			if _, err := fmt.Fprintf(w, "%s/-%s\r\n", baseURLFromRequest(r), id.String()); err != nil {
+			if _, err := fmt.Fprintf(w, "%s/-%s\r\n", baseURLFromRequest(r), entry.ID.String()); err != nil {
 				log.Fatalf("failed to write HTTP response: %v", err)
 			}
 		}
@@ -163,6 +234,7 @@ func (s Server) entryMetadataFromRequest(r *http.Request) (picoshare.UploadMetad
 		Filename   string `json:"filename"`
 		Expiration string `json:"expiration"`
 		Note       string `json:"note"`
+		Slug       string `json:"slug"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
@@ -189,10 +261,16 @@ func (s Server) entryMetadataFromRequest(r *http.Request) (picoshare.UploadMetad
 		return picoshare.UploadMetadata{}, err
 	}
 
+	slug, err := parseEntrySlug(payload.Slug)
+	if err != nil {
+		return picoshare.UploadMetadata{}, err
+	}
+
 	return picoshare.UploadMetadata{
 		Filename: filename,
 		Expires:  expiration,
 		Note:     note,
+		Slug:     slug,
 	}, nil
 }
 
@@ -219,12 +297,30 @@ func parseEntryID(s string) (picoshare.EntryID, error) {
 	return picoshare.EntryID(s), nil
 }
 
-func (s Server) insertFileFromRequest(r *http.Request, expiration picoshare.ExpirationTime, guestLinkID picoshare.GuestLinkID) (picoshare.EntryID, error) {
+// parseEntrySlug parses a custom alias for an entry's download link. Download
+// links accept either an entry ID or a slug, so a slug must not look like an
+// entry ID.
+func parseEntrySlug(s string) (picoshare.EntrySlug, error) {
+	slug, err := parse.EntrySlug(s)
+	if err != nil {
+		return picoshare.EntrySlug(""), err
+	}
+	if _, err := parseEntryID(slug.String()); err == nil {
+		return picoshare.EntrySlug(""), fmt.Errorf("custom link (%v) must not look like a file ID", slug)
+	}
+	return slug, nil
+}
+
+// insertFileFromRequest saves the file in a multipart upload request and returns
+// the metadata of the new entry. For guest uploads, gl is the guest link through
+// which the guest uploaded the file, and guestSession is the guest's browser
+// session.
+func (s Server) insertFileFromRequest(r *http.Request, expiration picoshare.ExpirationTime, gl picoshare.GuestLink, guestSession picoshare.GuestSessionKey) (picoshare.UploadMetadata, error) {
 	// ParseMultipartForm can go above the limit we set, so set a conservative RAM
 	// limit to avoid exhausting RAM on servers with limited resources.
 	multipartMaxMemory := mibToBytes(1)
 	if err := r.ParseMultipartForm(multipartMaxMemory); err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
 	}
 	defer func() {
 		if err := r.MultipartForm.RemoveAll(); err != nil {
@@ -234,53 +330,146 @@ func (s Server) insertFileFromRequest(r *http.Request, expiration picoshare.Expi
 
 	reader, metadata, err := r.FormFile("file")
 	if err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
 	}
 
 	fileSize, err := picoshare.FileSizeFromInt64(metadata.Size)
 	if err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
 	}
 
 	filename, err := parse.Filename(metadata.Filename)
 	if err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
 	}
 
 	contentType, err := parseContentType(metadata.Header.Get("Content-Type"))
 	if err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
 	}
 
-	note, err := parse.FileNote(r.FormValue("note"))
+	note, sender, err := noteAndSenderFromRequest(r, gl)
 	if err != nil {
-		return picoshare.EntryID(""), err
+		return picoshare.UploadMetadata{}, err
+	}
+
+	if !gl.AllowedFileTypes.IsUnrestricted() {
+		sniffedType, err := sniffContentType(reader)
+		if err != nil {
+			return picoshare.UploadMetadata{}, err
+		}
+		if !gl.AllowedFileTypes.Allows(filename, sniffedType) {
+			return picoshare.UploadMetadata{}, &disallowedFileTypeError{
+				filename:    filename,
+				contentType: sniffedType,
+				allowed:     gl.AllowedFileTypes,
+			}
+		}
+		// Serve the file as the type that PicoShare checked rather than the type
+		// the client claimed, so that guests can't slip a different type past
+		// the allow-list.
+		contentType = sniffedType
 	}
 
-	if guestLinkID != "" && note.Value != nil {
-		return picoshare.EntryID(""), errors.New("guest uploads cannot have file notes")
+	// If the client specifies a checksum, the data store rejects the upload if
+	// the file contents don't match it.
+	expectedChecksum, err := parse.SHA256Checksum(r.FormValue("sha256"))
+	if err != nil {
+		return picoshare.UploadMetadata{}, err
 	}
 
 	id := generateEntryID()
-	err = s.getDB(r).InsertEntry(reader,
+	err = s.getDB(r).InsertEntry(r.Context(), reader,
 		picoshare.UploadMetadata{
 			ID:          id,
 			Filename:    filename,
 			ContentType: contentType,
 			Note:        note,
 			GuestLink: picoshare.GuestLink{
-				ID: guestLinkID,
+				ID: gl.ID,
 			},
-			Uploaded: s.clock.Now(),
-			Expires:  expiration,
-			Size:     fileSize,
+			Sender:       sender,
+			GuestSession: guestSession,
+			Uploaded:     s.clock.Now(),
+			Expires:      expiration,
+			Size:         fileSize,
+			SHA256:       expectedChecksum,
 		})
-	if err != nil {
+	if mismatch, ok := errors.AsType[store.ChecksumMismatchError](err); ok {
+		return picoshare.UploadMetadata{}, mismatch
+	} else if err != nil {
 		log.Printf("failed to save entry: %v", err)
-		return picoshare.EntryID(""), dbError{err}
+		return picoshare.UploadMetadata{}, dbError{err}
+	}
+
+	entry, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to read metadata of new entry: %v", err)
+		return picoshare.UploadMetadata{}, dbError{err}
+	}
+
+	return entry, nil
+}
+
+// noteAndSenderFromRequest parses the note and, for guest uploads, the sender
+// details from a multipart upload request. Guests can attach a note and sender
+// details only if their guest link allows it.
+func noteAndSenderFromRequest(r *http.Request, gl picoshare.GuestLink) (picoshare.FileNote, picoshare.GuestSender, error) {
+	if gl.Empty() {
+		note, err := parse.FileNote(r.FormValue("note"))
+		return note, picoshare.GuestSender{}, err
 	}
 
-	return id, nil
+	rawNote := r.FormValue("note")
+	rawName := r.FormValue("senderName")
+	rawEmail := r.FormValue("senderEmail")
+	if !gl.AllowSenderDetails {
+		if rawNote != "" || rawName != "" || rawEmail != "" {
+			return picoshare.FileNote{}, picoshare.GuestSender{}, errors.New("this guest link doesn't accept notes or sender details")
+		}
+		return picoshare.FileNote{}, picoshare.GuestSender{}, nil
+	}
+
+	note, err := parse.GuestFileNote(rawNote)
+	if err != nil {
+		return picoshare.FileNote{}, picoshare.GuestSender{}, err
+	}
+
+	name, err := parse.SenderName(rawName)
+	if err != nil {
+		return picoshare.FileNote{}, picoshare.GuestSender{}, err
+	}
+
+	email, err := parse.SenderEmail(rawEmail)
+	if err != nil {
+		return picoshare.FileNote{}, picoshare.GuestSender{}, err
+	}
+
+	return note, picoshare.GuestSender{Name: name, Email: email}, nil
+}
+
+// disallowedFileTypeError occurs when a guest uploads a file that isn't one of
+// the guest link's allowed file types.
+type disallowedFileTypeError struct {
+	filename    picoshare.Filename
+	contentType picoshare.ContentType
+	allowed     picoshare.GuestUploadFileTypes
+}
+
+func (e *disallowedFileTypeError) Error() string {
+	return fmt.Sprintf("%s (%s) is not an allowed file type; this link accepts only %s", e.filename, e.contentType, e.allowed)
+}
+
+// sniffContentType determines a file's media type from its first bytes rather
+// than trusting the type that the client reports.
+func sniffContentType(f multipart.File) (picoshare.ContentType, error) {
+	// DetectContentType considers at most the first 512 bytes.
+	header := make([]byte, 512)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return picoshare.ContentType(""), err
+	}
+	return picoshare.ContentType(http.DetectContentType(header[:n])), nil
 }
 
 func parseContentType(s string) (picoshare.ContentType, error) {
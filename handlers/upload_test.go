@@ -3,12 +3,14 @@ package handlers_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"reflect"
 	"strings"
 	"testing"
@@ -102,7 +104,10 @@ func TestEntryPost(t *testing.T) {
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			dataStore := test_sqlite.New()
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
 
 			formData, contentType := createMultipartFormBody(tt.filename, tt.note, bytes.NewBuffer([]byte(tt.contents)))
 
@@ -137,7 +142,7 @@ func TestEntryPost(t *testing.T) {
 				t.Fatalf("response is not valid JSON: %v", body)
 			}
 
-			entry, err := dataStore.GetEntryMetadata(picoshare.EntryID(response.ID))
+			entry, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID(response.ID))
 			if err != nil {
 				t.Fatalf("failed to get expected entry %v from data store: %v", response.ID, err)
 			}
@@ -150,7 +155,7 @@ func TestEntryPost(t *testing.T) {
 				t.Errorf("expiration=%v, want=%v", got, want)
 			}
 
-			entryFile, err := dataStore.ReadEntryFile(entry.ID)
+			entryFile, err := dataStore.ReadEntryFile(context.Background(), entry.ID)
 			if err != nil {
 				t.Fatalf("failed to read file for entry %v: %v", entry.ID, err)
 			}
@@ -162,6 +167,83 @@ func TestEntryPost(t *testing.T) {
 	}
 }
 
+func TestEntryPostChecksum(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		checksum    string
+		status      int
+	}{
+		{
+			description: "accepts upload without a checksum",
+			checksum:    "",
+			status:      http.StatusOK,
+		},
+		{
+			description: "accepts upload that matches checksum",
+			checksum:    "ebc2689f897aa333887187a499a15658989ca923cbd49ecc8080b6eef955cdc6",
+			status:      http.StatusOK,
+		},
+		{
+			description: "rejects upload that doesn't match checksum",
+			checksum:    "68e656b251e67e8358bef8483ab0d51c6619f3e7a1a9f0e75838d41ff368f728",
+			status:      http.StatusBadRequest,
+		},
+		{
+			description: "rejects invalid checksum",
+			checksum:    "not-a-checksum",
+			status:      http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
+
+			var b bytes.Buffer
+			mw := multipart.NewWriter(&b)
+			f, err := mw.CreateFormFile("file", "dummy.txt")
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			f.Write([]byte("dummy bytes"))
+			if err := mw.WriteField("sha256", tt.checksum); err != nil {
+				t.Fatalf("failed to write checksum field: %v", err)
+			}
+			mw.Close()
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/api/entry?expiration=2040-01-01T00:00:00Z",
+				&b,
+			)
+			req.Header.Add("Content-Type", mw.FormDataContentType())
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if res.StatusCode != http.StatusOK {
+				return
+			}
+
+			var response handlers.EntryPostResponse
+			if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+
+			if got, want := response.SHA256, "ebc2689f897aa333887187a499a15658989ca923cbd49ecc8080b6eef955cdc6"; got != want {
+				t.Errorf("sha256=%s, want=%s", got, want)
+			}
+		})
+	}
+}
+
 func TestEntryPut(t *testing.T) {
 	originalEntry := picoshare.UploadMetadata{
 		ID:          picoshare.EntryID("AAAAAAAAAA"),
@@ -171,6 +253,15 @@ func TestEntryPut(t *testing.T) {
 		Expires:     mustParseExpirationTime("2024-12-15T21:52:33Z"),
 		Note:        picoshare.FileNote{},
 	}
+	// Another entry already uses this slug.
+	otherEntry := picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("CCCCCCCCCC"),
+		Slug:        picoshare.EntrySlug("taken-name"),
+		Filename:    picoshare.Filename("other.mp3"),
+		ContentType: picoshare.ContentType("audio/mpeg"),
+		Uploaded:    mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:     picoshare.NeverExpire,
+	}
 	for _, tt := range []struct {
 		description      string
 		targetID         string
@@ -178,6 +269,7 @@ func TestEntryPut(t *testing.T) {
 		filenameExpected string
 		expiresExpected  picoshare.ExpirationTime
 		noteExpected     picoshare.FileNote
+		slugExpected     picoshare.EntrySlug
 		status           int
 	}{
 		{
@@ -231,6 +323,59 @@ func TestEntryPut(t *testing.T) {
 			noteExpected:     picoshare.FileNote{},
 			status:           http.StatusBadRequest,
 		},
+		{
+			description: "sets a custom slug",
+			targetID:    "AAAAAAAAAA",
+			payload: `{
+				"filename": "cool-song.mp3",
+				"note":"My latest track",
+				"slug": "Great-Song"
+			}`,
+			filenameExpected: "cool-song.mp3",
+			noteExpected:     makeNote("My latest track"),
+			expiresExpected:  picoshare.NeverExpire,
+			slugExpected:     picoshare.EntrySlug("great-song"),
+			status:           http.StatusOK,
+		},
+		{
+			description: "rejects slug with invalid characters",
+			targetID:    "AAAAAAAAAA",
+			payload: `{
+				"filename": "cool-song.mp3",
+				"note":"My latest track",
+				"slug": "cool_song"
+			}`,
+			filenameExpected: "original-filename.mp3",
+			expiresExpected:  mustParseExpirationTime("2024-12-15T21:52:33Z"),
+			noteExpected:     picoshare.FileNote{},
+			status:           http.StatusBadRequest,
+		},
+		{
+			description: "rejects slug that looks like an entry ID",
+			targetID:    "AAAAAAAAAA",
+			payload: `{
+				"filename": "cool-song.mp3",
+				"note":"My latest track",
+				"slug": "q3reportxy"
+			}`,
+			filenameExpected: "original-filename.mp3",
+			expiresExpected:  mustParseExpirationTime("2024-12-15T21:52:33Z"),
+			noteExpected:     picoshare.FileNote{},
+			status:           http.StatusBadRequest,
+		},
+		{
+			description: "rejects slug that another entry uses",
+			targetID:    "AAAAAAAAAA",
+			payload: `{
+				"filename": "cool-song.mp3",
+				"note":"My latest track",
+				"slug": "taken-name"
+			}`,
+			filenameExpected: "original-filename.mp3",
+			expiresExpected:  mustParseExpirationTime("2024-12-15T21:52:33Z"),
+			noteExpected:     picoshare.FileNote{},
+			status:           http.StatusConflict,
+		},
 		{
 			description: "ignores non-existent entry ID",
 			targetID:    "BBBBBBBBBB",
@@ -250,8 +395,14 @@ func TestEntryPut(t *testing.T) {
 			originalData := "dummy original data"
 			metadata := originalEntry
 			metadata.Size = mustParseFileSize(len(originalData))
-			dataStore.InsertEntry(strings.NewReader((originalData)), metadata)
-			s := handlers.New(mockAuthenticator{}, &dataStore, nilSpaceChecker, nilGarbageCollector, handlers.NewClock())
+			dataStore.InsertEntry(context.Background(), strings.NewReader((originalData)), metadata)
+			otherMetadata := otherEntry
+			otherMetadata.Size = mustParseFileSize(len(originalData))
+			dataStore.InsertEntry(context.Background(), strings.NewReader((originalData)), otherMetadata)
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+			})
 
 			req := httptest.NewRequest(
 				http.MethodPut,
@@ -268,7 +419,7 @@ func TestEntryPut(t *testing.T) {
 				t.Fatalf("status=%d, want=%d", got, want)
 			}
 
-			entry, err := dataStore.GetEntryMetadata(picoshare.EntryID(originalEntry.ID))
+			entry, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID(originalEntry.ID))
 			if err != nil {
 				t.Fatalf("failed to get expected entry %v from data store: %v", originalEntry.ID, err)
 			}
@@ -280,6 +431,10 @@ func TestEntryPut(t *testing.T) {
 			if got, want := entry.Note.String(), tt.noteExpected.String(); got != want {
 				t.Errorf("note=%v, want=%v", got, want)
 			}
+
+			if got, want := entry.Slug, tt.slugExpected; got != want {
+				t.Errorf("slug=%v, want=%v", got, want)
+			}
 		})
 	}
 }
@@ -326,6 +481,46 @@ func TestGuestUpload(t *testing.T) {
 			status:                     http.StatusUnauthorized,
 			fileExpirationTimeExpected: picoshare.NeverExpire,
 		},
+		{
+			description: "guest link whose upload window hasn't started",
+			guestLinkInStore: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:         mustParseTime("2022-05-26T00:00:00Z"),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				UploadWindow:    picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			},
+			currentTime:                mustParseTime("2024-01-01T00:00:00Z"),
+			url:                        "/api/guest/abcdefgh23456789?expiration=2030-01-01T00:00:00Z",
+			status:                     http.StatusOK,
+			fileExpirationTimeExpected: mustParseExpirationTime("2030-01-01T00:00:00Z"),
+		},
+		{
+			description: "guest link whose upload window has closed",
+			guestLinkInStore: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:         mustParseTime("2022-05-26T00:00:00Z"),
+				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				UploadWindow:    picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			},
+			entriesInStore: []picoshare.UploadEntry{
+				{
+					UploadMetadata: picoshare.UploadMetadata{
+						ID:       picoshare.EntryID("dummy-entry1"),
+						Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+						GuestLink: picoshare.GuestLink{
+							ID: picoshare.GuestLinkID("abcdefgh23456789"),
+						},
+						Expires: picoshare.NeverExpire,
+					},
+				},
+			},
+			currentTime:                mustParseTime("2024-01-03T00:00:00Z"),
+			url:                        "/api/guest/abcdefgh23456789?expiration=2030-01-01T00:00:00Z",
+			status:                     http.StatusUnauthorized,
+			fileExpirationTimeExpected: picoshare.NeverExpire,
+		},
 		{
 			description: "disabled guest link",
 			guestLinkInStore: picoshare.GuestLink{
@@ -563,19 +758,23 @@ func TestGuestUpload(t *testing.T) {
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			dataStore := test_sqlite.New()
-			if err := dataStore.InsertGuestLink(tt.guestLinkInStore); err != nil {
+			if err := dataStore.InsertGuestLink(context.Background(), tt.guestLinkInStore); err != nil {
 				t.Fatalf("failed to insert dummy guest link: %v", err)
 			}
 			for _, entry := range tt.entriesInStore {
 				data := "dummy data"
 				entry.UploadMetadata.Size = mustParseFileSize(len(data))
-				if err := dataStore.InsertEntry(strings.NewReader(data), entry.UploadMetadata); err != nil {
+				if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), entry.UploadMetadata); err != nil {
 					t.Fatalf("failed to insert dummy entry: %v", err)
 				}
 			}
 
 			c := mockClock{tt.currentTime}
-			s := handlers.New(authenticator, &dataStore, nilSpaceChecker, nilGarbageCollector, c)
+			s := handlers.New(handlers.Options{
+				Authenticator: authenticator,
+				Store:         &dataStore,
+				Clock:         c,
+			})
 
 			filename := "dummyimage.png"
 			contents := "dummy bytes"
@@ -593,7 +792,7 @@ func TestGuestUpload(t *testing.T) {
 				t.Fatalf("status=%d, want=%d", got, want)
 			}
 
-			entries, err := dataStore.GetEntriesMetadata()
+			entries, err := dataStore.GetEntriesMetadata(context.Background())
 			if err != nil {
 				t.Fatalf("failed to list entries metadata: %v", err)
 			}
@@ -626,7 +825,7 @@ func TestGuestUpload(t *testing.T) {
 				t.Fatalf("response is not valid JSON: %v", body)
 			}
 
-			entry, err := dataStore.GetEntryMetadata(picoshare.EntryID(response.ID))
+			entry, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID(response.ID))
 			if err != nil {
 				t.Fatalf("failed to get expected entry %v from data store: %v", response.ID, err)
 			}
@@ -639,7 +838,7 @@ func TestGuestUpload(t *testing.T) {
 				t.Errorf("file expiration=%v, want=%v", got, want)
 			}
 
-			entryFile, err := dataStore.ReadEntryFile(entry.ID)
+			entryFile, err := dataStore.ReadEntryFile(context.Background(), entry.ID)
 			if err != nil {
 				t.Fatalf("failed to read entry file for %v: %v", entry.ID, err)
 			}
@@ -695,12 +894,16 @@ func TestGuestUploadAcceptHeader(t *testing.T) {
 				UrlExpires:      mustParseExpirationTime("2030-01-02T03:04:25Z"),
 				MaxFileLifetime: picoshare.FileLifetimeInfinite,
 			}
-			if err := dataStore.InsertGuestLink(guestLink); err != nil {
+			if err := dataStore.InsertGuestLink(context.Background(), guestLink); err != nil {
 				t.Fatalf("failed to insert dummy guest link: %v", err)
 			}
 
 			c := mockClock{mustParseTime("2024-01-01T00:00:00Z")}
-			s := handlers.New(authenticator, &dataStore, nilSpaceChecker, nilGarbageCollector, c)
+			s := handlers.New(handlers.Options{
+				Authenticator: authenticator,
+				Store:         &dataStore,
+				Clock:         c,
+			})
 
 			filename := "dummyimage.png"
 			contents := "dummy bytes"
@@ -756,6 +959,340 @@ func TestGuestUploadAcceptHeader(t *testing.T) {
 	}
 }
 
+func TestGuestUploadAllowedFileTypes(t *testing.T) {
+	for _, tt := range []struct {
+		description      string
+		allowedFileTypes picoshare.GuestUploadFileTypes
+		filename         string
+		contents         string
+		status           int
+	}{
+		{
+			description:      "accepts any file when guest link has no restrictions",
+			allowedFileTypes: nil,
+			filename:         "notes.txt",
+			contents:         "dummy text",
+			status:           http.StatusOK,
+		},
+		{
+			description:      "accepts PDF that matches both extension and media type",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+			filename:         "report.pdf",
+			contents:         "%PDF-1.4\n%dummy pdf",
+			status:           http.StatusOK,
+		},
+		{
+			description:      "matches extensions case-insensitively",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{".pdf"},
+			filename:         "REPORT.PDF",
+			contents:         "%PDF-1.4\n%dummy pdf",
+			status:           http.StatusOK,
+		},
+		{
+			description:      "rejects text file disguised with a PDF extension",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+			filename:         "report.pdf",
+			contents:         "dummy text",
+			status:           http.StatusUnsupportedMediaType,
+		},
+		{
+			description:      "rejects PDF with a disallowed extension",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+			filename:         "report.txt",
+			contents:         "%PDF-1.4\n%dummy pdf",
+			status:           http.StatusUnsupportedMediaType,
+		},
+		{
+			description:      "accepts any image when media type has a wildcard",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{"image/*"},
+			filename:         "photo.gif",
+			contents:         "GIF89a dummy gif",
+			status:           http.StatusOK,
+		},
+		{
+			description:      "rejects non-image when only images are allowed",
+			allowedFileTypes: picoshare.GuestUploadFileTypes{"image/*"},
+			filename:         "photo.gif",
+			contents:         "dummy text",
+			status:           http.StatusUnsupportedMediaType,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			guestLink := picoshare.GuestLink{
+				ID:               picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:          mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:       picoshare.NeverExpire,
+				MaxFileLifetime:  picoshare.FileLifetimeInfinite,
+				AllowedFileTypes: tt.allowedFileTypes,
+			}
+			if err := dataStore.InsertGuestLink(context.Background(), guestLink); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+
+			c := mockClock{mustParseTime("2024-01-01T00:00:00Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			formData, contentType := createMultipartFormBody(tt.filename, "", strings.NewReader(tt.contents))
+			req := httptest.NewRequest(http.MethodPost, "/api/guest/abcdefgh23456789", formData)
+			req.Header.Add("Content-Type", contentType)
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			gl, err := dataStore.GetGuestLink(context.Background(), guestLink.ID)
+			if err != nil {
+				t.Fatalf("failed to retrieve guest link from datastore: %v", err)
+			}
+			wantUploads := 0
+			if tt.status == http.StatusOK {
+				wantUploads = 1
+			}
+			if got, want := gl.FilesUploaded, wantUploads; got != want {
+				t.Errorf("files uploaded=%d, want=%d", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestUploadStoresSniffedContentType(t *testing.T) {
+	for _, tt := range []struct {
+		description         string
+		allowedFileTypes    picoshare.GuestUploadFileTypes
+		declaredType        string
+		contents            string
+		contentTypeExpected picoshare.ContentType
+	}{
+		{
+			description:         "stores declared type when guest link has no restrictions",
+			allowedFileTypes:    nil,
+			declaredType:        "text/html",
+			contents:            "%PDF-1.4\n%dummy pdf",
+			contentTypeExpected: picoshare.ContentType("text/html"),
+		},
+		{
+			description:         "stores sniffed type when guest link restricts file types",
+			allowedFileTypes:    picoshare.GuestUploadFileTypes{".pdf"},
+			declaredType:        "text/html",
+			contents:            "%PDF-1.4\n%dummy pdf",
+			contentTypeExpected: picoshare.ContentType("application/pdf"),
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			guestLink := picoshare.GuestLink{
+				ID:               picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:          mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:       picoshare.NeverExpire,
+				MaxFileLifetime:  picoshare.FileLifetimeInfinite,
+				AllowedFileTypes: tt.allowedFileTypes,
+			}
+			if err := dataStore.InsertGuestLink(context.Background(), guestLink); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+
+			c := mockClock{mustParseTime("2024-01-01T00:00:00Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", `form-data; name="file"; filename="report.pdf"`)
+			h.Set("Content-Type", tt.declaredType)
+			f, err := mw.CreatePart(h)
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			if _, err := f.Write([]byte(tt.contents)); err != nil {
+				t.Fatalf("failed to write form file: %v", err)
+			}
+			if err := mw.Close(); err != nil {
+				t.Fatalf("failed to close multipart writer: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/guest/abcdefgh23456789", &body)
+			req.Header.Add("Content-Type", mw.FormDataContentType())
+			req.Header.Add("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			var response handlers.EntryPostResponse
+			if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+
+			entry, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID(response.ID))
+			if err != nil {
+				t.Fatalf("failed to get entry metadata: %v", err)
+			}
+
+			if got, want := entry.ContentType, tt.contentTypeExpected; got != want {
+				t.Errorf("contentType=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestUploadSenderDetails(t *testing.T) {
+	for _, tt := range []struct {
+		description        string
+		allowSenderDetails bool
+		fields             map[string]string
+		status             int
+		senderExpected     picoshare.GuestSender
+		noteExpected       string
+	}{
+		{
+			description:        "accepts upload without sender details",
+			allowSenderDetails: true,
+			fields:             map[string]string{},
+			status:             http.StatusOK,
+		},
+		{
+			description:        "saves sender details and note",
+			allowSenderDetails: true,
+			fields: map[string]string{
+				"senderName":  "Jane Doe",
+				"senderEmail": "jane@example.com",
+				"note":        "Here are the signed forms",
+			},
+			status: http.StatusOK,
+			senderExpected: picoshare.GuestSender{
+				Name:  picoshare.SenderName("Jane Doe"),
+				Email: picoshare.SenderEmail("jane@example.com"),
+			},
+			noteExpected: "Here are the signed forms",
+		},
+		{
+			description:        "rejects invalid sender email",
+			allowSenderDetails: true,
+			fields: map[string]string{
+				"senderEmail": "not an email",
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			description:        "rejects guest note that's too long",
+			allowSenderDetails: true,
+			fields: map[string]string{
+				"note": strings.Repeat("A", parse.MaxGuestFileNoteBytes+1),
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			description:        "rejects note when guest link doesn't allow sender details",
+			allowSenderDetails: false,
+			fields: map[string]string{
+				"note": "Here are the signed forms",
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			description:        "rejects sender name when guest link doesn't allow sender details",
+			allowSenderDetails: false,
+			fields: map[string]string{
+				"senderName": "Jane Doe",
+			},
+			status: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := test_sqlite.New()
+			guestLink := picoshare.GuestLink{
+				ID:                 picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:            mustParseTime("2024-01-01T00:00:00Z"),
+				UrlExpires:         picoshare.NeverExpire,
+				MaxFileLifetime:    picoshare.FileLifetimeInfinite,
+				AllowSenderDetails: tt.allowSenderDetails,
+			}
+			if err := dataStore.InsertGuestLink(context.Background(), guestLink); err != nil {
+				t.Fatalf("failed to insert dummy guest link: %v", err)
+			}
+
+			c := mockClock{mustParseTime("2024-01-01T00:00:00Z")}
+			s := handlers.New(handlers.Options{
+				Authenticator: mockAuthenticator{},
+				Store:         &dataStore,
+				Clock:         c,
+			})
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			f, err := mw.CreateFormFile("file", "forms.txt")
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			if _, err := f.Write([]byte("dummy upload")); err != nil {
+				t.Fatalf("failed to write form file: %v", err)
+			}
+			for name, value := range tt.fields {
+				if err := mw.WriteField(name, value); err != nil {
+					t.Fatalf("failed to write form field %s: %v", name, err)
+				}
+			}
+			if err := mw.Close(); err != nil {
+				t.Fatalf("failed to close multipart writer: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/guest/abcdefgh23456789", &body)
+			req.Header.Add("Content-Type", mw.FormDataContentType())
+			req.Header.Add("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if got, want := res.StatusCode, tt.status; got != want {
+				t.Fatalf("status=%d, want=%d", got, want)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response handlers.EntryPostResponse
+			if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatalf("response is not valid JSON: %v", err)
+			}
+
+			entry, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID(response.ID))
+			if err != nil {
+				t.Fatalf("failed to get entry metadata: %v", err)
+			}
+
+			if got, want := entry.Sender, tt.senderExpected; got != want {
+				t.Errorf("sender=%+v, want=%+v", got, want)
+			}
+			var note string
+			if entry.Note.Value != nil {
+				note = *entry.Note.Value
+			}
+			if got, want := note, tt.noteExpected; got != want {
+				t.Errorf("note=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
 func createMultipartFormBody(filename, note string, r io.Reader) (io.Reader, string) {
 	var b bytes.Buffer
 	bw := bufio.NewWriter(&b)
@@ -7,16 +7,21 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"maps"
 	"math"
 	"net/http"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mileusna/useragent"
 	"github.com/mtlynch/picoshare/build"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_passphrase"
+	"github.com/mtlynch/picoshare/handlers/auth/guest_session"
 	"github.com/mtlynch/picoshare/handlers/parse"
 	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space"
 	"github.com/mtlynch/picoshare/store"
 )
 
@@ -27,6 +32,7 @@ type commonProps struct {
 	Title           string
 	IsAuthenticated bool
 	CspNonce        string
+	Branding        picoshare.BrandingSettings
 }
 
 func (s Server) indexGet() http.HandlerFunc {
@@ -76,6 +82,20 @@ func (s Server) guestLinkIndexGet() http.HandlerFunc {
 			}
 			return fmt.Sprintf("%d", int(*limit))
 		},
+		"formatTotalSizeLimit": func(limit picoshare.GuestUploadMaxTotalBytes) string {
+			if limit == picoshare.GuestUploadUnlimitedTotalBytes {
+				return "Unlimited"
+			}
+			return humanReadableDiskUsage(*limit)
+		},
+		"formatBytesUploaded": humanReadableDiskUsage,
+		"formatRemainingBytes": func(gl picoshare.GuestLink) string {
+			remaining, ok := gl.RemainingBytes()
+			if !ok {
+				return ""
+			}
+			return fmt.Sprintf("%s remaining", humanReadableDiskUsage(remaining))
+		},
 		"formatExpiration": func(et picoshare.ExpirationTime) string {
 			if et == picoshare.NeverExpire {
 				return "Never"
@@ -88,11 +108,26 @@ func (s Server) guestLinkIndexGet() http.HandlerFunc {
 			}
 			return fmt.Sprintf("%s (%.0f days%s)", t.Format(time.DateOnly), math.Abs(delta.Hours())/24, suffix)
 		},
+		"formatUploadWindow": func(gl picoshare.GuestLink) string {
+			if gl.UploadWindow == picoshare.NoUploadWindow {
+				return ""
+			}
+			end, ok := gl.UploadWindowEnd()
+			if !ok {
+				return fmt.Sprintf("Closes %s after first upload", gl.UploadWindow.FriendlyName())
+			}
+			verb := "closes"
+			if end.Before(s.clock.Now()) {
+				verb = "closed"
+			}
+			return fmt.Sprintf("Window started %s, %s %s",
+				gl.FirstUpload.Local().Format(time.DateTime), verb, end.Local().Format(time.DateTime))
+		},
 	}
 
 	t := parseTemplatesWithFuncs(fns, "templates/pages/guest-link-index.html")
 	return func(w http.ResponseWriter, r *http.Request) {
-		links, err := s.getDB(r).GetGuestLinks()
+		links, err := s.getDB(r).GetGuestLinks(r.Context())
 		if err != nil {
 			log.Printf("failed to retrieve guest links: %v", err)
 			http.Error(w, "Failed to retrieve guest links", http.StatusInternalServerError)
@@ -116,48 +151,249 @@ func (s Server) guestLinkIndexGet() http.HandlerFunc {
 	}
 }
 
+// guestFileLifetimes are the lifetimes that guest link forms offer for the
+// files that guests upload.
+var guestFileLifetimes = []picoshare.FileLifetime{
+	picoshare.NewFileLifetimeInDays(1),
+	picoshare.NewFileLifetimeInDays(7),
+	picoshare.NewFileLifetimeInDays(30),
+	picoshare.NewFileLifetimeInYears(1),
+	picoshare.FileLifetimeInfinite,
+}
+
+type fileLifetimeOption struct {
+	FileLifetime picoshare.FileLifetime
+	IsDefault    bool
+}
+
+// makeFileLifetimeOptions lists the standard guest file lifetimes, marking
+// selected as the default. If selected or any of the extra lifetimes aren't
+// standard, the list includes them first so that the page can show them.
+func makeFileLifetimeOptions(selected picoshare.FileLifetime, extra ...picoshare.FileLifetime) []fileLifetimeOption {
+	options := []fileLifetimeOption{}
+	for _, lt := range guestFileLifetimes {
+		options = append(options, fileLifetimeOption{lt, lt.Equal(selected)})
+	}
+	for _, lt := range append([]picoshare.FileLifetime{selected}, extra...) {
+		if slices.ContainsFunc(options, func(o fileLifetimeOption) bool {
+			return o.FileLifetime.Equal(lt)
+		}) {
+			continue
+		}
+		options = append([]fileLifetimeOption{{lt, lt.Equal(selected)}}, options...)
+	}
+	return options
+}
+
+// guestLinkSettingsFuncs are the template functions of the pages that choose
+// guest link settings, either for a single guest link or for a preset.
+var guestLinkSettingsFuncs = template.FuncMap{
+	"formatLifetime": func(flt picoshare.FileLifetime) string {
+		return flt.String()
+	},
+	"bytesToMegabytes": func(b *uint64) uint64 {
+		return *b / (1024 * 1024)
+	},
+}
+
+// guestUploadWindows are the upload windows that guest link forms offer.
+var guestUploadWindows = []picoshare.GuestLinkUploadWindow{
+	picoshare.NoUploadWindow,
+	picoshare.GuestLinkUploadWindow(time.Hour),
+	picoshare.GuestLinkUploadWindow(24 * time.Hour),
+	picoshare.GuestLinkUploadWindow(7 * 24 * time.Hour),
+	picoshare.GuestLinkUploadWindow(30 * 24 * time.Hour),
+}
+
+type uploadWindowOption struct {
+	Window    picoshare.GuestLinkUploadWindow
+	IsDefault bool
+}
+
+// makeUploadWindowOptions lists the standard upload windows, marking selected
+// as the default. If selected isn't standard, the list includes it after the
+// option for no window.
+func makeUploadWindowOptions(selected picoshare.GuestLinkUploadWindow) []uploadWindowOption {
+	options := []uploadWindowOption{}
+	for _, w := range guestUploadWindows {
+		options = append(options, uploadWindowOption{w, w == selected})
+	}
+	if !slices.Contains(guestUploadWindows, selected) {
+		options = slices.Insert(options, 1, uploadWindowOption{selected, true})
+	}
+	return options
+}
+
 func (s Server) guestLinksNewGet() http.HandlerFunc {
 	fns := template.FuncMap{
 		"formatExpiration": func(t time.Time) string {
 			return t.Format(time.RFC3339)
 		},
-		"formatLifetime": func(flt picoshare.FileLifetime) string {
-			return flt.String()
-		},
 	}
+	maps.Copy(fns, guestLinkSettingsFuncs)
 
 	t := parseTemplatesWithFuncs(fns, "templates/pages/guest-link-create.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := s.getDB(r).ReadSettings(r.Context())
+		if err != nil {
+			log.Printf("failed to read settings: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to read settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// To duplicate a guest link, the page starts from a copy of the source
+		// link's settings.
+		var source picoshare.GuestLink
+		if rawID := r.URL.Query().Get("from"); rawID != "" {
+			id, err := parseGuestLinkID(rawID)
+			if err != nil {
+				log.Printf("error parsing guest link ID: %v", err)
+				http.Error(w, fmt.Sprintf("bad guest link ID: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			source, err = s.getDB(r).GetGuestLink(r.Context(), id)
+			if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+				http.Error(w, "guest link not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				log.Printf("error retrieving guest link with id %v: %v", id, err)
+				http.Error(w, "failed to retrieve guest link", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		type expirationOption struct {
 			FriendlyName string
 			Expiration   time.Time
 			IsDefault    bool
 		}
-		type fileLifetimeOption struct {
-			FileLifetime picoshare.FileLifetime
+		expirationOptions := []expirationOption{}
+		if !source.Empty() && source.UrlExpires != picoshare.NeverExpire {
+			// Give the copy as long to live as the source link had when the owner
+			// created it.
+			days := max(1, int(math.Round(source.UrlExpires.Time().Sub(source.Created).Hours()/24)))
+			friendlyName := "1 day"
+			if days != 1 {
+				friendlyName = fmt.Sprintf("%d days", days)
+			}
+			expirationOptions = append(expirationOptions, expirationOption{
+				FriendlyName: friendlyName + " (same as original)",
+				Expiration:   s.clock.Now().AddDate(0, 0, days),
+				IsDefault:    true,
+			})
+		}
+		expirationOptions = append(expirationOptions,
+			expirationOption{"1 day", s.clock.Now().AddDate(0, 0, 1), false},
+			expirationOption{"7 days", s.clock.Now().AddDate(0, 0, 7), false},
+			expirationOption{"30 days", s.clock.Now().AddDate(0, 0, 30), false},
+			expirationOption{"1 year", s.clock.Now().AddDate(1, 0, 0), false},
+			expirationOption{"Never", time.Time(picoshare.NeverExpire), len(expirationOptions) == 0},
+		)
+
+		selectedLifetime := picoshare.FileLifetimeInfinite
+		if !source.Empty() {
+			selectedLifetime = source.MaxFileLifetime
+		}
+		presetLifetimes := []picoshare.FileLifetime{}
+		for _, preset := range settings.GuestLinkPresets {
+			presetLifetimes = append(presetLifetimes, preset.MaxFileLifetime)
+		}
+
+		if err := t.Execute(w, struct {
+			commonProps
+			Source                picoshare.GuestLink
+			Presets               []picoshare.GuestLinkPreset
+			ExpirationOptions     []expirationOption
+			FileLifetimeOptions   []fileLifetimeOption
+			UploadWindowOptions   []uploadWindowOption
+			MaxInstructionsLength int
+		}{
+			commonProps:           makeCommonProps("PicoShare - New Guest Link", r.Context()),
+			Source:                source,
+			Presets:               settings.GuestLinkPresets,
+			ExpirationOptions:     expirationOptions,
+			FileLifetimeOptions:   makeFileLifetimeOptions(selectedLifetime, presetLifetimes...),
+			UploadWindowOptions:   makeUploadWindowOptions(source.UploadWindow),
+			MaxInstructionsLength: parse.MaxGuestLinkInstructionsBytes,
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func (s Server) guestLinkEditGet() http.HandlerFunc {
+	fns := template.FuncMap{
+		"formatExpiration": func(t time.Time) string {
+			return t.Format(time.RFC3339)
+		},
+		"formatDate": func(t time.Time) string {
+			return t.Local().Format(time.DateOnly)
+		},
+		"formatTimestamp": func(t time.Time) string {
+			return t.Format(time.RFC3339)
+		},
+	}
+	maps.Copy(fns, guestLinkSettingsFuncs)
+
+	t := parseTemplatesWithFuncs(fns, "templates/pages/guest-link-edit.html")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseGuestLinkID(mux.Vars(r)["id"])
+		if err != nil {
+			log.Printf("error parsing guest link ID: %v", err)
+			http.Error(w, fmt.Sprintf("bad guest link ID: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), id)
+		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
+			http.Error(w, "guest link not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("error retrieving guest link with id %v: %v", id, err)
+			http.Error(w, "failed to retrieve guest link", http.StatusInternalServerError)
+			return
+		}
+
+		type expirationOption struct {
+			FriendlyName string
+			Expiration   time.Time
 			IsDefault    bool
 		}
+
+		expirationOptions := []expirationOption{}
+		if gl.UrlExpires != picoshare.NeverExpire {
+			expirationOptions = append(expirationOptions, expirationOption{
+				FriendlyName: fmt.Sprintf("Unchanged (%s)", gl.UrlExpires.Time().Local().Format(time.DateOnly)),
+				Expiration:   gl.UrlExpires.Time(),
+				IsDefault:    true,
+			})
+		}
+		expirationOptions = append(expirationOptions,
+			expirationOption{"1 day", s.clock.Now().AddDate(0, 0, 1), false},
+			expirationOption{"7 days", s.clock.Now().AddDate(0, 0, 7), false},
+			expirationOption{"30 days", s.clock.Now().AddDate(0, 0, 30), false},
+			expirationOption{"1 year", s.clock.Now().AddDate(1, 0, 0), false},
+			expirationOption{"Never", time.Time(picoshare.NeverExpire), gl.UrlExpires == picoshare.NeverExpire},
+		)
+
 		if err := t.Execute(w, struct {
 			commonProps
-			ExpirationOptions   []expirationOption
-			FileLifetimeOptions []fileLifetimeOption
+			GuestLink             picoshare.GuestLink
+			ExpirationOptions     []expirationOption
+			FileLifetimeOptions   []fileLifetimeOption
+			UploadWindowOptions   []uploadWindowOption
+			MaxInstructionsLength int
 		}{
-			commonProps: makeCommonProps("PicoShare - New Guest Link", r.Context()),
-			ExpirationOptions: []expirationOption{
-				{"1 day", s.clock.Now().AddDate(0, 0, 1), false},
-				{"7 days", s.clock.Now().AddDate(0, 0, 7), false},
-				{"30 days", s.clock.Now().AddDate(0, 0, 30), false},
-				{"1 year", s.clock.Now().AddDate(1, 0, 0), false},
-				{"Never", time.Time(picoshare.NeverExpire), true},
-			},
-			FileLifetimeOptions: []fileLifetimeOption{
-				{picoshare.NewFileLifetimeInDays(1), false},
-				{picoshare.NewFileLifetimeInDays(7), false},
-				{picoshare.NewFileLifetimeInDays(30), false},
-				{picoshare.NewFileLifetimeInYears(1), false},
-				{picoshare.FileLifetimeInfinite, true},
-			},
+			commonProps:           makeCommonProps("PicoShare - Edit Guest Link", r.Context()),
+			GuestLink:             gl,
+			ExpirationOptions:     expirationOptions,
+			FileLifetimeOptions:   makeFileLifetimeOptions(gl.MaxFileLifetime),
+			UploadWindowOptions:   makeUploadWindowOptions(gl.UploadWindow),
+			MaxInstructionsLength: parse.MaxGuestLinkInstructionsBytes,
 		}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -185,7 +421,7 @@ func (s Server) fileIndexGet() http.HandlerFunc {
 	t := parseTemplatesWithFuncs(fns, "templates/pages/file-index.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		em, err := s.getDB(r).GetEntriesMetadata()
+		em, err := s.getDB(r).GetEntriesMetadata(r.Context())
 		if err != nil {
 			log.Printf("failed to retrieve entries metadata: %v", err)
 			http.Error(w, "failed to retrieve file index", http.StatusInternalServerError)
@@ -232,7 +468,7 @@ func (s Server) fileEditGet() http.HandlerFunc {
 			return
 		}
 
-		metadata, err := s.getDB(r).GetEntryMetadata(id)
+		metadata, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
 		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 			http.Error(w, "entry not found", http.StatusNotFound)
 			return
@@ -244,10 +480,12 @@ func (s Server) fileEditGet() http.HandlerFunc {
 
 		if err := t.Execute(w, struct {
 			commonProps
-			Metadata picoshare.UploadMetadata
+			Metadata      picoshare.UploadMetadata
+			MaxSlugLength int
 		}{
-			commonProps: makeCommonProps("PicoShare - Edit", r.Context()),
-			Metadata:    metadata,
+			commonProps:   makeCommonProps("PicoShare - Edit", r.Context()),
+			Metadata:      metadata,
+			MaxSlugLength: parse.MaxEntrySlugLength,
 		}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -286,7 +524,7 @@ func (s Server) fileInfoGet() http.HandlerFunc {
 			return
 		}
 
-		metadata, err := s.getDB(r).GetEntryMetadata(id)
+		metadata, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
 		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 			http.Error(w, "entry not found", http.StatusNotFound)
 			return
@@ -296,7 +534,7 @@ func (s Server) fileInfoGet() http.HandlerFunc {
 			return
 		}
 
-		downloads, err := s.getDB(r).GetEntryDownloads(id)
+		downloads, err := s.getDB(r).GetEntryDownloads(r.Context(), id)
 		if err != nil {
 			log.Printf("error retrieving downloads for id %v: %v", id, err)
 			http.Error(w, "failed to retrieve downloads", http.StatusInternalServerError)
@@ -339,7 +577,7 @@ func (s Server) fileDownloadsGet() http.HandlerFunc {
 
 		db := s.getDB(r)
 
-		metadata, err := db.GetEntryMetadata(id)
+		metadata, err := db.GetEntryMetadata(r.Context(), id)
 		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 			http.Error(w, "entry not found", http.StatusNotFound)
 			return
@@ -349,7 +587,7 @@ func (s Server) fileDownloadsGet() http.HandlerFunc {
 			return
 		}
 
-		downloads, err := db.GetEntryDownloads(id)
+		downloads, err := db.GetEntryDownloads(r.Context(), id)
 		if err != nil {
 			log.Printf("error retrieving downloads for id %v: %v", id, err)
 			http.Error(w, "failed to retrieve downloads", http.StatusInternalServerError)
@@ -418,7 +656,7 @@ func (s Server) fileConfirmDeleteGet() http.HandlerFunc {
 			return
 		}
 
-		metadata, err := s.getDB(r).GetEntryMetadata(id)
+		metadata, err := s.getDB(r).GetEntryMetadata(r.Context(), id)
 		if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
 			http.Error(w, "entry not found", http.StatusNotFound)
 			return
@@ -473,7 +711,7 @@ func (s Server) uploadGet() http.HandlerFunc {
 		"templates/pages/upload.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		settings, err := s.getDB(r).ReadSettings()
+		settings, err := s.getDB(r).ReadSettings(r.Context())
 		if err != nil {
 			http.Error(w, fmt.Sprintf("failed to read settings from database: %v", err), http.StatusInternalServerError)
 			return
@@ -543,20 +781,66 @@ func (s Server) uploadGet() http.HandlerFunc {
 	}
 }
 
+// guestUpload is a file that a guest link's upload page lists.
+type guestUpload struct {
+	Entry picoshare.UploadMetadata
+	// CanDelete indicates whether the guest viewing the page can delete the
+	// file.
+	CanDelete bool
+}
+
+// guestUploadsFromRequest returns the files that the guest link's upload page
+// lists for the client making the request.
+func (s Server) guestUploadsFromRequest(r *http.Request, gl picoshare.GuestLink) ([]guestUpload, error) {
+	if gl.UploadListing == picoshare.GuestUploadListingNone {
+		return []guestUpload{}, nil
+	}
+
+	entries, err := s.getDB(r).GetGuestLinkEntriesMetadata(r.Context(), gl.ID)
+	if err != nil {
+		return []guestUpload{}, err
+	}
+
+	uploads := []guestUpload{}
+	for _, entry := range entries {
+		owned := guest_session.Owns(r, gl, entry)
+		if !owned && gl.UploadListing == picoshare.GuestUploadListingSession {
+			continue
+		}
+		uploads = append(uploads, guestUpload{
+			Entry:     entry,
+			CanDelete: owned && gl.CanDeleteUploads(),
+		})
+	}
+
+	return uploads, nil
+}
+
 func (s Server) guestUploadGet() http.HandlerFunc {
 	fns := template.FuncMap{
 		"formatExpiration": func(t time.Time) string {
 			return t.Format(time.RFC3339)
-		}}
+		},
+		"formatFileSize": humanReadableFileSize,
+		"formatUploadTime": func(t time.Time) string {
+			return t.Format(time.DateTime)
+		},
+	}
 
 	t := parseTemplatesWithFuncs(
 		fns,
 		"templates/custom-elements/expiration-picker.html",
 		"templates/custom-elements/upload-link-box.html",
 		"templates/custom-elements/upload-links.html",
+		"templates/partials/guest-instructions.html",
+		"templates/partials/guest-uploads.html",
 		"templates/pages/upload.html")
 
-	tInactive := parseTemplates("templates/pages/guest-link-inactive.html")
+	tInactive := parseTemplatesWithFuncs(
+		fns,
+		"templates/partials/guest-uploads.html",
+		"templates/pages/guest-link-inactive.html")
+	tLocked := parseTemplates("templates/pages/guest-link-locked.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		guestLinkID, err := parseGuestLinkID(mux.Vars(r)["guestLinkID"])
@@ -566,7 +850,7 @@ func (s Server) guestUploadGet() http.HandlerFunc {
 			return
 		}
 
-		gl, err := s.getDB(r).GetGuestLink(guestLinkID)
+		gl, err := s.getDB(r).GetGuestLink(r.Context(), guestLinkID)
 		if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); ok {
 			http.Error(w, "Invalid guest link ID", http.StatusNotFound)
 			return
@@ -577,10 +861,46 @@ func (s Server) guestUploadGet() http.HandlerFunc {
 		}
 
 		if !gl.IsActive() {
+			// If the link is inactive only because guests reached its upload
+			// limits, let guests review and delete the files they uploaded. Once
+			// the link expires or the owner disables it, guests see no uploads.
+			limitsReachedOnly := gl.HasReachedUploadLimits() && !gl.IsExpired() && !gl.IsDisabled
+			uploads := []guestUpload{}
+			listing := picoshare.GuestLink{}
+			if limitsReachedOnly && gl.CanDeleteUploads() && s.guestLinkUnlocker.Authenticate(r, gl) {
+				uploads, err = s.guestUploadsFromRequest(r, gl)
+				if err != nil {
+					log.Printf("failed to retrieve uploads for guest link %v: %v", gl.ID, err)
+					http.Error(w, "Failed to retrieve uploads", http.StatusInternalServerError)
+					return
+				}
+				listing = gl
+			}
+
 			if err := tInactive.Execute(w, struct {
 				commonProps
+				GuestLinkMetadata picoshare.GuestLink
+				GuestUploads      []guestUpload
+			}{
+				commonProps:       makeCommonProps("PicoShare - Guest Link Inactive", r.Context()),
+				GuestLinkMetadata: listing,
+				GuestUploads:      uploads,
+			}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		if !s.guestLinkUnlocker.Authenticate(r, gl) {
+			if err := tLocked.Execute(w, struct {
+				commonProps
+				GuestLinkID      picoshare.GuestLinkID
+				PassphraseHeader string
 			}{
-				commonProps: makeCommonProps("PicoShare - Guest Link Inactive", r.Context()),
+				commonProps:      makeCommonProps("PicoShare - Passphrase Required", r.Context()),
+				GuestLinkID:      gl.ID,
+				PassphraseHeader: guest_passphrase.HeaderName,
 			}); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -638,14 +958,38 @@ func (s Server) guestUploadGet() http.HandlerFunc {
 			})
 		}
 
+		uploads, err := s.guestUploadsFromRequest(r, gl)
+		if err != nil {
+			log.Printf("failed to retrieve uploads for guest link %v: %v", gl.ID, err)
+			http.Error(w, "Failed to retrieve uploads", http.StatusInternalServerError)
+			return
+		}
+
+		instructions, err := renderMarkdown(gl.Instructions.String())
+		if err != nil {
+			log.Printf("failed to render instructions for guest link %v: %v", gl.ID, err)
+			http.Error(w, "Failed to render instructions", http.StatusInternalServerError)
+			return
+		}
+
 		if err := t.Execute(w, struct {
 			commonProps
-			ExpirationOptions []expirationOption
-			GuestLinkMetadata picoshare.GuestLink
+			ExpirationOptions    []expirationOption
+			GuestLinkMetadata    picoshare.GuestLink
+			GuestUploads         []guestUpload
+			Instructions         template.HTML
+			MaxNoteLength        int
+			MaxSenderNameLength  int
+			MaxSenderEmailLength int
 		}{
-			commonProps:       makeCommonProps("PicoShare - Upload", r.Context()),
-			ExpirationOptions: expirationOptions,
-			GuestLinkMetadata: gl,
+			commonProps:          makeCommonProps("PicoShare - Upload", r.Context()),
+			ExpirationOptions:    expirationOptions,
+			GuestLinkMetadata:    gl,
+			GuestUploads:         uploads,
+			Instructions:         instructions,
+			MaxNoteLength:        parse.MaxGuestFileNoteBytes,
+			MaxSenderNameLength:  parse.MaxSenderNameBytes,
+			MaxSenderEmailLength: parse.MaxSenderEmailBytes,
 		}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -653,11 +997,26 @@ func (s Server) guestUploadGet() http.HandlerFunc {
 	}
 }
 
+// guestLinkPresetView is a guest link preset as the settings page shows it.
+type guestLinkPresetView struct {
+	picoshare.GuestLinkPreset
+	FileLifetimeOptions []fileLifetimeOption
+	MaxNameLength       int
+}
+
+func makeGuestLinkPresetView(preset picoshare.GuestLinkPreset) guestLinkPresetView {
+	return guestLinkPresetView{
+		GuestLinkPreset:     preset,
+		FileLifetimeOptions: makeFileLifetimeOptions(preset.MaxFileLifetime),
+		MaxNameLength:       parse.MaxGuestLinkPresetNameLength,
+	}
+}
+
 func (s Server) settingsGet() http.HandlerFunc {
-	t := parseTemplates("templates/pages/settings.html")
+	t := parseTemplatesWithFuncs(guestLinkSettingsFuncs, "templates/pages/settings.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		settings, err := s.getDB(r).ReadSettings()
+		settings, err := s.getDB(r).ReadSettings(r.Context())
 		if err != nil {
 			http.Error(w, fmt.Sprintf("failed to read settings from database: %v", err), http.StatusInternalServerError)
 			return
@@ -677,16 +1036,101 @@ func (s Server) settingsGet() http.HandlerFunc {
 			}
 		}
 
+		// Never send credentials back to the browser. The page only needs to know
+		// whether they're set.
+		notifications := settings.Notifications
+		hasSMTPPassword := notifications.Email.Password != ""
+		hasNtfyAccessToken := notifications.Ntfy.AccessToken != ""
+		notifications.Email.Password = ""
+		notifications.Ntfy.AccessToken = ""
+
+		presets := []guestLinkPresetView{}
+		for _, preset := range settings.GuestLinkPresets {
+			presets = append(presets, makeGuestLinkPresetView(preset))
+		}
+
+		if err := t.Execute(w, struct {
+			commonProps
+			DefaultExpiration     uint16
+			ExpirationTimeUnit    string
+			DefaultNeverExpire    bool
+			Notifications         picoshare.NotificationSettings
+			HasSMTPPassword       bool
+			HasNtfyAccessToken    bool
+			EventWebhookURL       string
+			HasEventWebhookSecret bool
+			MaxInstanceNameLength int
+			MaxLogoKiB            int
+			GuestLinkPresets      []guestLinkPresetView
+			NewGuestLinkPreset    guestLinkPresetView
+			MaxGuestLinkPresets   int
+		}{
+			commonProps:           makeCommonProps("PicoShare - Settings", r.Context()),
+			DefaultExpiration:     defaultExpiration,
+			ExpirationTimeUnit:    expirationTimeUnit,
+			DefaultNeverExpire:    defaultNeverExpire,
+			Notifications:         notifications,
+			HasSMTPPassword:       hasSMTPPassword,
+			HasNtfyAccessToken:    hasNtfyAccessToken,
+			EventWebhookURL:       settings.EventWebhook.URL,
+			HasEventWebhookSecret: settings.EventWebhook.Secret != "",
+			MaxInstanceNameLength: parse.MaxInstanceNameLength,
+			MaxLogoKiB:            parse.MaxBrandingLogoBytes / 1024,
+			GuestLinkPresets:      presets,
+			NewGuestLinkPreset: makeGuestLinkPresetView(picoshare.GuestLinkPreset{
+				MaxFileLifetime: picoshare.NewFileLifetimeInDays(30),
+			}),
+			MaxGuestLinkPresets: parse.MaxGuestLinkPresets,
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// maxWebhookDeliveriesShown limits the webhook delivery log to the most recent
+// deliveries.
+const maxWebhookDeliveriesShown = 100
+
+func (s Server) webhookDeliveriesGet() http.HandlerFunc {
+	fns := template.FuncMap{
+		"formatDeliveryTime": func(t time.Time) string {
+			return t.Format(time.RFC3339)
+		},
+		"deliveryStateClass": func(state picoshare.WebhookDeliveryState) string {
+			switch state {
+			case picoshare.WebhookDeliveryDelivered:
+				return "text-bg-success"
+			case picoshare.WebhookDeliveryFailed:
+				return "text-bg-danger"
+			}
+			return "text-bg-secondary"
+		},
+	}
+	t := parseTemplatesWithFuncs(fns, "templates/pages/webhook-deliveries.html")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := s.getDB(r).ReadSettings(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read settings from database: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		deliveries, err := s.getDB(r).GetWebhookDeliveries(r.Context(), maxWebhookDeliveriesShown)
+		if err != nil {
+			log.Printf("error retrieving webhook deliveries: %v", err)
+			http.Error(w, fmt.Sprintf("failed to retrieve webhook deliveries: %v", err), http.StatusInternalServerError)
+			return
+		}
+
 		if err := t.Execute(w, struct {
 			commonProps
-			DefaultExpiration  uint16
-			ExpirationTimeUnit string
-			DefaultNeverExpire bool
+			EventWebhookURL string
+			Deliveries      []picoshare.WebhookDelivery
 		}{
-			commonProps:        makeCommonProps("PicoShare - Settings", r.Context()),
-			DefaultExpiration:  defaultExpiration,
-			ExpirationTimeUnit: expirationTimeUnit,
-			DefaultNeverExpire: defaultNeverExpire,
+			commonProps:     makeCommonProps("PicoShare - Webhook Deliveries", r.Context()),
+			EventWebhookURL: settings.EventWebhook.URL,
+			Deliveries:      deliveries,
 		}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -700,32 +1144,82 @@ func (s Server) systemInformationGet() http.HandlerFunc {
 		"percentage": func(part, total uint64) string {
 			return fmt.Sprintf("%.0f%%", 100.0*(float64(part)/float64(total)))
 		},
+		"quotaBytes": func(limit picoshare.GuestUploadMaxTotalBytes) uint64 {
+			return *limit
+		},
+		"formatFileSize": humanReadableFileSize,
+		"formatEvictionPolicy": func(policy string) string {
+			return space.EvictionPolicy(policy).FriendlyName()
+		},
 	}
 	t := parseTemplatesWithFuncs(fns, "templates/pages/system-information.html")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		spaceUsage, err := s.spaceChecker.Check()
+		spaceUsage, err := s.spaceChecker.Check(r.Context())
 		if err != nil {
 			log.Printf("error checking available space: %v", err)
 			http.Error(w, fmt.Sprintf("failed to check available space: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		corruptEntries, err := s.getDB(r).GetCorruptEntries(r.Context())
+		if err != nil {
+			log.Printf("error retrieving corrupt entries: %v", err)
+			http.Error(w, fmt.Sprintf("failed to retrieve corrupt entries: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		evictedEntries, err := s.getDB(r).GetEvictedEntries(r.Context())
+		if err != nil {
+			log.Printf("error retrieving evicted entries: %v", err)
+			http.Error(w, fmt.Sprintf("failed to retrieve evicted entries: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		guestLinks, err := s.getDB(r).GetGuestLinks(r.Context())
+		if err != nil {
+			log.Printf("error retrieving guest links: %v", err)
+			http.Error(w, fmt.Sprintf("failed to retrieve guest links: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		guestLinkQuotas := []picoshare.GuestLink{}
+		for _, gl := range guestLinks {
+			if gl.MaxTotalBytes != picoshare.GuestUploadUnlimitedTotalBytes {
+				guestLinkQuotas = append(guestLinkQuotas, gl)
+			}
+		}
+		sort.Slice(guestLinkQuotas, func(i, j int) bool {
+			return guestLinkQuotas[i].Created.After(guestLinkQuotas[j].Created)
+		})
+
 		if err := t.Execute(w, struct {
 			commonProps
 			TotalServingBytes uint64
+			TotalStoredBytes  uint64
 			DatabaseFileBytes uint64
 			UsedBytes         uint64
 			TotalBytes        uint64
+			StorageLimits     space.Limits
+			AvailableBytes    uint64
+			GuestLinkQuotas   []picoshare.GuestLink
+			EvictedEntries    []picoshare.EvictedEntry
+			CorruptEntries    []picoshare.CorruptEntry
 			BuildTime         time.Time
 			Version           string
 			Revision          string
 		}{
 			commonProps:       makeCommonProps("PicoShare - System Information", r.Context()),
 			TotalServingBytes: spaceUsage.TotalServingBytes,
+			TotalStoredBytes:  spaceUsage.TotalStoredBytes,
 			DatabaseFileBytes: spaceUsage.DatabaseFileSize,
 			UsedBytes:         spaceUsage.FileSystemUsedBytes,
 			TotalBytes:        spaceUsage.FileSystemTotalBytes,
+			StorageLimits:     s.storageLimits,
+			AvailableBytes:    s.storageLimits.AvailableBytes(spaceUsage),
+			GuestLinkQuotas:   guestLinkQuotas,
+			EvictedEntries:    evictedEntries,
+			CorruptEntries:    corruptEntries,
 			BuildTime:         build.Time(),
 			Version:           build.Version(),
 			Revision:          build.Revision(),
@@ -755,10 +1249,12 @@ func humanReadableDiskUsage(b uint64) string {
 }
 
 func makeCommonProps(title string, ctx context.Context) commonProps {
+	b := brandingFromContext(ctx)
 	return commonProps{
-		Title:           title,
+		Title:           brandedTitle(title, b),
 		IsAuthenticated: isAuthenticated(ctx),
 		CspNonce:        cspNonce(ctx),
+		Branding:        b,
 	}
 }
 
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/mtlynch/picoshare/webhook"
+)
+
+// queueWebhook adds an event to the queue for the owner's event webhook. A
+// failure to queue the event never fails the request that caused it.
+func (s Server) queueWebhook(r *http.Request, ev webhook.Event) {
+	if err := webhook.Enqueue(r.Context(), s.getDB(r), ev); err != nil {
+		log.Printf("failed to queue %s webhook: %v", ev.Type, err)
+	}
+}
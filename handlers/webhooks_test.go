@@ -0,0 +1,261 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+	"github.com/mtlynch/picoshare/webhook"
+)
+
+var dummyEventWebhookSettings = picoshare.EventWebhookSettings{
+	URL:    "https://hooks.example.com/picoshare-events",
+	Secret: "s3cr3t",
+}
+
+func newStoreWithEventWebhook(t *testing.T) sqlite.Store {
+	dataStore := test_sqlite.New()
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		EventWebhook:        dummyEventWebhookSettings,
+	}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+	return dataStore
+}
+
+func mustReadWebhookPayloads(t *testing.T, dataStore sqlite.Store) []webhook.Payload {
+	deliveries, err := dataStore.GetWebhookDeliveries(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+
+	payloads := []webhook.Payload{}
+	// The store returns the newest deliveries first, so reverse them to match
+	// the order of the events.
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		d := deliveries[i]
+		if got, want := d.URL, dummyEventWebhookSettings.URL; got != want {
+			t.Errorf("delivery URL=%v, want=%v", got, want)
+		}
+		if got, want := d.State, picoshare.WebhookDeliveryPending; got != want {
+			t.Errorf("delivery state=%v, want=%v", got, want)
+		}
+		var payload webhook.Payload
+		if err := json.Unmarshal(d.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode webhook payload %s: %v", d.Payload, err)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+func TestEntryDeleteQueuesWebhook(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t)
+	data := "dummy data"
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), picoshare.UploadMetadata{
+		ID:       dummyTextEntry.ID,
+		Filename: dummyTextEntry.Filename,
+		Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:  picoshare.NeverExpire,
+		Size:     mustParseFileSize(len(data)),
+	}); err != nil {
+		t.Fatalf("failed to insert dummy entry: %v", err)
+	}
+
+	c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+		Clock:         c,
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/entry/TTTTTTTTTT", nil)
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+
+	payloads := mustReadWebhookPayloads(t, dataStore)
+	if got, want := len(payloads), 1; got != want {
+		t.Fatalf("webhook deliveries=%d, want=%d", got, want)
+	}
+	if got, want := payloads[0].Type, picoshare.WebhookEventEntryDeleted; got != want {
+		t.Errorf("event type=%v, want=%v", got, want)
+	}
+	if got, want := payloads[0].Time, c.Now(); !got.Equal(want) {
+		t.Errorf("event time=%v, want=%v", got, want)
+	}
+	if got, want := payloads[0].Data.Entry.Filename, dummyTextEntry.Filename.String(); got != want {
+		t.Errorf("filename=%v, want=%v", got, want)
+	}
+}
+
+func TestEntryGetQueuesWebhook(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t)
+	data := "dummy data"
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader(data), picoshare.UploadMetadata{
+		ID:       dummyTextEntry.ID,
+		Filename: dummyTextEntry.Filename,
+		Uploaded: mustParseTime("2023-01-01T00:00:00Z"),
+		Expires:  picoshare.NeverExpire,
+		Size:     mustParseFileSize(len(data)),
+	}); err != nil {
+		t.Fatalf("failed to insert dummy entry: %v", err)
+	}
+
+	c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+		Clock:         c,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/-TTTTTTTTTT", nil)
+	req.Header.Set("User-Agent", "curl/8.5.0")
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+
+	payloads := mustReadWebhookPayloads(t, dataStore)
+	if got, want := len(payloads), 1; got != want {
+		t.Fatalf("webhook deliveries=%d, want=%d", got, want)
+	}
+	if got, want := payloads[0].Type, picoshare.WebhookEventEntryDownloaded; got != want {
+		t.Errorf("event type=%v, want=%v", got, want)
+	}
+	if payloads[0].Data.Download == nil {
+		t.Fatalf("payload has no download details")
+	}
+	if got, want := payloads[0].Data.Download.UserAgent, "curl/8.5.0"; got != want {
+		t.Errorf("user agent=%v, want=%v", got, want)
+	}
+	if got, want := payloads[0].Data.Download.Time, c.Now(); !got.Equal(want) {
+		t.Errorf("download time=%v, want=%v", got, want)
+	}
+}
+
+func TestGuestLinkLifecycleQueuesWebhooks(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t)
+	c := mockClock{mustParseTime("2024-01-02T03:04:05Z")}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+		Clock:         c,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/guest-links", strings.NewReader(`{
+			"label": "For Jane",
+			"urlExpirationTime": "2030-01-02T03:04:25Z",
+			"fileLifetime": "876000h0m0s",
+			"maxFileBytes": null,
+			"maxFileUploads": null
+		}`))
+	req.Header.Add("Content-Type", "text/json")
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("POST status=%d, want=%d", got, want)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// Disabling the link twice reports only the first change.
+	for range 2 {
+		req = httptest.NewRequest(http.MethodPut, "/api/guest-links/"+created.ID+"/disable", nil)
+		rec = httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, req)
+		if got, want := rec.Result().StatusCode, http.StatusNoContent; got != want {
+			t.Fatalf("disable status=%d, want=%d", got, want)
+		}
+	}
+
+	payloads := mustReadWebhookPayloads(t, dataStore)
+	wantTypes := []picoshare.WebhookEventType{
+		picoshare.WebhookEventGuestLinkCreated,
+		picoshare.WebhookEventGuestLinkDisabled,
+	}
+	if got, want := len(payloads), len(wantTypes); got != want {
+		t.Fatalf("webhook deliveries=%d, want=%d", got, want)
+	}
+	for i, wantType := range wantTypes {
+		if got, want := payloads[i].Type, wantType; got != want {
+			t.Errorf("event %d type=%v, want=%v", i, got, want)
+		}
+		if payloads[i].Data.GuestLink == nil {
+			t.Fatalf("event %d has no guest link", i)
+		}
+		if got, want := payloads[i].Data.GuestLink.ID, created.ID; got != want {
+			t.Errorf("event %d guest link ID=%v, want=%v", i, got, want)
+		}
+		if got, want := payloads[i].Data.GuestLink.Label, "For Jane"; got != want {
+			t.Errorf("event %d guest link label=%v, want=%v", i, got, want)
+		}
+	}
+	if got, want := payloads[1].Data.GuestLink.IsDisabled, true; got != want {
+		t.Errorf("disabled event isDisabled=%v, want=%v", got, want)
+	}
+}
+
+func TestWebhookDeliveriesGet(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t)
+	if err := dataStore.InsertWebhookDelivery(context.Background(), picoshare.WebhookDelivery{
+		ID:             picoshare.WebhookDeliveryID("dummydeliveryid00001"),
+		EventType:      picoshare.WebhookEventEntryCreated,
+		URL:            dummyEventWebhookSettings.URL,
+		Payload:        []byte(`{}`),
+		State:          picoshare.WebhookDeliveryFailed,
+		Attempts:       10,
+		Created:        mustParseTime("2024-01-01T00:00:00Z"),
+		LastAttempt:    mustParseTime("2024-01-02T00:00:00Z"),
+		LastStatusCode: http.StatusBadGateway,
+		LastError:      "webhook responded with 502 Bad Gateway: upstream down",
+	}); err != nil {
+		t.Fatalf("failed to insert webhook delivery: %v", err)
+	}
+	s := handlers.New(handlers.Options{
+		Authenticator: mockAuthenticator{},
+		Store:         &dataStore,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/settings/webhook-deliveries", nil)
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status=%d, want=%d", got, want)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"dummydeliveryid00001",
+		"entry.created",
+		"502",
+		"upstream down",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("deliveries page doesn't contain %q", want)
+		}
+	}
+}
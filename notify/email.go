@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// implicitTLSPort is the SMTP submission port that expects clients to start
+// TLS immediately rather than upgrade the connection with STARTTLS.
+const implicitTLSPort = 465
+
+type emailChannel struct {
+	settings picoshare.EmailNotificationSettings
+}
+
+func (ec emailChannel) Name() string {
+	return "email"
+}
+
+// Send emails the event through the configured SMTP server. If the server
+// supports STARTTLS, Send encrypts the connection before it authenticates.
+func (ec emailChannel) Send(ctx context.Context, ev Event) error {
+	msg, err := ec.composeMessage(ev)
+	if err != nil {
+		return err
+	}
+
+	client, err := ec.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := client.conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	if ec.settings.Port != implicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: ec.settings.Host}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ec.settings.Username != "" {
+		auth := smtp.PlainAuth("", ec.settings.Username, ec.settings.Password, ec.settings.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(ec.sender()); err != nil {
+		return err
+	}
+	if err := client.Rcpt(ec.settings.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+type smtpClient struct {
+	*smtp.Client
+	conn net.Conn
+}
+
+func (ec emailChannel) dial(ctx context.Context) (smtpClient, error) {
+	port := ec.settings.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(ec.settings.Host, strconv.Itoa(int(port)))
+
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if port == implicitTLSPort {
+		tlsDialer := tls.Dialer{
+			NetDialer: &dialer,
+			Config:    &tls.Config{ServerName: ec.settings.Host},
+		}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return smtpClient{}, err
+	}
+
+	client, err := smtp.NewClient(conn, ec.settings.Host)
+	if err != nil {
+		conn.Close()
+		return smtpClient{}, err
+	}
+
+	return smtpClient{Client: client, conn: conn}, nil
+}
+
+// sender returns the address that notification emails come from. If the
+// owner didn't choose one, PicoShare sends emails from the recipient address.
+func (ec emailChannel) sender() string {
+	if ec.settings.From == "" {
+		return ec.settings.To
+	}
+	return ec.settings.From
+}
+
+func (ec emailChannel) composeMessage(ev Event) ([]byte, error) {
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	if _, err := qp.Write([]byte(ev.Message())); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	for _, header := range []struct {
+		name  string
+		value string
+	}{
+		{"From", (&mail.Address{Name: "PicoShare", Address: ec.sender()}).String()},
+		{"To", (&mail.Address{Address: ec.settings.To}).String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", ev.Title())},
+		{"Date", ev.Time.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", header.name, header.value)
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Title summarizes the event in a single line.
+func (ev Event) Title() string {
+	switch ev.Kind {
+	case EventGuestUpload:
+		return fmt.Sprintf("New guest upload: %s", ev.Entry.Filename)
+	case EventFirstDownload:
+		return fmt.Sprintf("First download: %s", ev.Entry.Filename)
+	case EventTest:
+		return "PicoShare test notification"
+	}
+	return fmt.Sprintf("PicoShare event: %s", ev.Kind)
+}
+
+// Message describes the event in plain text.
+func (ev Event) Message() string {
+	var b strings.Builder
+	switch ev.Kind {
+	case EventGuestUpload:
+		fmt.Fprintf(&b, "%s uploaded %s (%s) through the guest link %s.\n", ev.senderDescription(), ev.Entry.Filename, formatSize(ev.Entry.Size.UInt64()), ev.guestLinkDescription())
+		if ev.Entry.Note.Value != nil {
+			fmt.Fprintf(&b, "\nNote: %s\n", *ev.Entry.Note.Value)
+		}
+	case EventFirstDownload:
+		fmt.Fprintf(&b, "Someone downloaded %s for the first time.\n", ev.Entry.Filename)
+	case EventTest:
+		b.WriteString("Your PicoShare notification settings work.\n")
+	default:
+		fmt.Fprintf(&b, "PicoShare recorded a %s event.\n", ev.Kind)
+	}
+	if ev.FileURL != "" {
+		fmt.Fprintf(&b, "\n%s\n", ev.FileURL)
+	}
+	return b.String()
+}
+
+func (ev Event) senderDescription() string {
+	sender := ev.Entry.Sender
+	switch {
+	case !sender.Name.Empty() && !sender.Email.Empty():
+		return fmt.Sprintf("%s (%s)", sender.Name, sender.Email)
+	case !sender.Name.Empty():
+		return sender.Name.String()
+	case !sender.Email.Empty():
+		return sender.Email.String()
+	}
+	return "A guest"
+}
+
+func (ev Event) guestLinkDescription() string {
+	if !ev.GuestLink.Label.Empty() {
+		return fmt.Sprintf("%q", ev.GuestLink.Label)
+	}
+	return ev.GuestLink.ID.String()
+}
+
+func formatSize(b uint64) string {
+	const unit = 1024
+
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "kMGTPE"[exp])
+}
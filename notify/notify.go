@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type (
+	EventKind string
+
+	// Event describes something that happened in PicoShare that the owner might
+	// want to know about.
+	Event struct {
+		Kind EventKind
+		Time time.Time
+		// Entry is the file that the event concerns. It's empty for test events.
+		Entry picoshare.UploadMetadata
+		// GuestLink is the guest link through which a guest uploaded the file,
+		// or empty if the event doesn't involve a guest link.
+		GuestLink picoshare.GuestLink
+		// FileURL is the address of the page where the owner can view the file.
+		FileURL string
+	}
+
+	// channel is a destination that PicoShare can send notifications to.
+	channel interface {
+		Name() string
+		Send(context.Context, Event) error
+	}
+
+	// Dispatcher sends notifications through the channels that the owner has
+	// configured.
+	Dispatcher struct {
+		httpClient *http.Client
+		pending    *sync.WaitGroup
+	}
+)
+
+const (
+	EventGuestUpload   = EventKind("guest_upload")
+	EventFirstDownload = EventKind("first_download")
+	EventTest          = EventKind("test")
+)
+
+// sendTimeout limits how long PicoShare spends delivering a single
+// notification through all of its channels.
+const sendTimeout = 30 * time.Second
+
+// NewDispatcher creates a Dispatcher that delivers notifications over the
+// network.
+func NewDispatcher() Dispatcher {
+	return NewDispatcherWithClient(&http.Client{Timeout: sendTimeout})
+}
+
+// NewDispatcherWithClient creates a Dispatcher that sends HTTP-based
+// notifications through the given client.
+func NewDispatcherWithClient(client *http.Client) Dispatcher {
+	return Dispatcher{
+		httpClient: client,
+		pending:    &sync.WaitGroup{},
+	}
+}
+
+// Notify sends the event in the background if the settings subscribe to that
+// kind of event. Notify logs delivery failures rather than returning them so
+// that a broken notification channel never fails the request that triggered
+// the event.
+func (d Dispatcher) Notify(settings picoshare.NotificationSettings, ev Event) {
+	if !isSubscribed(settings, ev.Kind) || !settings.HasChannels() {
+		return
+	}
+	d.pending.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := d.Send(ctx, settings, ev); err != nil {
+			log.Printf("failed to send %s notification: %v", ev.Kind, err)
+		}
+	})
+}
+
+// Send delivers the event through every channel that the settings configure,
+// regardless of which events the settings subscribe to. It returns an error
+// describing every channel that failed.
+func (d Dispatcher) Send(ctx context.Context, settings picoshare.NotificationSettings, ev Event) error {
+	channels := d.channels(settings)
+	if len(channels) == 0 {
+		return errors.New("no notification channels are configured")
+	}
+
+	var errs []error
+	for _, ch := range channels {
+		if err := ch.Send(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wait blocks until the dispatcher finishes sending all the notifications
+// that it's sending in the background.
+func (d Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+func (d Dispatcher) channels(settings picoshare.NotificationSettings) []channel {
+	channels := []channel{}
+	if settings.Email.IsConfigured() {
+		channels = append(channels, emailChannel{settings.Email})
+	}
+	if settings.Webhook.IsConfigured() {
+		channels = append(channels, webhookChannel{settings.Webhook, d.httpClient})
+	}
+	if settings.Ntfy.IsConfigured() {
+		channels = append(channels, ntfyChannel{settings.Ntfy, d.httpClient})
+	}
+	return channels
+}
+
+func isSubscribed(settings picoshare.NotificationSettings, kind EventKind) bool {
+	switch kind {
+	case EventGuestUpload:
+		return settings.OnGuestUpload
+	case EventFirstDownload:
+		return settings.OnFirstDownload
+	case EventTest:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/notify"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newCaptureServer starts a stand-in for a webhook or ntfy server that
+// records every request it receives and responds with the given status.
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, func() []capturedRequest) {
+	var mu sync.Mutex
+	requests := []capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, capturedRequest{r.Header.Clone(), body})
+		mu.Unlock()
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, "topic is read-only")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest{}, requests...)
+	}
+}
+
+// fakeSMTPServer is a minimal stand-in for an SMTP server that accepts a
+// single message and records the session.
+type fakeSMTPServer struct {
+	listener net.Listener
+	done     chan struct{}
+
+	auth string
+	from string
+	to   string
+	data string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) port() uint16 {
+	return uint16(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = line
+			reply("250 OK")
+		case "RCPT":
+			s.to = line
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) wait(t *testing.T) {
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for SMTP session to finish")
+	}
+}
+
+func makeGuestUploadEvent() notify.Event {
+	note := "Here are the scans"
+	return notify.Event{
+		Kind: notify.EventGuestUpload,
+		Time: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Entry: picoshare.UploadMetadata{
+			ID:          picoshare.EntryID("AAAAAAAAAA"),
+			Filename:    picoshare.Filename("scans.zip"),
+			ContentType: picoshare.ContentType("application/zip"),
+			Note:        picoshare.FileNote{Value: &note},
+			Sender: picoshare.GuestSender{
+				Name:  picoshare.SenderName("Jane Doe"),
+				Email: picoshare.SenderEmail("jane@example.com"),
+			},
+			Size: mustParseFileSize(2048),
+		},
+		GuestLink: picoshare.GuestLink{
+			ID:    picoshare.GuestLinkID("abcdefgh23456789"),
+			Label: picoshare.GuestLinkLabel("Tax documents"),
+		},
+		FileURL: "https://picoshare.example.com/files/AAAAAAAAAA/info",
+	}
+}
+
+func mustParseFileSize(n uint64) picoshare.FileSize {
+	size, err := picoshare.FileSizeFromUint64(n)
+	if err != nil {
+		panic(err)
+	}
+	return size
+}
+
+func TestSendWebhook(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK)
+
+	d := notify.NewDispatcherWithClient(srv.Client())
+	err := d.Send(context.Background(), picoshare.NotificationSettings{
+		Webhook: picoshare.WebhookNotificationSettings{URL: srv.URL + "/hook"},
+	}, makeGuestUploadEvent())
+	if err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+
+	reqs := requests()
+	if got, want := len(reqs), 1; got != want {
+		t.Fatalf("webhook requests=%d, want=%d", got, want)
+	}
+	if got, want := reqs[0].header.Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Content-Type=%s, want=%s", got, want)
+	}
+
+	var payload notify.WebhookPayload
+	if err := json.Unmarshal(reqs[0].body, &payload); err != nil {
+		t.Fatalf("failed to decode webhook payload: %v", err)
+	}
+	note := "Here are the scans"
+	want := notify.WebhookPayload{
+		Event:   notify.EventGuestUpload,
+		Time:    time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Title:   "New guest upload: scans.zip",
+		Message: makeGuestUploadEvent().Message(),
+		File: &notify.WebhookFile{
+			ID:          "AAAAAAAAAA",
+			Filename:    "scans.zip",
+			Size:        2048,
+			ContentType: "application/zip",
+			URL:         "https://picoshare.example.com/files/AAAAAAAAAA/info",
+			Note:        &note,
+			SenderName:  "Jane Doe",
+			SenderEmail: "jane@example.com",
+		},
+		GuestLink: &notify.WebhookGuestLink{
+			ID:    "abcdefgh23456789",
+			Label: "Tax documents",
+		},
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("payload=%+v, want=%+v", payload, want)
+	}
+}
+
+func TestSendNtfy(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK)
+
+	d := notify.NewDispatcherWithClient(srv.Client())
+	err := d.Send(context.Background(), picoshare.NotificationSettings{
+		Ntfy: picoshare.NtfyNotificationSettings{
+			TopicURL:    srv.URL + "/picoshare",
+			AccessToken: "tk_dummy",
+		},
+	}, makeGuestUploadEvent())
+	if err != nil {
+		t.Fatalf("failed to publish to ntfy: %v", err)
+	}
+
+	reqs := requests()
+	if got, want := len(reqs), 1; got != want {
+		t.Fatalf("ntfy requests=%d, want=%d", got, want)
+	}
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"Title", "New guest upload: scans.zip"},
+		{"Tags", "inbox_tray"},
+		{"Click", "https://picoshare.example.com/files/AAAAAAAAAA/info"},
+		{"Authorization", "Bearer tk_dummy"},
+	} {
+		if got := reqs[0].header.Get(tt.header); got != tt.want {
+			t.Errorf("%s=%q, want=%q", tt.header, got, tt.want)
+		}
+	}
+	if got, want := string(reqs[0].body), "Jane Doe (jane@example.com) uploaded scans.zip (2.00 kB) through the guest link \"Tax documents\".\n\nNote: Here are the scans\n\nhttps://picoshare.example.com/files/AAAAAAAAAA/info\n"; got != want {
+		t.Errorf("body=%q, want=%q", got, want)
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+
+	d := notify.NewDispatcher()
+	err := d.Send(context.Background(), picoshare.NotificationSettings{
+		Email: picoshare.EmailNotificationSettings{
+			Host:     "127.0.0.1",
+			Port:     srv.port(),
+			Username: "picoshare",
+			Password: "hunter2",
+			From:     "picoshare@example.com",
+			To:       "owner@example.com",
+		},
+	}, makeGuestUploadEvent())
+	if err != nil {
+		t.Fatalf("failed to send email: %v", err)
+	}
+	srv.wait(t)
+
+	if got, want := srv.auth, "AUTH PLAIN "+base64.StdEncoding.EncodeToString([]byte("\x00picoshare\x00hunter2")); got != want {
+		t.Errorf("auth=%q, want=%q", got, want)
+	}
+	if got, want := srv.from, "MAIL FROM:<picoshare@example.com>"; !strings.HasPrefix(got, want) {
+		t.Errorf("from=%q, want prefix %q", got, want)
+	}
+	if got, want := srv.to, "RCPT TO:<owner@example.com>"; got != want {
+		t.Errorf("to=%q, want=%q", got, want)
+	}
+	for _, want := range []string{
+		"From: \"PicoShare\" <picoshare@example.com>\r\n",
+		"To: <owner@example.com>\r\n",
+		"Subject: New guest upload: scans.zip\r\n",
+		"Date: Wed, 01 May 2024 12:30:00 +0000\r\n",
+		"\r\nJane Doe (jane@example.com) uploaded scans.zip",
+		"Note: Here are the scans\r\n",
+	} {
+		if !strings.Contains(srv.data, want) {
+			t.Errorf("email data=%q, want it to contain %q", srv.data, want)
+		}
+	}
+}
+
+func TestSendReportsFailedChannels(t *testing.T) {
+	okSrv, okRequests := newCaptureServer(t, http.StatusOK)
+	failSrv, _ := newCaptureServer(t, http.StatusForbidden)
+
+	d := notify.NewDispatcherWithClient(http.DefaultClient)
+	err := d.Send(context.Background(), picoshare.NotificationSettings{
+		Webhook: picoshare.WebhookNotificationSettings{URL: okSrv.URL},
+		Ntfy:    picoshare.NtfyNotificationSettings{TopicURL: failSrv.URL},
+	}, notify.Event{Kind: notify.EventTest, Time: time.Now()})
+	if err == nil {
+		t.Fatalf("expected error from failing ntfy server, got nil")
+	}
+	if got, want := err.Error(), "ntfy: "; !strings.HasPrefix(got, want) {
+		t.Errorf("err=%q, want prefix %q", got, want)
+	}
+	if got, want := err.Error(), "topic is read-only"; !strings.Contains(got, want) {
+		t.Errorf("err=%q, want it to contain %q", got, want)
+	}
+	if got, want := len(okRequests()), 1; got != want {
+		t.Errorf("webhook requests=%d, want=%d", got, want)
+	}
+}
+
+func TestSendWithoutChannels(t *testing.T) {
+	d := notify.NewDispatcher()
+	if err := d.Send(context.Background(), picoshare.NotificationSettings{}, notify.Event{Kind: notify.EventTest}); err == nil {
+		t.Errorf("expected error when no channels are configured, got nil")
+	}
+}
+
+func TestNotifyRespectsSubscriptions(t *testing.T) {
+	for _, tt := range []struct {
+		description  string
+		settings     picoshare.NotificationSettings
+		kind         notify.EventKind
+		wantRequests int
+	}{
+		{
+			description:  "sends guest upload when subscribed",
+			settings:     picoshare.NotificationSettings{OnGuestUpload: true},
+			kind:         notify.EventGuestUpload,
+			wantRequests: 1,
+		},
+		{
+			description:  "skips guest upload when not subscribed",
+			settings:     picoshare.NotificationSettings{OnFirstDownload: true},
+			kind:         notify.EventGuestUpload,
+			wantRequests: 0,
+		},
+		{
+			description:  "sends first download when subscribed",
+			settings:     picoshare.NotificationSettings{OnFirstDownload: true},
+			kind:         notify.EventFirstDownload,
+			wantRequests: 1,
+		},
+		{
+			description:  "skips first download when not subscribed",
+			settings:     picoshare.NotificationSettings{OnGuestUpload: true},
+			kind:         notify.EventFirstDownload,
+			wantRequests: 0,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			srv, requests := newCaptureServer(t, http.StatusOK)
+			tt.settings.Webhook.URL = srv.URL
+
+			d := notify.NewDispatcherWithClient(srv.Client())
+			ev := makeGuestUploadEvent()
+			ev.Kind = tt.kind
+			d.Notify(tt.settings, ev)
+			d.Wait()
+
+			if got, want := len(requests()), tt.wantRequests; got != want {
+				t.Errorf("requests=%d, want=%d", got, want)
+			}
+		})
+	}
+}
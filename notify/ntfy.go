@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type ntfyChannel struct {
+	settings picoshare.NtfyNotificationSettings
+	client   *http.Client
+}
+
+func (nc ntfyChannel) Name() string {
+	return "ntfy"
+}
+
+// Send publishes the event to an ntfy topic. See: https://docs.ntfy.sh/publish/
+func (nc ntfyChannel) Send(ctx context.Context, ev Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nc.settings.TopicURL, strings.NewReader(ev.Message()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "PicoShare")
+	// ntfy decodes RFC 2047 encoded words, which lets titles include
+	// characters that HTTP headers can't carry directly.
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", ev.Title()))
+	req.Header.Set("Tags", ntfyTag(ev.Kind))
+	if ev.FileURL != "" {
+		req.Header.Set("Click", ev.FileURL)
+	}
+	if nc.settings.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+nc.settings.AccessToken)
+	}
+
+	return doNotificationRequest(nc.client, req)
+}
+
+// ntfyTag chooses the emoji that ntfy shows next to the notification.
+func ntfyTag(kind EventKind) string {
+	switch kind {
+	case EventGuestUpload:
+		return "inbox_tray"
+	case EventFirstDownload:
+		return "outbox_tray"
+	}
+	return "bell"
+}
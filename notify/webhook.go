@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type (
+	webhookChannel struct {
+		settings picoshare.WebhookNotificationSettings
+		client   *http.Client
+	}
+
+	// WebhookPayload is the JSON body that PicoShare sends to webhooks.
+	WebhookPayload struct {
+		Event     EventKind         `json:"event"`
+		Time      time.Time         `json:"time"`
+		Title     string            `json:"title"`
+		Message   string            `json:"message"`
+		File      *WebhookFile      `json:"file,omitempty"`
+		GuestLink *WebhookGuestLink `json:"guestLink,omitempty"`
+	}
+
+	WebhookFile struct {
+		ID          string  `json:"id"`
+		Filename    string  `json:"filename"`
+		Size        uint64  `json:"size"`
+		ContentType string  `json:"contentType"`
+		URL         string  `json:"url,omitempty"`
+		Note        *string `json:"note,omitempty"`
+		SenderName  string  `json:"senderName,omitempty"`
+		SenderEmail string  `json:"senderEmail,omitempty"`
+	}
+
+	WebhookGuestLink struct {
+		ID    string `json:"id"`
+		Label string `json:"label,omitempty"`
+	}
+)
+
+func (wc webhookChannel) Name() string {
+	return "webhook"
+}
+
+func (wc webhookChannel) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(NewWebhookPayload(ev))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wc.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PicoShare")
+
+	return doNotificationRequest(wc.client, req)
+}
+
+// NewWebhookPayload converts an event into the body of a webhook request.
+func NewWebhookPayload(ev Event) WebhookPayload {
+	payload := WebhookPayload{
+		Event:   ev.Kind,
+		Time:    ev.Time.UTC(),
+		Title:   ev.Title(),
+		Message: ev.Message(),
+	}
+	if ev.Entry.ID != "" {
+		payload.File = &WebhookFile{
+			ID:          ev.Entry.ID.String(),
+			Filename:    ev.Entry.Filename.String(),
+			Size:        ev.Entry.Size.UInt64(),
+			ContentType: ev.Entry.ContentType.String(),
+			URL:         ev.FileURL,
+			Note:        ev.Entry.Note.Value,
+			SenderName:  ev.Entry.Sender.Name.String(),
+			SenderEmail: ev.Entry.Sender.Email.String(),
+		}
+	}
+	if !ev.GuestLink.ID.Empty() {
+		payload.GuestLink = &WebhookGuestLink{
+			ID:    ev.GuestLink.ID.String(),
+			Label: ev.GuestLink.Label.String(),
+		}
+	}
+	return payload
+}
+
+// doNotificationRequest sends an HTTP request to a notification service and
+// checks that the service accepted it.
+func doNotificationRequest(client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// Include the start of the response, as services usually explain the
+		// problem in the body.
+		detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s responded with %s: %s", req.URL.Host, res.Status, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
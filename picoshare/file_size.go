@@ -7,9 +7,21 @@ var (
 	ErrNegativeFileSize = errors.New("file size must be positive")
 )
 
-type FileSize struct {
-	size uint64
-}
+type (
+	FileSize struct {
+		size uint64
+	}
+
+	// DataSizes measures the file data that PicoShare holds.
+	DataSizes struct {
+		// TotalBytes is the sum of the sizes of all entries. Identical entries
+		// count once per entry.
+		TotalBytes uint64
+		// StoredBytes is the number of bytes that the database physically uses to
+		// store file data.
+		StoredBytes uint64
+	}
+)
 
 func FileSizeFromInt(val int) (FileSize, error) {
 	return FileSizeFromInt64(int64(val))
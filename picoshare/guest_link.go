@@ -1,14 +1,37 @@
 package picoshare
 
 import (
+	"fmt"
+	"mime"
+	"strings"
 	"time"
 )
 
 type (
-	GuestLinkID             string
-	GuestLinkLabel          string
-	GuestUploadMaxFileBytes *uint64
-	GuestUploadCountLimit   *int
+	GuestLinkID              string
+	GuestLinkLabel           string
+	GuestUploadMaxFileBytes  *uint64
+	GuestUploadCountLimit    *int
+	GuestUploadMaxTotalBytes *uint64
+	// GuestLinkPassphraseHash is a hash of the passphrase that guests must
+	// enter to use a guest link. An empty hash means that the link doesn't
+	// require a passphrase.
+	GuestLinkPassphraseHash string
+	// GuestUploadFileTypes restricts which files guests can upload through a
+	// guest link. Each entry is either a file extension, such as ".pdf", or a
+	// media type, such as "application/pdf" or "image/*". An empty list allows
+	// any file.
+	GuestUploadFileTypes []string
+	// GuestUploadListing controls which of the files uploaded through a guest
+	// link the guest link's upload page lists.
+	GuestUploadListing string
+	// GuestLinkInstructions are Markdown-formatted instructions that the guest
+	// link's upload page shows to guests.
+	GuestLinkInstructions string
+	// GuestLinkUploadWindow is how long a guest link keeps working after a guest
+	// first uploads a file through it. A zero window means that the link has no
+	// upload window.
+	GuestLinkUploadWindow time.Duration
 
 	GuestLink struct {
 		ID              GuestLinkID
@@ -18,14 +41,63 @@ type (
 		MaxFileLifetime FileLifetime
 		MaxFileBytes    GuestUploadMaxFileBytes
 		MaxFileUploads  GuestUploadCountLimit
-		IsDisabled      bool
-		FilesUploaded   int
+		// MaxTotalBytes limits the combined size of all the files that guests
+		// have uploaded through the link and that PicoShare still stores.
+		MaxTotalBytes GuestUploadMaxTotalBytes
+		IsDisabled    bool
+		FilesUploaded int
+		// BytesUploaded is the combined size of all the files that guests have
+		// uploaded through the link and that PicoShare still stores.
+		BytesUploaded uint64
+		// LastModified is the time an admin last edited the link, or the zero
+		// time if nobody has edited the link since its creation.
+		LastModified     time.Time
+		PassphraseHash   GuestLinkPassphraseHash
+		AllowedFileTypes GuestUploadFileTypes
+		// AllowSenderDetails indicates whether guests can attach their name,
+		// email address, and a note to the files they upload.
+		AllowSenderDetails bool
+		UploadListing      GuestUploadListing
+		Instructions       GuestLinkInstructions
+		// UploadWindow limits how long the link works once a guest starts using
+		// it. The link expires when its upload window ends or at UrlExpires,
+		// whichever comes first.
+		UploadWindow GuestLinkUploadWindow
+		// FirstUpload is the time that a guest first uploaded a file through the
+		// link, or the zero time if nobody has.
+		FirstUpload time.Time
 	}
+
+	// GuestLinkPreset is a named set of limits that the owner can apply to a new
+	// guest link instead of entering each limit by hand.
+	GuestLinkPreset struct {
+		Name            string
+		MaxFileLifetime FileLifetime
+		MaxFileBytes    GuestUploadMaxFileBytes
+		MaxFileUploads  GuestUploadCountLimit
+		MaxTotalBytes   GuestUploadMaxTotalBytes
+	}
+)
+
+const (
+	// GuestUploadListingNone hides uploaded files from guests.
+	GuestUploadListingNone = GuestUploadListing("")
+	// GuestUploadListingSession lists the files that the guest uploaded during
+	// their current browser session.
+	GuestUploadListingSession = GuestUploadListing("session")
+	// GuestUploadListingAll lists every file that guests uploaded through the
+	// link.
+	GuestUploadListingAll = GuestUploadListing("all")
 )
 
+// NoUploadWindow means that a guest link works until its expiration time,
+// regardless of when guests first use it.
+const NoUploadWindow = GuestLinkUploadWindow(0)
+
 var (
 	GuestUploadUnlimitedFileSize    = GuestUploadMaxFileBytes(nil)
 	GuestUploadUnlimitedFileUploads = GuestUploadCountLimit(nil)
+	GuestUploadUnlimitedTotalBytes  = GuestUploadMaxTotalBytes(nil)
 )
 
 func (glid GuestLinkID) Empty() bool {
@@ -40,6 +112,12 @@ func (gl GuestLink) Empty() bool {
 	return gl.ID.Empty()
 }
 
+// IsPassphraseProtected returns true if guests must enter a passphrase before
+// they can upload through the link.
+func (gl GuestLink) IsPassphraseProtected() bool {
+	return gl.PassphraseHash != ""
+}
+
 func (gl GuestLink) CanAcceptMoreFiles() bool {
 	if gl.MaxFileUploads == GuestUploadUnlimitedFileUploads {
 		return true
@@ -47,15 +125,92 @@ func (gl GuestLink) CanAcceptMoreFiles() bool {
 	return gl.FilesUploaded < *gl.MaxFileUploads
 }
 
+// RemainingBytes returns the number of bytes that guests can still upload
+// through the link before they reach its total byte quota. If the link has no
+// quota, ok is false.
+func (gl GuestLink) RemainingBytes() (remaining uint64, ok bool) {
+	if gl.MaxTotalBytes == GuestUploadUnlimitedTotalBytes {
+		return 0, false
+	}
+	if gl.BytesUploaded >= *gl.MaxTotalBytes {
+		return 0, true
+	}
+	return *gl.MaxTotalBytes - gl.BytesUploaded, true
+}
+
+// IsQuotaExhausted returns true if guests have used the link's entire total
+// byte quota.
+func (gl GuestLink) IsQuotaExhausted() bool {
+	remaining, ok := gl.RemainingBytes()
+	return ok && remaining == 0
+}
+
 func (gl GuestLink) IsExpired() bool {
+	if windowEnd, ok := gl.UploadWindowEnd(); ok && time.Now().After(windowEnd) {
+		return true
+	}
 	if gl.UrlExpires == NeverExpire {
 		return false
 	}
 	return time.Now().After(time.Time(gl.UrlExpires))
 }
 
+// UploadWindowEnd returns the time when the link's upload window closes. If
+// the link has no upload window or nobody has uploaded through it yet, ok is
+// false.
+func (gl GuestLink) UploadWindowEnd() (end time.Time, ok bool) {
+	if gl.UploadWindow == NoUploadWindow || gl.FirstUpload.IsZero() {
+		return time.Time{}, false
+	}
+	return gl.FirstUpload.Add(time.Duration(gl.UploadWindow)), true
+}
+
+// HasReachedUploadLimits returns true if guests have uploaded as many files or
+// as many bytes as the link allows.
+func (gl GuestLink) HasReachedUploadLimits() bool {
+	return !gl.CanAcceptMoreFiles() || gl.IsQuotaExhausted()
+}
+
 func (gl GuestLink) IsActive() bool {
-	return !gl.IsExpired() && gl.CanAcceptMoreFiles() && !gl.IsDisabled
+	return !gl.IsExpired() && !gl.HasReachedUploadLimits() && !gl.IsDisabled
+}
+
+// CanDeleteUploads returns true if guests can still delete the files they
+// uploaded through the link. Unlike IsActive, this ignores the link's upload
+// limits so that a guest who reaches a limit with a mistaken upload can delete
+// it and try again.
+func (gl GuestLink) CanDeleteUploads() bool {
+	return gl.UploadListing != GuestUploadListingNone && !gl.IsExpired() && !gl.IsDisabled
+}
+
+func (gul GuestUploadListing) String() string {
+	return string(gul)
+}
+
+func (w GuestLinkUploadWindow) String() string {
+	return time.Duration(w).String()
+}
+
+// FriendlyName describes the window in whole days, or in hours if it's
+// shorter than a day or doesn't divide evenly into days.
+func (w GuestLinkUploadWindow) FriendlyName() string {
+	d := time.Duration(w)
+	value, unit := int64(d/time.Hour), "hour"
+	if d%(hoursPerDay*time.Hour) == 0 {
+		value, unit = int64(d/(hoursPerDay*time.Hour)), "day"
+	}
+	if value != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", value, unit)
+}
+
+func (gli GuestLinkInstructions) Empty() bool {
+	return gli.String() == ""
+}
+
+func (gli GuestLinkInstructions) String() string {
+	return string(gli)
 }
 
 func (label GuestLinkLabel) Empty() bool {
@@ -65,3 +220,63 @@ func (label GuestLinkLabel) Empty() bool {
 func (label GuestLinkLabel) String() string {
 	return string(label)
 }
+
+// IsUnrestricted returns true if guests can upload files of any type.
+func (ft GuestUploadFileTypes) IsUnrestricted() bool {
+	return len(ft) == 0
+}
+
+// Allows returns true if a file with the given name and contents of the given
+// media type is an allowed file type. If the list includes extensions, the
+// filename must end with one of them. If the list includes media types, the
+// file's contents must match one of them.
+func (ft GuestUploadFileTypes) Allows(filename Filename, contentType ContentType) bool {
+	var extensions, mediaTypes []string
+	for _, t := range ft {
+		if strings.HasPrefix(t, ".") {
+			extensions = append(extensions, t)
+		} else {
+			mediaTypes = append(mediaTypes, t)
+		}
+	}
+
+	if len(extensions) > 0 && !hasAnySuffix(strings.ToLower(filename.String()), extensions) {
+		return false
+	}
+
+	if len(mediaTypes) > 0 && !matchesAnyMediaType(contentType, mediaTypes) {
+		return false
+	}
+
+	return true
+}
+
+func (ft GuestUploadFileTypes) String() string {
+	return strings.Join(ft, ", ")
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyMediaType(contentType ContentType, patterns []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType.String())
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == pattern {
+			return true
+		}
+	}
+	return false
+}
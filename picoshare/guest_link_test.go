@@ -0,0 +1,260 @@
+package picoshare_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func TestGuestUploadFileTypesAllows(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		fileTypes   picoshare.GuestUploadFileTypes
+		filename    picoshare.Filename
+		contentType picoshare.ContentType
+		want        bool
+	}{
+		{
+			description: "empty list allows any file",
+			fileTypes:   picoshare.GuestUploadFileTypes{},
+			filename:    picoshare.Filename("program.exe"),
+			contentType: picoshare.ContentType("application/octet-stream"),
+			want:        true,
+		},
+		{
+			description: "allows matching extension",
+			fileTypes:   picoshare.GuestUploadFileTypes{".pdf", ".docx"},
+			filename:    picoshare.Filename("resume.pdf"),
+			contentType: picoshare.ContentType("application/pdf"),
+			want:        true,
+		},
+		{
+			description: "matches extensions case-insensitively",
+			fileTypes:   picoshare.GuestUploadFileTypes{".pdf"},
+			filename:    picoshare.Filename("RESUME.PDF"),
+			contentType: picoshare.ContentType("application/pdf"),
+			want:        true,
+		},
+		{
+			description: "allows multi-part extensions",
+			fileTypes:   picoshare.GuestUploadFileTypes{".tar.gz"},
+			filename:    picoshare.Filename("backup.tar.gz"),
+			contentType: picoshare.ContentType("application/x-gzip"),
+			want:        true,
+		},
+		{
+			description: "rejects other extensions",
+			fileTypes:   picoshare.GuestUploadFileTypes{".pdf"},
+			filename:    picoshare.Filename("resume.exe"),
+			contentType: picoshare.ContentType("application/pdf"),
+			want:        false,
+		},
+		{
+			description: "allows matching media type",
+			fileTypes:   picoshare.GuestUploadFileTypes{"application/pdf"},
+			filename:    picoshare.Filename("resume"),
+			contentType: picoshare.ContentType("application/pdf"),
+			want:        true,
+		},
+		{
+			description: "ignores media type parameters",
+			fileTypes:   picoshare.GuestUploadFileTypes{"text/plain"},
+			filename:    picoshare.Filename("notes.txt"),
+			contentType: picoshare.ContentType("text/plain; charset=utf-8"),
+			want:        true,
+		},
+		{
+			description: "allows media type wildcards",
+			fileTypes:   picoshare.GuestUploadFileTypes{"image/*"},
+			filename:    picoshare.Filename("photo.jpg"),
+			contentType: picoshare.ContentType("image/jpeg"),
+			want:        true,
+		},
+		{
+			description: "rejects other media types",
+			fileTypes:   picoshare.GuestUploadFileTypes{"image/*", "application/pdf"},
+			filename:    picoshare.Filename("program.pdf"),
+			contentType: picoshare.ContentType("application/octet-stream"),
+			want:        false,
+		},
+		{
+			description: "requires both extension and media type when both are listed",
+			fileTypes:   picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+			filename:    picoshare.Filename("resume.txt"),
+			contentType: picoshare.ContentType("application/pdf"),
+			want:        false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if got, want := tt.fileTypes.Allows(tt.filename, tt.contentType), tt.want; got != want {
+				t.Errorf("allows=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkRemainingBytes(t *testing.T) {
+	makeLimit := func(b uint64) picoshare.GuestUploadMaxTotalBytes {
+		return picoshare.GuestUploadMaxTotalBytes(&b)
+	}
+	for _, tt := range []struct {
+		description   string
+		maxTotalBytes picoshare.GuestUploadMaxTotalBytes
+		bytesUploaded uint64
+		wantRemaining uint64
+		wantOk        bool
+		wantExhausted bool
+	}{
+		{
+			description:   "link without a quota has no remaining bytes",
+			maxTotalBytes: picoshare.GuestUploadUnlimitedTotalBytes,
+			bytesUploaded: 5000,
+			wantRemaining: 0,
+			wantOk:        false,
+			wantExhausted: false,
+		},
+		{
+			description:   "unused link has its entire quota remaining",
+			maxTotalBytes: makeLimit(1000),
+			bytesUploaded: 0,
+			wantRemaining: 1000,
+			wantOk:        true,
+			wantExhausted: false,
+		},
+		{
+			description:   "partially used link has the difference remaining",
+			maxTotalBytes: makeLimit(1000),
+			bytesUploaded: 400,
+			wantRemaining: 600,
+			wantOk:        true,
+			wantExhausted: false,
+		},
+		{
+			description:   "link that used its quota exactly is exhausted",
+			maxTotalBytes: makeLimit(1000),
+			bytesUploaded: 1000,
+			wantRemaining: 0,
+			wantOk:        true,
+			wantExhausted: true,
+		},
+		{
+			description:   "link whose quota shrank below usage is exhausted",
+			maxTotalBytes: makeLimit(1000),
+			bytesUploaded: 1500,
+			wantRemaining: 0,
+			wantOk:        true,
+			wantExhausted: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			gl := picoshare.GuestLink{
+				MaxTotalBytes: tt.maxTotalBytes,
+				BytesUploaded: tt.bytesUploaded,
+			}
+
+			remaining, ok := gl.RemainingBytes()
+			if got, want := remaining, tt.wantRemaining; got != want {
+				t.Errorf("remaining=%d, want=%d", got, want)
+			}
+			if got, want := ok, tt.wantOk; got != want {
+				t.Errorf("ok=%v, want=%v", got, want)
+			}
+			if got, want := gl.IsQuotaExhausted(), tt.wantExhausted; got != want {
+				t.Errorf("IsQuotaExhausted=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkIsExpired(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		description string
+		guestLink   picoshare.GuestLink
+		wantExpired bool
+	}{
+		{
+			description: "link that never expires is active",
+			guestLink: picoshare.GuestLink{
+				UrlExpires: picoshare.NeverExpire,
+			},
+			wantExpired: false,
+		},
+		{
+			description: "link past its expiration time is expired",
+			guestLink: picoshare.GuestLink{
+				UrlExpires: picoshare.ExpirationTime(now.Add(-time.Minute)),
+			},
+			wantExpired: true,
+		},
+		{
+			description: "upload window doesn't start until the first upload",
+			guestLink: picoshare.GuestLink{
+				UrlExpires:   picoshare.NeverExpire,
+				UploadWindow: picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			},
+			wantExpired: false,
+		},
+		{
+			description: "link is active during its upload window",
+			guestLink: picoshare.GuestLink{
+				UrlExpires:   picoshare.NeverExpire,
+				UploadWindow: picoshare.GuestLinkUploadWindow(24 * time.Hour),
+				FirstUpload:  now.Add(-23 * time.Hour),
+			},
+			wantExpired: false,
+		},
+		{
+			description: "link expires when its upload window ends",
+			guestLink: picoshare.GuestLink{
+				UrlExpires:   picoshare.NeverExpire,
+				UploadWindow: picoshare.GuestLinkUploadWindow(24 * time.Hour),
+				FirstUpload:  now.Add(-25 * time.Hour),
+			},
+			wantExpired: true,
+		},
+		{
+			description: "expiration time applies during the upload window",
+			guestLink: picoshare.GuestLink{
+				UrlExpires:   picoshare.ExpirationTime(now.Add(-time.Minute)),
+				UploadWindow: picoshare.GuestLinkUploadWindow(24 * time.Hour),
+				FirstUpload:  now.Add(-time.Hour),
+			},
+			wantExpired: true,
+		},
+		{
+			description: "first upload doesn't matter without an upload window",
+			guestLink: picoshare.GuestLink{
+				UrlExpires:  picoshare.NeverExpire,
+				FirstUpload: now.Add(-1000 * time.Hour),
+			},
+			wantExpired: false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if got, want := tt.guestLink.IsExpired(), tt.wantExpired; got != want {
+				t.Errorf("expired=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuestLinkUploadWindowFriendlyName(t *testing.T) {
+	for _, tt := range []struct {
+		window picoshare.GuestLinkUploadWindow
+		want   string
+	}{
+		{picoshare.GuestLinkUploadWindow(time.Hour), "1 hour"},
+		{picoshare.GuestLinkUploadWindow(12 * time.Hour), "12 hours"},
+		{picoshare.GuestLinkUploadWindow(24 * time.Hour), "1 day"},
+		{picoshare.GuestLinkUploadWindow(36 * time.Hour), "36 hours"},
+		{picoshare.GuestLinkUploadWindow(7 * 24 * time.Hour), "7 days"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			if got, want := tt.window.FriendlyName(), tt.want; got != want {
+				t.Errorf("friendly name=%v, want=%v", got, want)
+			}
+		})
+	}
+}
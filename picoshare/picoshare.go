@@ -11,22 +11,72 @@ type (
 	ContentType    string
 	ExpirationTime time.Time
 
+	// EntrySlug is an optional alias for an entry's ID that the owner chooses
+	// so that the entry's download link is easier to read.
+	EntrySlug string
+
+	// SHA256Checksum is the lowercase, hex-encoded SHA-256 digest of a file's
+	// contents.
+	SHA256Checksum string
+
 	FileNote struct {
 		Value *string
 	}
 
+	SenderName  string
+	SenderEmail string
+
+	// GuestSender identifies the guest who uploaded a file through a guest
+	// link. Both fields are empty if the guest didn't provide them or if the
+	// PicoShare owner uploaded the file.
+	GuestSender struct {
+		Name  SenderName
+		Email SenderEmail
+	}
+
+	// GuestSessionKey identifies the browser session in which a guest uploaded
+	// a file. It's empty for files that the owner uploaded or that a guest
+	// uploaded without a browser, such as from the command line.
+	GuestSessionKey string
+
 	UploadMetadata struct {
 		ID            EntryID
+		Slug          EntrySlug
 		Filename      Filename
 		Note          FileNote
 		ContentType   ContentType
 		Uploaded      time.Time
 		Expires       ExpirationTime
 		Size          FileSize
+		SHA256        SHA256Checksum
 		GuestLink     GuestLink
+		Sender        GuestSender
+		GuestSession  GuestSessionKey
 		DownloadCount uint64
 	}
 
+	// CorruptEntry describes an entry whose stored data failed an integrity
+	// check.
+	CorruptEntry struct {
+		ID          EntryID
+		Filename    Filename
+		Detected    time.Time
+		Reason      string
+		Quarantined bool
+	}
+
+	// EvictedEntry describes an entry that PicoShare deleted automatically to
+	// bring its storage usage back under its storage limit.
+	EvictedEntry struct {
+		ID       EntryID
+		Filename Filename
+		Size     FileSize
+		Uploaded time.Time
+		Evicted  time.Time
+		// Policy is the eviction policy that chose the entry.
+		Policy string
+	}
+
 	DownloadRecord struct {
 		Time      time.Time
 		ClientIP  string
@@ -46,6 +96,14 @@ func (id EntryID) String() string {
 	return string(id)
 }
 
+func (s EntrySlug) String() string {
+	return string(s)
+}
+
+func (s EntrySlug) Empty() bool {
+	return s == ""
+}
+
 func (f Filename) String() string {
 	return string(f)
 }
@@ -54,6 +112,14 @@ func (ct ContentType) String() string {
 	return string(ct)
 }
 
+func (c SHA256Checksum) String() string {
+	return string(c)
+}
+
+func (c SHA256Checksum) Empty() bool {
+	return c == ""
+}
+
 func (et ExpirationTime) String() string {
 	return et.Time().String()
 }
@@ -68,3 +134,31 @@ func (n FileNote) String() string {
 	}
 	return *n.Value
 }
+
+func (n SenderName) String() string {
+	return string(n)
+}
+
+func (n SenderName) Empty() bool {
+	return n == ""
+}
+
+func (e SenderEmail) String() string {
+	return string(e)
+}
+
+func (e SenderEmail) Empty() bool {
+	return e == ""
+}
+
+func (k GuestSessionKey) String() string {
+	return string(k)
+}
+
+func (k GuestSessionKey) Empty() bool {
+	return k == ""
+}
+
+func (gs GuestSender) Empty() bool {
+	return gs.Name.Empty() && gs.Email.Empty()
+}
@@ -2,10 +2,137 @@ package picoshare
 
 import "fmt"
 
-type Settings struct {
-	DefaultFileLifetime FileLifetime
-}
+type (
+	Settings struct {
+		DefaultFileLifetime FileLifetime
+		Notifications       NotificationSettings
+		EventWebhook        EventWebhookSettings
+		Branding            BrandingSettings
+		GuestLinkPresets    []GuestLinkPreset
+	}
+
+	// NotificationSettings control which events PicoShare reports to the owner
+	// and the channels through which it reports them.
+	NotificationSettings struct {
+		OnGuestUpload   bool
+		OnFirstDownload bool
+		Email           EmailNotificationSettings
+		Webhook         WebhookNotificationSettings
+		Ntfy            NtfyNotificationSettings
+	}
+
+	// EmailNotificationSettings describe an SMTP server that PicoShare sends
+	// notification emails through.
+	EmailNotificationSettings struct {
+		Host     string
+		Port     uint16
+		Username string
+		Password string
+		From     string
+		To       string
+	}
+
+	// WebhookNotificationSettings describe a URL that PicoShare sends a JSON
+	// POST request to for each notification.
+	WebhookNotificationSettings struct {
+		URL string
+	}
+
+	// NtfyNotificationSettings describe an ntfy topic that PicoShare publishes
+	// notifications to.
+	NtfyNotificationSettings struct {
+		TopicURL    string
+		AccessToken string
+	}
+
+	// EventWebhookSettings describe a URL that PicoShare reports every change to
+	// entries and guest links to. Unlike notifications, PicoShare queues event
+	// webhooks in its database and retries them until the webhook accepts them.
+	EventWebhookSettings struct {
+		URL string
+		// Secret is the key that PicoShare signs requests with, so that the
+		// webhook can verify that they came from PicoShare.
+		Secret string
+	}
+
+	// BrandingSettings customize how PicoShare's pages look, so that guests
+	// recognize whose instance they're uploading to.
+	BrandingSettings struct {
+		// Name replaces "PicoShare" in page titles and the navigation bar. If
+		// it's empty, pages use the default name.
+		Name string
+		Logo BrandingLogo
+		// AccentColor is a CSS hex color, such as "#1a73e8", that replaces the
+		// default color of buttons and links. If it's empty, pages use the
+		// default colors.
+		AccentColor string
+	}
+
+	// BrandingLogo is an image that PicoShare shows next to the instance name
+	// in the navigation bar.
+	BrandingLogo struct {
+		ContentType ContentType
+		Data        []byte
+	}
+)
+
+// defaultInstanceName is the name that pages show if the owner hasn't chosen
+// one.
+const defaultInstanceName = "PicoShare"
 
 func (s Settings) String() string {
-	return fmt.Sprintf("{lifetime=%s}", s.DefaultFileLifetime.FriendlyName())
+	return fmt.Sprintf("{lifetime=%s, notifications=%s, eventWebhook=%v, branding=%s, guestLinkPresets=%d}", s.DefaultFileLifetime.FriendlyName(), s.Notifications, s.EventWebhook.IsConfigured(), s.Branding, len(s.GuestLinkPresets))
+}
+
+// String summarizes the notification settings without revealing any
+// credentials.
+func (ns NotificationSettings) String() string {
+	return fmt.Sprintf("{guestUpload=%v, firstDownload=%v, email=%v, webhook=%v, ntfy=%v}",
+		ns.OnGuestUpload, ns.OnFirstDownload, ns.Email.IsConfigured(), ns.Webhook.IsConfigured(), ns.Ntfy.IsConfigured())
+}
+
+// HasChannels returns true if the settings configure at least one channel
+// for PicoShare to send notifications through.
+func (ns NotificationSettings) HasChannels() bool {
+	return ns.Email.IsConfigured() || ns.Webhook.IsConfigured() || ns.Ntfy.IsConfigured()
+}
+
+func (es EmailNotificationSettings) IsConfigured() bool {
+	return es.Host != "" && es.To != ""
+}
+
+func (ws WebhookNotificationSettings) IsConfigured() bool {
+	return ws.URL != ""
+}
+
+func (ns NtfyNotificationSettings) IsConfigured() bool {
+	return ns.TopicURL != ""
+}
+
+func (ws EventWebhookSettings) IsConfigured() bool {
+	return ws.URL != ""
+}
+
+// DisplayName returns the name that pages show for the PicoShare instance.
+func (bs BrandingSettings) DisplayName() string {
+	if bs.Name == "" {
+		return defaultInstanceName
+	}
+	return bs.Name
+}
+
+// HasLogo returns true if the owner has uploaded a logo. It checks only the
+// logo's content type, so it works on branding settings that the store read
+// without the logo's data.
+func (bs BrandingSettings) HasLogo() bool {
+	return bs.Logo.ContentType != ""
+}
+
+// String summarizes the branding settings without the logo's contents.
+func (bs BrandingSettings) String() string {
+	return fmt.Sprintf("{name=%q, logo=%v, accentColor=%q}", bs.Name, !bs.Logo.Empty(), bs.AccentColor)
+}
+
+func (bl BrandingLogo) Empty() bool {
+	return len(bl.Data) == 0
 }
@@ -0,0 +1,68 @@
+package picoshare
+
+import "time"
+
+type (
+	// WebhookEventType identifies a change in PicoShare that the owner's event
+	// webhook hears about.
+	WebhookEventType string
+
+	WebhookDeliveryID string
+
+	WebhookDeliveryState string
+
+	// WebhookDelivery is a single event that PicoShare sends or has sent to the
+	// owner's event webhook.
+	WebhookDelivery struct {
+		ID        WebhookDeliveryID
+		EventType WebhookEventType
+		URL       string
+		// Payload is the JSON body of the webhook request.
+		Payload []byte
+		State   WebhookDeliveryState
+		// Attempts is the number of times PicoShare has tried to deliver the
+		// event.
+		Attempts uint
+		Created  time.Time
+		// NextAttempt is when PicoShare will next try to deliver the event. It's
+		// the zero time if the delivery is no longer pending.
+		NextAttempt time.Time
+		// LastAttempt is when PicoShare last tried to deliver the event, or the
+		// zero time if it hasn't tried yet.
+		LastAttempt time.Time
+		// LastStatusCode is the HTTP status that the webhook returned on the
+		// last attempt, or zero if the webhook didn't respond.
+		LastStatusCode int
+		// LastError describes why the last attempt failed.
+		LastError string
+	}
+)
+
+const (
+	WebhookEventEntryCreated      = WebhookEventType("entry.created")
+	WebhookEventEntryUpdated      = WebhookEventType("entry.updated")
+	WebhookEventEntryDownloaded   = WebhookEventType("entry.downloaded")
+	WebhookEventEntryExpired      = WebhookEventType("entry.expired")
+	WebhookEventEntryEvicted      = WebhookEventType("entry.evicted")
+	WebhookEventEntryDeleted      = WebhookEventType("entry.deleted")
+	WebhookEventGuestLinkCreated  = WebhookEventType("guest_link.created")
+	WebhookEventGuestLinkDisabled = WebhookEventType("guest_link.disabled")
+)
+
+const (
+	WebhookDeliveryPending   = WebhookDeliveryState("pending")
+	WebhookDeliveryDelivered = WebhookDeliveryState("delivered")
+	WebhookDeliveryFailed    = WebhookDeliveryState("failed")
+)
+
+func (t WebhookEventType) String() string {
+	return string(t)
+}
+
+func (id WebhookDeliveryID) String() string {
+	return string(id)
+}
+
+func (s WebhookDeliveryState) String() string {
+	return string(s)
+}
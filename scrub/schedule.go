@@ -0,0 +1,30 @@
+package scrub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Scheduler struct {
+	scrubber *Scrubber
+	ticker   *time.Ticker
+}
+
+func NewScheduler(scrubber *Scrubber, interval time.Duration) Scheduler {
+	return Scheduler{
+		scrubber: scrubber,
+		ticker:   time.NewTicker(interval),
+	}
+}
+
+func (s *Scheduler) StartAsync() {
+	go func() {
+		for range s.ticker.C {
+			log.Printf("verifying integrity of stored data")
+			if err := s.scrubber.Scrub(context.Background()); err != nil {
+				log.Printf("data integrity check failed: %v", err)
+			}
+		}
+	}()
+}
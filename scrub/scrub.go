@@ -0,0 +1,90 @@
+package scrub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+type (
+	EntryVerifier interface {
+		GetEntriesMetadata(context.Context) ([]picoshare.UploadMetadata, error)
+		VerifyEntryData(context.Context, picoshare.EntryID) error
+		InsertCorruptEntry(context.Context, picoshare.CorruptEntry) error
+		DeleteCorruptEntry(context.Context, picoshare.EntryID) error
+	}
+
+	Scrubber struct {
+		verifier   EntryVerifier
+		quarantine bool
+		mu         sync.Mutex
+	}
+)
+
+// NewScrubber creates a Scrubber that checks the integrity of every entry's
+// stored data. If quarantine is true, the scrubber prevents clients from
+// downloading entries with corrupted data.
+func NewScrubber(verifier EntryVerifier, quarantine bool) Scrubber {
+	return Scrubber{
+		verifier:   verifier,
+		quarantine: quarantine,
+	}
+}
+
+// Scrub verifies the stored data of every entry and records the entries whose
+// data is corrupted. If an entry that was previously corrupted now passes
+// verification, Scrub clears its corruption record. If Scrub fails to verify
+// an entry, it moves on to the remaining entries and returns an error after
+// checking them.
+func (s *Scrubber) Scrub(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.verifier.GetEntriesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	corrupted := 0
+	failed := 0
+	for _, entry := range entries {
+		err := s.verifier.VerifyEntryData(ctx, entry.ID)
+		if corruption, ok := errors.AsType[store.EntryCorruptedError](err); ok {
+			corrupted++
+			if err := s.verifier.InsertCorruptEntry(ctx, picoshare.CorruptEntry{
+				ID:          entry.ID,
+				Detected:    time.Now(),
+				Reason:      corruption.Reason,
+				Quarantined: s.quarantine,
+			}); err != nil {
+				return err
+			}
+			continue
+		} else if _, ok := errors.AsType[store.EntryNotFoundError](err); ok {
+			// The entry was deleted after we listed it.
+			continue
+		} else if err != nil {
+			log.Printf("failed to verify data for entry %v: %v", entry.ID, err)
+			failed++
+			continue
+		}
+
+		if err := s.verifier.DeleteCorruptEntry(ctx, entry.ID); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("verified %d entries, found %d with corrupted data", len(entries)-failed, corrupted)
+
+	if failed > 0 {
+		return fmt.Errorf("failed to verify data for %d of %d entries", failed, len(entries))
+	}
+
+	return nil
+}
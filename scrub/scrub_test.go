@@ -0,0 +1,205 @@
+package scrub_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/scrub"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/sqlite"
+)
+
+func TestScrubRecordsAndQuarantinesCorruptEntries(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.NewWithChunkSize(dbPath, 5, false)
+
+	for _, entry := range []struct {
+		id       picoshare.EntryID
+		contents string
+	}{
+		{
+			id:       picoshare.EntryID("intact-entry"),
+			contents: "intact contents",
+		},
+		{
+			id:       picoshare.EntryID("missing-chunk"),
+			contents: "this entry loses a chunk",
+		},
+		{
+			id:       picoshare.EntryID("modified-chunk"),
+			contents: "this entry has a modified chunk",
+		},
+	} {
+		if err := dataStore.InsertEntry(context.Background(), strings.NewReader(entry.contents), picoshare.UploadMetadata{
+			ID:       entry.id,
+			Filename: picoshare.Filename("dummy.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.NeverExpire,
+			Size:     mustParseFileSize(len(entry.contents)),
+		}); err != nil {
+			t.Fatalf("failed to insert entry: %v", err)
+		}
+	}
+
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer raw.Close()
+
+	if _, err := raw.Exec(`
+	DELETE FROM
+		entries_data
+	WHERE
+		chunk_index = 1 AND
+		id = (SELECT blob_id FROM entries WHERE id = 'missing-chunk')`); err != nil {
+		t.Fatalf("failed to delete chunk: %v", err)
+	}
+	if _, err := raw.Exec(`
+	UPDATE
+		entries_data
+	SET
+		chunk = CAST('XXXXX' AS BLOB)
+	WHERE
+		chunk_index = 2 AND
+		id = (SELECT blob_id FROM entries WHERE id = 'modified-chunk')`); err != nil {
+		t.Fatalf("failed to modify chunk: %v", err)
+	}
+
+	s := scrub.NewScrubber(dataStore, true)
+	if err := s.Scrub(context.Background()); err != nil {
+		t.Fatalf("scrub failed: %v", err)
+	}
+
+	corrupted, err := dataStore.GetCorruptEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get corrupt entries: %v", err)
+	}
+
+	reasons := map[picoshare.EntryID]string{}
+	for _, ce := range corrupted {
+		if !ce.Quarantined {
+			t.Errorf("corrupt entry %v is not quarantined", ce.ID)
+		}
+		reasons[ce.ID] = ce.Reason
+	}
+
+	if got, want := reasons, map[picoshare.EntryID]string{
+		"missing-chunk":  "chunk 1 is missing",
+		"modified-chunk": "contents don't match the checksum recorded at upload time",
+	}; !maps.Equal(got, want) {
+		t.Errorf("corrupt entries=%v, want=%v", got, want)
+	}
+
+	if _, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("missing-chunk")); err != (store.EntryQuarantinedError{ID: "missing-chunk"}) {
+		t.Errorf("err=%v, want=%v", err, store.EntryQuarantinedError{ID: "missing-chunk"})
+	}
+
+	if _, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("intact-entry")); err != nil {
+		t.Errorf("failed to read intact entry: %v", err)
+	}
+}
+
+type flakyVerifier struct {
+	scrub.EntryVerifier
+	failingID picoshare.EntryID
+}
+
+func (fv flakyVerifier) VerifyEntryData(ctx context.Context, id picoshare.EntryID) error {
+	if id == fv.failingID {
+		return errors.New("dummy verification error")
+	}
+	return fv.EntryVerifier.VerifyEntryData(ctx, id)
+}
+
+func TestScrubContinuesPastVerificationErrors(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.NewWithChunkSize(dbPath, 5, false)
+
+	for _, entry := range []struct {
+		id       picoshare.EntryID
+		contents string
+	}{
+		{
+			id:       picoshare.EntryID("unverifiable"),
+			contents: "this entry fails to verify",
+		},
+		{
+			id:       picoshare.EntryID("missing-chunk"),
+			contents: "this entry loses a chunk",
+		},
+	} {
+		if err := dataStore.InsertEntry(context.Background(), strings.NewReader(entry.contents), picoshare.UploadMetadata{
+			ID:       entry.id,
+			Filename: picoshare.Filename("dummy.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.NeverExpire,
+			Size:     mustParseFileSize(len(entry.contents)),
+		}); err != nil {
+			t.Fatalf("failed to insert entry: %v", err)
+		}
+	}
+
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer raw.Close()
+
+	if _, err := raw.Exec(`
+	DELETE FROM
+		entries_data
+	WHERE
+		chunk_index = 1 AND
+		id = (SELECT blob_id FROM entries WHERE id = 'missing-chunk')`); err != nil {
+		t.Fatalf("failed to delete chunk: %v", err)
+	}
+
+	s := scrub.NewScrubber(flakyVerifier{
+		EntryVerifier: dataStore,
+		failingID:     picoshare.EntryID("unverifiable"),
+	}, false)
+	if err := s.Scrub(context.Background()); err == nil {
+		t.Errorf("expected scrub to report the verification error")
+	}
+
+	corrupted, err := dataStore.GetCorruptEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get corrupt entries: %v", err)
+	}
+
+	reasons := map[picoshare.EntryID]string{}
+	for _, ce := range corrupted {
+		reasons[ce.ID] = ce.Reason
+	}
+
+	if got, want := reasons, map[picoshare.EntryID]string{
+		"missing-chunk": "chunk 1 is missing",
+	}; !maps.Equal(got, want) {
+		t.Errorf("corrupt entries=%v, want=%v", got, want)
+	}
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustParseFileSize(val int) picoshare.FileSize {
+	fileSize, err := picoshare.FileSizeFromInt(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return fileSize
+}
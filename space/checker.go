@@ -1,6 +1,11 @@
 package space
 
-import "github.com/mtlynch/picoshare/space/checkers"
+import (
+	"context"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/space/checkers"
+)
 
 type (
 	FileSystemChecker interface {
@@ -8,7 +13,7 @@ type (
 	}
 
 	DatabaseChecker interface {
-		TotalSize() (uint64, error)
+		MeasureUsage(context.Context) (picoshare.DataSizes, error)
 	}
 
 	Checker struct {
@@ -19,8 +24,15 @@ type (
 	Usage struct {
 		// TotalServingBytes represents the sum total of the bytes of file data that
 		// PicoShare has of file uploads in the database. This is just file bytes
-		// and does not include PicoShare-specific metadata about the files.
+		// and does not include PicoShare-specific metadata about the files. It's
+		// the logical size of the uploads, so PicoShare counts identical uploads
+		// once for each upload.
 		TotalServingBytes uint64
+		// TotalStoredBytes represents the number of bytes of file data that
+		// PicoShare physically stores in the database. Identical uploads share a
+		// single copy of their data, and compression can shrink it further, so
+		// this can be smaller than TotalServingBytes.
+		TotalStoredBytes uint64
 		// DatabaseFileSize represents the total number of bytes on the filesystem
 		// dedicated to storing PicoShare's SQLite database files.
 		DatabaseFileSize uint64
@@ -38,6 +50,12 @@ func NewChecker(dbPath string, dbReader checkers.DatabaseMetadataReader) Checker
 	return NewCheckerFromCheckers(checkers.NewFileSystemChecker(dbPath), checkers.NewDatabaseChecker(dbReader))
 }
 
+// NewCheckerForRemoteDatabase creates a checker for a database on another
+// server. It reports filesystem usage for the local directory dir.
+func NewCheckerForRemoteDatabase(dir string, dbReader checkers.DatabaseMetadataReader) Checker {
+	return NewCheckerFromCheckers(checkers.NewFileSystemCheckerForDir(dir), checkers.NewDatabaseChecker(dbReader))
+}
+
 func NewCheckerFromCheckers(fsChecker FileSystemChecker, dbChecker DatabaseChecker) Checker {
 	return Checker{
 		fsChecker,
@@ -45,19 +63,20 @@ func NewCheckerFromCheckers(fsChecker FileSystemChecker, dbChecker DatabaseCheck
 	}
 }
 
-func (c Checker) Check() (Usage, error) {
+func (c Checker) Check(ctx context.Context) (Usage, error) {
 	fsUsage, err := c.fsChecker.MeasureUsage()
 	if err != nil {
 		return Usage{}, err
 	}
 
-	dbTotalSize, err := c.dbChecker.TotalSize()
+	dbUsage, err := c.dbChecker.MeasureUsage(ctx)
 	if err != nil {
 		return Usage{}, err
 	}
 
 	return Usage{
-		TotalServingBytes:    dbTotalSize,
+		TotalServingBytes:    dbUsage.TotalBytes,
+		TotalStoredBytes:     dbUsage.StoredBytes,
 		DatabaseFileSize:     fsUsage.PicoShareDbFileSize,
 		FileSystemUsedBytes:  fsUsage.UsedBytes,
 		FileSystemTotalBytes: fsUsage.TotalBytes,
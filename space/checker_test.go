@@ -1,9 +1,11 @@
 package space_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
+	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/space"
 	"github.com/mtlynch/picoshare/space/checkers"
 )
@@ -18,12 +20,16 @@ func (c mockFileSystemChecker) MeasureUsage() (checkers.PicoShareUsage, error) {
 }
 
 type mockDatabaseChecker struct {
-	totalSize uint64
-	err       error
+	totalSize  uint64
+	storedSize uint64
+	err        error
 }
 
-func (c mockDatabaseChecker) TotalSize() (uint64, error) {
-	return c.totalSize, c.err
+func (c mockDatabaseChecker) MeasureUsage(context.Context) (picoshare.DataSizes, error) {
+	return picoshare.DataSizes{
+		TotalBytes:  c.totalSize,
+		StoredBytes: c.storedSize,
+	}, c.err
 }
 
 func TestCheck(t *testing.T) {
@@ -34,6 +40,7 @@ func TestCheck(t *testing.T) {
 		fsUsage       checkers.PicoShareUsage
 		fsErr         error
 		dbUsage       uint64
+		dbStored      uint64
 		dbErr         error
 		usageExpected space.Usage
 		errExpected   error
@@ -47,11 +54,13 @@ func TestCheck(t *testing.T) {
 				},
 				PicoShareDbFileSize: 65,
 			},
-			fsErr:   nil,
-			dbUsage: 60,
-			dbErr:   nil,
+			fsErr:    nil,
+			dbUsage:  60,
+			dbStored: 45,
+			dbErr:    nil,
 			usageExpected: space.Usage{
 				TotalServingBytes:    60,
+				TotalStoredBytes:     45,
 				DatabaseFileSize:     65,
 				FileSystemUsedBytes:  70,
 				FileSystemTotalBytes: 100,
@@ -89,11 +98,12 @@ func TestCheck(t *testing.T) {
 				err:   tt.fsErr,
 			}
 			dbc := mockDatabaseChecker{
-				totalSize: tt.dbUsage,
-				err:       tt.dbErr,
+				totalSize:  tt.dbUsage,
+				storedSize: tt.dbStored,
+				err:        tt.dbErr,
 			}
 
-			usage, err := space.NewCheckerFromCheckers(fsc, dbc).Check()
+			usage, err := space.NewCheckerFromCheckers(fsc, dbc).Check(context.Background())
 			if got, want := err, tt.errExpected; got != want {
 				t.Fatalf("err=%v, want=%v", got, want)
 			}
@@ -1,6 +1,7 @@
 package checkers
 
 import (
+	"context"
 	"errors"
 	"math"
 	"math/big"
@@ -10,7 +11,7 @@ import (
 
 type (
 	DatabaseMetadataReader interface {
-		GetEntriesMetadata() ([]picoshare.UploadMetadata, error)
+		GetDataSizes(context.Context) (picoshare.DataSizes, error)
 	}
 
 	DatabaseChecker struct {
@@ -24,22 +25,11 @@ func NewDatabaseChecker(dbReader DatabaseMetadataReader) DatabaseChecker {
 	return DatabaseChecker{dbReader}
 }
 
-func (dbc DatabaseChecker) TotalSize() (uint64, error) {
-	dbTotal := big.NewInt(0)
-	entries, err := dbc.reader.GetEntriesMetadata()
-	if err != nil {
-		return 0, err
-	}
-
-	for _, entry := range entries {
-		bigSize, err := uint64ToBigInt(entry.Size.UInt64())
-		if err != nil {
-			return 0, err
-		}
-		dbTotal = dbTotal.Add(dbTotal, bigSize)
-	}
-
-	return bigIntToUint64(dbTotal)
+// MeasureUsage returns the total size of PicoShare's uploads and the number of
+// bytes that the database physically uses to store them. The database sums the
+// sizes, so MeasureUsage doesn't need to load the metadata of every entry.
+func (dbc DatabaseChecker) MeasureUsage(ctx context.Context) (picoshare.DataSizes, error) {
+	return dbc.reader.GetDataSizes(ctx)
 }
 
 func uint64ToBigInt(val uint64) (*big.Int, error) {
@@ -1,8 +1,8 @@
 package checkers_test
 
 import (
+	"context"
 	"errors"
-	"math"
 	"testing"
 
 	"github.com/mtlynch/picoshare/picoshare"
@@ -10,85 +10,58 @@ import (
 )
 
 type mockDatabaseReader struct {
-	metadataEntries []picoshare.UploadMetadata
-	err             error
+	sizes picoshare.DataSizes
+	err   error
 }
 
-func (r mockDatabaseReader) GetEntriesMetadata() ([]picoshare.UploadMetadata, error) {
-	return r.metadataEntries, r.err
+func (r mockDatabaseReader) GetDataSizes(context.Context) (picoshare.DataSizes, error) {
+	return r.sizes, r.err
 }
 
-func TestTotalSize(t *testing.T) {
+func TestDatabaseMeasureUsage(t *testing.T) {
 	dummyDatabaseReaderErr := errors.New("dummy database reader error")
 	for _, tt := range []struct {
 		description   string
-		dbEntries     []picoshare.UploadMetadata
+		dbSizes       picoshare.DataSizes
 		dbErr         error
-		totalExpected uint64
+		usageExpected picoshare.DataSizes
 		errExpected   error
 	}{
 		{
-			description: "returns the sum of the database entries",
-			dbEntries: []picoshare.UploadMetadata{
-				{
-					Size: mustParseFileSize(5),
-				},
-				{
-					Size: mustParseFileSize(3),
-				},
-				{
-					Size: mustParseFileSize(1),
-				},
+			description: "returns the sizes that the database reports",
+			dbSizes: picoshare.DataSizes{
+				TotalBytes:  9,
+				StoredBytes: 4,
 			},
-			dbErr:         nil,
-			totalExpected: 9,
-			errExpected:   nil,
-		},
-		{
-			description: "returns an error if the database sizes overflow int64",
-			dbEntries: []picoshare.UploadMetadata{
-				{
-					Size: mustParseFileSize(math.MaxUint64),
-				},
-				{
-					Size: mustParseFileSize(1),
-				},
+			dbErr: nil,
+			usageExpected: picoshare.DataSizes{
+				TotalBytes:  9,
+				StoredBytes: 4,
 			},
-			dbErr:         nil,
-			totalExpected: 0,
-			errExpected:   checkers.ErrSizeOverflow,
+			errExpected: nil,
 		},
 		{
-			description:   "returns error when database checker fails",
-			dbEntries:     []picoshare.UploadMetadata{},
+			description:   "returns error when database reader fails",
+			dbSizes:       picoshare.DataSizes{},
 			dbErr:         dummyDatabaseReaderErr,
-			totalExpected: 0,
+			usageExpected: picoshare.DataSizes{},
 			errExpected:   dummyDatabaseReaderErr,
 		},
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			r := mockDatabaseReader{
-				metadataEntries: tt.dbEntries,
-				err:             tt.dbErr,
+				sizes: tt.dbSizes,
+				err:   tt.dbErr,
 			}
 
-			total, err := checkers.NewDatabaseChecker(r).TotalSize()
+			usage, err := checkers.NewDatabaseChecker(r).MeasureUsage(context.Background())
 			if got, want := err, tt.errExpected; got != want {
 				t.Fatalf("err=%v, want=%v", got, want)
 			}
 
-			if got, want := total, tt.totalExpected; got != want {
-				t.Errorf("total=%+v, want=%+v", got, want)
+			if got, want := usage, tt.usageExpected; got != want {
+				t.Errorf("usage=%+v, want=%+v", got, want)
 			}
 		})
 	}
 }
-
-func mustParseFileSize(val uint64) picoshare.FileSize {
-	fileSize, err := picoshare.FileSizeFromUint64(val)
-	if err != nil {
-		panic(err)
-	}
-
-	return fileSize
-}
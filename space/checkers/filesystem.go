@@ -30,6 +30,7 @@ type (
 	linuxFileSystemReader struct{}
 
 	FileSystemChecker struct {
+		dir      string
 		dbPath   string
 		fsReader FileSystemReader
 	}
@@ -76,11 +77,22 @@ func NewFileSystemChecker(dbPath string) FileSystemChecker {
 
 func NewFileSystemCheckerWithReader(dbPath string, fsReader FileSystemReader) FileSystemChecker {
 	return FileSystemChecker{
+		dir:      dbPath,
 		dbPath:   dbPath,
 		fsReader: fsReader,
 	}
 }
 
+// NewFileSystemCheckerForDir creates a checker that measures the filesystem
+// containing dir. It's for databases that don't store their files locally, so
+// the checker reports that the database files use zero bytes.
+func NewFileSystemCheckerForDir(dir string) FileSystemChecker {
+	return FileSystemChecker{
+		dir:      dir,
+		fsReader: linuxFileSystemReader{},
+	}
+}
+
 func (fsc FileSystemChecker) MeasureUsage() (PicoShareUsage, error) {
 	fsu, err := fsc.measureWholeFilesystem()
 	if err != nil {
@@ -102,7 +114,7 @@ func (fsc FileSystemChecker) MeasureUsage() (PicoShareUsage, error) {
 }
 
 func (fsc FileSystemChecker) measureWholeFilesystem() (FileSystemUsage, error) {
-	fsStats, err := fsc.fsReader.GetFileSystemStats(fsc.dbPath)
+	fsStats, err := fsc.fsReader.GetFileSystemStats(fsc.dir)
 	if err != nil {
 		return FileSystemUsage{}, err
 	}
@@ -138,6 +150,10 @@ func (fsc FileSystemChecker) measureWholeFilesystem() (FileSystemUsage, error) {
 }
 
 func (fsc FileSystemChecker) measureDbFileUsage() (uint64, error) {
+	if fsc.dbPath == "" {
+		return 0, nil
+	}
+
 	// SQLite includes the .db file as well .db-shm and .db-wal.
 	dbFilePattern := fsc.dbPath + "*"
 	matches, err := fsc.fsReader.Glob(dbFilePattern)
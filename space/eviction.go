@@ -0,0 +1,53 @@
+package space
+
+import "fmt"
+
+// EvictionPolicy determines which entries PicoShare deletes when its stored
+// data exceeds the storage limit.
+type EvictionPolicy string
+
+const (
+	// EvictNothing means that PicoShare never deletes entries to free up space
+	// and instead refuses uploads that would exceed the storage limit.
+	EvictNothing = EvictionPolicy("")
+	// EvictOldestFirst deletes the entries that clients uploaded longest ago.
+	EvictOldestFirst = EvictionPolicy("oldest")
+	// EvictLeastRecentlyDownloadedFirst deletes the entries that clients
+	// downloaded longest ago. It treats entries that no client has downloaded as
+	// if a client downloaded them when they were uploaded.
+	EvictLeastRecentlyDownloadedFirst = EvictionPolicy("least-recently-downloaded")
+	// EvictLargestFirst deletes the largest entries.
+	EvictLargestFirst = EvictionPolicy("largest")
+)
+
+// ParseEvictionPolicy parses the name of an eviction policy.
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	for _, p := range []EvictionPolicy{
+		EvictOldestFirst,
+		EvictLeastRecentlyDownloadedFirst,
+		EvictLargestFirst,
+	} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return EvictNothing, fmt.Errorf("unrecognized eviction policy %q: must be %q, %q, or %q", s, EvictOldestFirst, EvictLeastRecentlyDownloadedFirst, EvictLargestFirst)
+}
+
+func (p EvictionPolicy) String() string {
+	return string(p)
+}
+
+// FriendlyName returns a human-readable description of the policy.
+func (p EvictionPolicy) FriendlyName() string {
+	switch p {
+	case EvictOldestFirst:
+		return "Oldest files first"
+	case EvictLeastRecentlyDownloadedFirst:
+		return "Least recently downloaded files first"
+	case EvictLargestFirst:
+		return "Largest files first"
+	default:
+		return "Never"
+	}
+}
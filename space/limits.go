@@ -0,0 +1,106 @@
+package space
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Limits represents the limits on how much disk space PicoShare's uploads may
+// consume.
+type Limits struct {
+	// MaxStoredBytes is the maximum number of bytes of file data that PicoShare
+	// stores. Zero means that PicoShare stores any amount of data.
+	MaxStoredBytes uint64
+	// MinFreeBytes is the number of bytes that PicoShare keeps free on the
+	// filesystem where its data lives. Zero means that PicoShare accepts uploads
+	// until the filesystem is full.
+	MinFreeBytes uint64
+	// EvictionPolicy determines how PicoShare enforces MaxStoredBytes. With
+	// EvictNothing, PicoShare refuses uploads that would exceed MaxStoredBytes.
+	// Otherwise, PicoShare accepts them, and once an upload finishes, the
+	// garbage collector deletes other entries according to the policy until
+	// usage is back under MaxStoredBytes. If that's not possible, PicoShare
+	// refuses the upload.
+	EvictionPolicy EvictionPolicy
+}
+
+// IsUnlimited returns true if the limits place no restriction on uploads.
+func (l Limits) IsUnlimited() bool {
+	return l.MaxStoredBytes == 0 && l.MinFreeBytes == 0
+}
+
+// AvailableBytes returns the number of bytes that new uploads can add before
+// PicoShare's usage reaches the limits. If the limits place no restriction on
+// uploads, AvailableBytes returns math.MaxUint64.
+func (l Limits) AvailableBytes(u Usage) uint64 {
+	available := uint64(math.MaxUint64)
+	if l.MaxStoredBytes > 0 {
+		if l.EvictionPolicy == EvictNothing {
+			available = min(available, subtractOrZero(l.MaxStoredBytes, u.TotalStoredBytes))
+		} else {
+			// Eviction can make room for any upload that's no larger than the
+			// storage limit itself.
+			available = min(available, l.MaxStoredBytes)
+		}
+	}
+	if l.MinFreeBytes > 0 {
+		freeBytes := subtractOrZero(u.FileSystemTotalBytes, u.FileSystemUsedBytes)
+		available = min(available, subtractOrZero(freeBytes, l.MinFreeBytes))
+	}
+	return available
+}
+
+func subtractOrZero(a, b uint64) uint64 {
+	if b >= a {
+		return 0
+	}
+	return a - b
+}
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	// Check longer suffixes first so that "MiB" doesn't match as "B".
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// ParseSize parses a human-readable amount of bytes, such as "500MiB" or
+// "20GB". A number without a unit is a number of bytes.
+func ParseSize(s string) (uint64, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return 0, errors.New("size must not be empty")
+	}
+
+	multiplier := uint64(1)
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(strings.ToUpper(raw), strings.ToUpper(unit.suffix)); ok {
+			raw = strings.TrimSpace(raw[:len(trimmed)])
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: must be a whole number with an optional unit (e.g., 500MiB, 20GB)", s)
+	}
+
+	if n > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+
+	return n * multiplier, nil
+}
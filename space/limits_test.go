@@ -0,0 +1,231 @@
+package space_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mtlynch/picoshare/space"
+)
+
+func TestAvailableBytes(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		limits      space.Limits
+		usage       space.Usage
+		want        uint64
+	}{
+		{
+			description: "no limits",
+			limits:      space.Limits{},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: math.MaxUint64,
+		},
+		{
+			description: "storage cap leaves room",
+			limits:      space.Limits{MaxStoredBytes: 80},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 30,
+		},
+		{
+			description: "storage cap is already exceeded",
+			limits:      space.Limits{MaxStoredBytes: 40},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 0,
+		},
+		{
+			description: "free space threshold leaves room",
+			limits:      space.Limits{MinFreeBytes: 10},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 20,
+		},
+		{
+			description: "filesystem is already below free space threshold",
+			limits:      space.Limits{MinFreeBytes: 40},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 0,
+		},
+		{
+			description: "free space threshold is stricter than storage cap",
+			limits:      space.Limits{MaxStoredBytes: 80, MinFreeBytes: 25},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 5,
+		},
+		{
+			description: "storage cap is stricter than free space threshold",
+			limits:      space.Limits{MaxStoredBytes: 55, MinFreeBytes: 10},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 5,
+		},
+		{
+			description: "eviction makes room for uploads beyond storage cap",
+			limits:      space.Limits{MaxStoredBytes: 40, EvictionPolicy: space.EvictOldestFirst},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 40,
+		},
+		{
+			description: "eviction can't make room for uploads larger than storage cap",
+			limits:      space.Limits{MaxStoredBytes: 20, EvictionPolicy: space.EvictLargestFirst},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 20,
+		},
+		{
+			description: "eviction doesn't relax free space threshold",
+			limits:      space.Limits{MaxStoredBytes: 40, MinFreeBytes: 25, EvictionPolicy: space.EvictOldestFirst},
+			usage: space.Usage{
+				TotalStoredBytes:     50,
+				FileSystemUsedBytes:  70,
+				FileSystemTotalBytes: 100,
+			},
+			want: 5,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if got, want := tt.limits.AvailableBytes(tt.usage), tt.want; got != want {
+				t.Errorf("available=%d, want=%d", got, want)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		want        uint64
+		wantErr     bool
+	}{
+		{
+			description: "plain number of bytes",
+			input:       "1048576",
+			want:        1048576,
+		},
+		{
+			description: "bytes suffix",
+			input:       "512B",
+			want:        512,
+		},
+		{
+			description: "binary units",
+			input:       "500MiB",
+			want:        500 * 1024 * 1024,
+		},
+		{
+			description: "decimal units",
+			input:       "20GB",
+			want:        20 * 1000 * 1000 * 1000,
+		},
+		{
+			description: "lowercase units with a space",
+			input:       "2 gib",
+			want:        2 * 1024 * 1024 * 1024,
+		},
+		{
+			description: "terabytes",
+			input:       "1TiB",
+			want:        1 << 40,
+		},
+		{
+			description: "empty string",
+			input:       "",
+			wantErr:     true,
+		},
+		{
+			description: "unit without a number",
+			input:       "GB",
+			wantErr:     true,
+		},
+		{
+			description: "negative number",
+			input:       "-5GB",
+			wantErr:     true,
+		},
+		{
+			description: "fractional number",
+			input:       "1.5GB",
+			wantErr:     true,
+		},
+		{
+			description: "unknown unit",
+			input:       "5PB",
+			wantErr:     true,
+		},
+		{
+			description: "overflows uint64",
+			input:       "99999999999TiB",
+			wantErr:     true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := space.ParseSize(tt.input)
+			if gotErr, wantErr := err != nil, tt.wantErr; gotErr != wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, wantErr)
+			}
+
+			if got, want := got, tt.want; got != want {
+				t.Errorf("size=%d, want=%d", got, want)
+			}
+		})
+	}
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		input   string
+		want    space.EvictionPolicy
+		wantErr bool
+	}{
+		{"oldest", space.EvictOldestFirst, false},
+		{"least-recently-downloaded", space.EvictLeastRecentlyDownloadedFirst, false},
+		{"largest", space.EvictLargestFirst, false},
+		{"", space.EvictNothing, true},
+		{"newest", space.EvictNothing, true},
+		{"LARGEST", space.EvictNothing, true},
+	} {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := space.ParseEvictionPolicy(tt.input)
+			if gotErr, wantErr := err != nil, tt.wantErr; gotErr != wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, wantErr)
+			}
+
+			if got, want := got, tt.want; got != want {
+				t.Errorf("policy=%v, want=%v", got, want)
+			}
+		})
+	}
+}
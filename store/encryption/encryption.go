@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/mtlynch/picoshare/random"
+)
+
+// KeySize is the number of bytes in both master keys and data keys.
+const KeySize = 32
+
+var (
+	// ErrInvalidMasterKey indicates that the master key is not a base64-encoded
+	// string of KeySize bytes.
+	ErrInvalidMasterKey = errors.New("encryption key must be 32 bytes, encoded in base64")
+
+	// ErrDecryptionFailed indicates that ciphertext was corrupted or that the
+	// caller used the wrong key to decrypt it.
+	ErrDecryptionFailed = errors.New("failed to decrypt data")
+)
+
+// MasterKey is the server-wide key that encrypts the data keys of individual
+// blobs. PicoShare never uses the master key to encrypt file data directly.
+type MasterKey struct {
+	aead cipher.AEAD
+}
+
+// DataKey is a key that encrypts a single blob of file data.
+type DataKey struct {
+	raw  []byte
+	aead cipher.AEAD
+}
+
+// WrappedKey is a data key encrypted with a master key.
+type WrappedKey []byte
+
+// ParseMasterKey creates a MasterKey from a base64-encoded string.
+func ParseMasterKey(s string) (MasterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != KeySize {
+		return MasterKey{}, ErrInvalidMasterKey
+	}
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return MasterKey{}, err
+	}
+
+	return MasterKey{aead: aead}, nil
+}
+
+// NewDataKey generates a random data key for a new blob and returns it along
+// with its wrapped form, which is safe to store alongside the blob.
+func (mk MasterKey) NewDataKey(blobID string) (DataKey, WrappedKey, error) {
+	raw := random.Bytes(KeySize)
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return DataKey{}, nil, err
+	}
+
+	dk := DataKey{raw: raw, aead: aead}
+	return dk, mk.Wrap(blobID, dk), nil
+}
+
+// Wrap encrypts a data key so that only the master key can recover it. The
+// wrapped key is bound to the blob ID, so it can't be transplanted onto a
+// different blob.
+func (mk MasterKey) Wrap(blobID string, dk DataKey) WrappedKey {
+	return WrappedKey(seal(mk.aead, dk.raw, []byte(blobID)))
+}
+
+// Unwrap recovers the data key for a blob from its wrapped form.
+func (mk MasterKey) Unwrap(blobID string, wk WrappedKey) (DataKey, error) {
+	raw, err := open(mk.aead, wk, []byte(blobID))
+	if err != nil {
+		return DataKey{}, err
+	}
+	if len(raw) != KeySize {
+		return DataKey{}, ErrDecryptionFailed
+	}
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	return DataKey{raw: raw, aead: aead}, nil
+}
+
+// SealChunk encrypts a single chunk of a blob. The chunk is bound to its blob
+// and position, so an attacker can't reorder chunks or swap them between blobs
+// without detection.
+func (dk DataKey) SealChunk(blobID string, chunkIndex int64, plaintext []byte) []byte {
+	return seal(dk.aead, plaintext, chunkAdditionalData(blobID, chunkIndex))
+}
+
+// OpenChunk decrypts a chunk that SealChunk encrypted.
+func (dk DataKey) OpenChunk(blobID string, chunkIndex int64, ciphertext []byte) ([]byte, error) {
+	return open(dk.aead, ciphertext, chunkAdditionalData(blobID, chunkIndex))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prefixes the result with a random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) []byte {
+	nonce := random.Bytes(aead.NonceSize())
+	return aead.Seal(nonce, nonce, plaintext, additionalData)
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+func chunkAdditionalData(blobID string, chunkIndex int64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(blobID), uint64(chunkIndex))
+}
@@ -0,0 +1,180 @@
+package encryption_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+const (
+	dummyMasterKey      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	dummyOtherMasterKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestParseMasterKey(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		input       string
+		err         error
+	}{
+		{
+			description: "accepts 32-byte base64 key",
+			input:       dummyMasterKey,
+			err:         nil,
+		},
+		{
+			description: "ignores trailing newline",
+			input:       dummyMasterKey + "\n",
+			err:         nil,
+		},
+		{
+			description: "rejects empty key",
+			input:       "",
+			err:         encryption.ErrInvalidMasterKey,
+		},
+		{
+			description: "rejects key that's too short",
+			input:       "MDEyMzQ1Njc4OWFiY2RlZg==",
+			err:         encryption.ErrInvalidMasterKey,
+		},
+		{
+			description: "rejects key that's not base64",
+			input:       "not a base64 key!",
+			err:         encryption.ErrInvalidMasterKey,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if _, err := encryption.ParseMasterKey(tt.input); err != tt.err {
+				t.Errorf("err=%v, want=%v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestWrapAndUnwrapDataKey(t *testing.T) {
+	mk := mustParseMasterKey(dummyMasterKey)
+	blobID := "AAAAAAAAAA"
+
+	dk, wrapped, err := mk.NewDataKey(blobID)
+	if err != nil {
+		t.Fatalf("failed to create data key: %v", err)
+	}
+
+	ciphertext := dk.SealChunk(blobID, 0, []byte("hello, world!"))
+
+	unwrapped, err := mk.Unwrap(blobID, wrapped)
+	if err != nil {
+		t.Fatalf("failed to unwrap data key: %v", err)
+	}
+
+	plaintext, err := unwrapped.OpenChunk(blobID, 0, ciphertext)
+	if err != nil {
+		t.Fatalf("failed to decrypt chunk: %v", err)
+	}
+	if got, want := string(plaintext), "hello, world!"; got != want {
+		t.Errorf("plaintext=%s, want=%s", got, want)
+	}
+
+	if _, err := mustParseMasterKey(dummyOtherMasterKey).Unwrap(blobID, wrapped); err != encryption.ErrDecryptionFailed {
+		t.Errorf("unwrap with wrong master key: err=%v, want=%v", err, encryption.ErrDecryptionFailed)
+	}
+
+	if _, err := mk.Unwrap("BBBBBBBBBB", wrapped); err != encryption.ErrDecryptionFailed {
+		t.Errorf("unwrap with wrong blob ID: err=%v, want=%v", err, encryption.ErrDecryptionFailed)
+	}
+}
+
+func TestRewrapDataKey(t *testing.T) {
+	oldKey := mustParseMasterKey(dummyMasterKey)
+	newKey := mustParseMasterKey(dummyOtherMasterKey)
+	blobID := "AAAAAAAAAA"
+
+	dk, wrapped, err := oldKey.NewDataKey(blobID)
+	if err != nil {
+		t.Fatalf("failed to create data key: %v", err)
+	}
+	ciphertext := dk.SealChunk(blobID, 3, []byte("hello, world!"))
+
+	unwrapped, err := oldKey.Unwrap(blobID, wrapped)
+	if err != nil {
+		t.Fatalf("failed to unwrap data key: %v", err)
+	}
+	rewrapped := newKey.Wrap(blobID, unwrapped)
+
+	if bytes.Equal(rewrapped, wrapped) {
+		t.Fatalf("rewrapped key is identical to original wrapped key")
+	}
+
+	dkNew, err := newKey.Unwrap(blobID, rewrapped)
+	if err != nil {
+		t.Fatalf("failed to unwrap rewrapped key: %v", err)
+	}
+
+	plaintext, err := dkNew.OpenChunk(blobID, 3, ciphertext)
+	if err != nil {
+		t.Fatalf("failed to decrypt chunk with rewrapped key: %v", err)
+	}
+	if got, want := string(plaintext), "hello, world!"; got != want {
+		t.Errorf("plaintext=%s, want=%s", got, want)
+	}
+}
+
+func TestOpenChunkRejectsTampering(t *testing.T) {
+	mk := mustParseMasterKey(dummyMasterKey)
+	blobID := "AAAAAAAAAA"
+	dk, _, err := mk.NewDataKey(blobID)
+	if err != nil {
+		t.Fatalf("failed to create data key: %v", err)
+	}
+	ciphertext := dk.SealChunk(blobID, 1, []byte("hello, world!"))
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 0xff
+
+	for _, tt := range []struct {
+		description string
+		blobID      string
+		chunkIndex  int64
+		ciphertext  []byte
+	}{
+		{
+			description: "modified ciphertext",
+			blobID:      blobID,
+			chunkIndex:  1,
+			ciphertext:  tampered,
+		},
+		{
+			description: "chunk moved to a different position",
+			blobID:      blobID,
+			chunkIndex:  2,
+			ciphertext:  ciphertext,
+		},
+		{
+			description: "chunk moved to a different blob",
+			blobID:      "BBBBBBBBBB",
+			chunkIndex:  1,
+			ciphertext:  ciphertext,
+		},
+		{
+			description: "truncated ciphertext",
+			blobID:      blobID,
+			chunkIndex:  1,
+			ciphertext:  ciphertext[:4],
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if _, err := dk.OpenChunk(tt.blobID, tt.chunkIndex, tt.ciphertext); err != encryption.ErrDecryptionFailed {
+				t.Errorf("err=%v, want=%v", err, encryption.ErrDecryptionFailed)
+			}
+		})
+	}
+}
+
+func mustParseMasterKey(s string) encryption.MasterKey {
+	mk, err := encryption.ParseMasterKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return mk
+}
@@ -0,0 +1,93 @@
+package file
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+var (
+	encoder = mustCreateEncoder()
+	decoder = mustCreateDecoder()
+
+	// alreadyCompressedTypes are media types that use their own compression, so
+	// compressing them again wastes CPU for little or no space savings.
+	alreadyCompressedTypes = map[string]bool{
+		"application/epub+zip":         true,
+		"application/gzip":             true,
+		"application/java-archive":     true,
+		"application/pdf":              true,
+		"application/vnd.rar":          true,
+		"application/x-7z-compressed":  true,
+		"application/x-bzip2":          true,
+		"application/x-gzip":           true,
+		"application/x-rar-compressed": true,
+		"application/x-xz":             true,
+		"application/zip":              true,
+		"application/zstd":             true,
+		"font/woff":                    true,
+		"font/woff2":                   true,
+	}
+
+	// uncompressedMediaTypes are exceptions to the image, audio, and video
+	// categories that store their data uncompressed.
+	uncompressedMediaTypes = map[string]bool{
+		"audio/wav":     true,
+		"audio/x-wav":   true,
+		"image/bmp":     true,
+		"image/svg+xml": true,
+		"image/tiff":    true,
+	}
+)
+
+// IsCompressible returns true if compressing a file of the given content type
+// is likely to save space.
+func IsCompressible(contentType picoshare.ContentType) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType.String())
+	if err != nil {
+		// If we can't parse the content type, we can't rule out that compression
+		// will help.
+		return true
+	}
+
+	if alreadyCompressedTypes[mediaType] {
+		return false
+	}
+	if uncompressedMediaTypes[mediaType] {
+		return true
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func compressChunk(chunk []byte) []byte {
+	return encoder.EncodeAll(chunk, make([]byte, 0, len(chunk)))
+}
+
+func decompressChunk(chunk []byte, uncompressedLength int) ([]byte, error) {
+	return decoder.DecodeAll(chunk, make([]byte, 0, uncompressedLength))
+}
+
+func mustCreateEncoder() *zstd.Encoder {
+	e, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func mustCreateDecoder() *zstd.Decoder {
+	d, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,35 @@
+package file_test
+
+import (
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/file"
+)
+
+func TestIsCompressible(t *testing.T) {
+	for _, tt := range []struct {
+		contentType picoshare.ContentType
+		expected    bool
+	}{
+		{"text/plain", true},
+		{"text/csv;charset=utf-8", true},
+		{"application/json", true},
+		{"application/octet-stream", true},
+		{"image/svg+xml", true},
+		{"audio/wav", true},
+		{"", true},
+		{"application/zip", false},
+		{"application/gzip", false},
+		{"image/png", false},
+		{"image/jpeg", false},
+		{"video/mp4", false},
+		{"audio/mpeg", false},
+	} {
+		t.Run(tt.contentType.String(), func(t *testing.T) {
+			if got, want := file.IsCompressible(tt.contentType), tt.expected; got != want {
+				t.Errorf("IsCompressible(%s)=%v, want=%v", tt.contentType, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/wrapped"
+)
+
+// defaultBatchSize is the number of chunks the reader fetches in a single
+// query. With the default chunk size, a batch is a bit over 1 MiB.
+const defaultBatchSize = 4
+
+type (
+	fileReader struct {
+		ctx        context.Context
+		db         wrapped.SqlQuerier
+		blobID     string
+		key        *encryption.DataKey
+		fileLength int64
+		chunkSize  int64
+		batchSize  int64
+		offset     int64
+
+		// batch holds the decoded chunks starting at chunk index batchStart.
+		batch      [][]byte
+		batchStart int64
+
+		// pending delivers the batch starting at chunk index pendingStart, which
+		// the reader fetches in the background while the caller consumes the
+		// current batch. pending is nil if there's no fetch in progress.
+		pending      <-chan batchResult
+		pendingStart int64
+	}
+
+	batchResult struct {
+		chunks [][]byte
+		err    error
+	}
+)
+
+// NewReader creates a reader for the file data of the given blob. If the
+// blob's data is encrypted, key must be the blob's data key. Otherwise, key
+// should be nil. Reads fail once ctx is canceled. The reader's queries use ?
+// placeholders for their parameters.
+func NewReader(ctx context.Context, db wrapped.SqlQuerier, blobID string, key *encryption.DataKey) (io.ReadSeeker, error) {
+	return NewReaderWithBatchSize(ctx, db, blobID, key, defaultBatchSize)
+}
+
+// NewReaderWithBatchSize creates a reader that fetches batchSize chunks per
+// query. Most callers should just use NewReader().
+func NewReaderWithBatchSize(ctx context.Context, db wrapped.SqlQuerier, blobID string, key *encryption.DataKey, batchSize int) (io.ReadSeeker, error) {
+	chunkSize, length, err := getChunkSizeAndFileLength(ctx, db, blobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(fileReader{
+		ctx:        ctx,
+		db:         db,
+		blobID:     blobID,
+		key:        key,
+		fileLength: length,
+		chunkSize:  chunkSize,
+		batchSize:  int64(batchSize),
+		offset:     0,
+	}), nil
+}
+
+func (fr *fileReader) Read(p []byte) (int, error) {
+	read := 0
+	for read < len(p) {
+		if fr.offset >= fr.fileLength {
+			return read, io.EOF
+		}
+
+		chunkIndex := fr.offset / fr.chunkSize
+		chunk, err := fr.loadChunk(chunkIndex)
+		if err != nil {
+			return read, err
+		}
+
+		// Move the start index to the position in the chunk we want to read.
+		readStart := fr.offset % fr.chunkSize
+		if readStart >= int64(len(chunk)) {
+			return read, fmt.Errorf("chunk %d is shorter than expected: %w", chunkIndex, io.ErrUnexpectedEOF)
+		}
+
+		n := copy(p[read:], chunk[readStart:])
+		read += n
+		fr.offset += int64(n)
+	}
+
+	return read, nil
+}
+
+func (fr *fileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		fr.offset = offset
+	case io.SeekCurrent:
+		fr.offset += offset
+	case io.SeekEnd:
+		fr.offset = int64(fr.fileLength) - offset
+	}
+	return fr.offset, nil
+}
+
+// loadChunk returns the decoded chunk at the given index. If the chunk isn't in
+// the current batch, loadChunk replaces the batch with the one that starts at
+// chunkIndex and starts fetching the batch after it in the background.
+func (fr *fileReader) loadChunk(chunkIndex int64) ([]byte, error) {
+	if chunkIndex >= fr.batchStart && chunkIndex < fr.batchStart+int64(len(fr.batch)) {
+		return fr.batch[chunkIndex-fr.batchStart], nil
+	}
+
+	var result batchResult
+	if fr.pending != nil && fr.pendingStart == chunkIndex {
+		result = <-fr.pending
+	} else {
+		// The caller sought away from the prefetched batch, so we abandon it. The
+		// fetch delivers to a buffered channel, so it finishes on its own.
+		result = fr.fetchBatch(chunkIndex)
+	}
+	fr.pending = nil
+
+	if result.err != nil {
+		log.Printf("reading chunks failed: %v", result.err)
+		return nil, result.err
+	}
+
+	fr.batch = result.chunks
+	fr.batchStart = chunkIndex
+
+	nextStart := chunkIndex + int64(len(fr.batch))
+	if nextStart*fr.chunkSize < fr.fileLength {
+		fr.pending = fr.prefetchBatch(nextStart)
+		fr.pendingStart = nextStart
+	}
+
+	return fr.batch[0], nil
+}
+
+func (fr *fileReader) prefetchBatch(start int64) <-chan batchResult {
+	c := make(chan batchResult, 1)
+	go func() {
+		c <- fr.fetchBatch(start)
+	}()
+	return c
+}
+
+// fetchBatch reads and decodes up to batchSize consecutive chunks, starting at
+// chunk index start.
+func (fr *fileReader) fetchBatch(start int64) batchResult {
+	rows, err := fr.db.QueryContext(fr.ctx, `
+	SELECT
+		chunk_index,
+		chunk,
+		uncompressed_length,
+		is_compressed
+	FROM
+		entries_data
+	WHERE
+		id = ? AND
+		chunk_index >= ? AND
+		chunk_index < ?
+	ORDER BY
+		chunk_index ASC`, fr.blobID, start, start+fr.batchSize)
+	if err != nil {
+		return batchResult{err: err}
+	}
+	defer rows.Close()
+
+	chunks := make([][]byte, 0, fr.batchSize)
+	for rows.Next() {
+		var chunkIndex int64
+		var chunk []byte
+		var uncompressedLength *int
+		var isCompressed bool
+		if err := rows.Scan(&chunkIndex, &chunk, &uncompressedLength, &isCompressed); err != nil {
+			return batchResult{err: err}
+		}
+
+		if expected := start + int64(len(chunks)); chunkIndex != expected {
+			return batchResult{err: fmt.Errorf("chunk %d is missing", expected)}
+		}
+
+		decoded, err := decodeChunk(fr.blobID, chunkIndex, chunk, uncompressedLength, isCompressed, fr.key)
+		if err != nil {
+			return batchResult{err: fmt.Errorf("failed to decode chunk %d: %w", chunkIndex, err)}
+		}
+		chunks = append(chunks, decoded)
+	}
+	if err := rows.Err(); err != nil {
+		return batchResult{err: err}
+	}
+
+	if len(chunks) == 0 {
+		return batchResult{err: fmt.Errorf("chunk %d is missing", start)}
+	}
+
+	return batchResult{chunks: chunks}
+}
+
+// decodeChunk recovers the original data of a chunk that the writer stored.
+func decodeChunk(blobID string, chunkIndex int64, chunk []byte, uncompressedLength *int, isCompressed bool, key *encryption.DataKey) ([]byte, error) {
+	if key != nil {
+		decrypted, err := key.OpenChunk(blobID, chunkIndex, chunk)
+		if err != nil {
+			return nil, err
+		}
+		chunk = decrypted
+	}
+
+	if isCompressed {
+		if uncompressedLength == nil {
+			return nil, errors.New("compressed chunk has no recorded length")
+		}
+		return decompressChunk(chunk, *uncompressedLength)
+	}
+
+	return chunk, nil
+}
+
+// getChunkSizeAndFileLength determines the chunk size that PicoShare used to
+// save the given blob in SQLite and the total length of the blob's data. Even
+// though the chunk size is theoretically a constant, it might change in
+// different versions of PicoShare. The chunk size is always in terms of
+// uncompressed bytes.
+func getChunkSizeAndFileLength(ctx context.Context, db wrapped.SqlQuerier, blobID string) (int64, int64, error) {
+	var chunkSize int64
+	var lastChunkIndex int64
+	var lastChunkSize int64
+	if err := db.QueryRowContext(ctx, `
+	SELECT
+		first_chunk.chunk_size,
+		last_chunk.chunk_index,
+		last_chunk.chunk_size
+	FROM
+		(
+			SELECT
+				COALESCE(uncompressed_length, LENGTH(chunk)) AS chunk_size
+			FROM
+				entries_data
+			WHERE
+				id = ?
+			ORDER BY
+				chunk_index ASC
+			LIMIT 1
+		) first_chunk,
+		(
+			SELECT
+				chunk_index,
+				COALESCE(uncompressed_length, LENGTH(chunk)) AS chunk_size
+			FROM
+				entries_data
+			WHERE
+				id = ?
+			ORDER BY
+				chunk_index DESC
+			LIMIT 1
+		) last_chunk`, blobID, blobID).Scan(&chunkSize, &lastChunkIndex, &lastChunkSize); err != nil {
+		return 0, 0, err
+	}
+
+	return chunkSize, (chunkSize * lastChunkIndex) + lastChunkSize, nil
+}
@@ -0,0 +1,207 @@
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mtlynch/picoshare/random"
+	"github.com/mtlynch/picoshare/store/file"
+)
+
+func TestReadFile(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	for _, tt := range []struct {
+		explanation string
+		batchSize   int
+		seekOffset  int64
+	}{
+		{
+			explanation: "reads one chunk per batch",
+			batchSize:   1,
+			seekOffset:  0,
+		},
+		{
+			explanation: "reads several chunks per batch",
+			batchSize:   3,
+			seekOffset:  0,
+		},
+		{
+			explanation: "reads the whole file in one batch",
+			batchSize:   100,
+			seekOffset:  0,
+		},
+		{
+			explanation: "seeks to the middle of a batch",
+			batchSize:   3,
+			seekOffset:  7,
+		},
+		{
+			explanation: "seeks to the start of a later batch",
+			batchSize:   3,
+			seekOffset:  15,
+		},
+		{
+			explanation: "seeks into the final chunk",
+			batchSize:   3,
+			seekOffset:  int64(len(data) - 1),
+		},
+	} {
+		t.Run(tt.explanation, func(t *testing.T) {
+			db := mustCreateChunkDB(t)
+			mustWriteFile(t, db, "dummy-id", data, 5)
+
+			r, err := file.NewReaderWithBatchSize(context.Background(), db, "dummy-id", nil, tt.batchSize)
+			if err != nil {
+				t.Fatalf("failed to create reader: %v", err)
+			}
+
+			// Read the first few bytes so that the reader has a prefetch in flight
+			// when we seek.
+			if _, err := r.Read(make([]byte, 2)); err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if _, err := r.Seek(tt.seekOffset, io.SeekStart); err != nil {
+				t.Fatalf("failed to seek: %v", err)
+			}
+
+			contents, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if got, want := contents, data[tt.seekOffset:]; !bytes.Equal(got, want) {
+				t.Errorf("contents=%s, want=%s", got, want)
+			}
+		})
+	}
+}
+
+func TestReadCompressedFile(t *testing.T) {
+	// The first chunk compresses well, but the second is too short for
+	// compression to shrink it, so the writer stores it as-is.
+	data := append(bytes.Repeat([]byte("A"), 600), []byte("hello, world!")...)
+
+	db := mustCreateChunkDB(t)
+	w := file.NewWriter(db, "dummy-id", 600, true, nil)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	r, err := file.NewReader(context.Background(), db, "dummy-id", nil)
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	if got, want := contents, data; !bytes.Equal(got, want) {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func TestReadFileWithMissingChunk(t *testing.T) {
+	db := mustCreateChunkDB(t)
+	mustWriteFile(t, db, "dummy-id", []byte("the quick brown fox jumps over the lazy dog"), 5)
+
+	if _, err := db.Exec(`DELETE FROM entries_data WHERE chunk_index = 4`); err != nil {
+		t.Fatalf("failed to delete chunk: %v", err)
+	}
+
+	r, err := file.NewReaderWithBatchSize(context.Background(), db, "dummy-id", nil, 3)
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Errorf("expected error reading file with a missing chunk")
+	}
+}
+
+func TestReadFileAfterContextCanceled(t *testing.T) {
+	db := mustCreateChunkDB(t)
+	mustWriteFile(t, db, "dummy-id", []byte("the quick brown fox jumps over the lazy dog"), 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := file.NewReaderWithBatchSize(ctx, db, "dummy-id", nil, 3)
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+
+	cancel()
+
+	if _, err := io.ReadAll(r); !errors.Is(err, context.Canceled) {
+		t.Errorf("err=%v, want=%v", err, context.Canceled)
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	const chunkSize = 32768 * 10
+	data := random.Bytes(64 << 20)
+
+	db := mustCreateChunkDB(b)
+	mustWriteFile(b, db, "dummy-id", data, chunkSize)
+
+	for _, batchSize := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("batch size %d", batchSize), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				r, err := file.NewReaderWithBatchSize(context.Background(), db, "dummy-id", nil, batchSize)
+				if err != nil {
+					b.Fatalf("failed to create reader: %v", err)
+				}
+				// Copy in small increments, like an HTTP response writer would.
+				if _, err := io.CopyBuffer(io.Discard, r, make([]byte, 32*1024)); err != nil {
+					b.Fatalf("failed to read file: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func mustCreateChunkDB(tb testing.TB) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(tb.TempDir(), "store.db"))
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`
+	PRAGMA journal_mode = WAL;
+	CREATE TABLE entries_data (
+		id TEXT NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		chunk BLOB NOT NULL,
+		uncompressed_length INTEGER,
+		is_compressed INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (id, chunk_index)
+	) STRICT;`); err != nil {
+		tb.Fatalf("failed to create entries_data table: %v", err)
+	}
+
+	return db
+}
+
+func mustWriteFile(tb testing.TB, db *sql.DB, blobID string, data []byte, chunkSize uint64) {
+	w := file.NewWriter(db, blobID, chunkSize, false, nil)
+	if _, err := w.Write(data); err != nil {
+		tb.Fatalf("failed to write file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatalf("failed to close writer: %v", err)
+	}
+}
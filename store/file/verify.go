@@ -0,0 +1,97 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/wrapped"
+)
+
+// CorruptionError indicates that the stored data of a blob is inconsistent, so
+// PicoShare can't reproduce the original file.
+type CorruptionError struct {
+	Reason string
+}
+
+func (e CorruptionError) Error() string {
+	return fmt.Sprintf("stored data is corrupted: %s", e.Reason)
+}
+
+// Verify reads every chunk of the given blob, checks that the chunks form a
+// complete file, and returns the SHA-256 checksum of the file's contents. If
+// the chunks are inconsistent, Verify returns a CorruptionError.
+func Verify(ctx context.Context, db wrapped.SqlQuerier, blobID string, key *encryption.DataKey) (picoshare.SHA256Checksum, error) {
+	rows, err := db.QueryContext(ctx, `
+	SELECT
+		chunk_index,
+		chunk,
+		uncompressed_length,
+		is_compressed
+	FROM
+		entries_data
+	WHERE
+		id = ?
+	ORDER BY
+		chunk_index ASC`, blobID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	expectedIndex := int64(0)
+	chunkSize := 0
+	lastChunkSize := 0
+	for rows.Next() {
+		var chunkIndex int64
+		var chunk []byte
+		var uncompressedLength *int
+		var isCompressed bool
+		if err := rows.Scan(&chunkIndex, &chunk, &uncompressedLength, &isCompressed); err != nil {
+			return "", err
+		}
+
+		if chunkIndex != expectedIndex {
+			return "", CorruptionError{Reason: fmt.Sprintf("chunk %d is missing", expectedIndex)}
+		}
+
+		// Only the final chunk can be shorter than the others.
+		if expectedIndex > 0 && lastChunkSize != chunkSize {
+			return "", CorruptionError{Reason: fmt.Sprintf("chunk %d has %d bytes, but chunk 0 has %d bytes", expectedIndex-1, lastChunkSize, chunkSize)}
+		}
+
+		decoded, err := decodeChunk(blobID, chunkIndex, chunk, uncompressedLength, isCompressed, key)
+		if err != nil {
+			return "", CorruptionError{Reason: fmt.Sprintf("failed to decode chunk %d: %v", chunkIndex, err)}
+		}
+
+		if uncompressedLength != nil && len(decoded) != *uncompressedLength {
+			return "", CorruptionError{Reason: fmt.Sprintf("chunk %d has %d bytes, but its recorded length is %d", chunkIndex, len(decoded), *uncompressedLength)}
+		}
+
+		if chunkIndex == 0 {
+			chunkSize = len(decoded)
+		} else if len(decoded) > chunkSize {
+			return "", CorruptionError{Reason: fmt.Sprintf("chunk %d has %d bytes, but chunk 0 has %d bytes", chunkIndex, len(decoded), chunkSize)}
+		}
+		lastChunkSize = len(decoded)
+
+		if _, err := h.Write(decoded); err != nil {
+			return "", err
+		}
+		expectedIndex++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if expectedIndex == 0 {
+		return "", CorruptionError{Reason: "file has no data"}
+	}
+
+	return picoshare.SHA256Checksum(hex.EncodeToString(h.Sum(nil))), nil
+}
@@ -0,0 +1,130 @@
+package file
+
+import (
+	"io"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/wrapped"
+)
+
+type (
+	// Writer writes file data to the database in chunks.
+	Writer interface {
+		io.WriteCloser
+
+		// StoredBytes returns the number of bytes that the writer has stored in
+		// the database after compression and encryption.
+		StoredBytes() uint64
+	}
+
+	writer struct {
+		ctx      wrapped.SqlDB
+		blobID   string
+		buf      []byte
+		written  int
+		stored   uint64
+		compress bool
+		key      *encryption.DataKey
+	}
+)
+
+// Create a new writer for the blob ID using the given SqlTx and splitting the
+// file into separate rows in the DB of at most chunkSize bytes. If compress is
+// true, the writer stores each chunk zstd-compressed unless compression fails
+// to make the chunk smaller. If key is non-nil, the writer encrypts each chunk
+// with key after compressing it. The writer's queries use ? placeholders for
+// their parameters.
+func NewWriter(ctx wrapped.SqlDB, blobID string, chunkSize uint64, compress bool, key *encryption.DataKey) Writer {
+	return new(writer{
+		ctx:      ctx,
+		blobID:   blobID,
+		buf:      make([]byte, chunkSize),
+		compress: compress,
+		key:      key,
+	})
+}
+
+// Write writes a buffer to the SQLite database.
+func (w *writer) Write(p []byte) (int, error) {
+	n := 0
+
+	min := func(a, b int) int {
+		if a < b {
+			return a
+		}
+		return b
+	}
+
+	for {
+		if n == len(p) {
+			break
+		}
+		dstStart := w.written % len(w.buf)
+		copySize := min(len(w.buf)-dstStart, len(p)-n)
+		dstEnd := dstStart + copySize
+		copy(w.buf[dstStart:dstEnd], p[n:n+copySize])
+		if dstEnd == len(w.buf) {
+			if err := w.flush(len(w.buf)); err != nil {
+				return n, err
+			}
+		}
+		w.written += copySize
+		n += copySize
+	}
+
+	return n, nil
+}
+
+func (w *writer) Close() error {
+	unflushed := w.written % len(w.buf)
+	if unflushed != 0 {
+		return w.flush(unflushed)
+	}
+	return nil
+}
+
+func (w *writer) StoredBytes() uint64 {
+	return w.stored
+}
+
+func (w *writer) flush(n int) error {
+	idx := w.written / len(w.buf)
+	chunk := w.buf[0:n]
+
+	// A nil uncompressed length indicates that the chunk is stored as-is.
+	var uncompressedLength *int
+	isCompressed := false
+	if w.compress {
+		if compressed := compressChunk(chunk); len(compressed) < len(chunk) {
+			chunk = compressed
+			uncompressedLength = &n
+			isCompressed = true
+		}
+	}
+
+	if w.key != nil {
+		chunk = w.key.SealChunk(w.blobID, int64(idx), chunk)
+		// An encrypted chunk is never stored as-is, so we always record its
+		// original length.
+		uncompressedLength = &n
+	}
+
+	_, err := w.ctx.Exec(`
+	INSERT INTO
+		entries_data
+	(
+		id,
+		chunk_index,
+		chunk,
+		uncompressed_length,
+		is_compressed
+	)
+	VALUES(?,?,?,?,?)`, w.blobID, idx, chunk, uncompressedLength, isCompressed)
+	if err != nil {
+		return err
+	}
+
+	w.stored += uint64(len(chunk))
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package file_test
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mtlynch/picoshare/store/file"
+)
+
+type (
+	mockChunkRow struct {
+		blobID             string
+		chunkIndex         int
+		chunk              []byte
+		uncompressedLength *int
+		isCompressed       bool
+	}
+
+	mockSqlDB struct {
+		rows []mockChunkRow
+		err  error
+	}
+)
+
+var errMockSqlFailure = errors.New("fake SQL error")
+
+func (db *mockSqlDB) Exec(query string, args ...any) (sql.Result, error) {
+	chunk := args[2].([]byte)
+	chunkCopy := make([]byte, len(chunk))
+	copy(chunkCopy, chunk)
+	db.rows = append(db.rows, mockChunkRow{
+		blobID:             args[0].(string),
+		chunkIndex:         args[1].(int),
+		chunk:              chunkCopy,
+		uncompressedLength: args[3].(*int),
+		isCompressed:       args[4].(bool),
+	})
+	return nil, db.err
+}
+
+func TestWriteFile(t *testing.T) {
+	for _, tt := range []struct {
+		explanation  string
+		blobID       string
+		data         []byte
+		chunkSize    uint64
+		sqlExecErr   error
+		errExpected  error
+		rowsExpected []mockChunkRow
+	}{
+		{
+			explanation: "data is smaller than chunk size",
+			blobID:      "dummy-id",
+			data:        []byte("hello, world!"),
+			chunkSize:   25,
+			rowsExpected: []mockChunkRow{
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 0,
+					chunk:      []byte("hello, world!"),
+				},
+			},
+		},
+		{
+			explanation: "data fits exactly in single chunk",
+			blobID:      "dummy-id",
+			data:        []byte("01234"),
+			chunkSize:   5,
+			rowsExpected: []mockChunkRow{
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 0,
+					chunk:      []byte("01234"),
+				},
+			},
+		},
+		{
+			explanation: "data occupies a partial chunk after the first",
+			blobID:      "dummy-id",
+			data:        []byte("0123456"),
+			chunkSize:   5,
+			rowsExpected: []mockChunkRow{
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 0,
+					chunk:      []byte("01234"),
+				},
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 1,
+					chunk:      []byte("56"),
+				},
+			},
+		},
+		{
+			explanation: "data spans exactly two chunks",
+			blobID:      "dummy-id",
+			data:        []byte("0123456789"),
+			chunkSize:   5,
+			rowsExpected: []mockChunkRow{
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 0,
+					chunk:      []byte("01234"),
+				},
+				{
+					blobID:     "dummy-id",
+					chunkIndex: 1,
+					chunk:      []byte("56789"),
+				},
+			},
+		},
+		{
+			explanation: "write fails when SQL transaction returns error",
+			blobID:      "dummy-id",
+			data:        []byte("0123456789"),
+			chunkSize:   5,
+			sqlExecErr:  errMockSqlFailure,
+			errExpected: errMockSqlFailure,
+		},
+	} {
+		t.Run(tt.explanation, func(t *testing.T) {
+			tx := mockSqlDB{
+				err: tt.sqlExecErr,
+			}
+
+			w := file.NewWriter(&tx, tt.blobID, tt.chunkSize, false, nil)
+			n, err := w.Write(tt.data)
+
+			if got, want := err, tt.errExpected; got != want {
+				t.Fatalf("err=%v, want=%v", err, tt.errExpected)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := n, len(tt.data); got != want {
+				t.Errorf("n=%d, want=%d", got, want)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Errorf("failed to close writer: %v", err)
+			}
+
+			if got, want := tx.rows, tt.rowsExpected; !reflect.DeepEqual(got, want) {
+				t.Errorf("rows=%v, want %v", tx.rows, tt.rowsExpected)
+			}
+		})
+	}
+}
+
+func TestWriteCompressedFile(t *testing.T) {
+	for _, tt := range []struct {
+		explanation        string
+		data               []byte
+		chunkSize          uint64
+		compressedExpected []bool
+	}{
+		{
+			explanation:        "compresses repetitive data",
+			data:               []byte(strings.Repeat("A", 1000)),
+			chunkSize:          600,
+			compressedExpected: []bool{true, true},
+		},
+		{
+			explanation:        "stores data as-is when compression doesn't shrink it",
+			data:               []byte("hello, world!"),
+			chunkSize:          25,
+			compressedExpected: []bool{false},
+		},
+	} {
+		t.Run(tt.explanation, func(t *testing.T) {
+			tx := mockSqlDB{}
+
+			w := file.NewWriter(&tx, "dummy-id", tt.chunkSize, true, nil)
+			if _, err := w.Write(tt.data); err != nil {
+				t.Fatalf("failed to write data: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("failed to close writer: %v", err)
+			}
+
+			if got, want := len(tx.rows), len(tt.compressedExpected); got != want {
+				t.Fatalf("rows=%d, want=%d", got, want)
+			}
+
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				t.Fatalf("failed to create zstd decoder: %v", err)
+			}
+			defer d.Close()
+
+			storedBytes := uint64(0)
+			for _, row := range tx.rows {
+				storedBytes += uint64(len(row.chunk))
+			}
+			if got, want := w.StoredBytes(), storedBytes; got != want {
+				t.Errorf("stored bytes=%d, want=%d", got, want)
+			}
+
+			restored := []byte{}
+			for i, row := range tx.rows {
+				if got, want := row.isCompressed, tt.compressedExpected[i]; got != want {
+					t.Fatalf("row %d compressed=%v, want=%v", i, got, want)
+				}
+				if got, want := row.uncompressedLength != nil, tt.compressedExpected[i]; got != want {
+					t.Fatalf("row %d has uncompressed length=%v, want=%v", i, got, want)
+				}
+				chunk := row.chunk
+				if row.isCompressed {
+					chunk, err = d.DecodeAll(row.chunk, nil)
+					if err != nil {
+						t.Fatalf("failed to decompress row %d: %v", i, err)
+					}
+					if got, want := len(chunk), *row.uncompressedLength; got != want {
+						t.Errorf("row %d uncompressed length=%d, want=%d", i, got, want)
+					}
+				}
+				restored = append(restored, chunk...)
+			}
+
+			if got, want := restored, tt.data; !bytes.Equal(got, want) {
+				t.Errorf("restored=%s, want=%s", got, want)
+			}
+		})
+	}
+}
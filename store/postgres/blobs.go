@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/random"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+const blobIDLength = 32
+
+var blobIDCharacters = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+
+func newBlobID() string {
+	return random.String(blobIDLength, blobIDCharacters)
+}
+
+// GetStoredDataSize returns the number of bytes that the database uses to store
+// file data. Entries with identical contents share their data, so they count
+// only once.
+func (s Store) GetStoredDataSize(ctx context.Context) (uint64, error) {
+	var size uint64
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		COALESCE(SUM(stored_size), 0)::BIGINT
+	FROM
+		blobs`).Scan(&size); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// GetDataSizes returns the total size of all entries and the number of bytes
+// that the database uses to store their data.
+func (s Store) GetDataSizes(ctx context.Context) (picoshare.DataSizes, error) {
+	var sizes picoshare.DataSizes
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		(
+			SELECT
+				COALESCE(SUM(blobs.size), 0)::BIGINT
+			FROM
+				entries
+			INNER JOIN
+				blobs ON entries.blob_id = blobs.id
+		),
+		(
+			SELECT
+				COALESCE(SUM(stored_size), 0)::BIGINT
+			FROM
+				blobs
+		)`).Scan(&sizes.TotalBytes, &sizes.StoredBytes); err != nil {
+		return picoshare.DataSizes{}, err
+	}
+
+	return sizes, nil
+}
+
+type blobRecord struct {
+	id          string
+	checksum    picoshare.SHA256Checksum
+	size        uint64
+	storedSize  uint64
+	isEncrypted bool
+}
+
+// finalizeBlob marks a pending blob as complete once its upload has finished
+// writing data to entries_data. If the store already has a complete blob with
+// the same contents and the same encryption status, finalizeBlob discards the
+// pending blob and returns the ID of the existing blob instead.
+//
+// finalizeBlob locks the existing blob until tx ends so that another instance
+// can't delete it before the new entry refers to it.
+func finalizeBlob(ctx context.Context, tx *sql.Tx, blob blobRecord) (string, error) {
+	var existingID string
+	err := tx.QueryRowContext(ctx, `
+	SELECT
+		id
+	FROM
+		blobs
+	WHERE
+		sha256 = $1 AND
+		NOT is_pending AND
+		(wrapped_data_key IS NOT NULL) = $2
+	LIMIT 1
+	FOR SHARE`, blob.checksum, blob.isEncrypted).Scan(&existingID)
+	if err == nil {
+		log.Printf("upload is identical to existing blob %s, discarding duplicate data", existingID)
+		if err := deletePendingBlob(ctx, tx, blob.id); err != nil {
+			return "", err
+		}
+		return existingID, nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+	UPDATE
+		blobs
+	SET
+		sha256 = $1,
+		size = $2,
+		stored_size = $3,
+		is_pending = FALSE
+	WHERE
+		id = $4 AND
+		is_pending`, blob.checksum, blob.size, blob.storedSize, blob.id)
+	if err != nil {
+		return "", err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if updated == 0 {
+		return "", errors.New("upload took so long that PicoShare deleted it as abandoned")
+	}
+
+	return blob.id, nil
+}
+
+// deleteBlobIfUnreferenced deletes a blob and its data if no entry references
+// it anymore.
+func deleteBlobIfUnreferenced(ctx context.Context, tx *sql.Tx, blobID string) error {
+	// Lock the blob before checking for references. If an upload in another
+	// transaction is attaching to the blob, this waits for the upload to commit,
+	// and the checks below see its entry.
+	if _, err := tx.ExecContext(ctx, `
+	SELECT
+		id
+	FROM
+		blobs
+	WHERE
+		id = $1
+	FOR UPDATE`, blobID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		entries_data
+	WHERE
+		id = $1 AND
+		NOT EXISTS (SELECT 1 FROM entries WHERE blob_id = $1)`, blobID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		blobs
+	WHERE
+		id = $1 AND
+		NOT EXISTS (SELECT 1 FROM entries WHERE blob_id = $1)`, blobID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readEntryBlob retrieves the ID of the blob that holds the given entry's file
+// data and the key that decrypts it. If the blob is not encrypted, the key is
+// nil.
+func (s Store) readEntryBlob(ctx context.Context, id picoshare.EntryID) (string, *encryption.DataKey, error) {
+	var blobID string
+	var wrappedKey []byte
+	err := s.db.QueryRowContext(ctx, `
+	SELECT
+		blobs.id,
+		blobs.wrapped_data_key
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		entries.id = $1`, id).Scan(&blobID, &wrappedKey)
+	if err == sql.ErrNoRows {
+		return "", nil, store.EntryNotFoundError{ID: id}
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	if wrappedKey == nil {
+		return blobID, nil, nil
+	}
+
+	if s.masterKey == nil {
+		return "", nil, fmt.Errorf("entry %s is encrypted, but no encryption key is configured", id)
+	}
+
+	key, err := s.masterKey.Unwrap(blobID, encryption.WrappedKey(wrappedKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to unwrap data key for entry %s: %w", id, err)
+	}
+
+	return blobID, &key, nil
+}
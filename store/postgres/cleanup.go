@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Purge deletes expired entries and clears orphaned rows from the database.
+func (s Store) Purge(ctx context.Context) error {
+	log.Printf("deleting expired entries and orphaned data from database")
+	if err := s.deleteExpiredEntries(ctx); err != nil {
+		return err
+	}
+
+	if err := s.deleteOrphanedRows(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s Store) deleteExpiredEntries(ctx context.Context) error {
+	log.Printf("deleting expired entries from database")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete expired entries: %v", err)
+		}
+	}()
+
+	currentTime := normalizeTime(time.Now())
+
+	if _, err = tx.ExecContext(ctx, `
+	DELETE FROM
+		downloads
+	WHERE
+		entry_id IN (
+			SELECT
+				id
+			FROM
+				entries
+			WHERE
+				entries.expiration_time < $1
+		)`, currentTime); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+	DELETE FROM
+		corrupt_entries
+	WHERE
+		entry_id IN (
+			SELECT
+				id
+			FROM
+				entries
+			WHERE
+				entries.expiration_time < $1
+		)`, currentTime); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+	DELETE FROM
+		entries
+	WHERE
+		entries.expiration_time < $1`, currentTime); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s Store) deleteOrphanedRows(ctx context.Context) error {
+	log.Printf("purging orphaned rows from database")
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete orphaned rows: %v", err)
+		}
+	}()
+
+	// Delete blobs that no entry references. This happens when we delete the
+	// last entry that shares a blob's data. Pending blobs belong to uploads in
+	// progress, so they don't have entries yet, unless the instance that was
+	// handling the upload stopped long ago.
+	rows, err := tx.QueryContext(ctx, `
+	SELECT
+		id
+	FROM
+		blobs
+	WHERE
+		(NOT is_pending OR creation_time < $1) AND
+		NOT EXISTS (
+			SELECT
+				1
+			FROM
+				entries
+			WHERE
+				entries.blob_id = blobs.id
+		)`, time.Now().Add(-abandonedUploadAge))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	blobIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		blobIDs = append(blobIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Another instance's upload might attach to one of the blobs after we listed
+	// it, so check each blob again while holding its lock.
+	for _, id := range blobIDs {
+		if err := deleteBlobIfUnreferenced(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("purge completed successfully (%d unreferenced blobs)", len(blobIDs))
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func (s Store) InsertEntryDownload(ctx context.Context, id picoshare.EntryID, r picoshare.DownloadRecord) error {
+	log.Printf("recording download of file ID %s from client %s", id.String(), r.ClientIP)
+	if _, err := s.db.ExecContext(ctx, `
+	INSERT INTO
+		downloads
+	(
+		entry_id,
+		download_timestamp,
+		client_ip,
+		user_agent
+	)
+	VALUES($1, $2, $3, $4)`,
+		id.String(),
+		normalizeTime(r.Time),
+		r.ClientIP,
+		r.UserAgent,
+	); err != nil {
+		log.Printf("insert into downloads table failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s Store) GetEntryDownloads(ctx context.Context, id picoshare.EntryID) ([]picoshare.DownloadRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		download_timestamp,
+		client_ip,
+		user_agent
+	FROM
+		downloads
+	WHERE
+		entry_id = $1
+	ORDER BY
+		download_timestamp DESC`, id)
+	if err != nil {
+		return []picoshare.DownloadRecord{}, err
+	}
+	defer rows.Close()
+
+	downloads := []picoshare.DownloadRecord{}
+	for rows.Next() {
+		var downloadTime time.Time
+		var clientIP string
+		var userAgent string
+
+		if err := rows.Scan(&downloadTime, &clientIP, &userAgent); err != nil {
+			return []picoshare.DownloadRecord{}, err
+		}
+
+		downloads = append(downloads, picoshare.DownloadRecord{
+			Time:      downloadTime.UTC(),
+			ClientIP:  clientIP,
+			UserAgent: userAgent,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.DownloadRecord{}, err
+	}
+
+	return downloads, nil
+}
+
+// MarkEntryDownloaded records that a client downloaded the entry. It returns
+// true only for the call that marks the entry for the first time, so concurrent
+// downloads agree on which of them came first.
+func (s Store) MarkEntryDownloaded(ctx context.Context, id picoshare.EntryID) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE
+		entries
+	SET
+		is_downloaded = TRUE
+	WHERE
+		id = $1 AND
+		NOT is_downloaded`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
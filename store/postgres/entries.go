@@ -0,0 +1,537 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/file"
+)
+
+func (s Store) GetEntriesMetadata(ctx context.Context) ([]picoshare.UploadMetadata, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		entries.id AS id,
+		entries.slug AS slug,
+		entries.filename AS filename,
+		entries.note AS note,
+		entries.sender_name AS sender_name,
+		entries.sender_email AS sender_email,
+		entries.content_type AS content_type,
+		entries.upload_time AS upload_time,
+		entries.expiration_time AS expiration_time,
+		blobs.size AS file_size
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		blobs.size IS NOT NULL`)
+	if err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+	defer rows.Close()
+
+	ee := []picoshare.UploadMetadata{}
+	for rows.Next() {
+		var id string
+		var slug sql.NullString
+		var filename string
+		var note *string
+		var senderName sql.NullString
+		var senderEmail sql.NullString
+		var contentType string
+		var uploadTime time.Time
+		var expirationTime time.Time
+		var fileSizeRaw uint64
+		if err = rows.Scan(&id, &slug, &filename, &note, &senderName, &senderEmail, &contentType, &uploadTime, &expirationTime, &fileSizeRaw); err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		ee = append(ee, picoshare.UploadMetadata{
+			ID:          picoshare.EntryID(id),
+			Slug:        picoshare.EntrySlug(slug.String),
+			Filename:    picoshare.Filename(filename),
+			Note:        picoshare.FileNote{Value: note},
+			Sender:      guestSenderFromColumns(senderName, senderEmail),
+			ContentType: picoshare.ContentType(contentType),
+			Uploaded:    uploadTime.UTC(),
+			Expires:     picoshare.ExpirationTime(expirationTime.UTC()),
+			Size:        fileSize,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+
+	return ee, nil
+}
+
+// GetGuestLinkEntriesMetadata returns the metadata of the entries that guests
+// uploaded through the given guest link, newest first.
+func (s Store) GetGuestLinkEntriesMetadata(ctx context.Context, id picoshare.GuestLinkID) ([]picoshare.UploadMetadata, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		entries.id AS id,
+		entries.filename AS filename,
+		entries.guest_session AS guest_session,
+		entries.content_type AS content_type,
+		entries.upload_time AS upload_time,
+		entries.expiration_time AS expiration_time,
+		blobs.size AS file_size
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		entries.guest_link_id = $1 AND
+		blobs.size IS NOT NULL
+	ORDER BY
+		entries.upload_time DESC,
+		entries.id DESC`, id)
+	if err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+	defer rows.Close()
+
+	ee := []picoshare.UploadMetadata{}
+	for rows.Next() {
+		var entryID string
+		var filename string
+		var guestSession sql.NullString
+		var contentType string
+		var uploadTime time.Time
+		var expirationTime time.Time
+		var fileSizeRaw uint64
+		if err = rows.Scan(&entryID, &filename, &guestSession, &contentType, &uploadTime, &expirationTime, &fileSizeRaw); err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		ee = append(ee, picoshare.UploadMetadata{
+			ID:           picoshare.EntryID(entryID),
+			Filename:     picoshare.Filename(filename),
+			GuestSession: picoshare.GuestSessionKey(guestSession.String),
+			ContentType:  picoshare.ContentType(contentType),
+			Uploaded:     uploadTime.UTC(),
+			Expires:      picoshare.ExpirationTime(expirationTime.UTC()),
+			Size:         fileSize,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+
+	return ee, nil
+}
+
+func (s Store) ReadEntryFile(ctx context.Context, id picoshare.EntryID) (io.ReadSeeker, error) {
+	quarantined, err := s.isEntryQuarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		return nil, store.EntryQuarantinedError{ID: id}
+	}
+
+	blobID, key, err := s.readEntryBlob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.NewReader(ctx, rebindingDB{s.db}, blobID, key)
+}
+
+func (s Store) GetEntryMetadata(ctx context.Context, id picoshare.EntryID) (picoshare.UploadMetadata, error) {
+	var slug sql.NullString
+	var filename string
+	var note *string
+	var senderName sql.NullString
+	var senderEmail sql.NullString
+	var contentType string
+	var uploadTime time.Time
+	var expirationTime time.Time
+	var fileSizeRaw uint64
+	var guestLinkID *picoshare.GuestLinkID
+	var guestSession sql.NullString
+	var checksum *string
+	err := s.db.QueryRowContext(ctx, `
+	SELECT
+		entries.slug AS slug,
+		entries.filename AS filename,
+		entries.note AS note,
+		entries.sender_name AS sender_name,
+		entries.sender_email AS sender_email,
+		entries.content_type AS content_type,
+		entries.upload_time AS upload_time,
+		entries.expiration_time AS expiration_time,
+		blobs.size AS file_size,
+		entries.guest_link_id AS guest_link_id,
+		entries.guest_session AS guest_session,
+		blobs.sha256 AS sha256
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		entries.id = $1 AND
+		blobs.size IS NOT NULL`, id).Scan(&slug, &filename, &note, &senderName, &senderEmail, &contentType, &uploadTime, &expirationTime, &fileSizeRaw, &guestLinkID, &guestSession, &checksum)
+	if err == sql.ErrNoRows {
+		return picoshare.UploadMetadata{}, store.EntryNotFoundError{ID: id}
+	} else if err != nil {
+		return picoshare.UploadMetadata{}, err
+	}
+
+	var guestLink picoshare.GuestLink
+	if guestLinkID != nil && !guestLinkID.Empty() {
+		guestLink, err = s.GetGuestLink(ctx, *guestLinkID)
+		if err != nil {
+			return picoshare.UploadMetadata{}, err
+		}
+	}
+
+	fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+	if err != nil {
+		return picoshare.UploadMetadata{}, err
+	}
+
+	var sha256Checksum picoshare.SHA256Checksum
+	if checksum != nil {
+		sha256Checksum = picoshare.SHA256Checksum(*checksum)
+	}
+
+	return picoshare.UploadMetadata{
+		ID:           id,
+		Slug:         picoshare.EntrySlug(slug.String),
+		Filename:     picoshare.Filename(filename),
+		GuestLink:    guestLink,
+		Note:         picoshare.FileNote{Value: note},
+		Sender:       guestSenderFromColumns(senderName, senderEmail),
+		GuestSession: picoshare.GuestSessionKey(guestSession.String),
+		ContentType:  picoshare.ContentType(contentType),
+		Uploaded:     uploadTime.UTC(),
+		Expires:      picoshare.ExpirationTime(expirationTime.UTC()),
+		Size:         fileSize,
+		SHA256:       sha256Checksum,
+	}, nil
+}
+
+func (s Store) InsertEntry(ctx context.Context, reader io.Reader, metadata picoshare.UploadMetadata) error {
+	log.Printf("saving new entry %s", metadata.ID)
+
+	// Like the SQLite store, we stage the file data under a pending blob and
+	// commit it in batches rather than holding one large transaction open for
+	// the whole upload.
+	blobID := newBlobID()
+	var key *encryption.DataKey
+	var wrappedKey encryption.WrappedKey
+	if s.masterKey != nil {
+		dk, wk, err := s.masterKey.NewDataKey(blobID)
+		if err != nil {
+			return err
+		}
+		key = &dk
+		wrappedKey = wk
+	}
+
+	if err := s.createPendingBlob(ctx, blobID, wrappedKey); err != nil {
+		return err
+	}
+
+	size, storedSize, checksum, err := s.stageBlobData(ctx, reader, blobID, file.IsCompressible(metadata.ContentType), key)
+	if err != nil {
+		s.discardPendingBlob(blobID)
+		return err
+	}
+
+	if size == 0 {
+		s.discardPendingBlob(blobID)
+		return picoshare.ErrEmptyFile
+	}
+
+	if !metadata.SHA256.Empty() && metadata.SHA256 != checksum {
+		s.discardPendingBlob(blobID)
+		return store.ChecksumMismatchError{
+			Expected: metadata.SHA256,
+			Actual:   checksum,
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.discardPendingBlob(blobID)
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback insert entry: %v", err)
+		}
+		if !committed {
+			s.discardPendingBlob(blobID)
+		}
+	}()
+
+	finalBlobID, err := finalizeBlob(ctx, tx, blobRecord{
+		id:          blobID,
+		checksum:    checksum,
+		size:        uint64(size),
+		storedSize:  storedSize,
+		isEncrypted: key != nil,
+	})
+	if err != nil {
+		log.Printf("finalizing blob failed, aborting transaction: %v", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO
+		entries
+	(
+		id,
+		guest_link_id,
+		filename,
+		note,
+		sender_name,
+		sender_email,
+		guest_session,
+		content_type,
+		upload_time,
+		expiration_time,
+		blob_id,
+		slug
+	)
+	VALUES($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, NULLIF($12, ''))`,
+		metadata.ID,
+		metadata.GuestLink.ID,
+		metadata.Filename,
+		metadata.Note.Value,
+		metadata.Sender.Name,
+		metadata.Sender.Email,
+		metadata.GuestSession,
+		metadata.ContentType,
+		normalizeTime(metadata.Uploaded),
+		normalizeTime(time.Time(metadata.Expires)),
+		finalBlobID,
+		metadata.Slug,
+	); err != nil {
+		log.Printf("insert into entries table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	// A guest link's first upload starts its upload window.
+	if !metadata.GuestLink.ID.Empty() {
+		if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			guest_links
+		SET
+			first_upload_time = $1
+		WHERE
+			id = $2 AND
+			first_upload_time IS NULL`,
+			normalizeTime(metadata.Uploaded),
+			metadata.GuestLink.ID); err != nil {
+			log.Printf("recording first upload for guest link %s failed, aborting transaction: %v", metadata.GuestLink.ID, err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
+}
+
+// stageBlobData writes the data from reader to entries_data under the given
+// pending blob, committing the chunks in batches. It returns the size of the
+// data, the number of bytes it occupies in the database, and its checksum.
+func (s Store) stageBlobData(ctx context.Context, reader io.Reader, blobID string, compress bool, key *encryption.DataKey) (int64, uint64, picoshare.SHA256Checksum, error) {
+	batch := newBatchedExecer(ctx, s.db, stagingBatchSize)
+	defer batch.Rollback()
+
+	w := file.NewWriter(batch, blobID, s.chunkSize, compress, key)
+	h := sha256.New()
+	size, err := io.Copy(w, io.TeeReader(reader, h))
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	// Close() flushes the buffer, and it can fail.
+	if err := w.Close(); err != nil {
+		return 0, 0, "", err
+	}
+
+	if err := batch.Commit(); err != nil {
+		return 0, 0, "", err
+	}
+
+	return size, w.StoredBytes(), picoshare.SHA256Checksum(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+func (s Store) UpdateEntryMetadata(ctx context.Context, id picoshare.EntryID, metadata picoshare.UploadMetadata) error {
+	log.Printf("updating metadata for entry %s", id)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback entry metadata update: %v", err)
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+	UPDATE entries
+	SET
+		filename = $1,
+		expiration_time = $2,
+		note = $3
+	WHERE
+		id = $4`,
+		metadata.Filename,
+		normalizeTime(time.Time(metadata.Expires)),
+		metadata.Note.Value,
+		id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return store.EntryNotFoundError{ID: id}
+	}
+
+	if !metadata.Slug.Empty() {
+		var otherID string
+		err := tx.QueryRowContext(ctx, `
+		SELECT
+			id
+		FROM
+			entries
+		WHERE
+			slug = $1 AND
+			id != $2`, metadata.Slug, id).Scan(&otherID)
+		if err == nil {
+			return store.EntrySlugConflictError{Slug: metadata.Slug}
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE entries
+	SET
+		slug = NULLIF($1, '')
+	WHERE
+		id = $2`, metadata.Slug, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetEntryIDBySlug returns the ID of the entry with the given slug.
+func (s Store) GetEntryIDBySlug(ctx context.Context, slug picoshare.EntrySlug) (picoshare.EntryID, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, `
+	SELECT
+		id
+	FROM
+		entries
+	WHERE
+		slug = $1`, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return picoshare.EntryID(""), store.EntrySlugNotFoundError{Slug: slug}
+	} else if err != nil {
+		return picoshare.EntryID(""), err
+	}
+
+	return picoshare.EntryID(id), nil
+}
+
+func (s Store) DeleteEntry(ctx context.Context, id picoshare.EntryID) error {
+	log.Printf("deleting entry %v", id)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete entry: %v", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		downloads
+	WHERE
+		entry_id = $1`, id); err != nil {
+		log.Printf("delete from downloads table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		corrupt_entries
+	WHERE
+		entry_id = $1`, id); err != nil {
+		log.Printf("delete from corrupt_entries table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	var blobID string
+	if err := tx.QueryRowContext(ctx, `
+	DELETE FROM
+		entries
+	WHERE
+		id = $1
+	RETURNING
+		blob_id`, id).Scan(&blobID); err == sql.ErrNoRows {
+		return tx.Commit()
+	} else if err != nil {
+		log.Printf("delete from entries table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	// Other entries with identical contents might still share the entry's data.
+	if err := deleteBlobIfUnreferenced(ctx, tx, blobID); err != nil {
+		log.Printf("delete from blobs table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// guestSenderFromColumns converts the sender columns of an entry, which are
+// NULL for entries without sender details, into a GuestSender.
+func guestSenderFromColumns(name, email sql.NullString) picoshare.GuestSender {
+	return picoshare.GuestSender{
+		Name:  picoshare.SenderName(name.String),
+		Email: picoshare.SenderEmail(email.String),
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// GetLatestDownloadTimes returns the time of the most recent download of each
+// entry that clients have downloaded at least once.
+func (s Store) GetLatestDownloadTimes(ctx context.Context) (map[picoshare.EntryID]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		entry_id,
+		MAX(download_timestamp) AS latest_download
+	FROM
+		downloads
+	GROUP BY
+		entry_id`)
+	if err != nil {
+		return map[picoshare.EntryID]time.Time{}, err
+	}
+	defer rows.Close()
+
+	latest := map[picoshare.EntryID]time.Time{}
+	for rows.Next() {
+		var id string
+		var downloadTime time.Time
+		if err := rows.Scan(&id, &downloadTime); err != nil {
+			return map[picoshare.EntryID]time.Time{}, err
+		}
+
+		latest[picoshare.EntryID(id)] = downloadTime.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return map[picoshare.EntryID]time.Time{}, err
+	}
+
+	return latest, nil
+}
+
+// GetEvictedEntries returns the entries that PicoShare has evicted, most
+// recent first.
+func (s Store) GetEvictedEntries(ctx context.Context) ([]picoshare.EvictedEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		entry_id,
+		filename,
+		file_size,
+		upload_time,
+		eviction_time,
+		policy
+	FROM
+		evictions
+	ORDER BY
+		eviction_time DESC`)
+	if err != nil {
+		return []picoshare.EvictedEntry{}, err
+	}
+	defer rows.Close()
+
+	evicted := []picoshare.EvictedEntry{}
+	for rows.Next() {
+		var id string
+		var filename string
+		var fileSizeRaw uint64
+		var uploadTime time.Time
+		var evictionTime time.Time
+		var policy string
+		if err := rows.Scan(&id, &filename, &fileSizeRaw, &uploadTime, &evictionTime, &policy); err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+		if err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		evicted = append(evicted, picoshare.EvictedEntry{
+			ID:       picoshare.EntryID(id),
+			Filename: picoshare.Filename(filename),
+			Size:     fileSize,
+			Uploaded: uploadTime.UTC(),
+			Evicted:  evictionTime.UTC(),
+			Policy:   policy,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.EvictedEntry{}, err
+	}
+
+	return evicted, nil
+}
+
+// InsertEvictedEntry records that PicoShare evicted an entry.
+func (s Store) InsertEvictedEntry(ctx context.Context, entry picoshare.EvictedEntry) error {
+	log.Printf("recording eviction of entry %v", entry.ID)
+
+	if _, err := s.db.ExecContext(ctx, `
+	INSERT INTO
+		evictions
+	(
+		entry_id,
+		filename,
+		file_size,
+		upload_time,
+		eviction_time,
+		policy
+	)
+	VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.ID,
+		entry.Filename,
+		entry.Size.UInt64(),
+		normalizeTime(entry.Uploaded),
+		normalizeTime(entry.Evicted),
+		entry.Policy); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+func (s Store) GetGuestLink(ctx context.Context, id picoshare.GuestLinkID) (picoshare.GuestLink, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT
+			guest_links.id AS id,
+			guest_links.label AS label,
+			guest_links.is_disabled AS is_disabled,
+			guest_links.max_file_bytes AS max_file_bytes,
+			guest_links.max_file_uploads AS max_file_uploads,
+			guest_links.max_total_bytes AS max_total_bytes,
+			guest_links.creation_time AS creation_time,
+			guest_links.url_expiration_time AS url_expiration_time,
+			guest_links.file_expiration_time AS file_expiration_time,
+			guest_links.last_modified_time AS last_modified_time,
+			guest_links.passphrase_hash AS passphrase_hash,
+			guest_links.allowed_file_types AS allowed_file_types,
+			guest_links.allow_sender_details AS allow_sender_details,
+			guest_links.upload_listing AS upload_listing,
+			guest_links.instructions AS instructions,
+			guest_links.upload_window AS upload_window,
+			guest_links.first_upload_time AS first_upload_time,
+			COUNT(entries.id) AS entry_count,
+			COALESCE(SUM(blobs.size), 0) AS bytes_uploaded
+		FROM
+			guest_links
+		LEFT JOIN
+			entries ON guest_links.id = entries.guest_link_id
+		LEFT JOIN
+			blobs ON entries.blob_id = blobs.id
+		WHERE
+			guest_links.id = $1
+		GROUP BY
+			guest_links.id`, id)
+
+	gl, err := guestLinkFromRow(row)
+	if err == sql.ErrNoRows {
+		return picoshare.GuestLink{}, store.GuestLinkNotFoundError{ID: id}
+	}
+
+	return gl, err
+}
+
+func (s Store) GetGuestLinks(ctx context.Context) ([]picoshare.GuestLink, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			guest_links.id AS id,
+			guest_links.label AS label,
+			guest_links.is_disabled AS is_disabled,
+			guest_links.max_file_bytes AS max_file_bytes,
+			guest_links.max_file_uploads AS max_file_uploads,
+			guest_links.max_total_bytes AS max_total_bytes,
+			guest_links.creation_time AS creation_time,
+			guest_links.url_expiration_time AS url_expiration_time,
+			guest_links.file_expiration_time AS file_expiration_time,
+			guest_links.last_modified_time AS last_modified_time,
+			guest_links.passphrase_hash AS passphrase_hash,
+			guest_links.allowed_file_types AS allowed_file_types,
+			guest_links.allow_sender_details AS allow_sender_details,
+			guest_links.upload_listing AS upload_listing,
+			guest_links.instructions AS instructions,
+			guest_links.upload_window AS upload_window,
+			guest_links.first_upload_time AS first_upload_time,
+			COUNT(entries.id) AS entry_count,
+			COALESCE(SUM(blobs.size), 0) AS bytes_uploaded
+		FROM
+			guest_links
+		LEFT JOIN
+			entries ON guest_links.id = entries.guest_link_id
+		LEFT JOIN
+			blobs ON entries.blob_id = blobs.id
+		GROUP BY
+			guest_links.id`)
+	if err != nil {
+		return []picoshare.GuestLink{}, err
+	}
+	defer rows.Close()
+
+	gls := []picoshare.GuestLink{}
+	for rows.Next() {
+		gl, err := guestLinkFromRow(rows)
+		if err != nil {
+			return []picoshare.GuestLink{}, err
+		}
+
+		gls = append(gls, gl)
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.GuestLink{}, err
+	}
+
+	return gls, nil
+}
+
+func (s Store) InsertGuestLink(ctx context.Context, guestLink picoshare.GuestLink) error {
+	log.Printf("saving new guest link %s", guestLink.ID)
+
+	if _, err := s.db.ExecContext(ctx, `
+	INSERT INTO guest_links
+		(
+			id,
+			label,
+			is_disabled,
+			max_file_bytes,
+			max_file_uploads,
+			max_total_bytes,
+			creation_time,
+			url_expiration_time,
+			file_expiration_time,
+			passphrase_hash,
+			allowed_file_types,
+			allow_sender_details,
+			upload_listing,
+			instructions,
+			upload_window
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), $15)
+	`,
+		guestLink.ID,
+		guestLink.Label,
+		guestLink.IsDisabled,
+		guestLink.MaxFileBytes,
+		guestLink.MaxFileUploads,
+		guestLink.MaxTotalBytes,
+		normalizeTime(guestLink.Created),
+		normalizeTime(time.Time(guestLink.UrlExpires)),
+		guestLink.MaxFileLifetime.String(),
+		nullablePassphraseHash(guestLink.PassphraseHash),
+		formatAllowedFileTypes(guestLink.AllowedFileTypes),
+		guestLink.AllowSenderDetails,
+		guestLink.UploadListing,
+		guestLink.Instructions,
+		formatUploadWindow(guestLink.UploadWindow)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s Store) UpdateGuestLink(ctx context.Context, id picoshare.GuestLinkID, guestLink picoshare.GuestLink) error {
+	log.Printf("updating guest link %s", id)
+
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE guest_links
+	SET
+		label = $1,
+		max_file_bytes = $2,
+		max_file_uploads = $3,
+		max_total_bytes = $4,
+		url_expiration_time = $5,
+		file_expiration_time = $6,
+		last_modified_time = $7,
+		passphrase_hash = $8,
+		allowed_file_types = $9,
+		allow_sender_details = $10,
+		upload_listing = NULLIF($11, ''),
+		instructions = NULLIF($12, ''),
+		upload_window = $13
+	WHERE
+		id = $14`,
+		guestLink.Label,
+		guestLink.MaxFileBytes,
+		guestLink.MaxFileUploads,
+		guestLink.MaxTotalBytes,
+		normalizeTime(time.Time(guestLink.UrlExpires)),
+		guestLink.MaxFileLifetime.String(),
+		normalizeTime(guestLink.LastModified),
+		nullablePassphraseHash(guestLink.PassphraseHash),
+		formatAllowedFileTypes(guestLink.AllowedFileTypes),
+		guestLink.AllowSenderDetails,
+		guestLink.UploadListing,
+		guestLink.Instructions,
+		formatUploadWindow(guestLink.UploadWindow),
+		id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return store.GuestLinkNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (s Store) DeleteGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
+	log.Printf("deleting guest link %s", id)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete guest link: %v", err)
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `
+	UPDATE
+		entries
+	SET
+		guest_link_id = NULL
+	WHERE
+		guest_link_id = $1`, id); err != nil {
+		log.Printf("removing references to guest link %s from entries table failed: %v", id, err)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+	DELETE FROM
+		guest_links
+	WHERE
+		id = $1`, id); err != nil {
+		log.Printf("deleting %s from guest_links table failed: %v", id, err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s Store) DisableGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
+	log.Printf("disabling guest link %s", id)
+
+	if _, err := s.db.ExecContext(ctx, `
+	UPDATE
+		guest_links
+	SET
+		is_disabled = TRUE
+	WHERE
+		id = $1`, id); err != nil {
+		log.Printf("disabling guest link %s failed: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s Store) EnableGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
+	log.Printf("enabling guest link %s", id)
+
+	if _, err := s.db.ExecContext(ctx, `
+	UPDATE
+		guest_links
+	SET
+		is_disabled = FALSE
+	WHERE
+		id = $1`, id); err != nil {
+		log.Printf("enabling guest link %s failed: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func guestLinkFromRow(row rowScanner) (picoshare.GuestLink, error) {
+	var id picoshare.GuestLinkID
+	var label picoshare.GuestLinkLabel
+	var isDisabled bool
+	var maxFileBytes picoshare.GuestUploadMaxFileBytes
+	var maxFileUploads picoshare.GuestUploadCountLimit
+	var maxTotalBytes picoshare.GuestUploadMaxTotalBytes
+	var creationTime time.Time
+	var urlExpirationTime time.Time
+	var fileLifetimeRaw *string
+	var lastModified *time.Time
+	var passphraseHash sql.NullString
+	var allowedFileTypes sql.NullString
+	var allowSenderDetails bool
+	var uploadListing sql.NullString
+	var instructions sql.NullString
+	var uploadWindowRaw sql.NullString
+	var firstUpload *time.Time
+	var filesUploaded int
+	var bytesUploaded uint64
+
+	if err := row.Scan(&id, &label, &isDisabled, &maxFileBytes, &maxFileUploads, &maxTotalBytes, &creationTime, &urlExpirationTime, &fileLifetimeRaw, &lastModified, &passphraseHash, &allowedFileTypes, &allowSenderDetails, &uploadListing, &instructions, &uploadWindowRaw, &firstUpload, &filesUploaded, &bytesUploaded); err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	fileLifetime := picoshare.FileLifetimeInfinite
+	if fileLifetimeRaw != nil {
+		var err error
+		fileLifetime, err = parseFileLifetime(*fileLifetimeRaw)
+		if err != nil {
+			return picoshare.GuestLink{}, err
+		}
+	}
+
+	var lastModifiedUTC time.Time
+	if lastModified != nil {
+		lastModifiedUTC = lastModified.UTC()
+	}
+
+	uploadWindow, err := parseUploadWindow(uploadWindowRaw)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	var firstUploadUTC time.Time
+	if firstUpload != nil {
+		firstUploadUTC = firstUpload.UTC()
+	}
+
+	return picoshare.GuestLink{
+		ID:                 id,
+		Label:              label,
+		IsDisabled:         isDisabled,
+		MaxFileBytes:       maxFileBytes,
+		MaxFileUploads:     maxFileUploads,
+		MaxTotalBytes:      maxTotalBytes,
+		FilesUploaded:      filesUploaded,
+		BytesUploaded:      bytesUploaded,
+		Created:            creationTime.UTC(),
+		UrlExpires:         picoshare.ExpirationTime(urlExpirationTime.UTC()),
+		MaxFileLifetime:    fileLifetime,
+		LastModified:       lastModifiedUTC,
+		PassphraseHash:     picoshare.GuestLinkPassphraseHash(passphraseHash.String),
+		AllowedFileTypes:   parseAllowedFileTypes(allowedFileTypes),
+		AllowSenderDetails: allowSenderDetails,
+		UploadListing:      picoshare.GuestUploadListing(uploadListing.String),
+		Instructions:       picoshare.GuestLinkInstructions(instructions.String),
+		UploadWindow:       uploadWindow,
+		FirstUpload:        firstUploadUTC,
+	}, nil
+}
+
+func formatUploadWindow(w picoshare.GuestLinkUploadWindow) sql.NullString {
+	return sql.NullString{String: w.String(), Valid: w != picoshare.NoUploadWindow}
+}
+
+func parseUploadWindow(raw sql.NullString) (picoshare.GuestLinkUploadWindow, error) {
+	if !raw.Valid {
+		return picoshare.NoUploadWindow, nil
+	}
+	d, err := time.ParseDuration(raw.String)
+	if err != nil {
+		return picoshare.NoUploadWindow, err
+	}
+	return picoshare.GuestLinkUploadWindow(d), nil
+}
+
+func nullablePassphraseHash(h picoshare.GuestLinkPassphraseHash) sql.NullString {
+	return sql.NullString{String: string(h), Valid: h != ""}
+}
+
+func formatAllowedFileTypes(ft picoshare.GuestUploadFileTypes) sql.NullString {
+	return sql.NullString{String: strings.Join(ft, ","), Valid: !ft.IsUnrestricted()}
+}
+
+func parseAllowedFileTypes(raw sql.NullString) picoshare.GuestUploadFileTypes {
+	if !raw.Valid {
+		return nil
+	}
+	return picoshare.GuestUploadFileTypes(strings.Split(raw.String, ","))
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/file"
+)
+
+// VerifyEntryData reads all of an entry's stored data to check that it's
+// complete and that it matches the entry's checksum. If the data is damaged,
+// VerifyEntryData returns a store.EntryCorruptedError.
+func (s Store) VerifyEntryData(ctx context.Context, id picoshare.EntryID) error {
+	blobID, key, err := s.readEntryBlob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var expected *string
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		sha256
+	FROM
+		blobs
+	WHERE
+		id = $1`, blobID).Scan(&expected); err != nil {
+		return err
+	}
+
+	actual, err := file.Verify(ctx, rebindingDB{s.db}, blobID, key)
+	if corruption, ok := errors.AsType[file.CorruptionError](err); ok {
+		return store.EntryCorruptedError{ID: id, Reason: corruption.Reason}
+	} else if err != nil {
+		return err
+	}
+
+	if expected != nil && picoshare.SHA256Checksum(*expected) != actual {
+		return store.EntryCorruptedError{ID: id, Reason: "contents don't match the checksum recorded at upload time"}
+	}
+
+	return nil
+}
+
+func (s Store) GetCorruptEntries(ctx context.Context) ([]picoshare.CorruptEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		corrupt_entries.entry_id AS entry_id,
+		entries.filename AS filename,
+		corrupt_entries.detection_time AS detection_time,
+		corrupt_entries.reason AS reason,
+		corrupt_entries.is_quarantined AS is_quarantined
+	FROM
+		corrupt_entries
+	INNER JOIN
+		entries ON corrupt_entries.entry_id = entries.id
+	ORDER BY
+		corrupt_entries.detection_time DESC`)
+	if err != nil {
+		return []picoshare.CorruptEntry{}, err
+	}
+	defer rows.Close()
+
+	ce := []picoshare.CorruptEntry{}
+	for rows.Next() {
+		var id string
+		var filename string
+		var detectionTime time.Time
+		var reason string
+		var quarantined bool
+		if err := rows.Scan(&id, &filename, &detectionTime, &reason, &quarantined); err != nil {
+			return []picoshare.CorruptEntry{}, err
+		}
+
+		ce = append(ce, picoshare.CorruptEntry{
+			ID:          picoshare.EntryID(id),
+			Filename:    picoshare.Filename(filename),
+			Detected:    detectionTime.UTC(),
+			Reason:      reason,
+			Quarantined: quarantined,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.CorruptEntry{}, err
+	}
+
+	return ce, nil
+}
+
+// InsertCorruptEntry records that an entry's data is corrupted, replacing any
+// previous record for the same entry.
+func (s Store) InsertCorruptEntry(ctx context.Context, entry picoshare.CorruptEntry) error {
+	log.Printf("recording corrupt entry %v: %s", entry.ID, entry.Reason)
+
+	if _, err := s.db.ExecContext(ctx, `
+	INSERT INTO
+		corrupt_entries
+	(
+		entry_id,
+		detection_time,
+		reason,
+		is_quarantined
+	)
+	VALUES($1, $2, $3, $4)
+	ON CONFLICT (entry_id) DO UPDATE SET
+		detection_time = excluded.detection_time,
+		reason = excluded.reason,
+		is_quarantined = excluded.is_quarantined`,
+		entry.ID,
+		normalizeTime(entry.Detected),
+		entry.Reason,
+		entry.Quarantined); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCorruptEntry clears the record that an entry is corrupted, if one
+// exists.
+func (s Store) DeleteCorruptEntry(ctx context.Context, id picoshare.EntryID) error {
+	if _, err := s.db.ExecContext(ctx, `
+	DELETE FROM
+		corrupt_entries
+	WHERE
+		entry_id = $1`, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s Store) isEntryQuarantined(ctx context.Context, id picoshare.EntryID) (bool, error) {
+	var quarantined bool
+	err := s.db.QueryRowContext(ctx, `
+	SELECT
+		is_quarantined
+	FROM
+		corrupt_entries
+	WHERE
+		entry_id = $1`, id).Scan(&quarantined)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return quarantined, nil
+}
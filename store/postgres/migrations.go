@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+)
+
+// migrationLockID identifies the advisory lock that serializes migrations when
+// several PicoShare instances start at the same time.
+const migrationLockID = 0x7069636f // "pico"
+
+//go:embed migrations/*.sql
+var migrationsFs embed.FS
+
+func applyMigrations(db *sql.DB) {
+	migrationsSubFs, err := fs.Sub(migrationsFs, "migrations")
+	if err != nil {
+		log.Fatalf("failed to open migrations directory: %v", err)
+	}
+
+	if err := runMigrations(context.Background(), db, migrationsSubFs); err != nil {
+		log.Fatalf("failed to apply database migrations: %v", err)
+	}
+}
+
+// runMigrations applies the migrations in fsys in lexicographic order by
+// filename. PostgreSQL has no equivalent to SQLite's user_version pragma, so
+// the schema_version table tracks the index of the last applied migration.
+func runMigrations(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback migrations: %v", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_version table: %w", err)
+	}
+
+	version := 0
+	if err := tx.QueryRowContext(ctx, `
+	SELECT
+		version
+	FROM
+		schema_version`).Scan(&version); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for i, name := range names {
+		migrationVersion := i + 1
+		if migrationVersion <= version {
+			continue
+		}
+
+		query, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("read migration %q: %w", name, err)
+		}
+
+		log.Printf("applying migration %s", name)
+		if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+			return fmt.Errorf("apply migration %q: %w", name, err)
+		}
+	}
+
+	if len(names) > version {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_version`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			schema_version
+		(
+			version
+		)
+		VALUES($1)`, len(names)); err != nil {
+			return fmt.Errorf("update schema version: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
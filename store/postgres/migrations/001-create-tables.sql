@@ -0,0 +1,100 @@
+-- The initial PostgreSQL schema matches the schema that the SQLite store
+-- reached through its own migrations.
+CREATE TABLE blobs (
+    id TEXT PRIMARY KEY,
+    -- sha256 is the hex-encoded SHA-256 digest of the blob's original data.
+    sha256 TEXT CHECK (
+        sha256 IS NULL OR length(sha256) = 64
+    ),
+    size BIGINT CHECK (
+        size IS NULL OR size > 0
+    ),
+    stored_size BIGINT CHECK (
+        stored_size IS NULL OR stored_size > 0
+    ),
+    -- wrapped_data_key is the blob's data key, encrypted with the server's master
+    -- key. A NULL value means that the blob's data is not encrypted.
+    wrapped_data_key BYTEA,
+    -- A pending blob belongs to an upload that's still writing its data.
+    is_pending BOOLEAN NOT NULL DEFAULT FALSE,
+    creation_time TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_blobs_sha256 ON blobs (sha256);
+
+CREATE TABLE entries_data (
+    id TEXT NOT NULL,
+    chunk_index INTEGER NOT NULL CHECK (chunk_index >= 0),
+    chunk BYTEA NOT NULL,
+    uncompressed_length INTEGER CHECK (
+        uncompressed_length IS NULL OR uncompressed_length > 0
+    ),
+    PRIMARY KEY (id, chunk_index)
+);
+
+CREATE TABLE guest_links (
+    id TEXT PRIMARY KEY,
+    label TEXT CHECK (
+        label IS NULL OR length(label) <= 200
+    ),
+    max_file_bytes BIGINT CHECK (
+        max_file_bytes IS NULL OR max_file_bytes > 0
+    ),
+    max_file_uploads INTEGER CHECK (
+        max_file_uploads IS NULL OR max_file_uploads > 0
+    ),
+    creation_time TIMESTAMPTZ NOT NULL,
+    url_expiration_time TIMESTAMPTZ NOT NULL,
+    -- file_expiration_time is the lifetime of files that guests upload, as a Go
+    -- duration string.
+    file_expiration_time TEXT,
+    is_disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE entries (
+    id TEXT PRIMARY KEY,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    upload_time TIMESTAMPTZ NOT NULL,
+    expiration_time TIMESTAMPTZ NOT NULL,
+    -- guest_link_id identifies which guest link (if any) the client used to
+    -- upload this entry.
+    guest_link_id TEXT,
+    note TEXT,
+    blob_id TEXT NOT NULL
+);
+
+CREATE INDEX idx_entries_guest_link_id ON entries (guest_link_id);
+CREATE INDEX idx_entries_blob_id ON entries (blob_id);
+
+CREATE TABLE downloads (
+    entry_id TEXT NOT NULL,
+    download_timestamp TIMESTAMPTZ NOT NULL,
+    client_ip TEXT,
+    user_agent TEXT
+);
+
+CREATE INDEX idx_downloads_entry_id ON downloads (entry_id);
+
+CREATE TABLE settings (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    default_expiration_in_days INTEGER CHECK (
+        default_expiration_in_days IS NULL
+        OR default_expiration_in_days >= 0
+    )
+);
+
+INSERT INTO settings (
+    id,
+    default_expiration_in_days
+) VALUES (
+    1,
+    30
+);
+
+CREATE TABLE corrupt_entries (
+    entry_id TEXT PRIMARY KEY,
+    detection_time TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL,
+    is_quarantined BOOLEAN NOT NULL DEFAULT FALSE
+);
@@ -0,0 +1,5 @@
+-- max_total_bytes limits the combined size of the files that guests upload
+-- through a guest link. A NULL value means that the link has no quota.
+ALTER TABLE guest_links ADD COLUMN max_total_bytes BIGINT CHECK (
+    max_total_bytes IS NULL OR max_total_bytes > 0
+);
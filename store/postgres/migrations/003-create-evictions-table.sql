@@ -0,0 +1,13 @@
+-- Record entries that PicoShare deleted automatically to bring its storage
+-- usage back under its storage limit. The entries themselves are gone, so each
+-- record keeps a copy of the metadata that the eviction log displays.
+CREATE TABLE evictions (
+    entry_id TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    file_size BIGINT NOT NULL CHECK (file_size > 0),
+    upload_time TIMESTAMPTZ NOT NULL,
+    eviction_time TIMESTAMPTZ NOT NULL,
+    policy TEXT NOT NULL
+);
+
+CREATE INDEX idx_evictions_eviction_time ON evictions (eviction_time);
@@ -0,0 +1,3 @@
+-- last_modified_time records when an admin last edited a guest link. A NULL
+-- value means that nobody has edited the link since its creation.
+ALTER TABLE guest_links ADD COLUMN last_modified_time TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- allowed_file_types is a comma-separated list of the file extensions and media
+-- types that guests may upload through a guest link. A NULL value means that
+-- guests may upload any type of file.
+ALTER TABLE guest_links ADD COLUMN allowed_file_types TEXT;
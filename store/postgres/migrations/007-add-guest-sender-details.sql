@@ -0,0 +1,8 @@
+-- allow_sender_details indicates whether guests can attach their name, email
+-- address, and a note to the files they upload through a guest link.
+ALTER TABLE guest_links ADD COLUMN allow_sender_details BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- sender_name and sender_email identify the guest who uploaded an entry. They
+-- are NULL if the guest didn't provide them or if the owner uploaded the entry.
+ALTER TABLE entries ADD COLUMN sender_name TEXT;
+ALTER TABLE entries ADD COLUMN sender_email TEXT;
@@ -0,0 +1,17 @@
+-- notify_on_guest_upload and notify_on_first_download control which events
+-- PicoShare reports to the owner.
+ALTER TABLE settings ADD COLUMN notify_on_guest_upload BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE settings ADD COLUMN notify_on_first_download BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The remaining columns configure the channels that PicoShare sends
+-- notifications through. They are NULL if the owner hasn't configured the
+-- channel.
+ALTER TABLE settings ADD COLUMN smtp_host TEXT;
+ALTER TABLE settings ADD COLUMN smtp_port INTEGER;
+ALTER TABLE settings ADD COLUMN smtp_username TEXT;
+ALTER TABLE settings ADD COLUMN smtp_password TEXT;
+ALTER TABLE settings ADD COLUMN smtp_from TEXT;
+ALTER TABLE settings ADD COLUMN smtp_to TEXT;
+ALTER TABLE settings ADD COLUMN webhook_url TEXT;
+ALTER TABLE settings ADD COLUMN ntfy_topic_url TEXT;
+ALTER TABLE settings ADD COLUMN ntfy_access_token TEXT;
@@ -0,0 +1,27 @@
+-- event_webhook_url and event_webhook_secret configure the webhook that
+-- PicoShare reports changes to entries and guest links to.
+ALTER TABLE settings ADD COLUMN event_webhook_url TEXT;
+ALTER TABLE settings ADD COLUMN event_webhook_secret TEXT;
+
+-- Queue of events for the event webhook. PicoShare keeps each delivery after
+-- it succeeds or gives up so that the owner can review recent deliveries.
+CREATE TABLE webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    url TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    state TEXT NOT NULL CHECK (state IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    creation_time TIMESTAMPTZ NOT NULL,
+    -- next_attempt_time is NULL once the delivery is no longer pending.
+    next_attempt_time TIMESTAMPTZ,
+    last_attempt_time TIMESTAMPTZ,
+    last_status_code INTEGER,
+    last_error TEXT
+);
+
+CREATE INDEX idx_webhook_deliveries_next_attempt_time
+ON webhook_deliveries (state, next_attempt_time);
+
+CREATE INDEX idx_webhook_deliveries_creation_time
+ON webhook_deliveries (creation_time);
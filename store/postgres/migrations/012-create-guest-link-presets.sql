@@ -0,0 +1,16 @@
+-- Named sets of limits that the owner can apply when they create a guest
+-- link. position keeps the presets in the order that the owner listed them.
+CREATE TABLE guest_link_presets (
+    position INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    file_expiration_time TEXT NOT NULL,
+    max_file_bytes BIGINT CHECK (
+        max_file_bytes IS NULL OR max_file_bytes > 0
+    ),
+    max_file_uploads INTEGER CHECK (
+        max_file_uploads IS NULL OR max_file_uploads > 0
+    ),
+    max_total_bytes BIGINT CHECK (
+        max_total_bytes IS NULL OR max_total_bytes > 0
+    )
+);
@@ -0,0 +1,17 @@
+-- Instances that share a database can delete a blob while another instance's
+-- upload attaches to it, so have the database reject entries and chunks that
+-- refer to a missing blob.
+DELETE FROM
+    entries_data
+WHERE
+    NOT EXISTS (SELECT 1 FROM blobs WHERE blobs.id = entries_data.id);
+
+ALTER TABLE entries_data
+    ADD CONSTRAINT fk_entries_data_blob_id
+    FOREIGN KEY (id) REFERENCES blobs (id);
+
+-- Entries that already lost their blob can't get it back, so skip validating
+-- existing rows and enforce the key only for changes from now on.
+ALTER TABLE entries
+    ADD CONSTRAINT fk_entries_blob_id
+    FOREIGN KEY (blob_id) REFERENCES blobs (id) NOT VALID;
@@ -0,0 +1,5 @@
+-- claimed_until is the time until which a sender has claimed the delivery to
+-- attempt it. Other senders skip claimed deliveries, so each attempt happens
+-- once even if several PicoShare instances work through the queue at the same
+-- time. It's NULL when no sender has claimed the delivery.
+ALTER TABLE webhook_deliveries ADD COLUMN claimed_until TIMESTAMPTZ;
@@ -0,0 +1,18 @@
+-- Record whether each chunk is stored zstd-compressed, so that readers don't
+-- have to infer it from the chunk's length.
+ALTER TABLE entries_data ADD COLUMN is_compressed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The writer only kept a compressed chunk if it was shorter than the original
+-- data. Encrypted chunks carry a 12-byte AES-GCM nonce and a 16-byte
+-- authentication tag on top of the compressed data.
+UPDATE entries_data
+SET
+    is_compressed = TRUE
+FROM
+    blobs
+WHERE
+    blobs.id = entries_data.id
+    AND entries_data.uncompressed_length IS NOT NULL
+    AND length(entries_data.chunk)
+    - CASE WHEN blobs.wrapped_data_key IS NULL THEN 0 ELSE 28 END
+    < entries_data.uncompressed_length;
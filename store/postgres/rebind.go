@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// rebindingDB adapts the queries of the file package, which use ? placeholders,
+// to PostgreSQL's $1, $2, ... placeholders.
+type rebindingDB struct {
+	db *sql.DB
+}
+
+func (r rebindingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.db.QueryContext(ctx, rebind(query), args...)
+}
+
+func (r rebindingDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.db.QueryRowContext(ctx, rebind(query), args...)
+}
+
+// rebind replaces each ? placeholder in query with its positional PostgreSQL
+// equivalent. It assumes that no string literals in the query contain a ?.
+func rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c != '?' {
+			b.WriteRune(c)
+			continue
+		}
+		n++
+		b.WriteString("$")
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// We only store one set of settings at a time, so we used a fixed row ID.
+const settingsRowID = 1
+
+func (s Store) ReadSettings(ctx context.Context) (picoshare.Settings, error) {
+	var expirationInDays uint16
+	var notifyOnGuestUpload, notifyOnFirstDownload bool
+	var smtpHost, smtpUsername, smtpPassword, smtpFrom, smtpTo sql.NullString
+	var smtpPort sql.NullInt32
+	var webhookURL, ntfyTopicURL, ntfyAccessToken sql.NullString
+	var eventWebhookURL, eventWebhookSecret sql.NullString
+	var brandName, brandLogoContentType, brandAccentColor sql.NullString
+	var brandLogo []byte
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		default_expiration_in_days,
+		notify_on_guest_upload,
+		notify_on_first_download,
+		smtp_host,
+		smtp_port,
+		smtp_username,
+		smtp_password,
+		smtp_from,
+		smtp_to,
+		webhook_url,
+		ntfy_topic_url,
+		ntfy_access_token,
+		event_webhook_url,
+		event_webhook_secret,
+		brand_name,
+		brand_logo,
+		brand_logo_content_type,
+		brand_accent_color
+	FROM
+		settings
+	WHERE
+		id = $1`, settingsRowID).Scan(
+		&expirationInDays,
+		&notifyOnGuestUpload,
+		&notifyOnFirstDownload,
+		&smtpHost,
+		&smtpPort,
+		&smtpUsername,
+		&smtpPassword,
+		&smtpFrom,
+		&smtpTo,
+		&webhookURL,
+		&ntfyTopicURL,
+		&ntfyAccessToken,
+		&eventWebhookURL,
+		&eventWebhookSecret,
+		&brandName,
+		&brandLogo,
+		&brandLogoContentType,
+		&brandAccentColor); err != nil {
+		if err == sql.ErrNoRows {
+			return picoshare.Settings{}, nil
+		}
+		return picoshare.Settings{}, err
+	}
+
+	presets, err := s.readGuestLinkPresets(ctx)
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
+	return picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(expirationInDays),
+		Notifications: picoshare.NotificationSettings{
+			OnGuestUpload:   notifyOnGuestUpload,
+			OnFirstDownload: notifyOnFirstDownload,
+			Email: picoshare.EmailNotificationSettings{
+				Host:     smtpHost.String,
+				Port:     uint16(smtpPort.Int32),
+				Username: smtpUsername.String,
+				Password: smtpPassword.String,
+				From:     smtpFrom.String,
+				To:       smtpTo.String,
+			},
+			Webhook: picoshare.WebhookNotificationSettings{
+				URL: webhookURL.String,
+			},
+			Ntfy: picoshare.NtfyNotificationSettings{
+				TopicURL:    ntfyTopicURL.String,
+				AccessToken: ntfyAccessToken.String,
+			},
+		},
+		EventWebhook: picoshare.EventWebhookSettings{
+			URL:    eventWebhookURL.String,
+			Secret: eventWebhookSecret.String,
+		},
+		Branding: picoshare.BrandingSettings{
+			Name: brandName.String,
+			Logo: picoshare.BrandingLogo{
+				ContentType: picoshare.ContentType(brandLogoContentType.String),
+				Data:        brandLogo,
+			},
+			AccentColor: brandAccentColor.String,
+		},
+		GuestLinkPresets: presets,
+	}, nil
+}
+
+// ReadBrandingSettings reads the branding settings without the logo's data,
+// which can be large, so that pages can show the branding cheaply. The logo's
+// content type is set only if there's a logo. ReadBrandingLogo reads the logo
+// itself.
+func (s Store) ReadBrandingSettings(ctx context.Context) (picoshare.BrandingSettings, error) {
+	var brandName, brandLogoContentType, brandAccentColor sql.NullString
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		brand_name,
+		CASE
+			WHEN brand_logo IS NULL THEN NULL
+			ELSE brand_logo_content_type
+		END,
+		brand_accent_color
+	FROM
+		settings
+	WHERE
+		id = $1`, settingsRowID).Scan(&brandName, &brandLogoContentType, &brandAccentColor); err != nil {
+		if err == sql.ErrNoRows {
+			return picoshare.BrandingSettings{}, nil
+		}
+		return picoshare.BrandingSettings{}, err
+	}
+
+	return picoshare.BrandingSettings{
+		Name: brandName.String,
+		Logo: picoshare.BrandingLogo{
+			ContentType: picoshare.ContentType(brandLogoContentType.String),
+		},
+		AccentColor: brandAccentColor.String,
+	}, nil
+}
+
+// ReadBrandingLogo reads the logo that pages show next to the instance name.
+func (s Store) ReadBrandingLogo(ctx context.Context) (picoshare.BrandingLogo, error) {
+	var brandLogoContentType sql.NullString
+	var brandLogo []byte
+	if err := s.db.QueryRowContext(ctx, `
+	SELECT
+		brand_logo,
+		brand_logo_content_type
+	FROM
+		settings
+	WHERE
+		id = $1`, settingsRowID).Scan(&brandLogo, &brandLogoContentType); err != nil {
+		if err == sql.ErrNoRows {
+			return picoshare.BrandingLogo{}, nil
+		}
+		return picoshare.BrandingLogo{}, err
+	}
+
+	return picoshare.BrandingLogo{
+		ContentType: picoshare.ContentType(brandLogoContentType.String),
+		Data:        brandLogo,
+	}, nil
+}
+
+func (s Store) readGuestLinkPresets(ctx context.Context) ([]picoshare.GuestLinkPreset, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT
+		name,
+		file_expiration_time,
+		max_file_bytes,
+		max_file_uploads,
+		max_total_bytes
+	FROM
+		guest_link_presets
+	ORDER BY
+		position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []picoshare.GuestLinkPreset
+	for rows.Next() {
+		var name, fileLifetimeRaw string
+		var maxFileBytes picoshare.GuestUploadMaxFileBytes
+		var maxFileUploads picoshare.GuestUploadCountLimit
+		var maxTotalBytes picoshare.GuestUploadMaxTotalBytes
+		if err := rows.Scan(&name, &fileLifetimeRaw, &maxFileBytes, &maxFileUploads, &maxTotalBytes); err != nil {
+			return nil, err
+		}
+
+		fileLifetime, err := parseFileLifetime(fileLifetimeRaw)
+		if err != nil {
+			return nil, err
+		}
+
+		presets = append(presets, picoshare.GuestLinkPreset{
+			Name:            name,
+			MaxFileLifetime: fileLifetime,
+			MaxFileBytes:    maxFileBytes,
+			MaxFileUploads:  maxFileUploads,
+			MaxTotalBytes:   maxTotalBytes,
+		})
+	}
+
+	return presets, rows.Err()
+}
+
+func (s Store) UpdateSettings(ctx context.Context, settings picoshare.Settings) error {
+	log.Printf("saving new settings: %s", settings)
+	n := settings.Notifications
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback update settings: %v", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE
+		settings
+	SET
+		default_expiration_in_days = $1,
+		notify_on_guest_upload = $2,
+		notify_on_first_download = $3,
+		smtp_host = NULLIF($4, ''),
+		smtp_port = NULLIF($5, 0),
+		smtp_username = NULLIF($6, ''),
+		smtp_password = NULLIF($7, ''),
+		smtp_from = NULLIF($8, ''),
+		smtp_to = NULLIF($9, ''),
+		webhook_url = NULLIF($10, ''),
+		ntfy_topic_url = NULLIF($11, ''),
+		ntfy_access_token = NULLIF($12, ''),
+		event_webhook_url = NULLIF($13, ''),
+		event_webhook_secret = NULLIF($14, ''),
+		brand_name = NULLIF($15, ''),
+		brand_logo = NULLIF($16, ''::BYTEA),
+		brand_logo_content_type = NULLIF($17, ''),
+		brand_accent_color = NULLIF($18, '')
+	WHERE
+		id = $19`,
+		settings.DefaultFileLifetime.Days(),
+		n.OnGuestUpload,
+		n.OnFirstDownload,
+		n.Email.Host,
+		int32(n.Email.Port),
+		n.Email.Username,
+		n.Email.Password,
+		n.Email.From,
+		n.Email.To,
+		n.Webhook.URL,
+		n.Ntfy.TopicURL,
+		n.Ntfy.AccessToken,
+		settings.EventWebhook.URL,
+		settings.EventWebhook.Secret,
+		settings.Branding.Name,
+		settings.Branding.Logo.Data,
+		settings.Branding.Logo.ContentType.String(),
+		settings.Branding.AccentColor,
+		settingsRowID); err != nil {
+		return err
+	}
+
+	// The owner edits presets as a list, so replace the whole list.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM guest_link_presets`); err != nil {
+		return err
+	}
+	for i, preset := range settings.GuestLinkPresets {
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			guest_link_presets
+		(
+			position,
+			name,
+			file_expiration_time,
+			max_file_bytes,
+			max_file_uploads,
+			max_total_bytes
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+			i,
+			preset.Name,
+			preset.MaxFileLifetime.String(),
+			preset.MaxFileBytes,
+			preset.MaxFileUploads,
+			preset.MaxTotalBytes); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
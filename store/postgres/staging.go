@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+const (
+	// stagingBatchSize is the number of chunks that an upload commits per
+	// transaction.
+	stagingBatchSize = 16
+
+	// abandonedUploadAge is how long an upload can stay pending before Purge()
+	// assumes that the instance handling it stopped. Other instances might still
+	// be writing their uploads, so unlike the SQLite store, we can't clear
+	// pending uploads at startup.
+	abandonedUploadAge = 24 * time.Hour
+)
+
+// batchedExecer runs statements from the file package in transactions of at
+// most batchSize statements each.
+type batchedExecer struct {
+	ctx       context.Context
+	db        *sql.DB
+	tx        *sql.Tx
+	batchSize int
+	executed  int
+}
+
+func newBatchedExecer(ctx context.Context, db *sql.DB, batchSize int) *batchedExecer {
+	return &batchedExecer{
+		ctx:       ctx,
+		db:        db,
+		batchSize: batchSize,
+	}
+}
+
+func (b *batchedExecer) Exec(query string, args ...any) (sql.Result, error) {
+	if b.tx == nil {
+		tx, err := b.db.BeginTx(b.ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		b.tx = tx
+	}
+
+	res, err := b.tx.ExecContext(b.ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.executed++
+	if b.executed%b.batchSize == 0 {
+		if err := b.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Commit commits any statements that aren't yet part of a committed batch.
+func (b *batchedExecer) Commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	tx := b.tx
+	b.tx = nil
+	return tx.Commit()
+}
+
+// Rollback discards any statements that aren't yet part of a committed batch.
+func (b *batchedExecer) Rollback() {
+	if b.tx == nil {
+		return
+	}
+	if err := b.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Printf("failed to rollback staged chunks: %v", err)
+	}
+	b.tx = nil
+}
+
+// createPendingBlob records a blob for an upload that's about to start writing
+// chunks.
+func (s Store) createPendingBlob(ctx context.Context, blobID string, wrappedKey encryption.WrappedKey) error {
+	_, err := s.db.ExecContext(ctx, `
+	INSERT INTO
+		blobs
+	(
+		id,
+		wrapped_data_key,
+		is_pending
+	)
+	VALUES($1, $2, TRUE)`, blobID, []byte(wrappedKey))
+	return err
+}
+
+// discardPendingBlob deletes a pending blob and the chunks that its upload
+// staged. It doesn't accept a context because it has to clean up even when the
+// upload failed because its context was canceled.
+func (s Store) discardPendingBlob(blobID string) {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+		return
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback discard of staged upload: %v", err)
+		}
+	}()
+
+	if err := deletePendingBlob(ctx, tx, blobID); err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+	}
+}
+
+func deletePendingBlob(ctx context.Context, tx *sql.Tx, blobID string) error {
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		entries_data
+	WHERE
+		id = $1`, blobID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		blobs
+	WHERE
+		id = $1 AND
+		is_pending`, blobID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+const webhookDeliveryColumns = `
+		id,
+		event_type,
+		url,
+		payload,
+		state,
+		attempts,
+		creation_time,
+		next_attempt_time,
+		last_attempt_time,
+		last_status_code,
+		last_error`
+
+// InsertWebhookDelivery adds an event to the queue for the event webhook.
+func (s Store) InsertWebhookDelivery(ctx context.Context, d picoshare.WebhookDelivery) error {
+	if _, err := s.db.ExecContext(ctx, `
+	INSERT INTO
+		webhook_deliveries
+	(`+webhookDeliveryColumns+`
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		d.ID,
+		d.EventType,
+		d.URL,
+		string(d.Payload),
+		d.State,
+		d.Attempts,
+		normalizeTime(d.Created),
+		nullableTime(d.NextAttempt),
+		nullableTime(d.LastAttempt),
+		nullableStatusCode(d.LastStatusCode),
+		sql.NullString{String: d.LastError, Valid: d.LastError != ""}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt to deliver an event.
+func (s Store) UpdateWebhookDelivery(ctx context.Context, d picoshare.WebhookDelivery) error {
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE
+		webhook_deliveries
+	SET
+		state = $1,
+		attempts = $2,
+		next_attempt_time = $3,
+		last_attempt_time = $4,
+		last_status_code = $5,
+		last_error = $6,
+		claimed_until = NULL
+	WHERE
+		id = $7`,
+		d.State,
+		d.Attempts,
+		nullableTime(d.NextAttempt),
+		nullableTime(d.LastAttempt),
+		nullableStatusCode(d.LastStatusCode),
+		sql.NullString{String: d.LastError, Valid: d.LastError != ""},
+		d.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return store.WebhookDeliveryNotFoundError{ID: d.ID}
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit of the most recent webhook
+// deliveries, newest first.
+func (s Store) GetWebhookDeliveries(ctx context.Context, limit int) ([]picoshare.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT`+webhookDeliveryColumns+`
+	FROM
+		webhook_deliveries
+	ORDER BY
+		creation_time DESC,
+		id DESC
+	LIMIT $1`, limit)
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+	defer rows.Close()
+
+	return webhookDeliveriesFromRows(rows)
+}
+
+// ClaimDueWebhookDeliveries claims up to limit of the pending webhook
+// deliveries that are due for another attempt at the given time and that no
+// other sender has claimed. The claim lasts until claimedUntil or until
+// UpdateWebhookDelivery records the outcome of the attempt. It returns the
+// claimed deliveries, oldest first.
+//
+// SKIP LOCKED makes concurrent senders on other PicoShare instances claim
+// different deliveries rather than wait for each other.
+func (s Store) ClaimDueWebhookDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]picoshare.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	UPDATE
+		webhook_deliveries
+	SET
+		claimed_until = $1
+	WHERE
+		id IN (
+			SELECT
+				id
+			FROM
+				webhook_deliveries
+			WHERE
+				state = $2 AND
+				next_attempt_time <= $3 AND
+				(claimed_until IS NULL OR claimed_until <= $3)
+			ORDER BY
+				creation_time ASC,
+				id ASC
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+	RETURNING`+webhookDeliveryColumns,
+		normalizeTime(claimedUntil), picoshare.WebhookDeliveryPending, normalizeTime(now), limit)
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+	defer rows.Close()
+
+	deliveries, err := webhookDeliveriesFromRows(rows)
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+
+	// RETURNING doesn't preserve the order of the subquery.
+	slices.SortStableFunc(deliveries, func(a, b picoshare.WebhookDelivery) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return deliveries, nil
+}
+
+// DeleteWebhookDeliveriesBefore deletes webhook deliveries that PicoShare
+// created before the given time and is no longer trying to deliver.
+func (s Store) DeleteWebhookDeliveriesBefore(ctx context.Context, t time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `
+	DELETE FROM
+		webhook_deliveries
+	WHERE
+		state != $1 AND
+		creation_time < $2`, picoshare.WebhookDeliveryPending, normalizeTime(t)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func webhookDeliveriesFromRows(rows *sql.Rows) ([]picoshare.WebhookDelivery, error) {
+	deliveries := []picoshare.WebhookDelivery{}
+	for rows.Next() {
+		var id, eventType, url, payload, state string
+		var attempts uint
+		var created time.Time
+		var nextAttempt, lastAttempt *time.Time
+		var lastStatusCode sql.NullInt64
+		var lastError sql.NullString
+		if err := rows.Scan(&id, &eventType, &url, &payload, &state, &attempts, &created, &nextAttempt, &lastAttempt, &lastStatusCode, &lastError); err != nil {
+			return []picoshare.WebhookDelivery{}, err
+		}
+
+		deliveries = append(deliveries, picoshare.WebhookDelivery{
+			ID:             picoshare.WebhookDeliveryID(id),
+			EventType:      picoshare.WebhookEventType(eventType),
+			URL:            url,
+			Payload:        []byte(payload),
+			State:          picoshare.WebhookDeliveryState(state),
+			Attempts:       attempts,
+			Created:        created.UTC(),
+			NextAttempt:    utcOrZero(nextAttempt),
+			LastAttempt:    utcOrZero(lastAttempt),
+			LastStatusCode: int(lastStatusCode.Int64),
+			LastError:      lastError.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+
+	return deliveries, nil
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	normalized := normalizeTime(t)
+	return &normalized
+}
+
+func utcOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+func nullableStatusCode(code int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(code), Valid: code != 0}
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/random"
+	"github.com/mtlynch/picoshare/store"
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+const blobIDLength = 32
+
+var blobIDCharacters = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+
+func newBlobID() string {
+	return random.String(blobIDLength, blobIDCharacters)
+}
+
+// GetStoredDataSize returns the number of bytes that the database uses to store
+// file data. Entries with identical contents share their data, so they count
+// only once.
+func (s Store) GetStoredDataSize(ctx context.Context) (uint64, error) {
+	var size uint64
+	if err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		COALESCE(SUM(stored_size), 0)
+	FROM
+		blobs`).Scan(&size); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// GetDataSizes returns the total size of all entries and the number of bytes
+// that the database uses to store their data.
+func (s Store) GetDataSizes(ctx context.Context) (picoshare.DataSizes, error) {
+	var sizes picoshare.DataSizes
+	if err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		(
+			SELECT
+				COALESCE(SUM(blobs.size), 0)
+			FROM
+				entries
+			INNER JOIN
+				blobs ON entries.blob_id = blobs.id
+		),
+		(
+			SELECT
+				COALESCE(SUM(stored_size), 0)
+			FROM
+				blobs
+		)`).Scan(&sizes.TotalBytes, &sizes.StoredBytes); err != nil {
+		return picoshare.DataSizes{}, err
+	}
+
+	return sizes, nil
+}
+
+type blobRecord struct {
+	id          string
+	checksum    picoshare.SHA256Checksum
+	size        uint64
+	storedSize  uint64
+	isEncrypted bool
+}
+
+// finalizeBlob marks a pending blob as complete once its upload has finished
+// writing data to entries_data. If the store already has a complete blob with
+// the same contents and the same encryption status, finalizeBlob discards the
+// pending blob and returns the ID of the existing blob instead.
+func finalizeBlob(ctx context.Context, tx *sql.Tx, blob blobRecord) (string, error) {
+	// Write before reading anything. If the transaction reads first, SQLite
+	// can't upgrade it to a write transaction after a concurrent upload
+	// commits, and it fails immediately instead of waiting for the lock.
+	res, err := tx.ExecContext(ctx, `
+	UPDATE
+		blobs
+	SET
+		sha256 = :sha256,
+		size = :size,
+		stored_size = :stored_size,
+		is_pending = 0
+	WHERE
+		id = :blob_id AND
+		is_pending = 1`,
+		sql.Named("blob_id", blob.id),
+		sql.Named("sha256", blob.checksum),
+		sql.Named("size", blob.size),
+		sql.Named("stored_size", blob.storedSize))
+	if err != nil {
+		return "", err
+	}
+
+	// If the blob is no longer pending, the garbage collector deleted it as an
+	// abandoned upload while the upload was still writing data.
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if updated == 0 {
+		return "", errors.New("upload took so long that PicoShare deleted it as abandoned")
+	}
+
+	var existingID string
+	err = tx.QueryRowContext(ctx, `
+	SELECT
+		id
+	FROM
+		blobs
+	WHERE
+		sha256 = :sha256 AND
+		is_pending = 0 AND
+		id != :blob_id AND
+		(wrapped_data_key IS NOT NULL) = :is_encrypted
+	LIMIT 1`,
+		sql.Named("sha256", blob.checksum),
+		sql.Named("blob_id", blob.id),
+		sql.Named("is_encrypted", blob.isEncrypted)).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return blob.id, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	log.Printf("upload is identical to existing blob %s, discarding duplicate data", existingID)
+	// No entry references the new blob yet, so this always deletes it.
+	if err := deleteBlobIfUnreferenced(ctx, tx, blob.id); err != nil {
+		return "", err
+	}
+
+	return existingID, nil
+}
+
+// deleteBlobIfUnreferenced deletes a blob and its data if no entry references
+// it anymore.
+func deleteBlobIfUnreferenced(ctx context.Context, tx *sql.Tx, blobID string) error {
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		entries_data
+	WHERE
+		id = :blob_id AND
+		NOT EXISTS (SELECT 1 FROM entries WHERE blob_id = :blob_id)`,
+		sql.Named("blob_id", blobID)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		blobs
+	WHERE
+		id = :blob_id AND
+		NOT EXISTS (SELECT 1 FROM entries WHERE blob_id = :blob_id)`,
+		sql.Named("blob_id", blobID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readEntryBlob retrieves the ID of the blob that holds the given entry's file
+// data and the key that decrypts it. If the blob is not encrypted, the key is
+// nil.
+func (s Store) readEntryBlob(ctx context.Context, id picoshare.EntryID) (string, *encryption.DataKey, error) {
+	var blobID string
+	var wrappedKey []byte
+	err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		blobs.id,
+		blobs.wrapped_data_key
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		entries.id = :entry_id`, sql.Named("entry_id", id)).Scan(&blobID, &wrappedKey)
+	if err == sql.ErrNoRows {
+		return "", nil, store.EntryNotFoundError{ID: id}
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	if wrappedKey == nil {
+		return blobID, nil, nil
+	}
+
+	if s.masterKey == nil {
+		return "", nil, fmt.Errorf("entry %s is encrypted, but no encryption key is configured", id)
+	}
+
+	key, err := s.masterKey.Unwrap(blobID, encryption.WrappedKey(wrappedKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to unwrap data key for entry %s: %w", id, err)
+	}
+
+	return blobID, &key, nil
+}
@@ -8,23 +8,23 @@ import (
 )
 
 // Purge deletes expired entries and clears orphaned rows from the database.
-func (s Store) Purge() error {
+func (s Store) Purge(ctx context.Context) error {
 	log.Printf("deleting expired entries and orphaned data from database")
-	if err := s.deleteExpiredEntries(); err != nil {
+	if err := s.deleteExpiredEntries(ctx); err != nil {
 		return err
 	}
 
-	if err := s.deleteOrphanedRows(); err != nil {
+	if err := s.deleteOrphanedRows(ctx); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s Store) deleteExpiredEntries() error {
+func (s Store) deleteExpiredEntries(ctx context.Context) error {
 	log.Printf("deleting expired entries from database")
 
-	tx, err := s.ctx.BeginTx(context.Background(), nil)
+	tx, err := s.ctx.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -37,7 +37,7 @@ func (s Store) deleteExpiredEntries() error {
 
 	currentTime := formatTime(time.Now())
 
-	if _, err = tx.Exec(`
+	if _, err = tx.ExecContext(ctx, `
    DELETE FROM
    	downloads
    WHERE
@@ -53,11 +53,11 @@ func (s Store) deleteExpiredEntries() error {
 		return err
 	}
 
-	if _, err = tx.Exec(`
+	if _, err = tx.ExecContext(ctx, `
    DELETE FROM
-   	entries_data
+   	corrupt_entries
    WHERE
-   	id IN (
+   	entry_id IN (
    		SELECT
    			id
    		FROM
@@ -69,7 +69,7 @@ func (s Store) deleteExpiredEntries() error {
 		return err
 	}
 
-	if _, err = tx.Exec(`
+	if _, err = tx.ExecContext(ctx, `
    DELETE FROM
    	entries
    WHERE
@@ -82,24 +82,54 @@ func (s Store) deleteExpiredEntries() error {
 	return tx.Commit()
 }
 
-func (s Store) deleteOrphanedRows() error {
+func (s Store) deleteOrphanedRows(ctx context.Context) error {
 	log.Printf("purging orphaned rows from database")
 
-	// Delete rows from entries_data if they don't reference valid rows in
-	// entries. This can happen if the entry insertion fails partway through.
-	rows, err := s.ctx.Exec(`
+	tx, err := s.ctx.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete orphaned rows: %v", err)
+		}
+	}()
+
+	// Delete blobs that no entry references. This happens when we delete the
+	// last entry that shares a blob's data. Pending blobs belong to uploads in
+	// progress, so they don't have entries yet.
+	if _, err := tx.ExecContext(ctx, `
+   	DELETE FROM
+   		blobs
+   	WHERE
+   		is_pending = 0 AND
+   		NOT EXISTS (
+   			SELECT
+   				1
+   			FROM
+   				entries
+   			WHERE
+   				entries.blob_id = blobs.id
+   		)`); err != nil {
+		return err
+	}
+
+	// Delete rows from entries_data if they don't reference valid rows in blobs.
+	// This can happen if we just deleted the blob that owned the rows.
+	rows, err := tx.ExecContext(ctx, `
    	DELETE FROM
    		entries_data
    	WHERE
    	id IN (
    		SELECT
-   			DISTINCT entries_data.id AS entry_id
+   			DISTINCT entries_data.id AS blob_id
    		FROM
    			entries_data
    		LEFT JOIN
-   			entries ON entries_data.id = entries.id
+   			blobs ON entries_data.id = blobs.id
    		WHERE
-   			entries.id IS NULL
+   			blobs.id IS NULL
    		)`)
 	if err != nil {
 		return err
@@ -110,6 +140,10 @@ func (s Store) deleteOrphanedRows() error {
 		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
 	log.Printf("purge completed successfully (%d rows affected)", ra)
 
 	return nil
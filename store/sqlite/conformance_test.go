@@ -0,0 +1,37 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, opts storetest.Options) storetest.Store {
+		// Use a database file rather than a shared in-memory database so that
+		// concurrent writers wait for each other the way they do in production
+		// instead of failing with a locked table.
+		dbPath := filepath.Join(t.TempDir(), "store.db")
+		var s sqlite.Store
+		if opts.MasterKey != nil {
+			s = sqlite.NewWithChunkSizeAndEncryption(dbPath, opts.ChunkSize, *opts.MasterKey, false)
+		} else {
+			s = sqlite.NewWithChunkSize(dbPath, opts.ChunkSize, false)
+		}
+		return &s
+	})
+}
+
+func TestKeyRotationConformance(t *testing.T) {
+	storetest.RunKeyRotation(t, func(t *testing.T, opts storetest.Options) (storetest.KeyRotator, storetest.ReopenStoreFn) {
+		dbPath := filepath.Join(t.TempDir(), "store.db")
+		s := sqlite.NewWithChunkSizeAndEncryption(dbPath, opts.ChunkSize, *opts.MasterKey, false)
+		return &s, func(masterKey encryption.MasterKey) storetest.Store {
+			reopened := sqlite.NewWithChunkSizeAndEncryption(dbPath, opts.ChunkSize, masterKey, false)
+			return &reopened
+		}
+	})
+}
@@ -1,15 +1,16 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
 	"github.com/mtlynch/picoshare/picoshare"
 )
 
-func (s Store) InsertEntryDownload(id picoshare.EntryID, r picoshare.DownloadRecord) error {
+func (s Store) InsertEntryDownload(ctx context.Context, id picoshare.EntryID, r picoshare.DownloadRecord) error {
 	log.Printf("recording download of file ID %s from client %s", id.String(), r.ClientIP)
-	if _, err := s.ctx.Exec(`
+	if _, err := s.ctx.ExecContext(ctx, `
 	INSERT INTO
 		downloads
 	(
@@ -30,8 +31,8 @@ func (s Store) InsertEntryDownload(id picoshare.EntryID, r picoshare.DownloadRec
 	return nil
 }
 
-func (s Store) GetEntryDownloads(id picoshare.EntryID) ([]picoshare.DownloadRecord, error) {
-	rows, err := s.ctx.Query(`
+func (s Store) GetEntryDownloads(ctx context.Context, id picoshare.EntryID) ([]picoshare.DownloadRecord, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
 	SELECT
 		download_timestamp,
 		client_ip,
@@ -72,3 +73,27 @@ func (s Store) GetEntryDownloads(id picoshare.EntryID) ([]picoshare.DownloadReco
 
 	return downloads, nil
 }
+
+// MarkEntryDownloaded records that a client downloaded the entry. It returns
+// true only for the call that marks the entry for the first time, so concurrent
+// downloads agree on which of them came first.
+func (s Store) MarkEntryDownloaded(ctx context.Context, id picoshare.EntryID) (bool, error) {
+	res, err := s.ctx.ExecContext(ctx, `
+	UPDATE
+		entries
+	SET
+		is_downloaded = 1
+	WHERE
+		id = :entry_id AND
+		is_downloaded = 0`, sql.Named("entry_id", id))
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
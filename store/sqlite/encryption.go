@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+// RotateEncryptionKey re-wraps the data key of every encrypted blob so that
+// newKey protects it instead of the store's current master key. The file data
+// itself stays the same, so rotation is fast regardless of how much data the
+// store holds. It returns the number of blobs it updated. After rotation,
+// callers must reopen the store with newKey to read encrypted entries.
+func (s Store) RotateEncryptionKey(ctx context.Context, newKey encryption.MasterKey) (int, error) {
+	if s.masterKey == nil {
+		return 0, errors.New("store has no encryption key to rotate")
+	}
+
+	log.Printf("rotating encryption key")
+
+	tx, err := s.ctx.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback key rotation: %v", err)
+		}
+	}()
+
+	type wrappedBlobKey struct {
+		blobID string
+		key    encryption.WrappedKey
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT
+		id,
+		wrapped_data_key
+	FROM
+		blobs
+	WHERE
+		wrapped_data_key IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	wrappedKeys := []wrappedBlobKey{}
+	for rows.Next() {
+		var id string
+		var wrappedKey []byte
+		if err := rows.Scan(&id, &wrappedKey); err != nil {
+			return 0, err
+		}
+		wrappedKeys = append(wrappedKeys, wrappedBlobKey{
+			blobID: id,
+			key:    encryption.WrappedKey(wrappedKey),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, wk := range wrappedKeys {
+		dk, err := s.masterKey.Unwrap(wk.blobID, wk.key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unwrap data key for blob %s: %w", wk.blobID, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			blobs
+		SET
+			wrapped_data_key = :wrapped_data_key
+		WHERE
+			id = :blob_id`,
+			sql.Named("wrapped_data_key", []byte(newKey.Wrap(wk.blobID, dk))),
+			sql.Named("blob_id", wk.blobID)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("re-wrapped data keys for %d blobs", len(wrappedKeys))
+
+	return len(wrappedKeys), nil
+}
@@ -0,0 +1,123 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/sqlite"
+)
+
+const (
+	dummyMasterKey      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	dummyOtherMasterKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestEncryptedEntryIsNotStoredInPlaintext(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	db := sqlite.NewWithEncryption(dbPath, mustParseMasterKey(dummyMasterKey), false)
+
+	// Use data that's too short to compress so that the only thing that changes
+	// the stored bytes is encryption.
+	input := "hello, world!"
+	if err := db.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("dummy-id"),
+		Filename:    "dummy-file.txt",
+		ContentType: "text/plain",
+		Uploaded:    mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:     mustParseExpirationTime("2040-01-01T00:00:00Z"),
+		Size:        mustParseFileSize(len(input)),
+	}); err != nil {
+		t.Fatalf("failed to insert file into sqlite: %v", err)
+	}
+
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer raw.Close()
+
+	var chunk []byte
+	if err := raw.QueryRow(`
+	SELECT
+		entries_data.chunk
+	FROM
+		entries_data
+	INNER JOIN
+		entries ON entries_data.id = entries.blob_id
+	WHERE
+		entries.id = 'dummy-id'`).Scan(&chunk); err != nil {
+		t.Fatalf("failed to read raw chunk: %v", err)
+	}
+
+	if bytes.Contains(chunk, []byte(input)) {
+		t.Errorf("stored chunk contains plaintext: %q", chunk)
+	}
+
+	plaintextStore := sqlite.New(dbPath, false)
+	if _, err := plaintextStore.ReadEntryFile(context.Background(), picoshare.EntryID("dummy-id")); err == nil {
+		t.Errorf("expected error reading encrypted entry without a key")
+	}
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	oldStore := sqlite.NewWithEncryption(dbPath, mustParseMasterKey(dummyMasterKey), false)
+
+	inputs := map[picoshare.EntryID]string{
+		"entry-a": "hello, world!",
+		"entry-b": "goodbye, world!",
+	}
+	for id, input := range inputs {
+		if err := oldStore.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+			ID:       id,
+			Filename: "dummy-file.txt",
+			Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+			Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+			Size:     mustParseFileSize(len(input)),
+		}); err != nil {
+			t.Fatalf("failed to insert file into sqlite: %v", err)
+		}
+	}
+
+	n, err := oldStore.RotateEncryptionKey(context.Background(), mustParseMasterKey(dummyOtherMasterKey))
+	if err != nil {
+		t.Fatalf("failed to rotate encryption key: %v", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("rotated=%d, want=%d", got, want)
+	}
+
+	newStore := sqlite.NewWithEncryption(dbPath, mustParseMasterKey(dummyOtherMasterKey), false)
+	for id, input := range inputs {
+		entryFile, err := newStore.ReadEntryFile(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to read entry %s with new key: %v", id, err)
+		}
+		contents, err := io.ReadAll(entryFile)
+		if err != nil {
+			t.Fatalf("failed to read entry contents: %v", err)
+		}
+		if got, want := string(contents), input; got != want {
+			t.Errorf("contents=%s, want=%s", got, want)
+		}
+	}
+
+	staleStore := sqlite.NewWithEncryption(dbPath, mustParseMasterKey(dummyMasterKey), false)
+	if _, err := staleStore.ReadEntryFile(context.Background(), picoshare.EntryID("entry-a")); err == nil {
+		t.Errorf("expected error reading entry with the old key after rotation")
+	}
+}
+
+func mustParseMasterKey(s string) encryption.MasterKey {
+	mk, err := encryption.ParseMasterKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return mk
+}
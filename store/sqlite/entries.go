@@ -2,37 +2,37 @@ package sqlite
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"io"
 	"log"
 
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/store"
-	"github.com/mtlynch/picoshare/store/sqlite/file"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/store/file"
 )
 
-func (s Store) GetEntriesMetadata() ([]picoshare.UploadMetadata, error) {
-	rows, err := s.ctx.Query(`
+func (s Store) GetEntriesMetadata(ctx context.Context) ([]picoshare.UploadMetadata, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
 	SELECT
 		entries.id AS id,
+		entries.slug AS slug,
 		entries.filename AS filename,
 		entries.note AS note,
+		entries.sender_name AS sender_name,
+		entries.sender_email AS sender_email,
 		entries.content_type AS content_type,
 		entries.upload_time AS upload_time,
 		entries.expiration_time AS expiration_time,
-		sizes.file_size AS file_size
+		blobs.size AS file_size
 	FROM
 		entries
 	INNER JOIN
-		(
-			SELECT
-				id,
-				SUM(LENGTH(chunk)) AS file_size
-			FROM
-				entries_data
-			GROUP BY
-				id
-		) sizes ON entries.id = sizes.id`)
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		blobs.size IS NOT NULL`)
 	if err != nil {
 		return []picoshare.UploadMetadata{}, err
 	}
@@ -40,13 +40,16 @@ func (s Store) GetEntriesMetadata() ([]picoshare.UploadMetadata, error) {
 	ee := []picoshare.UploadMetadata{}
 	for rows.Next() {
 		var id string
+		var slug sql.NullString
 		var filename string
 		var note *string
+		var senderName sql.NullString
+		var senderEmail sql.NullString
 		var contentType string
 		var uploadTimeRaw string
 		var expirationTimeRaw string
 		var fileSizeRaw uint64
-		if err = rows.Scan(&id, &filename, &note, &contentType, &uploadTimeRaw, &expirationTimeRaw, &fileSizeRaw); err != nil {
+		if err = rows.Scan(&id, &slug, &filename, &note, &senderName, &senderEmail, &contentType, &uploadTimeRaw, &expirationTimeRaw, &fileSizeRaw); err != nil {
 			return []picoshare.UploadMetadata{}, err
 		}
 
@@ -67,8 +70,10 @@ func (s Store) GetEntriesMetadata() ([]picoshare.UploadMetadata, error) {
 
 		ee = append(ee, picoshare.UploadMetadata{
 			ID:          picoshare.EntryID(id),
+			Slug:        picoshare.EntrySlug(slug.String),
 			Filename:    picoshare.Filename(filename),
 			Note:        picoshare.FileNote{Value: note},
+			Sender:      guestSenderFromColumns(senderName, senderEmail),
 			ContentType: picoshare.ContentType(contentType),
 			Uploaded:    ut,
 			Expires:     picoshare.ExpirationTime(et),
@@ -79,8 +84,93 @@ func (s Store) GetEntriesMetadata() ([]picoshare.UploadMetadata, error) {
 	return ee, nil
 }
 
-func (s Store) ReadEntryFile(id picoshare.EntryID) (io.ReadSeeker, error) {
-	r, err := file.NewReader(s.ctx, id)
+// GetGuestLinkEntriesMetadata returns the metadata of the entries that guests
+// uploaded through the given guest link, newest first.
+func (s Store) GetGuestLinkEntriesMetadata(ctx context.Context, id picoshare.GuestLinkID) ([]picoshare.UploadMetadata, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	SELECT
+		entries.id AS id,
+		entries.filename AS filename,
+		entries.guest_session AS guest_session,
+		entries.content_type AS content_type,
+		entries.upload_time AS upload_time,
+		entries.expiration_time AS expiration_time,
+		blobs.size AS file_size
+	FROM
+		entries
+	INNER JOIN
+		blobs ON entries.blob_id = blobs.id
+	WHERE
+		entries.guest_link_id = :guest_link_id AND
+		blobs.size IS NOT NULL
+	ORDER BY
+		entries.upload_time DESC,
+		entries.rowid DESC`, sql.Named("guest_link_id", id))
+	if err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+	defer rows.Close()
+
+	ee := []picoshare.UploadMetadata{}
+	for rows.Next() {
+		var entryID string
+		var filename string
+		var guestSession sql.NullString
+		var contentType string
+		var uploadTimeRaw string
+		var expirationTimeRaw string
+		var fileSizeRaw uint64
+		if err = rows.Scan(&entryID, &filename, &guestSession, &contentType, &uploadTimeRaw, &expirationTimeRaw, &fileSizeRaw); err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		ut, err := parseDatetime(uploadTimeRaw)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		et, err := parseDatetime(expirationTimeRaw)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+		if err != nil {
+			return []picoshare.UploadMetadata{}, err
+		}
+
+		ee = append(ee, picoshare.UploadMetadata{
+			ID:           picoshare.EntryID(entryID),
+			Filename:     picoshare.Filename(filename),
+			GuestSession: picoshare.GuestSessionKey(guestSession.String),
+			ContentType:  picoshare.ContentType(contentType),
+			Uploaded:     ut,
+			Expires:      picoshare.ExpirationTime(et),
+			Size:         fileSize,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.UploadMetadata{}, err
+	}
+
+	return ee, nil
+}
+
+func (s Store) ReadEntryFile(ctx context.Context, id picoshare.EntryID) (io.ReadSeeker, error) {
+	quarantined, err := s.isEntryQuarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		return nil, store.EntryQuarantinedError{ID: id}
+	}
+
+	blobID, key, err := s.readEntryBlob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := file.NewReader(ctx, s.ctx, blobID, key)
 	if err != nil {
 		return nil, err
 	}
@@ -88,37 +178,40 @@ func (s Store) ReadEntryFile(id picoshare.EntryID) (io.ReadSeeker, error) {
 	return r, nil
 }
 
-func (s Store) GetEntryMetadata(id picoshare.EntryID) (picoshare.UploadMetadata, error) {
+func (s Store) GetEntryMetadata(ctx context.Context, id picoshare.EntryID) (picoshare.UploadMetadata, error) {
+	var slug sql.NullString
 	var filename string
 	var note *string
+	var senderName sql.NullString
+	var senderEmail sql.NullString
 	var contentType string
 	var uploadTimeRaw string
 	var expirationTimeRaw string
 	var fileSizeRaw uint64
 	var guestLinkID *picoshare.GuestLinkID
-	err := s.ctx.QueryRow(`
+	var guestSession sql.NullString
+	var checksum *string
+	err := s.ctx.QueryRowContext(ctx, `
 	SELECT
+		entries.slug AS slug,
 		entries.filename AS filename,
 		entries.note AS note,
+		entries.sender_name AS sender_name,
+		entries.sender_email AS sender_email,
 		entries.content_type AS content_type,
 		entries.upload_time AS upload_time,
 		entries.expiration_time AS expiration_time,
-		sizes.file_size AS file_size,
-		entries.guest_link_id AS guest_link_id
+		blobs.size AS file_size,
+		entries.guest_link_id AS guest_link_id,
+		entries.guest_session AS guest_session,
+		blobs.sha256 AS sha256
 	FROM
 		entries
 	INNER JOIN
-		(
-			SELECT
-				id,
-				SUM(LENGTH(chunk)) AS file_size
-			FROM
-				entries_data
-			GROUP BY
-				id
-		) sizes ON entries.id = sizes.id
+		blobs ON entries.blob_id = blobs.id
 	WHERE
-		entries.id = :entry_id`, sql.Named("entry_id", id)).Scan(&filename, &note, &contentType, &uploadTimeRaw, &expirationTimeRaw, &fileSizeRaw, &guestLinkID)
+		entries.id = :entry_id AND
+		blobs.size IS NOT NULL`, sql.Named("entry_id", id)).Scan(&slug, &filename, &note, &senderName, &senderEmail, &contentType, &uploadTimeRaw, &expirationTimeRaw, &fileSizeRaw, &guestLinkID, &guestSession, &checksum)
 	if err == sql.ErrNoRows {
 		return picoshare.UploadMetadata{}, store.EntryNotFoundError{ID: id}
 	} else if err != nil {
@@ -127,7 +220,7 @@ func (s Store) GetEntryMetadata(id picoshare.EntryID) (picoshare.UploadMetadata,
 
 	var guestLink picoshare.GuestLink
 	if guestLinkID != nil && !guestLinkID.Empty() {
-		guestLink, err = s.GetGuestLink(*guestLinkID)
+		guestLink, err = s.GetGuestLink(ctx, *guestLinkID)
 		if err != nil {
 			return picoshare.UploadMetadata{}, err
 		}
@@ -148,68 +241,201 @@ func (s Store) GetEntryMetadata(id picoshare.EntryID) (picoshare.UploadMetadata,
 		return picoshare.UploadMetadata{}, err
 	}
 
+	// Entries that PicoShare stored before it tracked checksums have no
+	// checksum.
+	var sha256Checksum picoshare.SHA256Checksum
+	if checksum != nil {
+		sha256Checksum = picoshare.SHA256Checksum(*checksum)
+	}
+
 	return picoshare.UploadMetadata{
-		ID:          id,
-		Filename:    picoshare.Filename(filename),
-		GuestLink:   guestLink,
-		Note:        picoshare.FileNote{Value: note},
-		ContentType: picoshare.ContentType(contentType),
-		Uploaded:    ut,
-		Expires:     picoshare.ExpirationTime(et),
-		Size:        fileSize,
+		ID:           id,
+		Slug:         picoshare.EntrySlug(slug.String),
+		Filename:     picoshare.Filename(filename),
+		GuestLink:    guestLink,
+		Note:         picoshare.FileNote{Value: note},
+		Sender:       guestSenderFromColumns(senderName, senderEmail),
+		GuestSession: picoshare.GuestSessionKey(guestSession.String),
+		ContentType:  picoshare.ContentType(contentType),
+		Uploaded:     ut,
+		Expires:      picoshare.ExpirationTime(et),
+		Size:         fileSize,
+		SHA256:       sha256Checksum,
 	}, nil
 }
 
-func (s Store) InsertEntry(reader io.Reader, metadata picoshare.UploadMetadata) error {
+func (s Store) InsertEntry(ctx context.Context, reader io.Reader, metadata picoshare.UploadMetadata) error {
 	log.Printf("saving new entry %s", metadata.ID)
 
-	// Note: We deliberately don't use a transaction here, as it bloats memory, so
-	// we can end up in a state with orphaned entries data. We clean it up in
-	// Purge().
+	// We don't write the file data in a single transaction, as it bloats
+	// memory. Instead, we stage the data under a pending blob and commit it in
+	// batches. The entry only becomes visible when we finalize the blob, and
+	// we delete the data of pending blobs if the upload fails or if PicoShare
+	// stops before the upload finishes.
 	// See: https://github.com/mtlynch/picoshare/issues/284
-	w := file.NewWriter(s.ctx, metadata.ID, s.chunkSize)
-	if _, err := io.Copy(w, reader); err != nil {
+	blobID := newBlobID()
+	var key *encryption.DataKey
+	var wrappedKey encryption.WrappedKey
+	if s.masterKey != nil {
+		dk, wk, err := s.masterKey.NewDataKey(blobID)
+		if err != nil {
+			return err
+		}
+		key = &dk
+		wrappedKey = wk
+	}
+
+	if err := s.createPendingBlob(ctx, blobID, wrappedKey); err != nil {
 		return err
 	}
 
-	// Close() flushes the buffer, and it can fail.
-	if err := w.Close(); err != nil {
+	size, storedSize, checksum, err := s.stageBlobData(ctx, reader, blobID, file.IsCompressible(metadata.ContentType), key)
+	if err != nil {
+		s.discardPendingBlob(blobID)
+		return err
+	}
+
+	if size == 0 {
+		s.discardPendingBlob(blobID)
+		return picoshare.ErrEmptyFile
+	}
+
+	if !metadata.SHA256.Empty() && metadata.SHA256 != checksum {
+		s.discardPendingBlob(blobID)
+		return store.ChecksumMismatchError{
+			Expected: metadata.SHA256,
+			Actual:   checksum,
+		}
+	}
+
+	tx, err := s.ctx.BeginTx(ctx, nil)
+	if err != nil {
+		s.discardPendingBlob(blobID)
 		return err
 	}
 
-	_, err := s.ctx.Exec(`
+	committed := false
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback insert entry: %v", err)
+		}
+		if !committed {
+			s.discardPendingBlob(blobID)
+		}
+	}()
+
+	finalBlobID, err := finalizeBlob(ctx, tx, blobRecord{
+		id:          blobID,
+		checksum:    checksum,
+		size:        uint64(size),
+		storedSize:  storedSize,
+		isEncrypted: key != nil,
+	})
+	if err != nil {
+		log.Printf("finalizing blob failed, aborting transaction: %v", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
 	INSERT INTO
 		entries
 	(
 		id,
+		slug,
 		guest_link_id,
 		filename,
 		note,
+		sender_name,
+		sender_email,
+		guest_session,
 		content_type,
 		upload_time,
-		expiration_time
+		expiration_time,
+		blob_id
 	)
-	VALUES(:entry_id, NULLIF(:guest_link_id, ''), :filename, :note, :content_type, :upload_time, :expiration_time)`,
+	VALUES(:entry_id, NULLIF(:slug, ''), NULLIF(:guest_link_id, ''), :filename, :note, NULLIF(:sender_name, ''), NULLIF(:sender_email, ''), NULLIF(:guest_session, ''), :content_type, :upload_time, :expiration_time, :blob_id)`,
 		sql.Named("entry_id", metadata.ID),
+		sql.Named("slug", metadata.Slug),
 		sql.Named("guest_link_id", metadata.GuestLink.ID),
 		sql.Named("filename", metadata.Filename),
 		sql.Named("note", metadata.Note.Value),
+		sql.Named("sender_name", metadata.Sender.Name),
+		sql.Named("sender_email", metadata.Sender.Email),
+		sql.Named("guest_session", metadata.GuestSession),
 		sql.Named("content_type", metadata.ContentType),
 		sql.Named("upload_time", formatTime(metadata.Uploaded)),
 		sql.Named("expiration_time", formatExpirationTime(metadata.Expires)),
-	)
-	if err != nil {
+		sql.Named("blob_id", finalBlobID),
+	); err != nil {
 		log.Printf("insert into entries table failed, aborting transaction: %v", err)
 		return err
 	}
 
+	// A guest link's first upload starts its upload window.
+	if !metadata.GuestLink.ID.Empty() {
+		if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			guest_links
+		SET
+			first_upload_time = :upload_time
+		WHERE
+			id = :guest_link_id AND
+			first_upload_time IS NULL`,
+			sql.Named("upload_time", formatTime(metadata.Uploaded)),
+			sql.Named("guest_link_id", metadata.GuestLink.ID)); err != nil {
+			log.Printf("recording first upload for guest link %s failed, aborting transaction: %v", metadata.GuestLink.ID, err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+
 	return nil
 }
 
-func (s Store) UpdateEntryMetadata(id picoshare.EntryID, metadata picoshare.UploadMetadata) error {
+// stageBlobData writes the data from reader to entries_data under the given
+// pending blob, committing the chunks in batches. It returns the size of the
+// data, the number of bytes it occupies in the database, and its checksum.
+func (s Store) stageBlobData(ctx context.Context, reader io.Reader, blobID string, compress bool, key *encryption.DataKey) (int64, uint64, picoshare.SHA256Checksum, error) {
+	batch := newBatchedExecer(ctx, s.ctx, stagingBatchSize)
+	defer batch.Rollback()
+
+	w := file.NewWriter(batch, blobID, s.chunkSize, compress, key)
+	h := sha256.New()
+	size, err := io.Copy(w, io.TeeReader(reader, h))
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	// Close() flushes the buffer, and it can fail.
+	if err := w.Close(); err != nil {
+		return 0, 0, "", err
+	}
+
+	if err := batch.Commit(); err != nil {
+		return 0, 0, "", err
+	}
+
+	return size, w.StoredBytes(), picoshare.SHA256Checksum(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+func (s Store) UpdateEntryMetadata(ctx context.Context, id picoshare.EntryID, metadata picoshare.UploadMetadata) error {
 	log.Printf("updating metadata for entry %s", id)
 
-	res, err := s.ctx.Exec(`
+	tx, err := s.ctx.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback entry metadata update: %v", err)
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
 	UPDATE entries
 	SET
 		filename = :filename,
@@ -233,13 +459,62 @@ func (s Store) UpdateEntryMetadata(id picoshare.EntryID, metadata picoshare.Uplo
 		return store.EntryNotFoundError{ID: id}
 	}
 
-	return nil
+	if !metadata.Slug.Empty() {
+		var otherID string
+		err := tx.QueryRowContext(ctx, `
+		SELECT
+			id
+		FROM
+			entries
+		WHERE
+			slug = :slug AND
+			id != :entry_id`,
+			sql.Named("slug", metadata.Slug),
+			sql.Named("entry_id", id)).Scan(&otherID)
+		if err == nil {
+			return store.EntrySlugConflictError{Slug: metadata.Slug}
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE entries
+	SET
+		slug = NULLIF(:slug, '')
+	WHERE
+		id = :entry_id`,
+		sql.Named("slug", metadata.Slug),
+		sql.Named("entry_id", id)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s Store) DeleteEntry(id picoshare.EntryID) error {
+// GetEntryIDBySlug returns the ID of the entry with the given slug.
+func (s Store) GetEntryIDBySlug(ctx context.Context, slug picoshare.EntrySlug) (picoshare.EntryID, error) {
+	var id string
+	err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		id
+	FROM
+		entries
+	WHERE
+		slug = :slug`, sql.Named("slug", slug)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return picoshare.EntryID(""), store.EntrySlugNotFoundError{Slug: slug}
+	} else if err != nil {
+		return picoshare.EntryID(""), err
+	}
+
+	return picoshare.EntryID(id), nil
+}
+
+func (s Store) DeleteEntry(ctx context.Context, id picoshare.EntryID) error {
 	log.Printf("deleting entry %v", id)
 
-	tx, err := s.ctx.BeginTx(context.Background(), nil)
+	tx, err := s.ctx.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -250,7 +525,7 @@ func (s Store) DeleteEntry(id picoshare.EntryID) error {
 		}
 	}()
 
-	if _, err := tx.Exec(`
+	if _, err := tx.ExecContext(ctx, `
 	DELETE FROM
 		downloads
 	WHERE
@@ -259,16 +534,27 @@ func (s Store) DeleteEntry(id picoshare.EntryID) error {
 		return err
 	}
 
-	if _, err := tx.Exec(`
+	if _, err := tx.ExecContext(ctx, `
 	DELETE FROM
-		entries_data
+		corrupt_entries
 	WHERE
-		id = :entry_id`, sql.Named("entry_id", id)); err != nil {
-		log.Printf("delete from entries_data table failed, aborting transaction: %v", err)
+		entry_id = :entry_id`, sql.Named("entry_id", id)); err != nil {
+		log.Printf("delete from corrupt_entries table failed, aborting transaction: %v", err)
+		return err
+	}
+
+	var blobID string
+	if err := tx.QueryRowContext(ctx, `
+	SELECT
+		blob_id
+	FROM
+		entries
+	WHERE
+		id = :entry_id`, sql.Named("entry_id", id)).Scan(&blobID); err != nil && err != sql.ErrNoRows {
 		return err
 	}
 
-	if _, err := tx.Exec(`
+	if _, err := tx.ExecContext(ctx, `
 	DELETE FROM
 		entries
 	WHERE
@@ -277,5 +563,20 @@ func (s Store) DeleteEntry(id picoshare.EntryID) error {
 		return err
 	}
 
+	// Other entries with identical contents might still share the entry's data.
+	if err := deleteBlobIfUnreferenced(ctx, tx, blobID); err != nil {
+		log.Printf("delete from blobs table failed, aborting transaction: %v", err)
+		return err
+	}
+
 	return tx.Commit()
 }
+
+// guestSenderFromColumns converts the sender columns of an entry, which are
+// NULL for entries without sender details, into a GuestSender.
+func guestSenderFromColumns(name, email sql.NullString) picoshare.GuestSender {
+	return picoshare.GuestSender{
+		Name:  picoshare.SenderName(name.String),
+		Email: picoshare.SenderEmail(email.String),
+	}
+}
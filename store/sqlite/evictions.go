@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// GetLatestDownloadTimes returns the time of the most recent download of each
+// entry that clients have downloaded at least once.
+func (s Store) GetLatestDownloadTimes(ctx context.Context) (map[picoshare.EntryID]time.Time, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	SELECT
+		entry_id,
+		MAX(download_timestamp) AS latest_download
+	FROM
+		downloads
+	GROUP BY
+		entry_id`)
+	if err != nil {
+		return map[picoshare.EntryID]time.Time{}, err
+	}
+	defer rows.Close()
+
+	latest := map[picoshare.EntryID]time.Time{}
+	for rows.Next() {
+		var id string
+		var downloadTimeRaw string
+		if err := rows.Scan(&id, &downloadTimeRaw); err != nil {
+			return map[picoshare.EntryID]time.Time{}, err
+		}
+
+		dt, err := parseDatetime(downloadTimeRaw)
+		if err != nil {
+			return map[picoshare.EntryID]time.Time{}, err
+		}
+
+		latest[picoshare.EntryID(id)] = dt
+	}
+
+	return latest, nil
+}
+
+// GetEvictedEntries returns the entries that PicoShare has evicted, most
+// recent first.
+func (s Store) GetEvictedEntries(ctx context.Context) ([]picoshare.EvictedEntry, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	SELECT
+		entry_id,
+		filename,
+		file_size,
+		upload_time,
+		eviction_time,
+		policy
+	FROM
+		evictions
+	ORDER BY
+		eviction_time DESC`)
+	if err != nil {
+		return []picoshare.EvictedEntry{}, err
+	}
+	defer rows.Close()
+
+	evicted := []picoshare.EvictedEntry{}
+	for rows.Next() {
+		var id string
+		var filename string
+		var fileSizeRaw uint64
+		var uploadTimeRaw string
+		var evictionTimeRaw string
+		var policy string
+		if err := rows.Scan(&id, &filename, &fileSizeRaw, &uploadTimeRaw, &evictionTimeRaw, &policy); err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		fileSize, err := picoshare.FileSizeFromUint64(fileSizeRaw)
+		if err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		ut, err := parseDatetime(uploadTimeRaw)
+		if err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		et, err := parseDatetime(evictionTimeRaw)
+		if err != nil {
+			return []picoshare.EvictedEntry{}, err
+		}
+
+		evicted = append(evicted, picoshare.EvictedEntry{
+			ID:       picoshare.EntryID(id),
+			Filename: picoshare.Filename(filename),
+			Size:     fileSize,
+			Uploaded: ut,
+			Evicted:  et,
+			Policy:   policy,
+		})
+	}
+
+	return evicted, nil
+}
+
+// InsertEvictedEntry records that PicoShare evicted an entry.
+func (s Store) InsertEvictedEntry(ctx context.Context, entry picoshare.EvictedEntry) error {
+	log.Printf("recording eviction of entry %v", entry.ID)
+
+	if _, err := s.ctx.ExecContext(ctx, `
+	INSERT INTO
+		evictions
+	(
+		entry_id,
+		filename,
+		file_size,
+		upload_time,
+		eviction_time,
+		policy
+	)
+	VALUES(:entry_id, :filename, :file_size, :upload_time, :eviction_time, :policy)`,
+		sql.Named("entry_id", entry.ID),
+		sql.Named("filename", entry.Filename),
+		sql.Named("file_size", entry.Size.UInt64()),
+		sql.Named("upload_time", formatTime(entry.Uploaded)),
+		sql.Named("eviction_time", formatTime(entry.Evicted)),
+		sql.Named("policy", entry.Policy)); err != nil {
+		return err
+	}
+
+	return nil
+}
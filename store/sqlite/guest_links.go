@@ -4,27 +4,41 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/mtlynch/picoshare/picoshare"
 	"github.com/mtlynch/picoshare/store"
 )
 
-func (s Store) GetGuestLink(id picoshare.GuestLinkID) (picoshare.GuestLink, error) {
-	row := s.ctx.QueryRow(`
+func (s Store) GetGuestLink(ctx context.Context, id picoshare.GuestLinkID) (picoshare.GuestLink, error) {
+	row := s.ctx.QueryRowContext(ctx, `
 		SELECT
 			guest_links.id AS id,
 			guest_links.label AS label,
 			guest_links.is_disabled As is_disabled,
 			guest_links.max_file_bytes AS max_file_bytes,
 			guest_links.max_file_uploads AS max_file_uploads,
+			guest_links.max_total_bytes AS max_total_bytes,
 			guest_links.creation_time AS creation_time,
 			guest_links.url_expiration_time AS url_expiration_time,
 			guest_links.file_expiration_time AS file_expiration_time,
-			SUM(CASE WHEN entries.id IS NOT NULL THEN 1 ELSE 0 END) AS entry_count
+			guest_links.last_modified_time AS last_modified_time,
+			guest_links.passphrase_hash AS passphrase_hash,
+			guest_links.allowed_file_types AS allowed_file_types,
+			guest_links.allow_sender_details AS allow_sender_details,
+			guest_links.upload_listing AS upload_listing,
+			guest_links.instructions AS instructions,
+			guest_links.upload_window AS upload_window,
+			guest_links.first_upload_time AS first_upload_time,
+			SUM(CASE WHEN entries.id IS NOT NULL THEN 1 ELSE 0 END) AS entry_count,
+			COALESCE(SUM(blobs.size), 0) AS bytes_uploaded
 		FROM
 			guest_links
 		LEFT JOIN
 			entries ON guest_links.id = entries.guest_link_id
+		LEFT JOIN
+			blobs ON entries.blob_id = blobs.id
 		WHERE
 			guest_links.id=:id
 		GROUP BY
@@ -33,22 +47,34 @@ func (s Store) GetGuestLink(id picoshare.GuestLinkID) (picoshare.GuestLink, erro
 	return guestLinkFromRow(row)
 }
 
-func (s Store) GetGuestLinks() ([]picoshare.GuestLink, error) {
-	rows, err := s.ctx.Query(`
+func (s Store) GetGuestLinks(ctx context.Context) ([]picoshare.GuestLink, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
 		SELECT
 			guest_links.id AS id,
 			guest_links.label AS label,
 			guest_links.is_disabled As is_disabled,
 			guest_links.max_file_bytes AS max_file_bytes,
 			guest_links.max_file_uploads AS max_file_uploads,
+			guest_links.max_total_bytes AS max_total_bytes,
 			guest_links.creation_time AS creation_time,
 			guest_links.url_expiration_time AS url_expiration_time,
 			guest_links.file_expiration_time AS file_expiration_time,
-			SUM(CASE WHEN entries.id IS NOT NULL THEN 1 ELSE 0 END) AS entry_count
+			guest_links.last_modified_time AS last_modified_time,
+			guest_links.passphrase_hash AS passphrase_hash,
+			guest_links.allowed_file_types AS allowed_file_types,
+			guest_links.allow_sender_details AS allow_sender_details,
+			guest_links.upload_listing AS upload_listing,
+			guest_links.instructions AS instructions,
+			guest_links.upload_window AS upload_window,
+			guest_links.first_upload_time AS first_upload_time,
+			SUM(CASE WHEN entries.id IS NOT NULL THEN 1 ELSE 0 END) AS entry_count,
+			COALESCE(SUM(blobs.size), 0) AS bytes_uploaded
 		FROM
 			guest_links
 		LEFT JOIN
 			entries ON guest_links.id = entries.guest_link_id
+		LEFT JOIN
+			blobs ON entries.blob_id = blobs.id
 		GROUP BY
 			guest_links.id`)
 	if err != nil {
@@ -68,10 +94,10 @@ func (s Store) GetGuestLinks() ([]picoshare.GuestLink, error) {
 	return gls, nil
 }
 
-func (s *Store) InsertGuestLink(guestLink picoshare.GuestLink) error {
+func (s *Store) InsertGuestLink(ctx context.Context, guestLink picoshare.GuestLink) error {
 	log.Printf("saving new guest link %s", guestLink.ID)
 
-	if _, err := s.ctx.Exec(`
+	if _, err := s.ctx.ExecContext(ctx, `
 	INSERT INTO guest_links
 		(
 			id,
@@ -79,30 +105,94 @@ func (s *Store) InsertGuestLink(guestLink picoshare.GuestLink) error {
 			is_disabled,
 			max_file_bytes,
 			max_file_uploads,
+			max_total_bytes,
 			creation_time,
 			url_expiration_time,
-			file_expiration_time
+			file_expiration_time,
+			passphrase_hash,
+			allowed_file_types,
+			allow_sender_details,
+			upload_listing,
+			instructions,
+			upload_window
 		)
-		VALUES (:id, :label, :is_disabled,:max_file_bytes, :max_file_uploads, :creation_time, :url_expiration_time, :file_expiration_time)
+		VALUES (:id, :label, :is_disabled, :max_file_bytes, :max_file_uploads, :max_total_bytes, :creation_time, :url_expiration_time, :file_expiration_time, :passphrase_hash, :allowed_file_types, :allow_sender_details, NULLIF(:upload_listing, ''), NULLIF(:instructions, ''), :upload_window)
 	`,
 		sql.Named("id", guestLink.ID),
 		sql.Named("label", guestLink.Label),
 		sql.Named("is_disabled", guestLink.IsDisabled),
 		sql.Named("max_file_bytes", guestLink.MaxFileBytes),
 		sql.Named("max_file_uploads", guestLink.MaxFileUploads),
+		sql.Named("max_total_bytes", guestLink.MaxTotalBytes),
 		sql.Named("creation_time", formatTime(guestLink.Created)),
 		sql.Named("url_expiration_time", formatExpirationTime(guestLink.UrlExpires)),
-		sql.Named("file_expiration_time", formatFileLifetime(guestLink.MaxFileLifetime))); err != nil {
+		sql.Named("file_expiration_time", formatFileLifetime(guestLink.MaxFileLifetime)),
+		sql.Named("passphrase_hash", nullablePassphraseHash(guestLink.PassphraseHash)),
+		sql.Named("allowed_file_types", formatAllowedFileTypes(guestLink.AllowedFileTypes)),
+		sql.Named("allow_sender_details", guestLink.AllowSenderDetails),
+		sql.Named("upload_listing", guestLink.UploadListing),
+		sql.Named("instructions", guestLink.Instructions),
+		sql.Named("upload_window", formatUploadWindow(guestLink.UploadWindow))); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s Store) DeleteGuestLink(id picoshare.GuestLinkID) error {
+func (s Store) UpdateGuestLink(ctx context.Context, id picoshare.GuestLinkID, guestLink picoshare.GuestLink) error {
+	log.Printf("updating guest link %s", id)
+
+	res, err := s.ctx.ExecContext(ctx, `
+	UPDATE guest_links
+	SET
+		label = :label,
+		max_file_bytes = :max_file_bytes,
+		max_file_uploads = :max_file_uploads,
+		max_total_bytes = :max_total_bytes,
+		url_expiration_time = :url_expiration_time,
+		file_expiration_time = :file_expiration_time,
+		last_modified_time = :last_modified_time,
+		passphrase_hash = :passphrase_hash,
+		allowed_file_types = :allowed_file_types,
+		allow_sender_details = :allow_sender_details,
+		upload_listing = NULLIF(:upload_listing, ''),
+		instructions = NULLIF(:instructions, ''),
+		upload_window = :upload_window
+	WHERE
+		id = :id`,
+		sql.Named("label", guestLink.Label),
+		sql.Named("max_file_bytes", guestLink.MaxFileBytes),
+		sql.Named("max_file_uploads", guestLink.MaxFileUploads),
+		sql.Named("max_total_bytes", guestLink.MaxTotalBytes),
+		sql.Named("url_expiration_time", formatExpirationTime(guestLink.UrlExpires)),
+		sql.Named("file_expiration_time", formatFileLifetime(guestLink.MaxFileLifetime)),
+		sql.Named("last_modified_time", formatTime(guestLink.LastModified)),
+		sql.Named("passphrase_hash", nullablePassphraseHash(guestLink.PassphraseHash)),
+		sql.Named("allowed_file_types", formatAllowedFileTypes(guestLink.AllowedFileTypes)),
+		sql.Named("allow_sender_details", guestLink.AllowSenderDetails),
+		sql.Named("upload_listing", guestLink.UploadListing),
+		sql.Named("instructions", guestLink.Instructions),
+		sql.Named("upload_window", formatUploadWindow(guestLink.UploadWindow)),
+		sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return store.GuestLinkNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (s Store) DeleteGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
 	log.Printf("deleting guest link %s", id)
 
-	tx, err := s.ctx.BeginTx(context.Background(), nil)
+	tx, err := s.ctx.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -113,7 +203,7 @@ func (s Store) DeleteGuestLink(id picoshare.GuestLinkID) error {
 		}
 	}()
 
-	if _, err = tx.Exec(`
+	if _, err = tx.ExecContext(ctx, `
 	UPDATE
 		entries
 	SET
@@ -124,7 +214,7 @@ func (s Store) DeleteGuestLink(id picoshare.GuestLinkID) error {
 		return err
 	}
 
-	if _, err = tx.Exec(`
+	if _, err = tx.ExecContext(ctx, `
 	DELETE FROM
 		guest_links
 	WHERE
@@ -136,10 +226,10 @@ func (s Store) DeleteGuestLink(id picoshare.GuestLinkID) error {
 	return tx.Commit()
 }
 
-func (s Store) DisableGuestLink(id picoshare.GuestLinkID) error {
+func (s Store) DisableGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
 	log.Printf("disabling guest link %s", id)
 
-	_, err := s.ctx.Exec(`
+	_, err := s.ctx.ExecContext(ctx, `
     UPDATE
         guest_links
     SET
@@ -155,10 +245,10 @@ func (s Store) DisableGuestLink(id picoshare.GuestLinkID) error {
 	return nil
 }
 
-func (s Store) EnableGuestLink(id picoshare.GuestLinkID) error {
+func (s Store) EnableGuestLink(ctx context.Context, id picoshare.GuestLinkID) error {
 	log.Printf("enabling guest link %s", id)
 
-	_, err := s.ctx.Exec(`
+	_, err := s.ctx.ExecContext(ctx, `
 	UPDATE
 		guest_links
 	SET
@@ -180,12 +270,22 @@ func guestLinkFromRow(row rowScanner) (picoshare.GuestLink, error) {
 	var isDisabled bool
 	var maxFileBytes picoshare.GuestUploadMaxFileBytes
 	var maxFileUploads picoshare.GuestUploadCountLimit
+	var maxTotalBytes picoshare.GuestUploadMaxTotalBytes
 	var creationTimeRaw string
 	var urlExpirationTimeRaw string
 	var fileLifetimeRaw *string
+	var lastModifiedRaw *string
+	var passphraseHash sql.NullString
+	var allowedFileTypes sql.NullString
+	var allowSenderDetails bool
+	var uploadListing sql.NullString
+	var instructions sql.NullString
+	var uploadWindowRaw sql.NullString
+	var firstUploadRaw *string
 	var filesUploaded int
+	var bytesUploaded uint64
 
-	err := row.Scan(&id, &label, &isDisabled, &maxFileBytes, &maxFileUploads, &creationTimeRaw, &urlExpirationTimeRaw, &fileLifetimeRaw, &filesUploaded)
+	err := row.Scan(&id, &label, &isDisabled, &maxFileBytes, &maxFileUploads, &maxTotalBytes, &creationTimeRaw, &urlExpirationTimeRaw, &fileLifetimeRaw, &lastModifiedRaw, &passphraseHash, &allowedFileTypes, &allowSenderDetails, &uploadListing, &instructions, &uploadWindowRaw, &firstUploadRaw, &filesUploaded, &bytesUploaded)
 	if err == sql.ErrNoRows {
 		return picoshare.GuestLink{}, store.GuestLinkNotFoundError{ID: id}
 	} else if err != nil {
@@ -212,15 +312,76 @@ func guestLinkFromRow(row rowScanner) (picoshare.GuestLink, error) {
 		}
 	}
 
+	var lastModified time.Time
+	if lastModifiedRaw != nil {
+		lastModified, err = parseDatetime(*lastModifiedRaw)
+		if err != nil {
+			return picoshare.GuestLink{}, err
+		}
+	}
+
+	uploadWindow, err := parseUploadWindow(uploadWindowRaw)
+	if err != nil {
+		return picoshare.GuestLink{}, err
+	}
+
+	var firstUpload time.Time
+	if firstUploadRaw != nil {
+		firstUpload, err = parseDatetime(*firstUploadRaw)
+		if err != nil {
+			return picoshare.GuestLink{}, err
+		}
+	}
+
 	return picoshare.GuestLink{
-		ID:              id,
-		Label:           label,
-		IsDisabled:      isDisabled,
-		MaxFileBytes:    maxFileBytes,
-		MaxFileUploads:  maxFileUploads,
-		FilesUploaded:   filesUploaded,
-		Created:         ct,
-		UrlExpires:      picoshare.ExpirationTime(uet),
-		MaxFileLifetime: fileLifetime,
+		ID:                 id,
+		Label:              label,
+		IsDisabled:         isDisabled,
+		MaxFileBytes:       maxFileBytes,
+		MaxFileUploads:     maxFileUploads,
+		MaxTotalBytes:      maxTotalBytes,
+		FilesUploaded:      filesUploaded,
+		BytesUploaded:      bytesUploaded,
+		Created:            ct,
+		UrlExpires:         picoshare.ExpirationTime(uet),
+		MaxFileLifetime:    fileLifetime,
+		LastModified:       lastModified,
+		PassphraseHash:     picoshare.GuestLinkPassphraseHash(passphraseHash.String),
+		AllowedFileTypes:   parseAllowedFileTypes(allowedFileTypes),
+		AllowSenderDetails: allowSenderDetails,
+		UploadListing:      picoshare.GuestUploadListing(uploadListing.String),
+		Instructions:       picoshare.GuestLinkInstructions(instructions.String),
+		UploadWindow:       uploadWindow,
+		FirstUpload:        firstUpload,
 	}, nil
 }
+
+func formatUploadWindow(w picoshare.GuestLinkUploadWindow) sql.NullString {
+	return sql.NullString{String: w.String(), Valid: w != picoshare.NoUploadWindow}
+}
+
+func parseUploadWindow(raw sql.NullString) (picoshare.GuestLinkUploadWindow, error) {
+	if !raw.Valid {
+		return picoshare.NoUploadWindow, nil
+	}
+	d, err := time.ParseDuration(raw.String)
+	if err != nil {
+		return picoshare.NoUploadWindow, err
+	}
+	return picoshare.GuestLinkUploadWindow(d), nil
+}
+
+func nullablePassphraseHash(h picoshare.GuestLinkPassphraseHash) sql.NullString {
+	return sql.NullString{String: string(h), Valid: h != ""}
+}
+
+func formatAllowedFileTypes(ft picoshare.GuestUploadFileTypes) sql.NullString {
+	return sql.NullString{String: strings.Join(ft, ","), Valid: !ft.IsUnrestricted()}
+}
+
+func parseAllowedFileTypes(raw sql.NullString) picoshare.GuestUploadFileTypes {
+	if !raw.Valid {
+		return nil
+	}
+	return picoshare.GuestUploadFileTypes(strings.Split(raw.String, ","))
+}
@@ -0,0 +1,13 @@
+-- Record the original length of chunks that we store compressed. A NULL value
+-- means that the chunk is stored as-is, so its original length is the length of
+-- the blob.
+ALTER TABLE entries_data ADD COLUMN uncompressed_length INTEGER CHECK (
+    uncompressed_length IS NULL OR uncompressed_length > 0
+);
+
+-- Replace the file size index so that size calculations reflect the original
+-- file size rather than the number of bytes we store.
+DROP INDEX idx_entries_data_length;
+
+CREATE INDEX idx_entries_data_length
+ON entries_data (id, coalesce(uncompressed_length, length(chunk)));
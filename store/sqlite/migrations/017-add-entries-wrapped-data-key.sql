@@ -0,0 +1,7 @@
+-- Store the per-entry data key, encrypted with the server's master key. A NULL
+-- value means that the entry's data is not encrypted.
+--
+-- The chunks of an encrypted entry always have a non-NULL uncompressed_length
+-- because the stored blob never matches the original data. The reader treats a
+-- decrypted chunk as compressed only if it's shorter than uncompressed_length.
+ALTER TABLE entries ADD COLUMN wrapped_data_key BLOB;
@@ -0,0 +1,36 @@
+-- Separate file data from entries so that identical uploads share storage. A
+-- blob is the file data in the entries_data rows whose id matches the blob's
+-- id. Any number of entries can reference the same blob, and PicoShare deletes
+-- a blob only once no entry references it.
+CREATE TABLE blobs (
+    id TEXT PRIMARY KEY,
+    -- sha256 is the hex-encoded SHA-256 digest of the blob's original data. It's
+    -- NULL for blobs that PicoShare stored before it tracked digests.
+    sha256 TEXT CHECK (
+        sha256 IS NULL OR length(sha256) = 64
+    ),
+    -- wrapped_data_key is the blob's data key, encrypted with the server's master
+    -- key. A NULL value means that the blob's data is not encrypted.
+    wrapped_data_key BLOB
+) STRICT;
+
+CREATE INDEX idx_blobs_sha256 ON blobs (sha256);
+
+-- Every existing entry stores its data under its own ID, so each one becomes a
+-- blob with the same ID. Keeping the ID keeps existing encrypted data readable,
+-- as the ID is part of the authenticated data for the data key and chunks.
+INSERT INTO blobs (id, sha256, wrapped_data_key)
+SELECT
+    id,
+    NULL,
+    wrapped_data_key
+FROM
+    entries;
+
+ALTER TABLE entries ADD COLUMN blob_id TEXT;
+
+UPDATE entries SET blob_id = id;
+
+ALTER TABLE entries DROP COLUMN wrapped_data_key;
+
+CREATE INDEX idx_entries_blob_id ON entries (blob_id);
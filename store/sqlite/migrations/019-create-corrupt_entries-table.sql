@@ -0,0 +1,11 @@
+-- Record entries whose stored data failed an integrity check. PicoShare refuses
+-- to serve a quarantined entry rather than send the client a damaged file.
+CREATE TABLE corrupt_entries (
+    entry_id TEXT PRIMARY KEY,
+    detection_time TEXT NOT NULL CHECK (
+        datetime(detection_time) IS NOT NULL
+        AND datetime(detection_time) >= datetime('2022-02-20')
+    ),
+    reason TEXT NOT NULL,
+    is_quarantined INTEGER NOT NULL CHECK (is_quarantined IN (0, 1)) DEFAULT 0
+) STRICT;
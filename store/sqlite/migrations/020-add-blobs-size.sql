@@ -0,0 +1,31 @@
+-- Persist the size of each blob so that listing entries and measuring disk
+-- usage doesn't have to read every chunk in entries_data.
+-- https://github.com/mtlynch/picoshare/issues/220
+--
+-- size is the number of bytes in the blob's original data. stored_size is the
+-- number of bytes that the blob occupies in entries_data after compression and
+-- encryption. Both are NULL only for blobs that have no data.
+ALTER TABLE blobs ADD COLUMN size INTEGER CHECK (
+    size IS NULL OR size > 0
+);
+
+ALTER TABLE blobs ADD COLUMN stored_size INTEGER CHECK (
+    stored_size IS NULL OR stored_size > 0
+);
+
+UPDATE blobs
+SET
+    size = (
+        SELECT sum(coalesce(uncompressed_length, length(chunk)))
+        FROM entries_data
+        WHERE entries_data.id = blobs.id
+    ),
+    stored_size = (
+        SELECT sum(length(chunk))
+        FROM entries_data
+        WHERE entries_data.id = blobs.id
+    );
+
+-- We no longer calculate file sizes from entries_data, so the index that sped
+-- up those calculations is dead weight.
+DROP INDEX idx_entries_data_length;
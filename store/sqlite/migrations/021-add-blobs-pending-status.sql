@@ -0,0 +1,8 @@
+-- Track uploads that are still in progress. PicoShare creates a pending blob
+-- before it writes any chunks and clears the flag in the same transaction that
+-- creates the entry, so readers never see a partially written upload. Pending
+-- blobs that remain at startup belong to uploads that PicoShare abandoned when
+-- it stopped, so PicoShare deletes them.
+ALTER TABLE blobs ADD COLUMN is_pending INTEGER NOT NULL CHECK (
+    is_pending IN (0, 1)
+) DEFAULT 0;
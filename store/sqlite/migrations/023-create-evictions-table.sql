@@ -0,0 +1,17 @@
+-- Record entries that PicoShare deleted automatically to bring its storage
+-- usage back under its storage limit. The entries themselves are gone, so each
+-- record keeps a copy of the metadata that the eviction log displays.
+CREATE TABLE evictions (
+    entry_id TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    file_size INTEGER NOT NULL CHECK (file_size > 0),
+    upload_time TEXT NOT NULL CHECK (
+        datetime(upload_time) IS NOT NULL
+        AND datetime(upload_time) >= datetime('2022-02-20')
+    ),
+    eviction_time TEXT NOT NULL CHECK (
+        datetime(eviction_time) IS NOT NULL
+        AND datetime(eviction_time) >= datetime('2022-02-20')
+    ),
+    policy TEXT NOT NULL
+) STRICT;
@@ -0,0 +1,9 @@
+-- last_modified_time records when an admin last edited a guest link. A NULL
+-- value means that nobody has edited the link since its creation.
+ALTER TABLE guest_links ADD COLUMN last_modified_time TEXT CHECK (
+    last_modified_time IS NULL
+    OR (
+        datetime(last_modified_time) IS NOT NULL
+        AND datetime(last_modified_time) >= datetime('2022-02-20')
+    )
+);
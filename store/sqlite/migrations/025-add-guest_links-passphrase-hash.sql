@@ -0,0 +1,4 @@
+-- passphrase_hash is a bcrypt hash of the passphrase that guests must enter
+-- before they can upload through a guest link. A NULL value means that the
+-- link doesn't require a passphrase.
+ALTER TABLE guest_links ADD COLUMN passphrase_hash TEXT;
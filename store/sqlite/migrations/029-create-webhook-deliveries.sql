@@ -0,0 +1,36 @@
+-- event_webhook_url and event_webhook_secret configure the webhook that
+-- PicoShare reports changes to entries and guest links to.
+ALTER TABLE settings ADD COLUMN event_webhook_url TEXT;
+ALTER TABLE settings ADD COLUMN event_webhook_secret TEXT;
+
+-- Queue of events for the event webhook. PicoShare keeps each delivery after
+-- it succeeds or gives up so that the owner can review recent deliveries.
+CREATE TABLE webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    url TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    state TEXT NOT NULL CHECK (state IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    creation_time TEXT NOT NULL CHECK (
+        datetime(creation_time) IS NOT NULL
+        AND datetime(creation_time) >= datetime('2022-02-20')
+    ),
+    -- next_attempt_time is NULL once the delivery is no longer pending.
+    next_attempt_time TEXT CHECK (
+        next_attempt_time IS NULL
+        OR datetime(next_attempt_time) IS NOT NULL
+    ),
+    last_attempt_time TEXT CHECK (
+        last_attempt_time IS NULL
+        OR datetime(last_attempt_time) IS NOT NULL
+    ),
+    last_status_code INTEGER,
+    last_error TEXT
+) STRICT;
+
+CREATE INDEX idx_webhook_deliveries_next_attempt_time
+ON webhook_deliveries (state, next_attempt_time);
+
+CREATE INDEX idx_webhook_deliveries_creation_time
+ON webhook_deliveries (creation_time);
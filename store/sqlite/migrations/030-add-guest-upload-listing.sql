@@ -0,0 +1,10 @@
+-- upload_listing controls which uploaded files a guest link's upload page
+-- lists. NULL means that the page doesn't list any files.
+ALTER TABLE guest_links ADD COLUMN upload_listing TEXT CHECK (
+    upload_listing IN ('session', 'all')
+);
+
+-- guest_session identifies the guest's browser session when they uploaded the
+-- entry. It's NULL for entries that the owner uploaded or that a guest
+-- uploaded outside a browser.
+ALTER TABLE entries ADD COLUMN guest_session TEXT;
@@ -0,0 +1,10 @@
+-- instructions holds the Markdown-formatted instructions that the guest link's
+-- upload page shows to guests.
+ALTER TABLE guest_links ADD COLUMN instructions TEXT;
+
+-- The brand_* columns customize how PicoShare's pages look. A NULL value
+-- means that pages use PicoShare's default.
+ALTER TABLE settings ADD COLUMN brand_name TEXT;
+ALTER TABLE settings ADD COLUMN brand_logo BLOB;
+ALTER TABLE settings ADD COLUMN brand_logo_content_type TEXT;
+ALTER TABLE settings ADD COLUMN brand_accent_color TEXT;
@@ -0,0 +1,19 @@
+-- upload_window is how long the guest link keeps working after a guest first
+-- uploads a file through it, as a Go duration string. NULL means that the link
+-- has no upload window.
+ALTER TABLE guest_links ADD COLUMN upload_window TEXT;
+
+-- first_upload_time is when a guest first uploaded a file through the link.
+ALTER TABLE guest_links ADD COLUMN first_upload_time TEXT CHECK (
+    first_upload_time IS NULL
+    OR datetime(first_upload_time) IS NOT NULL
+);
+
+-- For links that guests have already used, the best record of the first
+-- upload is the oldest file that PicoShare still stores.
+UPDATE guest_links
+SET first_upload_time = (
+    SELECT MIN(entries.upload_time)
+    FROM entries
+    WHERE entries.guest_link_id = guest_links.id
+);
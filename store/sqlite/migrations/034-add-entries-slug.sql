@@ -0,0 +1,5 @@
+-- slug is an optional alias that the owner chooses for an entry's download
+-- link. NULL means that the entry has no alias.
+ALTER TABLE entries ADD COLUMN slug TEXT;
+
+CREATE UNIQUE INDEX idx_entries_slug ON entries (slug);
@@ -0,0 +1,13 @@
+-- is_downloaded records whether any client has downloaded the entry, so that
+-- PicoShare can tell the first download apart from later ones without reading
+-- the entry's download history. PicoShare sets it with a conditional update,
+-- so only one of several concurrent downloads counts as the first.
+ALTER TABLE entries ADD COLUMN is_downloaded INTEGER NOT NULL CHECK (
+    is_downloaded IN (0, 1)
+) DEFAULT 0;
+
+UPDATE entries
+SET
+    is_downloaded = 1
+WHERE
+    EXISTS (SELECT 1 FROM downloads WHERE downloads.entry_id = entries.id);
@@ -0,0 +1,22 @@
+-- Record whether each chunk is stored zstd-compressed, so that readers don't
+-- have to infer it from the chunk's length.
+ALTER TABLE entries_data ADD COLUMN is_compressed INTEGER NOT NULL CHECK (
+    is_compressed IN (0, 1)
+) DEFAULT 0;
+
+-- The writer only kept a compressed chunk if it was shorter than the original
+-- data. Encrypted chunks carry a 12-byte AES-GCM nonce and a 16-byte
+-- authentication tag on top of the compressed data.
+UPDATE entries_data
+SET
+    is_compressed = 1
+WHERE
+    uncompressed_length IS NOT NULL
+    AND length(chunk) - (
+        SELECT
+            CASE WHEN blobs.wrapped_data_key IS NULL THEN 0 ELSE 28 END
+        FROM
+            blobs
+        WHERE
+            blobs.id = entries_data.id
+    ) < uncompressed_length;
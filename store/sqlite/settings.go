@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
@@ -10,38 +11,294 @@ import (
 // We only store one set of settings at a time, so we used a fixed row ID.
 const settingsRowID = 1
 
-func (s Store) ReadSettings() (picoshare.Settings, error) {
+func (s Store) ReadSettings(ctx context.Context) (picoshare.Settings, error) {
 	var expirationInDays uint16
-	if err := s.ctx.QueryRow(`
+	var notifyOnGuestUpload, notifyOnFirstDownload bool
+	var smtpHost, smtpUsername, smtpPassword, smtpFrom, smtpTo sql.NullString
+	var smtpPort sql.NullInt32
+	var webhookURL, ntfyTopicURL, ntfyAccessToken sql.NullString
+	var eventWebhookURL, eventWebhookSecret sql.NullString
+	var brandName, brandLogoContentType, brandAccentColor sql.NullString
+	var brandLogo []byte
+	if err := s.ctx.QueryRowContext(ctx, `
    SELECT
-   	default_expiration_in_days
+   	default_expiration_in_days,
+   	notify_on_guest_upload,
+   	notify_on_first_download,
+   	smtp_host,
+   	smtp_port,
+   	smtp_username,
+   	smtp_password,
+   	smtp_from,
+   	smtp_to,
+   	webhook_url,
+   	ntfy_topic_url,
+   	ntfy_access_token,
+   	event_webhook_url,
+   	event_webhook_secret,
+   	brand_name,
+   	brand_logo,
+   	brand_logo_content_type,
+   	brand_accent_color
    FROM
    	settings
    WHERE
-   	id = :row_id`, sql.Named("row_id", settingsRowID)).Scan(&expirationInDays); err != nil {
+   	id = :row_id`, sql.Named("row_id", settingsRowID)).Scan(
+		&expirationInDays,
+		&notifyOnGuestUpload,
+		&notifyOnFirstDownload,
+		&smtpHost,
+		&smtpPort,
+		&smtpUsername,
+		&smtpPassword,
+		&smtpFrom,
+		&smtpTo,
+		&webhookURL,
+		&ntfyTopicURL,
+		&ntfyAccessToken,
+		&eventWebhookURL,
+		&eventWebhookSecret,
+		&brandName,
+		&brandLogo,
+		&brandLogoContentType,
+		&brandAccentColor); err != nil {
 		if err == sql.ErrNoRows {
 			return picoshare.Settings{}, nil
 		}
 		return picoshare.Settings{}, err
 	}
 
+	presets, err := s.readGuestLinkPresets(ctx)
+	if err != nil {
+		return picoshare.Settings{}, err
+	}
+
 	return picoshare.Settings{
 		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(expirationInDays),
+		Notifications: picoshare.NotificationSettings{
+			OnGuestUpload:   notifyOnGuestUpload,
+			OnFirstDownload: notifyOnFirstDownload,
+			Email: picoshare.EmailNotificationSettings{
+				Host:     smtpHost.String,
+				Port:     uint16(smtpPort.Int32),
+				Username: smtpUsername.String,
+				Password: smtpPassword.String,
+				From:     smtpFrom.String,
+				To:       smtpTo.String,
+			},
+			Webhook: picoshare.WebhookNotificationSettings{
+				URL: webhookURL.String,
+			},
+			Ntfy: picoshare.NtfyNotificationSettings{
+				TopicURL:    ntfyTopicURL.String,
+				AccessToken: ntfyAccessToken.String,
+			},
+		},
+		EventWebhook: picoshare.EventWebhookSettings{
+			URL:    eventWebhookURL.String,
+			Secret: eventWebhookSecret.String,
+		},
+		Branding: picoshare.BrandingSettings{
+			Name: brandName.String,
+			Logo: picoshare.BrandingLogo{
+				ContentType: picoshare.ContentType(brandLogoContentType.String),
+				Data:        brandLogo,
+			},
+			AccentColor: brandAccentColor.String,
+		},
+		GuestLinkPresets: presets,
+	}, nil
+}
+
+// ReadBrandingSettings reads the branding settings without the logo's data,
+// which can be large, so that pages can show the branding cheaply. The logo's
+// content type is set only if there's a logo. ReadBrandingLogo reads the logo
+// itself.
+func (s Store) ReadBrandingSettings(ctx context.Context) (picoshare.BrandingSettings, error) {
+	var brandName, brandLogoContentType, brandAccentColor sql.NullString
+	if err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		brand_name,
+		CASE
+			WHEN brand_logo IS NULL THEN NULL
+			ELSE brand_logo_content_type
+		END,
+		brand_accent_color
+	FROM
+		settings
+	WHERE
+		id = :row_id`, sql.Named("row_id", settingsRowID)).Scan(&brandName, &brandLogoContentType, &brandAccentColor); err != nil {
+		if err == sql.ErrNoRows {
+			return picoshare.BrandingSettings{}, nil
+		}
+		return picoshare.BrandingSettings{}, err
+	}
+
+	return picoshare.BrandingSettings{
+		Name: brandName.String,
+		Logo: picoshare.BrandingLogo{
+			ContentType: picoshare.ContentType(brandLogoContentType.String),
+		},
+		AccentColor: brandAccentColor.String,
 	}, nil
 }
 
-func (s Store) UpdateSettings(settings picoshare.Settings) error {
+// ReadBrandingLogo reads the logo that pages show next to the instance name.
+func (s Store) ReadBrandingLogo(ctx context.Context) (picoshare.BrandingLogo, error) {
+	var brandLogoContentType sql.NullString
+	var brandLogo []byte
+	if err := s.ctx.QueryRowContext(ctx, `
+	SELECT
+		brand_logo,
+		brand_logo_content_type
+	FROM
+		settings
+	WHERE
+		id = :row_id`, sql.Named("row_id", settingsRowID)).Scan(&brandLogo, &brandLogoContentType); err != nil {
+		if err == sql.ErrNoRows {
+			return picoshare.BrandingLogo{}, nil
+		}
+		return picoshare.BrandingLogo{}, err
+	}
+
+	return picoshare.BrandingLogo{
+		ContentType: picoshare.ContentType(brandLogoContentType.String),
+		Data:        brandLogo,
+	}, nil
+}
+
+func (s Store) readGuestLinkPresets(ctx context.Context) ([]picoshare.GuestLinkPreset, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	SELECT
+		name,
+		file_expiration_time,
+		max_file_bytes,
+		max_file_uploads,
+		max_total_bytes
+	FROM
+		guest_link_presets
+	ORDER BY
+		position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []picoshare.GuestLinkPreset
+	for rows.Next() {
+		var name, fileLifetimeRaw string
+		var maxFileBytes picoshare.GuestUploadMaxFileBytes
+		var maxFileUploads picoshare.GuestUploadCountLimit
+		var maxTotalBytes picoshare.GuestUploadMaxTotalBytes
+		if err := rows.Scan(&name, &fileLifetimeRaw, &maxFileBytes, &maxFileUploads, &maxTotalBytes); err != nil {
+			return nil, err
+		}
+
+		fileLifetime, err := parseFileLifetime(fileLifetimeRaw)
+		if err != nil {
+			return nil, err
+		}
+
+		presets = append(presets, picoshare.GuestLinkPreset{
+			Name:            name,
+			MaxFileLifetime: fileLifetime,
+			MaxFileBytes:    maxFileBytes,
+			MaxFileUploads:  maxFileUploads,
+			MaxTotalBytes:   maxTotalBytes,
+		})
+	}
+
+	return presets, rows.Err()
+}
+
+func (s Store) UpdateSettings(ctx context.Context, settings picoshare.Settings) error {
 	log.Printf("saving new settings: %s", settings)
 	expirationInDays := settings.DefaultFileLifetime.Days()
-	if _, err := s.ctx.Exec(`
+	n := settings.Notifications
+
+	tx, err := s.ctx.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback update settings: %v", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
    UPDATE
    	settings
    SET
-   	default_expiration_in_days = :expiration
+   	default_expiration_in_days = :expiration,
+   	notify_on_guest_upload = :notify_on_guest_upload,
+   	notify_on_first_download = :notify_on_first_download,
+   	smtp_host = NULLIF(:smtp_host, ''),
+   	smtp_port = NULLIF(:smtp_port, 0),
+   	smtp_username = NULLIF(:smtp_username, ''),
+   	smtp_password = NULLIF(:smtp_password, ''),
+   	smtp_from = NULLIF(:smtp_from, ''),
+   	smtp_to = NULLIF(:smtp_to, ''),
+   	webhook_url = NULLIF(:webhook_url, ''),
+   	ntfy_topic_url = NULLIF(:ntfy_topic_url, ''),
+   	ntfy_access_token = NULLIF(:ntfy_access_token, ''),
+   	event_webhook_url = NULLIF(:event_webhook_url, ''),
+   	event_webhook_secret = NULLIF(:event_webhook_secret, ''),
+   	brand_name = NULLIF(:brand_name, ''),
+   	brand_logo = NULLIF(:brand_logo, X''),
+   	brand_logo_content_type = NULLIF(:brand_logo_content_type, ''),
+   	brand_accent_color = NULLIF(:brand_accent_color, '')
    WHERE
-   	id = :row_id`, sql.Named("expiration", expirationInDays), sql.Named("row_id", settingsRowID)); err != nil {
+   	id = :row_id`,
+		sql.Named("expiration", expirationInDays),
+		sql.Named("notify_on_guest_upload", n.OnGuestUpload),
+		sql.Named("notify_on_first_download", n.OnFirstDownload),
+		sql.Named("smtp_host", n.Email.Host),
+		sql.Named("smtp_port", n.Email.Port),
+		sql.Named("smtp_username", n.Email.Username),
+		sql.Named("smtp_password", n.Email.Password),
+		sql.Named("smtp_from", n.Email.From),
+		sql.Named("smtp_to", n.Email.To),
+		sql.Named("webhook_url", n.Webhook.URL),
+		sql.Named("ntfy_topic_url", n.Ntfy.TopicURL),
+		sql.Named("ntfy_access_token", n.Ntfy.AccessToken),
+		sql.Named("event_webhook_url", settings.EventWebhook.URL),
+		sql.Named("event_webhook_secret", settings.EventWebhook.Secret),
+		sql.Named("brand_name", settings.Branding.Name),
+		sql.Named("brand_logo", settings.Branding.Logo.Data),
+		sql.Named("brand_logo_content_type", settings.Branding.Logo.ContentType.String()),
+		sql.Named("brand_accent_color", settings.Branding.AccentColor),
+		sql.Named("row_id", settingsRowID)); err != nil {
 		return err
 	}
 
-	return nil
+	// The owner edits presets as a list, so replace the whole list.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM guest_link_presets`); err != nil {
+		return err
+	}
+	for i, preset := range settings.GuestLinkPresets {
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			guest_link_presets
+		(
+			position,
+			name,
+			file_expiration_time,
+			max_file_bytes,
+			max_file_uploads,
+			max_total_bytes
+		)
+		VALUES (:position, :name, :file_expiration_time, :max_file_bytes, :max_file_uploads, :max_total_bytes)`,
+			sql.Named("position", i),
+			sql.Named("name", preset.Name),
+			sql.Named("file_expiration_time", formatFileLifetime(preset.MaxFileLifetime)),
+			sql.Named("max_file_bytes", preset.MaxFileBytes),
+			sql.Named("max_file_uploads", preset.MaxFileUploads),
+			sql.Named("max_total_bytes", preset.MaxTotalBytes)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
@@ -8,6 +8,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/encryption"
 )
 
 const (
@@ -20,6 +21,7 @@ type (
 	Store struct {
 		ctx       *sql.DB
 		chunkSize uint64
+		masterKey *encryption.MasterKey
 	}
 
 	rowScanner interface {
@@ -31,6 +33,21 @@ func New(path string, optimizeForLitestream bool) Store {
 	return NewWithChunkSize(path, defaultChunkSize, optimizeForLitestream)
 }
 
+// NewWithEncryption creates a SQLite-based datastore that encrypts the file
+// data of new entries with per-entry keys that it protects with masterKey.
+func NewWithEncryption(path string, masterKey encryption.MasterKey, optimizeForLitestream bool) Store {
+	return NewWithChunkSizeAndEncryption(path, defaultChunkSize, masterKey, optimizeForLitestream)
+}
+
+// NewWithChunkSizeAndEncryption creates a SQLite-based datastore with both a
+// user-specified chunk size and encryption. Most callers should use
+// NewWithEncryption().
+func NewWithChunkSizeAndEncryption(path string, chunkSize uint64, masterKey encryption.MasterKey, optimizeForLitestream bool) Store {
+	s := NewWithChunkSize(path, chunkSize, optimizeForLitestream)
+	s.masterKey = &masterKey
+	return s
+}
+
 // NewWithChunkSize creates a SQLite-based datastore with the user-specified
 // chunk size for writing files. Most callers should just use New().
 func NewWithChunkSize(path string, chunkSize uint64, optimizeForLitestream bool) Store {
@@ -61,10 +78,18 @@ func NewWithChunkSize(path string, chunkSize uint64, optimizeForLitestream bool)
 
 	applyMigrations(ctx)
 
-	return Store{
+	s := Store{
 		ctx:       ctx,
 		chunkSize: chunkSize,
 	}
+
+	// No uploads are in progress yet, so any pending upload data belongs to
+	// uploads that stopped when PicoShare last shut down.
+	if err := s.deleteAbandonedUploads(); err != nil {
+		log.Fatalf("failed to delete abandoned uploads: %v", err)
+	}
+
+	return s
 }
 
 func formatExpirationTime(et picoshare.ExpirationTime) string {
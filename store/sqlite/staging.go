@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+// stagingBatchSize is the number of chunks that an upload commits per
+// transaction. A single transaction for a whole upload bloats memory, while a
+// transaction per chunk makes uploads slow.
+// See: https://github.com/mtlynch/picoshare/issues/284
+const stagingBatchSize = 16
+
+// batchedExecer runs statements in transactions of at most batchSize
+// statements each.
+type batchedExecer struct {
+	ctx       context.Context
+	db        *sql.DB
+	tx        *sql.Tx
+	batchSize int
+	executed  int
+}
+
+func newBatchedExecer(ctx context.Context, db *sql.DB, batchSize int) *batchedExecer {
+	return &batchedExecer{
+		ctx:       ctx,
+		db:        db,
+		batchSize: batchSize,
+	}
+}
+
+func (b *batchedExecer) Exec(query string, args ...any) (sql.Result, error) {
+	if b.tx == nil {
+		tx, err := b.db.BeginTx(b.ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		b.tx = tx
+	}
+
+	res, err := b.tx.ExecContext(b.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.executed++
+	if b.executed%b.batchSize == 0 {
+		if err := b.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Commit commits any statements that aren't yet part of a committed batch.
+func (b *batchedExecer) Commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	tx := b.tx
+	b.tx = nil
+	return tx.Commit()
+}
+
+// Rollback discards any statements that aren't yet part of a committed batch.
+func (b *batchedExecer) Rollback() {
+	if b.tx == nil {
+		return
+	}
+	if err := b.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Printf("failed to rollback staged chunks: %v", err)
+	}
+	b.tx = nil
+}
+
+// createPendingBlob records a blob for an upload that's about to start writing
+// chunks.
+func (s Store) createPendingBlob(ctx context.Context, blobID string, wrappedKey encryption.WrappedKey) error {
+	_, err := s.ctx.ExecContext(ctx, `
+	INSERT INTO
+		blobs
+	(
+		id,
+		wrapped_data_key,
+		is_pending
+	)
+	VALUES(:blob_id, :wrapped_data_key, 1)`,
+		sql.Named("blob_id", blobID),
+		sql.Named("wrapped_data_key", []byte(wrappedKey)))
+	return err
+}
+
+// discardPendingBlob deletes a pending blob and the chunks that its upload
+// staged. It doesn't accept a context because it has to clean up even when the
+// upload failed because its context was canceled.
+func (s Store) discardPendingBlob(blobID string) {
+	tx, err := s.ctx.BeginTx(context.Background(), nil)
+	if err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+		return
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback discard of staged upload: %v", err)
+		}
+	}()
+
+	if err := deletePendingBlob(context.Background(), tx, blobID); err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("failed to discard staged upload %s: %v", blobID, err)
+	}
+}
+
+func deletePendingBlob(ctx context.Context, tx *sql.Tx, blobID string) error {
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		entries_data
+	WHERE
+		id = :blob_id`, sql.Named("blob_id", blobID)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM
+		blobs
+	WHERE
+		id = :blob_id AND
+		is_pending = 1`, sql.Named("blob_id", blobID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteAbandonedUploads deletes the staged data of uploads that never
+// finished. Callers must only call this when no uploads are in progress, such
+// as at startup.
+func (s Store) deleteAbandonedUploads() error {
+	tx, err := s.ctx.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback delete abandoned uploads: %v", err)
+		}
+	}()
+
+	if _, err := tx.Exec(`
+	DELETE FROM
+		entries_data
+	WHERE
+		id IN (
+			SELECT
+				id
+			FROM
+				blobs
+			WHERE
+				is_pending = 1
+		)`); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
+	DELETE FROM
+		blobs
+	WHERE
+		is_pending = 1`)
+	if err != nil {
+		return err
+	}
+
+	abandoned, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if abandoned > 0 {
+		log.Printf("deleted data from %d abandoned uploads", abandoned)
+	}
+
+	return nil
+}
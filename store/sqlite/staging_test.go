@@ -0,0 +1,234 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/sqlite"
+)
+
+func TestFailedUploadLeavesNoData(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.NewWithChunkSize(dbPath, 5, false)
+
+	// Write enough data that the upload commits several batches of chunks before
+	// it fails.
+	errUploadInterrupted := errors.New("dummy upload interrupted")
+	reader := io.MultiReader(
+		strings.NewReader(strings.Repeat("A", 500)),
+		failingReader{err: errUploadInterrupted})
+
+	if err := dataStore.InsertEntry(context.Background(), reader, picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("dummy-id"),
+		Filename: "dummy-file.txt",
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+	}); !errors.Is(err, errUploadInterrupted) {
+		t.Fatalf("err=%v, want=%v", err, errUploadInterrupted)
+	}
+
+	raw := mustOpenRawDB(t, dbPath)
+	if got, want := mustCountRows(t, raw, "entries_data"), 0; got != want {
+		t.Errorf("chunks after failed upload=%d, want=%d", got, want)
+	}
+	if got, want := mustCountRows(t, raw, "blobs"), 0; got != want {
+		t.Errorf("blobs after failed upload=%d, want=%d", got, want)
+	}
+}
+
+func TestCanceledUploadLeavesNoData(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.NewWithChunkSize(dbPath, 5, false)
+
+	// Simulate a client that disconnects partway through its upload.
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := io.MultiReader(
+		strings.NewReader(strings.Repeat("A", 500)),
+		cancelingReader{cancel: cancel},
+		strings.NewReader(strings.Repeat("B", 500)))
+
+	if err := dataStore.InsertEntry(ctx, reader, picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("dummy-id"),
+		Filename: "dummy-file.txt",
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want=%v", err, context.Canceled)
+	}
+
+	raw := mustOpenRawDB(t, dbPath)
+	if got, want := mustCountRows(t, raw, "entries_data"), 0; got != want {
+		t.Errorf("chunks after canceled upload=%d, want=%d", got, want)
+	}
+	if got, want := mustCountRows(t, raw, "blobs"), 0; got != want {
+		t.Errorf("blobs after canceled upload=%d, want=%d", got, want)
+	}
+}
+
+func TestAbandonedUploadIsDeletedAtStartup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.New(dbPath, false)
+
+	input := "hello, world!"
+	if err := dataStore.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("complete-entry"),
+		Filename: "dummy-file.txt",
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+		Size:     mustParseFileSize(len(input)),
+	}); err != nil {
+		t.Fatalf("failed to insert file into sqlite: %v", err)
+	}
+
+	// Simulate an upload that's still in progress.
+	raw := mustOpenRawDB(t, dbPath)
+	if _, err := raw.Exec(`
+	INSERT INTO blobs (id, is_pending) VALUES ('pending-blob', 1);
+	INSERT INTO entries_data (id, chunk_index, chunk) VALUES
+		('pending-blob', 0, CAST('hello' AS BLOB)),
+		('pending-blob', 1, CAST('world' AS BLOB));`); err != nil {
+		t.Fatalf("failed to insert pending blob: %v", err)
+	}
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge database: %v", err)
+	}
+
+	if got, want := mustCountRows(t, raw, "entries_data WHERE id = 'pending-blob'"), 2; got != want {
+		t.Errorf("pending chunks after purge=%d, want=%d", got, want)
+	}
+
+	// Reopening the store simulates a restart, which abandons the upload.
+	dataStore = sqlite.New(dbPath, false)
+
+	if got, want := mustCountRows(t, raw, "entries_data WHERE id = 'pending-blob'"), 0; got != want {
+		t.Errorf("pending chunks after restart=%d, want=%d", got, want)
+	}
+	if got, want := mustCountRows(t, raw, "blobs WHERE id = 'pending-blob'"), 0; got != want {
+		t.Errorf("pending blobs after restart=%d, want=%d", got, want)
+	}
+
+	entryFile, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("complete-entry"))
+	if err != nil {
+		t.Fatalf("failed to get entry from DB: %v", err)
+	}
+
+	contents, err := io.ReadAll(entryFile)
+	if err != nil {
+		t.Fatalf("failed to read entry contents: %v", err)
+	}
+	if got, want := string(contents), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func TestUploadFailsIfDeletedAsAbandoned(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	dataStore := sqlite.NewWithChunkSize(dbPath, 5, false)
+	raw := mustOpenRawDB(t, dbPath)
+
+	// Simulate another PicoShare process that deletes the upload's pending data
+	// as abandoned while the upload is still in progress. The first part of the
+	// upload fills exactly one batch of 16 five-byte chunks, so the upload has
+	// committed its staged data and holds no lock when the deletion happens.
+	reader := io.MultiReader(
+		strings.NewReader(strings.Repeat("A", 16*5)),
+		abandoningReader{t: t, db: raw},
+		strings.NewReader(strings.Repeat("B", 500)))
+
+	err := dataStore.InsertEntry(context.Background(), reader, picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("dummy-id"),
+		Filename: "dummy-file.txt",
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+	})
+	if err == nil {
+		t.Fatalf("expected upload to fail after PicoShare deleted it as abandoned")
+	}
+
+	if got, want := mustCountRows(t, raw, "entries"), 0; got != want {
+		t.Errorf("entries after abandoned upload=%d, want=%d", got, want)
+	}
+	if got, want := mustCountRows(t, raw, "blobs"), 0; got != want {
+		t.Errorf("blobs after abandoned upload=%d, want=%d", got, want)
+	}
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// cancelingReader cancels a context when a caller reads from it.
+type cancelingReader struct {
+	cancel context.CancelFunc
+}
+
+func (r cancelingReader) Read([]byte) (int, error) {
+	r.cancel()
+	return 0, io.EOF
+}
+
+// abandoningReader deletes every pending upload when a caller reads from it.
+type abandoningReader struct {
+	t  *testing.T
+	db *sql.DB
+}
+
+func (r abandoningReader) Read([]byte) (int, error) {
+	if _, err := r.db.Exec(`
+	DELETE FROM entries_data WHERE id IN (SELECT id FROM blobs WHERE is_pending = 1);
+	DELETE FROM blobs WHERE is_pending = 1;`); err != nil {
+		r.t.Fatalf("failed to delete pending uploads: %v", err)
+	}
+	return 0, io.EOF
+}
+
+func mustOpenRawDB(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustCountRows(t *testing.T, db *sql.DB, from string) int {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + from).Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return count
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustParseExpirationTime(s string) picoshare.ExpirationTime {
+	return picoshare.ExpirationTime(mustParseTime(s))
+}
+
+func mustParseFileSize(val int) picoshare.FileSize {
+	fileSize, err := picoshare.FileSizeFromInt(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return fileSize
+}
@@ -0,0 +1,235 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+const webhookDeliveryColumns = `
+		id,
+		event_type,
+		url,
+		payload,
+		state,
+		attempts,
+		creation_time,
+		next_attempt_time,
+		last_attempt_time,
+		last_status_code,
+		last_error`
+
+// InsertWebhookDelivery adds an event to the queue for the event webhook.
+func (s Store) InsertWebhookDelivery(ctx context.Context, d picoshare.WebhookDelivery) error {
+	if _, err := s.ctx.ExecContext(ctx, `
+	INSERT INTO
+		webhook_deliveries
+	(`+webhookDeliveryColumns+`
+	)
+	VALUES(:id, :event_type, :url, :payload, :state, :attempts, :creation_time, :next_attempt_time, :last_attempt_time, :last_status_code, :last_error)`,
+		sql.Named("id", d.ID),
+		sql.Named("event_type", d.EventType),
+		sql.Named("url", d.URL),
+		sql.Named("payload", string(d.Payload)),
+		sql.Named("state", d.State),
+		sql.Named("attempts", d.Attempts),
+		sql.Named("creation_time", formatTime(d.Created)),
+		sql.Named("next_attempt_time", nullableTime(d.NextAttempt)),
+		sql.Named("last_attempt_time", nullableTime(d.LastAttempt)),
+		sql.Named("last_status_code", nullableStatusCode(d.LastStatusCode)),
+		sql.Named("last_error", sql.NullString{String: d.LastError, Valid: d.LastError != ""})); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt to deliver an event.
+func (s Store) UpdateWebhookDelivery(ctx context.Context, d picoshare.WebhookDelivery) error {
+	res, err := s.ctx.ExecContext(ctx, `
+	UPDATE
+		webhook_deliveries
+	SET
+		state = :state,
+		attempts = :attempts,
+		next_attempt_time = :next_attempt_time,
+		last_attempt_time = :last_attempt_time,
+		last_status_code = :last_status_code,
+		last_error = :last_error,
+		claimed_until = NULL
+	WHERE
+		id = :id`,
+		sql.Named("id", d.ID),
+		sql.Named("state", d.State),
+		sql.Named("attempts", d.Attempts),
+		sql.Named("next_attempt_time", nullableTime(d.NextAttempt)),
+		sql.Named("last_attempt_time", nullableTime(d.LastAttempt)),
+		sql.Named("last_status_code", nullableStatusCode(d.LastStatusCode)),
+		sql.Named("last_error", sql.NullString{String: d.LastError, Valid: d.LastError != ""}))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return store.WebhookDeliveryNotFoundError{ID: d.ID}
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit of the most recent webhook
+// deliveries, newest first.
+func (s Store) GetWebhookDeliveries(ctx context.Context, limit int) ([]picoshare.WebhookDelivery, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	SELECT`+webhookDeliveryColumns+`
+	FROM
+		webhook_deliveries
+	ORDER BY
+		creation_time DESC,
+		rowid DESC
+	LIMIT :limit`, sql.Named("limit", limit))
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+	defer rows.Close()
+
+	return webhookDeliveriesFromRows(rows)
+}
+
+// ClaimDueWebhookDeliveries claims up to limit of the pending webhook
+// deliveries that are due for another attempt at the given time and that no
+// other sender has claimed. The claim lasts until claimedUntil or until
+// UpdateWebhookDelivery records the outcome of the attempt. It returns the
+// claimed deliveries, oldest first.
+func (s Store) ClaimDueWebhookDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]picoshare.WebhookDelivery, error) {
+	rows, err := s.ctx.QueryContext(ctx, `
+	UPDATE
+		webhook_deliveries
+	SET
+		claimed_until = :claimed_until
+	WHERE
+		id IN (
+			SELECT
+				id
+			FROM
+				webhook_deliveries
+			WHERE
+				state = :state AND
+				next_attempt_time <= :now AND
+				(claimed_until IS NULL OR claimed_until <= :now)
+			ORDER BY
+				creation_time ASC,
+				rowid ASC
+			LIMIT :limit
+		)
+	RETURNING`+webhookDeliveryColumns,
+		sql.Named("claimed_until", formatTime(claimedUntil)),
+		sql.Named("state", picoshare.WebhookDeliveryPending),
+		sql.Named("now", formatTime(now)),
+		sql.Named("limit", limit))
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+	defer rows.Close()
+
+	deliveries, err := webhookDeliveriesFromRows(rows)
+	if err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+
+	// RETURNING doesn't preserve the order of the subquery.
+	slices.SortStableFunc(deliveries, func(a, b picoshare.WebhookDelivery) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	return deliveries, nil
+}
+
+// DeleteWebhookDeliveriesBefore deletes webhook deliveries that PicoShare
+// created before the given time and is no longer trying to deliver.
+func (s Store) DeleteWebhookDeliveriesBefore(ctx context.Context, t time.Time) error {
+	if _, err := s.ctx.ExecContext(ctx, `
+	DELETE FROM
+		webhook_deliveries
+	WHERE
+		state != :pending AND
+		creation_time < :cutoff`,
+		sql.Named("pending", picoshare.WebhookDeliveryPending),
+		sql.Named("cutoff", formatTime(t))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func webhookDeliveriesFromRows(rows *sql.Rows) ([]picoshare.WebhookDelivery, error) {
+	deliveries := []picoshare.WebhookDelivery{}
+	for rows.Next() {
+		var id, eventType, url, payload, state string
+		var attempts uint
+		var creationTimeRaw string
+		var nextAttemptRaw, lastAttemptRaw, lastError sql.NullString
+		var lastStatusCode sql.NullInt64
+		if err := rows.Scan(&id, &eventType, &url, &payload, &state, &attempts, &creationTimeRaw, &nextAttemptRaw, &lastAttemptRaw, &lastStatusCode, &lastError); err != nil {
+			return []picoshare.WebhookDelivery{}, err
+		}
+
+		created, err := parseDatetime(creationTimeRaw)
+		if err != nil {
+			return []picoshare.WebhookDelivery{}, err
+		}
+		nextAttempt, err := parseNullableDatetime(nextAttemptRaw)
+		if err != nil {
+			return []picoshare.WebhookDelivery{}, err
+		}
+		lastAttempt, err := parseNullableDatetime(lastAttemptRaw)
+		if err != nil {
+			return []picoshare.WebhookDelivery{}, err
+		}
+
+		deliveries = append(deliveries, picoshare.WebhookDelivery{
+			ID:             picoshare.WebhookDeliveryID(id),
+			EventType:      picoshare.WebhookEventType(eventType),
+			URL:            url,
+			Payload:        []byte(payload),
+			State:          picoshare.WebhookDeliveryState(state),
+			Attempts:       attempts,
+			Created:        created,
+			NextAttempt:    nextAttempt,
+			LastAttempt:    lastAttempt,
+			LastStatusCode: int(lastStatusCode.Int64),
+			LastError:      lastError.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return []picoshare.WebhookDelivery{}, err
+	}
+
+	return deliveries, nil
+}
+
+func nullableTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(t), Valid: true}
+}
+
+func parseNullableDatetime(raw sql.NullString) (time.Time, error) {
+	if !raw.Valid {
+		return time.Time{}, nil
+	}
+	return parseDatetime(raw.String)
+}
+
+func nullableStatusCode(code int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(code), Valid: code != 0}
+}
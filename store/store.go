@@ -15,6 +15,25 @@ func (f EntryNotFoundError) Error() string {
 	return fmt.Sprintf("Could not find entry with ID %v", f.ID)
 }
 
+// EntrySlugNotFoundError occurs when no entry has the given slug.
+type EntrySlugNotFoundError struct {
+	Slug picoshare.EntrySlug
+}
+
+func (f EntrySlugNotFoundError) Error() string {
+	return fmt.Sprintf("Could not find entry with slug %v", f.Slug)
+}
+
+// EntrySlugConflictError occurs when a client tries to give an entry a slug
+// that another entry already uses.
+type EntrySlugConflictError struct {
+	Slug picoshare.EntrySlug
+}
+
+func (e EntrySlugConflictError) Error() string {
+	return fmt.Sprintf("another file already uses the link %v", e.Slug)
+}
+
 // GuestLinkNotFoundError occurs when no guest link exists with the given ID.
 type GuestLinkNotFoundError struct {
 	ID picoshare.GuestLinkID
@@ -23,3 +42,44 @@ type GuestLinkNotFoundError struct {
 func (f GuestLinkNotFoundError) Error() string {
 	return fmt.Sprintf("Could not find guest link with ID %v", f.ID)
 }
+
+// ChecksumMismatchError occurs when the contents of an upload don't match the
+// checksum that the client expected.
+type ChecksumMismatchError struct {
+	Expected picoshare.SHA256Checksum
+	Actual   picoshare.SHA256Checksum
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("upload has SHA-256 checksum %v, but client expected %v", e.Actual, e.Expected)
+}
+
+// EntryCorruptedError occurs when an entry's stored data is damaged.
+type EntryCorruptedError struct {
+	ID     picoshare.EntryID
+	Reason string
+}
+
+func (e EntryCorruptedError) Error() string {
+	return fmt.Sprintf("data for entry %v is corrupted: %s", e.ID, e.Reason)
+}
+
+// EntryQuarantinedError occurs when a client tries to read an entry that
+// PicoShare quarantined because its data is corrupted.
+type EntryQuarantinedError struct {
+	ID picoshare.EntryID
+}
+
+func (e EntryQuarantinedError) Error() string {
+	return fmt.Sprintf("entry %v is quarantined because its data is corrupted", e.ID)
+}
+
+// WebhookDeliveryNotFoundError occurs when no webhook delivery exists with the
+// given ID.
+type WebhookDeliveryNotFoundError struct {
+	ID picoshare.WebhookDeliveryID
+}
+
+func (e WebhookDeliveryNotFoundError) Error() string {
+	return fmt.Sprintf("Could not find webhook delivery with ID %v", e.ID)
+}
@@ -0,0 +1,252 @@
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+// concurrentClients is the number of simultaneous clients in the concurrency
+// tests.
+const concurrentClients = 8
+
+func testConcurrentUploads(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	contents := func(i int) string {
+		return strings.Repeat(fmt.Sprintf("contents of upload %d\n", i), 20)
+	}
+
+	errs := make(chan error, concurrentClients)
+	var wg sync.WaitGroup
+	for i := range concurrentClients {
+		wg.Go(func() {
+			input := contents(i)
+			errs <- dataStore.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+				ID:       picoshare.EntryID(fmt.Sprintf("entry-%d", i)),
+				Filename: picoshare.Filename("dummy-file.txt"),
+				Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+				Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+				Size:     mustParseFileSize(len(input)),
+			})
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent upload failed: %v", err)
+		}
+	}
+
+	for i := range concurrentClients {
+		id := picoshare.EntryID(fmt.Sprintf("entry-%d", i))
+		if got, want := mustReadEntry(t, dataStore, id), contents(i); got != want {
+			t.Errorf("contents of %v=%s, want=%s", id, got, want)
+		}
+	}
+}
+
+func testConcurrentIdenticalUploads(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := strings.Repeat("the same upload from every client\n", 20)
+	errs := make(chan error, concurrentClients)
+	var wg sync.WaitGroup
+	for i := range concurrentClients {
+		wg.Go(func() {
+			errs <- dataStore.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+				ID:       picoshare.EntryID(fmt.Sprintf("entry-%d", i)),
+				Filename: picoshare.Filename("dummy-file.txt"),
+				Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+				Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+				Size:     mustParseFileSize(len(input)),
+			})
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent upload failed: %v", err)
+		}
+	}
+
+	want := []picoshare.EntryID{}
+	for i := range concurrentClients {
+		want = append(want, picoshare.EntryID(fmt.Sprintf("entry-%d", i)))
+	}
+
+	entries, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(entries), want; !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries=%v, want=%v", got, want)
+	}
+
+	// Deleting all but one entry must leave the last one's data intact, no
+	// matter how the store shared data among the concurrent uploads.
+	for _, id := range want[1:] {
+		if err := dataStore.DeleteEntry(context.Background(), id); err != nil {
+			t.Fatalf("failed to delete entry: %v", err)
+		}
+	}
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge store: %v", err)
+	}
+
+	if got, want := mustReadEntry(t, dataStore, want[0]), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func testConcurrentReads(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := strings.Repeat("read by many clients at once\n", 20)
+	mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID: picoshare.EntryID("dummy-id"),
+	})
+
+	errs := make(chan error, concurrentClients)
+	var wg sync.WaitGroup
+	for i := range concurrentClients {
+		wg.Go(func() {
+			entryFile, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("dummy-id"))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			// Each client starts reading from a different offset, as if they
+			// each requested a different byte range.
+			offset := int64(i * len(input) / concurrentClients)
+			if _, err := entryFile.Seek(offset, io.SeekStart); err != nil {
+				errs <- err
+				return
+			}
+
+			contents, err := io.ReadAll(entryFile)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if got, want := string(contents), input[offset:]; got != want {
+				errs <- fmt.Errorf("contents from offset %d=%s, want=%s", offset, got, want)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent read failed: %v", err)
+	}
+}
+
+func testConcurrentFirstDownloads(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.EntryID("dummy-id")
+	mustInsertEntry(t, dataStore, "hello, world!", picoshare.UploadMetadata{
+		ID: id,
+	})
+
+	firsts := make(chan bool, concurrentClients)
+	errs := make(chan error, concurrentClients)
+	var wg sync.WaitGroup
+	for range concurrentClients {
+		wg.Go(func() {
+			first, err := dataStore.MarkEntryDownloaded(context.Background(), id)
+			if err != nil {
+				errs <- err
+				return
+			}
+			firsts <- first
+		})
+	}
+	wg.Wait()
+	close(firsts)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent download failed: %v", err)
+	}
+
+	firstCount := 0
+	for first := range firsts {
+		if first {
+			firstCount++
+		}
+	}
+	if got, want := firstCount, 1; got != want {
+		t.Errorf("first downloads=%d, want=%d", got, want)
+	}
+}
+
+func testConcurrentWebhookDeliveryClaims(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	const deliveryCount = 20
+	for i := range deliveryCount {
+		if err := dataStore.InsertWebhookDelivery(context.Background(), picoshare.WebhookDelivery{
+			ID:          picoshare.WebhookDeliveryID(fmt.Sprintf("delivery-%02d", i)),
+			EventType:   picoshare.WebhookEventEntryCreated,
+			URL:         "https://hooks.example.com/picoshare-events",
+			Payload:     []byte(`{}`),
+			State:       picoshare.WebhookDeliveryPending,
+			Created:     now.Add(-time.Duration(deliveryCount-i) * time.Minute),
+			NextAttempt: now,
+		}); err != nil {
+			t.Fatalf("failed to insert webhook delivery: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	claims := map[picoshare.WebhookDeliveryID]int{}
+	var wg sync.WaitGroup
+	for range concurrentClients {
+		wg.Go(func() {
+			for {
+				due, err := dataStore.ClaimDueWebhookDeliveries(context.Background(), now, now.Add(10*time.Minute), 3)
+				if err != nil {
+					t.Errorf("failed to claim due webhook deliveries: %v", err)
+					return
+				}
+				if len(due) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, d := range due {
+					claims[d.ID]++
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if got, want := len(claims), deliveryCount; got != want {
+		t.Errorf("claimed deliveries=%d, want=%d", got, want)
+	}
+	for id, n := range claims {
+		if n != 1 {
+			t.Errorf("delivery %v claimed %d times, want once", id, n)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func testDownloadRecords(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.EntryID("dummy-id")
+	mustInsertEntry(t, dataStore, "hello, world!", picoshare.UploadMetadata{
+		ID: id,
+	})
+
+	downloads, err := dataStore.GetEntryDownloads(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get downloads: %v", err)
+	}
+
+	if got, want := len(downloads), 0; got != want {
+		t.Fatalf("download count before any downloads=%d, want=%d", got, want)
+	}
+
+	first := picoshare.DownloadRecord{
+		Time:      mustParseTime("2025-05-26T00:00:00Z"),
+		ClientIP:  "1.2.3.4",
+		UserAgent: "curl/8.0.1",
+	}
+	second := picoshare.DownloadRecord{
+		Time:      mustParseTime("2025-05-27T00:00:00Z"),
+		ClientIP:  "5.6.7.8",
+		UserAgent: "Mozilla/5.0",
+	}
+	for _, r := range []picoshare.DownloadRecord{first, second} {
+		if err := dataStore.InsertEntryDownload(context.Background(), id, r); err != nil {
+			t.Fatalf("failed to insert download: %v", err)
+		}
+	}
+
+	downloads, err = dataStore.GetEntryDownloads(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get downloads: %v", err)
+	}
+
+	// The store returns the most recent downloads first.
+	want := []picoshare.DownloadRecord{second, first}
+	if got, want := len(downloads), len(want); got != want {
+		t.Fatalf("download count=%d, want=%d", got, want)
+	}
+	for i := range want {
+		if got, want := downloads[i], want[i]; !got.Time.Equal(want.Time) || got.ClientIP != want.ClientIP || got.UserAgent != want.UserAgent {
+			t.Errorf("download[%d]=%+v, want=%+v", i, got, want)
+		}
+	}
+}
+
+func testDeleteEntryDeletesDownloads(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.EntryID("dummy-id")
+	mustInsertEntry(t, dataStore, "hello, world!", picoshare.UploadMetadata{
+		ID: id,
+	})
+
+	if err := dataStore.InsertEntryDownload(context.Background(), id, picoshare.DownloadRecord{
+		Time:      mustParseTime("2025-05-26T00:00:00Z"),
+		ClientIP:  "1.2.3.4",
+		UserAgent: "curl/8.0.1",
+	}); err != nil {
+		t.Fatalf("failed to insert download: %v", err)
+	}
+
+	if err := dataStore.DeleteEntry(context.Background(), id); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	// If the store reuses the ID for a new entry, the new entry shouldn't
+	// inherit the old entry's downloads.
+	mustInsertEntry(t, dataStore, "new contents", picoshare.UploadMetadata{
+		ID: id,
+	})
+
+	downloads, err := dataStore.GetEntryDownloads(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get downloads: %v", err)
+	}
+
+	if got, want := len(downloads), 0; got != want {
+		t.Errorf("download count=%d, want=%d", got, want)
+	}
+}
+
+func testMarkEntryDownloaded(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.EntryID("dummy-id")
+	mustInsertEntry(t, dataStore, "hello, world!", picoshare.UploadMetadata{
+		ID: id,
+	})
+
+	for _, want := range []bool{true, false} {
+		first, err := dataStore.MarkEntryDownloaded(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to mark entry as downloaded: %v", err)
+		}
+		if got := first; got != want {
+			t.Errorf("first=%v, want=%v", got, want)
+		}
+	}
+
+	first, err := dataStore.MarkEntryDownloaded(context.Background(), picoshare.EntryID("missing-id"))
+	if err != nil {
+		t.Fatalf("failed to mark missing entry as downloaded: %v", err)
+	}
+	if got, want := first, false; got != want {
+		t.Errorf("first for missing entry=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/encryption"
+)
+
+const dummyOtherMasterKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+
+type (
+	// KeyRotator is a datastore that can re-wrap the data keys of its encrypted
+	// entries under a new master key.
+	KeyRotator interface {
+		Store
+		RotateEncryptionKey(context.Context, encryption.MasterKey) (int, error)
+	}
+
+	// ReopenStoreFn opens another datastore on the same database with a
+	// different master key.
+	ReopenStoreFn func(masterKey encryption.MasterKey) Store
+
+	// NewKeyRotatorFn creates an empty datastore with the given options and a
+	// function that reopens its database with a different master key.
+	NewKeyRotatorFn func(t *testing.T, opts Options) (KeyRotator, ReopenStoreFn)
+)
+
+// RunKeyRotation checks that a datastore's key rotation leaves every encrypted
+// entry readable with the new master key and only with the new master key.
+func RunKeyRotation(t *testing.T, newStore NewKeyRotatorFn) {
+	oldKey := mustParseMasterKey(dummyMasterKey)
+	newKey := mustParseMasterKey(dummyOtherMasterKey)
+	dataStore, reopen := newStore(t, Options{ChunkSize: defaultChunkSize, MasterKey: &oldKey})
+
+	inputs := map[picoshare.EntryID]string{
+		"entry-a": "hello, world!",
+		"entry-b": "goodbye, world!",
+	}
+	for id, input := range inputs {
+		mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+			ID: id,
+		})
+	}
+
+	n, err := dataStore.RotateEncryptionKey(context.Background(), newKey)
+	if err != nil {
+		t.Fatalf("failed to rotate encryption key: %v", err)
+	}
+	if got, want := n, len(inputs); got != want {
+		t.Errorf("rotated=%d, want=%d", got, want)
+	}
+
+	rotatedStore := reopen(newKey)
+	for id, input := range inputs {
+		if got, want := mustReadEntry(t, rotatedStore, id), input; got != want {
+			t.Errorf("contents=%s, want=%s", got, want)
+		}
+	}
+
+	staleStore := reopen(oldKey)
+	if _, err := staleStore.ReadEntryFile(context.Background(), picoshare.EntryID("entry-a")); err == nil {
+		t.Errorf("expected error reading entry with the old key after rotation")
+	}
+}
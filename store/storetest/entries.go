@@ -0,0 +1,565 @@
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+const dummyMasterKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func testInsertDeleteSingleEntry(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := "hello, world!"
+	mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID: picoshare.EntryID("dummy-id"),
+	})
+
+	if got, want := mustReadEntry(t, dataStore, picoshare.EntryID("dummy-id")), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+
+	meta, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := len(meta), 1; got != want {
+		t.Fatalf("entry count=%d, want=%d", got, want)
+	}
+
+	if got, want := meta[0].Size, mustParseFileSize(len(input)); !got.Equal(want) {
+		t.Errorf("size=%v, want=%v", got, want)
+	}
+
+	if err := dataStore.DeleteEntry(context.Background(), picoshare.EntryID("dummy-id")); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	meta, err = dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := len(meta), 0; got != want {
+		t.Errorf("entry count=%d, want=%d", got, want)
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), uint64(0); got != want {
+		t.Errorf("stored size after deleting all entries=%d, want=%d", got, want)
+	}
+}
+
+func testEntryMetadataRoundTrip(t *testing.T, newStore NewStoreFn) {
+	note := "for the quarterly review"
+	for _, tt := range []struct {
+		description string
+		meta        picoshare.UploadMetadata
+	}{
+		{
+			description: "entry with all optional fields",
+			meta: picoshare.UploadMetadata{
+				ID:          picoshare.EntryID("dummy-id"),
+				Slug:        picoshare.EntrySlug("q3-report"),
+				Filename:    picoshare.Filename("report.pdf"),
+				Note:        picoshare.FileNote{Value: &note},
+				ContentType: picoshare.ContentType("application/pdf"),
+				Uploaded:    mustParseTime("2025-05-25T01:02:03Z"),
+				Expires:     mustParseExpirationTime("2040-01-01T04:05:06Z"),
+				SHA256:      picoshare.SHA256Checksum("68e656b251e67e8358bef8483ab0d51c6619f3e7a1a9f0e75838d41ff368f728"),
+				Sender: picoshare.GuestSender{
+					Name:  picoshare.SenderName("Jane Doe"),
+					Email: picoshare.SenderEmail("jane@example.com"),
+				},
+			},
+		},
+		{
+			description: "entry with no note that never expires",
+			meta: picoshare.UploadMetadata{
+				ID:          picoshare.EntryID("dummy-id"),
+				Filename:    picoshare.Filename("report.pdf"),
+				ContentType: picoshare.ContentType("application/pdf"),
+				Uploaded:    mustParseTime("2025-05-25T01:02:03Z"),
+				Expires:     picoshare.NeverExpire,
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newDefaultStore(t, newStore)
+
+			want := mustInsertEntry(t, dataStore, "hello, world!", tt.meta)
+
+			got, err := dataStore.GetEntryMetadata(context.Background(), want.ID)
+			if err != nil {
+				t.Fatalf("failed to get entry metadata: %v", err)
+			}
+
+			assertMetadataEqual(t, got, want)
+		})
+	}
+}
+
+func testGetEntriesMetadata(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	meta, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(meta), []picoshare.EntryID{}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries in empty store=%v, want=%v", got, want)
+	}
+
+	inserted := map[picoshare.EntryID]picoshare.UploadMetadata{}
+	for _, id := range []picoshare.EntryID{"entry-c", "entry-a", "entry-b"} {
+		inserted[id] = mustInsertEntry(t, dataStore, "contents of "+id.String(), picoshare.UploadMetadata{
+			ID:          id,
+			Filename:    picoshare.Filename(id.String() + ".txt"),
+			ContentType: picoshare.ContentType("text/plain"),
+			Sender: picoshare.GuestSender{
+				Email: picoshare.SenderEmail(id.String() + "@example.com"),
+			},
+		})
+	}
+
+	meta, err = dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(meta), []picoshare.EntryID{"entry-a", "entry-b", "entry-c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries=%v, want=%v", got, want)
+	}
+
+	for _, m := range meta {
+		assertMetadataEqual(t, m, inserted[m.ID])
+	}
+}
+
+func testUpdateEntryMetadata(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := "hello, world!"
+	original := mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("dummy-id"),
+		Filename:    picoshare.Filename("old-name.txt"),
+		ContentType: picoshare.ContentType("text/plain"),
+		// Updating the metadata shouldn't erase the sender.
+		Sender: picoshare.GuestSender{
+			Name: picoshare.SenderName("Jane Doe"),
+		},
+	})
+
+	note := "updated note"
+	want := original
+	want.Filename = picoshare.Filename("new-name.txt")
+	want.Slug = picoshare.EntrySlug("new-name")
+	want.Note = picoshare.FileNote{Value: &note}
+	want.Expires = mustParseExpirationTime("2041-02-03T04:05:06Z")
+	if err := dataStore.UpdateEntryMetadata(context.Background(), original.ID, want); err != nil {
+		t.Fatalf("failed to update entry metadata: %v", err)
+	}
+
+	got, err := dataStore.GetEntryMetadata(context.Background(), original.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry metadata: %v", err)
+	}
+
+	assertMetadataEqual(t, got, want)
+
+	if got, want := mustReadEntry(t, dataStore, original.ID), input; got != want {
+		t.Errorf("contents after metadata update=%s, want=%s", got, want)
+	}
+
+	err = dataStore.UpdateEntryMetadata(context.Background(), picoshare.EntryID("missing-id"), want)
+	if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.EntryNotFoundError{ID: "missing-id"})
+	}
+}
+
+func testEntrySlugs(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	first := mustInsertEntry(t, dataStore, "first file", picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("entry-1"),
+		Filename: picoshare.Filename("first.txt"),
+	})
+	second := mustInsertEntry(t, dataStore, "second file", picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("entry-2"),
+		Filename: picoshare.Filename("second.txt"),
+	})
+	slug := picoshare.EntrySlug("q3-report")
+
+	_, err := dataStore.GetEntryIDBySlug(context.Background(), slug)
+	if _, ok := errors.AsType[store.EntrySlugNotFoundError](err); !ok {
+		t.Fatalf("err before setting slug=%v, want=%v", err, store.EntrySlugNotFoundError{Slug: slug})
+	}
+
+	first.Slug = slug
+	if err := dataStore.UpdateEntryMetadata(context.Background(), first.ID, first); err != nil {
+		t.Fatalf("failed to set slug: %v", err)
+	}
+
+	id, err := dataStore.GetEntryIDBySlug(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("failed to look up entry by slug: %v", err)
+	}
+	if got, want := id, first.ID; got != want {
+		t.Errorf("id=%v, want=%v", got, want)
+	}
+
+	// Saving an entry again keeps its own slug.
+	if err := dataStore.UpdateEntryMetadata(context.Background(), first.ID, first); err != nil {
+		t.Errorf("failed to save entry with unchanged slug: %v", err)
+	}
+
+	second.Slug = slug
+	err = dataStore.UpdateEntryMetadata(context.Background(), second.ID, second)
+	if _, ok := errors.AsType[store.EntrySlugConflictError](err); !ok {
+		t.Fatalf("err when reusing slug=%v, want=%v", err, store.EntrySlugConflictError{Slug: slug})
+	}
+
+	// Once the first entry gives up the slug, the second entry can take it.
+	first.Slug = picoshare.EntrySlug("")
+	if err := dataStore.UpdateEntryMetadata(context.Background(), first.ID, first); err != nil {
+		t.Fatalf("failed to clear slug: %v", err)
+	}
+	if err := dataStore.UpdateEntryMetadata(context.Background(), second.ID, second); err != nil {
+		t.Fatalf("failed to reuse released slug: %v", err)
+	}
+
+	id, err = dataStore.GetEntryIDBySlug(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("failed to look up entry by slug: %v", err)
+	}
+	if got, want := id, second.ID; got != want {
+		t.Errorf("id after reusing slug=%v, want=%v", got, want)
+	}
+}
+
+func testMissingEntry(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+	id := picoshare.EntryID("missing-id")
+
+	_, err := dataStore.GetEntryMetadata(context.Background(), id)
+	if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+		t.Errorf("GetEntryMetadata err=%v, want=%v", err, store.EntryNotFoundError{ID: id})
+	}
+
+	_, err = dataStore.ReadEntryFile(context.Background(), id)
+	if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+		t.Errorf("ReadEntryFile err=%v, want=%v", err, store.EntryNotFoundError{ID: id})
+	}
+
+	// Deleting an entry that doesn't exist is not an error.
+	if err := dataStore.DeleteEntry(context.Background(), id); err != nil {
+		t.Errorf("DeleteEntry err=%v, want=%v", err, nil)
+	}
+}
+
+func testInsertEmptyEntry(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	err := dataStore.InsertEntry(context.Background(), bytes.NewBufferString(""), picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("dummy-id"),
+		Filename: picoshare.Filename("empty.txt"),
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+		Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+	})
+	if got, want := err, picoshare.ErrEmptyFile; got != want {
+		t.Fatalf("err=%v, want=%v", got, want)
+	}
+
+	_, err = dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("dummy-id"))
+	if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.EntryNotFoundError{ID: "dummy-id"})
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), uint64(0); got != want {
+		t.Errorf("stored size after rejected upload=%d, want=%d", got, want)
+	}
+}
+
+func testInsertEntryChecksum(t *testing.T, newStore NewStoreFn) {
+	input := "hello, world!"
+	for _, tt := range []struct {
+		description string
+		expected    picoshare.SHA256Checksum
+		err         error
+	}{
+		{
+			description: "stores checksum when client doesn't specify one",
+			expected:    picoshare.SHA256Checksum(""),
+			err:         nil,
+		},
+		{
+			description: "accepts upload that matches expected checksum",
+			expected:    picoshare.SHA256Checksum("68e656b251e67e8358bef8483ab0d51c6619f3e7a1a9f0e75838d41ff368f728"),
+			err:         nil,
+		},
+		{
+			description: "rejects upload that doesn't match expected checksum",
+			expected:    picoshare.SHA256Checksum("09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"),
+			err: store.ChecksumMismatchError{
+				Expected: picoshare.SHA256Checksum("09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"),
+				Actual:   picoshare.SHA256Checksum("68e656b251e67e8358bef8483ab0d51c6619f3e7a1a9f0e75838d41ff368f728"),
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newDefaultStore(t, newStore)
+
+			err := dataStore.InsertEntry(context.Background(), bytes.NewBufferString(input), picoshare.UploadMetadata{
+				ID:       picoshare.EntryID("dummy-id"),
+				Filename: picoshare.Filename("dummy-file.txt"),
+				Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+				Expires:  mustParseExpirationTime("2040-01-01T00:00:00Z"),
+				Size:     mustParseFileSize(len(input)),
+				SHA256:   tt.expected,
+			})
+			if got, want := err, tt.err; got != want {
+				t.Fatalf("err=%v, want=%v", got, want)
+			}
+
+			if tt.err != nil {
+				if got, want := mustGetStoredDataSize(t, dataStore), uint64(0); got != want {
+					t.Errorf("stored size after rejected upload=%d, want=%d", got, want)
+				}
+				return
+			}
+
+			meta, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("dummy-id"))
+			if err != nil {
+				t.Fatalf("failed to get entry metadata: %v", err)
+			}
+
+			if got, want := meta.SHA256, picoshare.SHA256Checksum("68e656b251e67e8358bef8483ab0d51c6619f3e7a1a9f0e75838d41ff368f728"); got != want {
+				t.Errorf("checksum=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func testRangeReads(t *testing.T, newStore NewStoreFn) {
+	input := "hello, world!"
+	for _, chunkSize := range []uint64{1, 5, 13, 1024} {
+		for _, tt := range []struct {
+			description string
+			offset      int64
+			whence      int
+			want        string
+		}{
+			{
+				description: "reads from start of file",
+				offset:      0,
+				whence:      io.SeekStart,
+				want:        input,
+			},
+			{
+				description: "reads from middle of file",
+				offset:      7,
+				whence:      io.SeekStart,
+				want:        input[7:],
+			},
+			{
+				description: "reads last byte of file",
+				offset:      1,
+				whence:      io.SeekEnd,
+				want:        "!",
+			},
+			{
+				description: "reads nothing past end of file",
+				offset:      int64(len(input)),
+				whence:      io.SeekStart,
+				want:        "",
+			},
+		} {
+			t.Run(tt.description, func(t *testing.T) {
+				dataStore := newStore(t, Options{ChunkSize: chunkSize})
+				mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+					ID: picoshare.EntryID("dummy-id"),
+				})
+
+				entryFile, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("dummy-id"))
+				if err != nil {
+					t.Fatalf("failed to read entry: %v", err)
+				}
+
+				if _, err := entryFile.Seek(tt.offset, tt.whence); err != nil {
+					t.Fatalf("failed to seek file reader: %v", err)
+				}
+
+				contents, err := io.ReadAll(entryFile)
+				if err != nil {
+					t.Fatalf("failed to read entry contents: %v", err)
+				}
+
+				if got, want := string(contents), tt.want; got != want {
+					t.Errorf("contents with chunk size %d=%s, want=%s", chunkSize, got, want)
+				}
+			})
+		}
+	}
+}
+
+func testReadCompressedEntry(t *testing.T, newStore NewStoreFn) {
+	chunkSize := uint64(100)
+	dataStore := newStore(t, Options{ChunkSize: chunkSize})
+
+	input := strings.Repeat("all work and no play makes jack a dull boy\n", 10)
+	mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("dummy-id"),
+		ContentType: picoshare.ContentType("text/plain"),
+	})
+
+	meta, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("dummy-id"))
+	if err != nil {
+		t.Fatalf("failed to get entry metadata: %v", err)
+	}
+
+	if got, want := meta.Size, mustParseFileSize(len(input)); !got.Equal(want) {
+		t.Errorf("size=%d, want=%d", got.UInt64(), want.UInt64())
+	}
+
+	if got, want := mustReadEntry(t, dataStore, picoshare.EntryID("dummy-id")), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+
+	if got, limit := mustGetStoredDataSize(t, dataStore), uint64(len(input)); got >= limit {
+		t.Errorf("stored size of compressible entry=%d, want less than %d", got, limit)
+	}
+
+	entryFile, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("dummy-id"))
+	if err != nil {
+		t.Fatalf("failed to read entry: %v", err)
+	}
+
+	// Seek to a position in the middle of a chunk to simulate a range request.
+	offset := int64(chunkSize + 7)
+	if _, err := entryFile.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("failed to seek file reader: %v", err)
+	}
+
+	contents, err := io.ReadAll(entryFile)
+	if err != nil {
+		t.Fatalf("failed to read entry contents: %v", err)
+	}
+
+	if got, want := string(contents), input[offset:]; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func testReadEncryptedEntry(t *testing.T, newStore NewStoreFn) {
+	masterKey := mustParseMasterKey(dummyMasterKey)
+	dataStore := newStore(t, Options{ChunkSize: 100, MasterKey: &masterKey})
+
+	input := strings.Repeat("all work and no play makes jack a dull boy\n", 10)
+	mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("dummy-id"),
+		ContentType: picoshare.ContentType("text/plain"),
+	})
+
+	meta, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("dummy-id"))
+	if err != nil {
+		t.Fatalf("failed to get entry metadata: %v", err)
+	}
+
+	if got, want := meta.Size, mustParseFileSize(len(input)); !got.Equal(want) {
+		t.Errorf("size=%d, want=%d", got.UInt64(), want.UInt64())
+	}
+
+	entryFile, err := dataStore.ReadEntryFile(context.Background(), picoshare.EntryID("dummy-id"))
+	if err != nil {
+		t.Fatalf("failed to read entry: %v", err)
+	}
+
+	if _, err := entryFile.Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("failed to seek file reader: %v", err)
+	}
+
+	contents, err := io.ReadAll(entryFile)
+	if err != nil {
+		t.Fatalf("failed to read entry contents: %v", err)
+	}
+
+	if got, want := string(contents), input[5:]; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func testIdenticalEntriesShareData(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := "hello, world!"
+	for _, id := range []picoshare.EntryID{"entry-a", "entry-b"} {
+		mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+			ID: id,
+		})
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), uint64(len(input)); got != want {
+		t.Errorf("stored size=%d, want=%d", got, want)
+	}
+
+	if err := dataStore.DeleteEntry(context.Background(), picoshare.EntryID("entry-a")); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	if got, want := mustReadEntry(t, dataStore, picoshare.EntryID("entry-b")), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+
+	if err := dataStore.DeleteEntry(context.Background(), picoshare.EntryID("entry-b")); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), uint64(0); got != want {
+		t.Errorf("stored size after deleting all entries=%d, want=%d", got, want)
+	}
+}
+
+func testDataSizes(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	sizes, err := dataStore.GetDataSizes(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get data sizes: %v", err)
+	}
+	if got, want := sizes, (picoshare.DataSizes{}); got != want {
+		t.Errorf("sizes of empty store=%+v, want=%+v", got, want)
+	}
+
+	shared := "hello, world!"
+	for _, id := range []picoshare.EntryID{"entry-a", "entry-b"} {
+		mustInsertEntry(t, dataStore, shared, picoshare.UploadMetadata{
+			ID: id,
+		})
+	}
+	unique := "goodbye!"
+	mustInsertEntry(t, dataStore, unique, picoshare.UploadMetadata{
+		ID: picoshare.EntryID("entry-c"),
+	})
+
+	sizes, err = dataStore.GetDataSizes(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get data sizes: %v", err)
+	}
+	// Identical entries count once per entry in the total but share their
+	// stored data.
+	if got, want := sizes.TotalBytes, uint64(2*len(shared)+len(unique)); got != want {
+		t.Errorf("total size=%d, want=%d", got, want)
+	}
+	if got, want := sizes.StoredBytes, mustGetStoredDataSize(t, dataStore); got != want {
+		t.Errorf("stored size=%d, want=%d", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package storetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func testEvictedEntries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	evicted, err := dataStore.GetEvictedEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get evicted entries: %v", err)
+	}
+
+	if got, want := len(evicted), 0; got != want {
+		t.Fatalf("evicted entry count before any evictions=%d, want=%d", got, want)
+	}
+
+	first := picoshare.EvictedEntry{
+		ID:       picoshare.EntryID("first-evicted"),
+		Filename: picoshare.Filename("first.txt"),
+		Size:     mustParseFileSize(1024),
+		Uploaded: mustParseTime("2025-01-01T00:00:00Z"),
+		Evicted:  mustParseTime("2025-06-01T00:00:00Z"),
+		Policy:   "oldest",
+	}
+	second := picoshare.EvictedEntry{
+		ID:       picoshare.EntryID("second-evicted"),
+		Filename: picoshare.Filename("second.txt"),
+		Size:     mustParseFileSize(2048),
+		Uploaded: mustParseTime("2025-02-01T00:00:00Z"),
+		Evicted:  mustParseTime("2025-06-02T00:00:00Z"),
+		Policy:   "largest",
+	}
+	for _, e := range []picoshare.EvictedEntry{first, second} {
+		if err := dataStore.InsertEvictedEntry(context.Background(), e); err != nil {
+			t.Fatalf("failed to insert evicted entry: %v", err)
+		}
+	}
+
+	evicted, err = dataStore.GetEvictedEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get evicted entries: %v", err)
+	}
+
+	// The store returns the most recent evictions first.
+	want := []picoshare.EvictedEntry{second, first}
+	if got, want := len(evicted), len(want); got != want {
+		t.Fatalf("evicted entry count=%d, want=%d", got, want)
+	}
+	for i := range want {
+		assertEvictedEntryEqual(t, evicted[i], want[i])
+	}
+}
+
+func testLatestDownloadTimes(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, id := range []picoshare.EntryID{"popular-entry", "ignored-entry"} {
+		mustInsertEntry(t, dataStore, "contents of "+id.String(), picoshare.UploadMetadata{
+			ID: id,
+		})
+	}
+
+	for _, downloadTime := range []string{
+		"2025-05-27T00:00:00Z",
+		"2025-05-29T00:00:00Z",
+		"2025-05-28T00:00:00Z",
+	} {
+		if err := dataStore.InsertEntryDownload(context.Background(), picoshare.EntryID("popular-entry"), picoshare.DownloadRecord{
+			Time:      mustParseTime(downloadTime),
+			ClientIP:  "1.2.3.4",
+			UserAgent: "curl/8.0.1",
+		}); err != nil {
+			t.Fatalf("failed to insert download: %v", err)
+		}
+	}
+
+	latest, err := dataStore.GetLatestDownloadTimes(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get latest download times: %v", err)
+	}
+
+	want := map[picoshare.EntryID]time.Time{
+		picoshare.EntryID("popular-entry"): mustParseTime("2025-05-29T00:00:00Z"),
+	}
+	if got, want := latest, want; !reflect.DeepEqual(got, want) {
+		t.Errorf("latest downloads=%v, want=%v", got, want)
+	}
+}
+
+func assertEvictedEntryEqual(t *testing.T, got, want picoshare.EvictedEntry) {
+	t.Helper()
+	if got.ID != want.ID {
+		t.Errorf("id=%v, want=%v", got.ID, want.ID)
+	}
+	if got.Filename != want.Filename {
+		t.Errorf("filename=%v, want=%v", got.Filename, want.Filename)
+	}
+	if !got.Size.Equal(want.Size) {
+		t.Errorf("size=%d, want=%d", got.Size.UInt64(), want.Size.UInt64())
+	}
+	if !got.Uploaded.Equal(want.Uploaded) {
+		t.Errorf("uploaded=%v, want=%v", got.Uploaded, want.Uploaded)
+	}
+	if !got.Evicted.Equal(want.Evicted) {
+		t.Errorf("evicted=%v, want=%v", got.Evicted, want.Evicted)
+	}
+	if got.Policy != want.Policy {
+		t.Errorf("policy=%v, want=%v", got.Policy, want.Policy)
+	}
+}
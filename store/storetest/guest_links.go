@@ -0,0 +1,485 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+func testGuestLinkRoundTrip(t *testing.T, newStore NewStoreFn) {
+	maxFileBytes := uint64(1024)
+	maxFileUploads := 3
+	maxTotalBytes := uint64(4096)
+	for _, tt := range []struct {
+		description string
+		guestLink   picoshare.GuestLink
+	}{
+		{
+			description: "guest link with limits",
+			guestLink: picoshare.GuestLink{
+				ID:                 picoshare.GuestLinkID("abcdefgh23456789"),
+				Label:              picoshare.GuestLinkLabel("for my friend"),
+				Created:            mustParseTime("2025-05-25T01:02:03Z"),
+				UrlExpires:         mustParseExpirationTime("2030-01-01T04:05:06Z"),
+				MaxFileLifetime:    picoshare.NewFileLifetimeInDays(7),
+				MaxFileBytes:       picoshare.GuestUploadMaxFileBytes(&maxFileBytes),
+				MaxFileUploads:     picoshare.GuestUploadCountLimit(&maxFileUploads),
+				MaxTotalBytes:      picoshare.GuestUploadMaxTotalBytes(&maxTotalBytes),
+				PassphraseHash:     picoshare.GuestLinkPassphraseHash("dummy-passphrase-hash"),
+				AllowedFileTypes:   picoshare.GuestUploadFileTypes{".pdf", "application/pdf"},
+				AllowSenderDetails: true,
+				UploadListing:      picoshare.GuestUploadListingSession,
+				Instructions:       picoshare.GuestLinkInstructions("Please upload your **tax forms**."),
+				UploadWindow:       picoshare.GuestLinkUploadWindow(24 * time.Hour),
+			},
+		},
+		{
+			description: "guest link without limits",
+			guestLink: picoshare.GuestLink{
+				ID:              picoshare.GuestLinkID("abcdefgh23456789"),
+				Created:         mustParseTime("2025-05-25T01:02:03Z"),
+				UrlExpires:      picoshare.NeverExpire,
+				MaxFileLifetime: picoshare.FileLifetimeInfinite,
+				MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+				MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+				MaxTotalBytes:   picoshare.GuestUploadUnlimitedTotalBytes,
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newDefaultStore(t, newStore)
+
+			if err := dataStore.InsertGuestLink(context.Background(), tt.guestLink); err != nil {
+				t.Fatalf("failed to insert guest link: %v", err)
+			}
+
+			got, err := dataStore.GetGuestLink(context.Background(), tt.guestLink.ID)
+			if err != nil {
+				t.Fatalf("failed to get guest link: %v", err)
+			}
+			assertGuestLinkEqual(t, got, tt.guestLink)
+
+			all, err := dataStore.GetGuestLinks(context.Background())
+			if err != nil {
+				t.Fatalf("failed to get guest links: %v", err)
+			}
+
+			if got, want := len(all), 1; got != want {
+				t.Fatalf("guest link count=%d, want=%d", got, want)
+			}
+			assertGuestLinkEqual(t, all[0], tt.guestLink)
+		})
+	}
+}
+
+func testGuestLinkFileCounts(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	busyLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	idleLinkID := picoshare.GuestLinkID("bcdefgh234567890")
+	for _, id := range []picoshare.GuestLinkID{busyLinkID, idleLinkID} {
+		mustInsertGuestLink(t, dataStore, id)
+	}
+
+	// Guests upload the same file twice, so the uploads share their data, but
+	// each upload still counts toward the guest link's total bytes.
+	guestUpload := "uploaded by a guest"
+	for _, id := range []picoshare.EntryID{"entry-1", "entry-2"} {
+		mustInsertEntry(t, dataStore, guestUpload, picoshare.UploadMetadata{
+			ID:        id,
+			GuestLink: picoshare.GuestLink{ID: busyLinkID},
+		})
+	}
+	mustInsertEntry(t, dataStore, "uploaded by admin", picoshare.UploadMetadata{
+		ID: picoshare.EntryID("entry-3"),
+	})
+
+	for _, tt := range []struct {
+		id        picoshare.GuestLinkID
+		wantFiles int
+		wantBytes uint64
+	}{
+		{busyLinkID, 2, uint64(2 * len(guestUpload))},
+		{idleLinkID, 0, 0},
+	} {
+		gl, err := dataStore.GetGuestLink(context.Background(), tt.id)
+		if err != nil {
+			t.Fatalf("failed to get guest link: %v", err)
+		}
+
+		if got, want := gl.FilesUploaded, tt.wantFiles; got != want {
+			t.Errorf("files uploaded via %v=%d, want=%d", tt.id, got, want)
+		}
+		if got, want := gl.BytesUploaded, tt.wantBytes; got != want {
+			t.Errorf("bytes uploaded via %v=%d, want=%d", tt.id, got, want)
+		}
+	}
+
+	all, err := dataStore.GetGuestLinks(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get guest links: %v", err)
+	}
+
+	counts := map[picoshare.GuestLinkID]int{}
+	sizes := map[picoshare.GuestLinkID]uint64{}
+	for _, gl := range all {
+		counts[gl.ID] = gl.FilesUploaded
+		sizes[gl.ID] = gl.BytesUploaded
+	}
+	if got, want := counts[busyLinkID], 2; got != want {
+		t.Errorf("files uploaded via %v in guest link list=%d, want=%d", busyLinkID, got, want)
+	}
+	if got, want := sizes[busyLinkID], uint64(2*len(guestUpload)); got != want {
+		t.Errorf("bytes uploaded via %v in guest link list=%d, want=%d", busyLinkID, got, want)
+	}
+	if got, want := counts[idleLinkID], 0; got != want {
+		t.Errorf("files uploaded via %v in guest link list=%d, want=%d", idleLinkID, got, want)
+	}
+
+	meta, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("entry-1"))
+	if err != nil {
+		t.Fatalf("failed to get entry metadata: %v", err)
+	}
+
+	if got, want := meta.GuestLink.ID, busyLinkID; got != want {
+		t.Errorf("guest link ID=%s, want=%s", got, want)
+	}
+
+	meta, err = dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("entry-3"))
+	if err != nil {
+		t.Fatalf("failed to get entry metadata: %v", err)
+	}
+
+	if got, want := meta.GuestLink.ID, picoshare.GuestLinkID(""); got != want {
+		t.Errorf("guest link ID of admin upload=%s, want=%s", got, want)
+	}
+
+	if err := dataStore.DeleteEntry(context.Background(), picoshare.EntryID("entry-1")); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	gl, err := dataStore.GetGuestLink(context.Background(), busyLinkID)
+	if err != nil {
+		t.Fatalf("failed to get guest link: %v", err)
+	}
+
+	if got, want := gl.FilesUploaded, 1; got != want {
+		t.Errorf("files uploaded after deleting one=%d, want=%d", got, want)
+	}
+	if got, want := gl.BytesUploaded, uint64(len(guestUpload)); got != want {
+		t.Errorf("bytes uploaded after deleting one=%d, want=%d", got, want)
+	}
+}
+
+func testEnableDisableGuestLink(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.GuestLinkID("abcdefgh23456789")
+	mustInsertGuestLink(t, dataStore, id)
+
+	for _, tt := range []struct {
+		description string
+		fn          func(context.Context, picoshare.GuestLinkID) error
+		want        bool
+	}{
+		{"disable guest link", dataStore.DisableGuestLink, true},
+		{"disable guest link again", dataStore.DisableGuestLink, true},
+		{"enable guest link", dataStore.EnableGuestLink, false},
+		{"enable guest link again", dataStore.EnableGuestLink, false},
+	} {
+		if err := tt.fn(context.Background(), id); err != nil {
+			t.Fatalf("%s: %v", tt.description, err)
+		}
+
+		gl, err := dataStore.GetGuestLink(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get guest link: %v", err)
+		}
+
+		if got, want := gl.IsDisabled, tt.want; got != want {
+			t.Errorf("%s: disabled=%v, want=%v", tt.description, got, want)
+		}
+	}
+}
+
+func testUpdateGuestLink(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	id := picoshare.GuestLinkID("abcdefgh23456789")
+	mustInsertGuestLink(t, dataStore, id)
+
+	gl, err := dataStore.GetGuestLink(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get guest link: %v", err)
+	}
+
+	if !gl.LastModified.IsZero() {
+		t.Errorf("last modified=%v for a guest link nobody has edited, want zero time", gl.LastModified)
+	}
+
+	maxFileBytes := uint64(1024 * 1024)
+	maxFileUploads := 3
+	maxTotalBytes := uint64(5 * 1024 * 1024)
+	edited := picoshare.GuestLink{
+		ID:                 id,
+		Label:              picoshare.GuestLinkLabel("edited label"),
+		Created:            mustParseTime("2025-05-25T00:00:00Z"),
+		UrlExpires:         picoshare.ExpirationTime(mustParseTime("2030-01-01T00:00:00Z")),
+		MaxFileLifetime:    picoshare.NewFileLifetimeInDays(7),
+		MaxFileBytes:       picoshare.GuestUploadMaxFileBytes(&maxFileBytes),
+		MaxFileUploads:     picoshare.GuestUploadCountLimit(&maxFileUploads),
+		MaxTotalBytes:      picoshare.GuestUploadMaxTotalBytes(&maxTotalBytes),
+		LastModified:       mustParseTime("2025-06-01T12:00:00Z"),
+		PassphraseHash:     picoshare.GuestLinkPassphraseHash("dummy-passphrase-hash"),
+		AllowedFileTypes:   picoshare.GuestUploadFileTypes{"image/*"},
+		AllowSenderDetails: true,
+		UploadWindow:       picoshare.GuestLinkUploadWindow(7 * 24 * time.Hour),
+	}
+	if err := dataStore.UpdateGuestLink(context.Background(), id, edited); err != nil {
+		t.Fatalf("failed to update guest link: %v", err)
+	}
+
+	gl, err = dataStore.GetGuestLink(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get guest link: %v", err)
+	}
+
+	assertGuestLinkEqual(t, gl, edited)
+
+	missingID := picoshare.GuestLinkID("missing234567890")
+	err = dataStore.UpdateGuestLink(context.Background(), missingID, edited)
+	if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.GuestLinkNotFoundError{ID: missingID})
+	}
+}
+
+func testDeleteGuestLinkKeepsEntries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	mustInsertGuestLink(t, dataStore, guestLinkID)
+
+	input := "uploaded by a guest"
+	mustInsertEntry(t, dataStore, input, picoshare.UploadMetadata{
+		ID:        picoshare.EntryID("dummy-id"),
+		GuestLink: picoshare.GuestLink{ID: guestLinkID},
+	})
+
+	if err := dataStore.DeleteGuestLink(context.Background(), guestLinkID); err != nil {
+		t.Fatalf("failed to delete guest link: %v", err)
+	}
+
+	_, err := dataStore.GetGuestLink(context.Background(), guestLinkID)
+	if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.GuestLinkNotFoundError{ID: guestLinkID})
+	}
+
+	meta, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("dummy-id"))
+	if err != nil {
+		t.Fatalf("failed to get entry metadata after deleting its guest link: %v", err)
+	}
+
+	if got, want := meta.GuestLink.ID, picoshare.GuestLinkID(""); got != want {
+		t.Errorf("guest link ID=%s, want=%s", got, want)
+	}
+
+	if got, want := mustReadEntry(t, dataStore, picoshare.EntryID("dummy-id")), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+}
+
+func testGetGuestLinkEntriesMetadata(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	otherLinkID := picoshare.GuestLinkID("zyxwvuts98765432")
+	mustInsertGuestLink(t, dataStore, guestLinkID)
+	mustInsertGuestLink(t, dataStore, otherLinkID)
+
+	older := mustInsertEntry(t, dataStore, "first upload", picoshare.UploadMetadata{
+		ID:           picoshare.EntryID("guest-older"),
+		GuestLink:    picoshare.GuestLink{ID: guestLinkID},
+		GuestSession: picoshare.GuestSessionKey("dummy-session-key"),
+		Uploaded:     mustParseTime("2025-05-25T01:00:00Z"),
+	})
+	newer := mustInsertEntry(t, dataStore, "second upload", picoshare.UploadMetadata{
+		ID:        picoshare.EntryID("guest-newer"),
+		GuestLink: picoshare.GuestLink{ID: guestLinkID},
+		Uploaded:  mustParseTime("2025-05-25T02:00:00Z"),
+	})
+	mustInsertEntry(t, dataStore, "other link", picoshare.UploadMetadata{
+		ID:        picoshare.EntryID("other-link"),
+		GuestLink: picoshare.GuestLink{ID: otherLinkID},
+	})
+	mustInsertEntry(t, dataStore, "owner upload", picoshare.UploadMetadata{
+		ID: picoshare.EntryID("owner-upload"),
+	})
+
+	entries, err := dataStore.GetGuestLinkEntriesMetadata(context.Background(), guestLinkID)
+	if err != nil {
+		t.Fatalf("failed to get guest link entries: %v", err)
+	}
+
+	// The store returns the most recent uploads first.
+	want := []picoshare.UploadMetadata{newer, older}
+	if got, want := len(entries), len(want); got != want {
+		t.Fatalf("guest link entry count=%d, want=%d", got, want)
+	}
+	for i := range want {
+		assertMetadataEqual(t, entries[i], want[i])
+		if got, want := entries[i].GuestSession, want[i].GuestSession; got != want {
+			t.Errorf("entry %d guest session=%v, want=%v", i, got, want)
+		}
+	}
+
+	entries, err = dataStore.GetGuestLinkEntriesMetadata(context.Background(), picoshare.GuestLinkID("missing234567890"))
+	if err != nil {
+		t.Fatalf("failed to get entries of missing guest link: %v", err)
+	}
+	if got, want := len(entries), 0; got != want {
+		t.Errorf("missing guest link entry count=%d, want=%d", got, want)
+	}
+}
+
+func testGuestLinkFirstUpload(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	guestLinkID := picoshare.GuestLinkID("abcdefgh23456789")
+	mustInsertGuestLink(t, dataStore, guestLinkID)
+
+	gl, err := dataStore.GetGuestLink(context.Background(), guestLinkID)
+	if err != nil {
+		t.Fatalf("failed to get guest link: %v", err)
+	}
+	if !gl.FirstUpload.IsZero() {
+		t.Errorf("first upload before any uploads=%v, want zero time", gl.FirstUpload)
+	}
+
+	mustInsertEntry(t, dataStore, "owner upload", picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("owner-upload"),
+		Uploaded: mustParseTime("2025-05-25T00:00:00Z"),
+	})
+	mustInsertEntry(t, dataStore, "first upload", picoshare.UploadMetadata{
+		ID:        picoshare.EntryID("guest-first"),
+		GuestLink: picoshare.GuestLink{ID: guestLinkID},
+		Uploaded:  mustParseTime("2025-05-25T01:00:00Z"),
+	})
+	mustInsertEntry(t, dataStore, "second upload", picoshare.UploadMetadata{
+		ID:        picoshare.EntryID("guest-second"),
+		GuestLink: picoshare.GuestLink{ID: guestLinkID},
+		Uploaded:  mustParseTime("2025-05-25T02:00:00Z"),
+	})
+
+	// Deleting the first upload doesn't restart the upload window.
+	if err := dataStore.DeleteEntry(context.Background(), picoshare.EntryID("guest-first")); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	gl, err = dataStore.GetGuestLink(context.Background(), guestLinkID)
+	if err != nil {
+		t.Fatalf("failed to get guest link: %v", err)
+	}
+	if got, want := gl.FirstUpload, mustParseTime("2025-05-25T01:00:00Z"); !got.Equal(want) {
+		t.Errorf("first upload=%v, want=%v", got, want)
+	}
+}
+
+func testMissingGuestLink(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+	id := picoshare.GuestLinkID("abcdefgh23456789")
+
+	_, err := dataStore.GetGuestLink(context.Background(), id)
+	if _, ok := errors.AsType[store.GuestLinkNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.GuestLinkNotFoundError{ID: id})
+	}
+
+	all, err := dataStore.GetGuestLinks(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get guest links: %v", err)
+	}
+
+	if got, want := len(all), 0; got != want {
+		t.Errorf("guest link count=%d, want=%d", got, want)
+	}
+}
+
+func mustInsertGuestLink(t *testing.T, dataStore Store, id picoshare.GuestLinkID) {
+	t.Helper()
+	if err := dataStore.InsertGuestLink(context.Background(), picoshare.GuestLink{
+		ID:              id,
+		Created:         mustParseTime("2025-05-25T00:00:00Z"),
+		UrlExpires:      picoshare.NeverExpire,
+		MaxFileLifetime: picoshare.FileLifetimeInfinite,
+		MaxFileBytes:    picoshare.GuestUploadUnlimitedFileSize,
+		MaxFileUploads:  picoshare.GuestUploadUnlimitedFileUploads,
+	}); err != nil {
+		t.Fatalf("failed to insert guest link %v: %v", id, err)
+	}
+}
+
+func assertGuestLinkEqual(t *testing.T, got, want picoshare.GuestLink) {
+	t.Helper()
+	if got.ID != want.ID {
+		t.Errorf("id=%v, want=%v", got.ID, want.ID)
+	}
+	if got.Label != want.Label {
+		t.Errorf("label=%v, want=%v", got.Label, want.Label)
+	}
+	if !got.Created.Equal(want.Created) {
+		t.Errorf("created=%v, want=%v", got.Created, want.Created)
+	}
+	if !time.Time(got.UrlExpires).Equal(time.Time(want.UrlExpires)) {
+		t.Errorf("url expires=%v, want=%v", got.UrlExpires, want.UrlExpires)
+	}
+	if !got.MaxFileLifetime.Equal(want.MaxFileLifetime) {
+		t.Errorf("max file lifetime=%v, want=%v", got.MaxFileLifetime, want.MaxFileLifetime)
+	}
+	if got, want := formatLimit(got.MaxFileBytes), formatLimit(want.MaxFileBytes); got != want {
+		t.Errorf("max file bytes=%s, want=%s", got, want)
+	}
+	if got, want := formatLimit(got.MaxFileUploads), formatLimit(want.MaxFileUploads); got != want {
+		t.Errorf("max file uploads=%s, want=%s", got, want)
+	}
+	if got, want := formatLimit(got.MaxTotalBytes), formatLimit(want.MaxTotalBytes); got != want {
+		t.Errorf("max total bytes=%s, want=%s", got, want)
+	}
+	if got.IsDisabled != want.IsDisabled {
+		t.Errorf("disabled=%v, want=%v", got.IsDisabled, want.IsDisabled)
+	}
+	if !got.LastModified.Equal(want.LastModified) {
+		t.Errorf("last modified=%v, want=%v", got.LastModified, want.LastModified)
+	}
+	if got.PassphraseHash != want.PassphraseHash {
+		t.Errorf("passphrase hash=%v, want=%v", got.PassphraseHash, want.PassphraseHash)
+	}
+	if got, want := got.AllowedFileTypes.String(), want.AllowedFileTypes.String(); got != want {
+		t.Errorf("allowed file types=%s, want=%s", got, want)
+	}
+	if got.AllowSenderDetails != want.AllowSenderDetails {
+		t.Errorf("allow sender details=%v, want=%v", got.AllowSenderDetails, want.AllowSenderDetails)
+	}
+	if got.UploadListing != want.UploadListing {
+		t.Errorf("upload listing=%v, want=%v", got.UploadListing, want.UploadListing)
+	}
+	if got.Instructions != want.Instructions {
+		t.Errorf("instructions=%q, want=%q", got.Instructions, want.Instructions)
+	}
+	if got.UploadWindow != want.UploadWindow {
+		t.Errorf("upload window=%v, want=%v", got.UploadWindow, want.UploadWindow)
+	}
+	if !got.FirstUpload.Equal(want.FirstUpload) {
+		t.Errorf("first upload=%v, want=%v", got.FirstUpload, want.FirstUpload)
+	}
+}
+
+func formatLimit[T uint64 | int](limit *T) string {
+	if limit == nil {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d", *limit)
+}
@@ -0,0 +1,169 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+func testCorruptEntries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, id := range []picoshare.EntryID{"flagged-entry", "quarantined-entry"} {
+		mustInsertEntry(t, dataStore, "contents of "+id.String(), picoshare.UploadMetadata{
+			ID:       id,
+			Filename: picoshare.Filename(id.String() + ".txt"),
+		})
+	}
+
+	flagged := picoshare.CorruptEntry{
+		ID:          picoshare.EntryID("flagged-entry"),
+		Filename:    picoshare.Filename("flagged-entry.txt"),
+		Detected:    mustParseTime("2025-06-01T00:00:00Z"),
+		Reason:      "chunk 1 is missing",
+		Quarantined: false,
+	}
+	quarantined := picoshare.CorruptEntry{
+		ID:          picoshare.EntryID("quarantined-entry"),
+		Filename:    picoshare.Filename("quarantined-entry.txt"),
+		Detected:    mustParseTime("2025-06-02T00:00:00Z"),
+		Reason:      "chunk 0 failed authentication",
+		Quarantined: true,
+	}
+	for _, ce := range []picoshare.CorruptEntry{flagged, quarantined} {
+		if err := dataStore.InsertCorruptEntry(context.Background(), ce); err != nil {
+			t.Fatalf("failed to insert corrupt entry: %v", err)
+		}
+	}
+
+	corrupt, err := dataStore.GetCorruptEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get corrupt entries: %v", err)
+	}
+
+	// The store returns the most recently detected corruption first.
+	want := []picoshare.CorruptEntry{quarantined, flagged}
+	if got, want := len(corrupt), len(want); got != want {
+		t.Fatalf("corrupt entry count=%d, want=%d", got, want)
+	}
+	for i := range want {
+		assertCorruptEntryEqual(t, corrupt[i], want[i])
+	}
+
+	// The store still serves corrupted entries unless they're quarantined.
+	if got, want := mustReadEntry(t, dataStore, flagged.ID), "contents of flagged-entry"; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+
+	_, err = dataStore.ReadEntryFile(context.Background(), quarantined.ID)
+	if _, ok := errors.AsType[store.EntryQuarantinedError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.EntryQuarantinedError{ID: quarantined.ID})
+	}
+
+	// Recording corruption again replaces the earlier record.
+	requarantined := flagged
+	requarantined.Detected = mustParseTime("2025-06-03T00:00:00Z")
+	requarantined.Quarantined = true
+	if err := dataStore.InsertCorruptEntry(context.Background(), requarantined); err != nil {
+		t.Fatalf("failed to insert corrupt entry: %v", err)
+	}
+
+	if err := dataStore.DeleteCorruptEntry(context.Background(), quarantined.ID); err != nil {
+		t.Fatalf("failed to delete corrupt entry: %v", err)
+	}
+
+	if got, want := mustReadEntry(t, dataStore, quarantined.ID), "contents of quarantined-entry"; got != want {
+		t.Errorf("contents after clearing quarantine=%s, want=%s", got, want)
+	}
+
+	corrupt, err = dataStore.GetCorruptEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get corrupt entries: %v", err)
+	}
+
+	if got, want := len(corrupt), 1; got != want {
+		t.Fatalf("corrupt entry count=%d, want=%d", got, want)
+	}
+	assertCorruptEntryEqual(t, corrupt[0], requarantined)
+
+	// Deleting an entry also deletes its corruption record.
+	if err := dataStore.DeleteEntry(context.Background(), flagged.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	corrupt, err = dataStore.GetCorruptEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get corrupt entries: %v", err)
+	}
+
+	if got, want := len(corrupt), 0; got != want {
+		t.Errorf("corrupt entry count after deleting entry=%d, want=%d", got, want)
+	}
+}
+
+func testVerifyEntryData(t *testing.T, newStore NewStoreFn) {
+	masterKey := mustParseMasterKey(dummyMasterKey)
+	for _, tt := range []struct {
+		description string
+		opts        Options
+		contentType picoshare.ContentType
+	}{
+		{
+			description: "intact entry",
+			opts:        Options{ChunkSize: defaultChunkSize},
+		},
+		{
+			description: "intact compressed entry",
+			opts:        Options{ChunkSize: defaultChunkSize},
+			contentType: picoshare.ContentType("text/plain"),
+		},
+		{
+			description: "intact encrypted entry",
+			opts:        Options{ChunkSize: defaultChunkSize, MasterKey: &masterKey},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			dataStore := newStore(t, tt.opts)
+			mustInsertEntry(t, dataStore, strings.Repeat("hello, world!\n", 10), picoshare.UploadMetadata{
+				ID:          picoshare.EntryID("dummy-id"),
+				ContentType: tt.contentType,
+			})
+
+			if err := dataStore.VerifyEntryData(context.Background(), picoshare.EntryID("dummy-id")); err != nil {
+				t.Errorf("err=%v, want=%v", err, nil)
+			}
+		})
+	}
+
+	t.Run("missing entry", func(t *testing.T) {
+		dataStore := newDefaultStore(t, newStore)
+
+		err := dataStore.VerifyEntryData(context.Background(), picoshare.EntryID("missing-id"))
+		if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+			t.Errorf("err=%v, want=%v", err, store.EntryNotFoundError{ID: "missing-id"})
+		}
+	})
+}
+
+func assertCorruptEntryEqual(t *testing.T, got, want picoshare.CorruptEntry) {
+	t.Helper()
+	if got.ID != want.ID {
+		t.Errorf("id=%v, want=%v", got.ID, want.ID)
+	}
+	if got.Filename != want.Filename {
+		t.Errorf("filename=%v, want=%v", got.Filename, want.Filename)
+	}
+	if !got.Detected.Equal(want.Detected) {
+		t.Errorf("detected=%v, want=%v", got.Detected, want.Detected)
+	}
+	if got.Reason != want.Reason {
+		t.Errorf("reason=%v, want=%v", got.Reason, want.Reason)
+	}
+	if got.Quarantined != want.Quarantined {
+		t.Errorf("quarantined=%v, want=%v", got.Quarantined, want.Quarantined)
+	}
+}
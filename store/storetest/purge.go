@@ -0,0 +1,186 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+func testPurgeEmptyStore(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge store: %v", err)
+	}
+
+	remaining, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(remaining), []picoshare.EntryID{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries=%v, want=%v", got, want)
+	}
+}
+
+func testPurgeExpiredEntries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, m := range []picoshare.UploadMetadata{
+		{
+			ID:      picoshare.EntryID("expired-long-ago"),
+			Expires: mustParseExpirationTime("2024-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.EntryID("expired-just-now"),
+			Expires: makeRelativeExpirationTime(-1 * time.Second),
+		},
+		{
+			ID:      picoshare.EntryID("expires-soon"),
+			Expires: makeRelativeExpirationTime(5 * time.Minute),
+		},
+		{
+			ID:      picoshare.EntryID("expires-later"),
+			Expires: mustParseExpirationTime("3000-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.EntryID("never-expires"),
+			Expires: picoshare.NeverExpire,
+		},
+	} {
+		mustInsertEntry(t, dataStore, "contents of "+m.ID.String(), m)
+	}
+
+	if err := dataStore.InsertEntryDownload(context.Background(), picoshare.EntryID("expired-long-ago"), picoshare.DownloadRecord{
+		Time:      mustParseTime("2023-06-01T12:00:00Z"),
+		ClientIP:  "192.168.1.1",
+		UserAgent: "test-agent",
+	}); err != nil {
+		t.Fatalf("failed to insert download: %v", err)
+	}
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge store: %v", err)
+	}
+
+	remaining, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(remaining), []picoshare.EntryID{"expires-later", "expires-soon", "never-expires"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after purge=%v, want=%v", got, want)
+	}
+
+	for _, id := range []picoshare.EntryID{"expired-long-ago", "expired-just-now"} {
+		_, err := dataStore.ReadEntryFile(context.Background(), id)
+		if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+			t.Errorf("err reading %v after purge=%v, want=%v", id, err, store.EntryNotFoundError{ID: id})
+		}
+	}
+
+	downloads, err := dataStore.GetEntryDownloads(context.Background(), picoshare.EntryID("expired-long-ago"))
+	if err != nil {
+		t.Fatalf("failed to get downloads: %v", err)
+	}
+
+	if got, want := len(downloads), 0; got != want {
+		t.Errorf("downloads of purged entry=%d, want=%d", got, want)
+	}
+
+	var wantStoredSize uint64
+	for _, id := range []picoshare.EntryID{"expires-later", "expires-soon", "never-expires"} {
+		contents := mustReadEntry(t, dataStore, id)
+		if got, want := contents, "contents of "+id.String(); got != want {
+			t.Errorf("contents=%s, want=%s", got, want)
+		}
+		wantStoredSize += uint64(len(contents))
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), wantStoredSize; got != want {
+		t.Errorf("stored size after purge=%d, want=%d", got, want)
+	}
+}
+
+func testPurgeKeepsUnexpiredEntries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	inserted := map[picoshare.EntryID]picoshare.UploadMetadata{}
+	for _, m := range []picoshare.UploadMetadata{
+		{
+			ID:      picoshare.EntryID("expires-later"),
+			Expires: mustParseExpirationTime("4000-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.EntryID("expires-sooner"),
+			Expires: mustParseExpirationTime("3000-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.EntryID("never-expires"),
+			Expires: picoshare.NeverExpire,
+		},
+	} {
+		inserted[m.ID] = mustInsertEntry(t, dataStore, "dummy data", m)
+	}
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge store: %v", err)
+	}
+
+	remaining, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries metadata: %v", err)
+	}
+
+	if got, want := entryIDs(remaining), []picoshare.EntryID{"expires-later", "expires-sooner", "never-expires"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries after purge=%v, want=%v", got, want)
+	}
+
+	for _, m := range remaining {
+		assertMetadataEqual(t, m, inserted[m.ID])
+	}
+}
+
+func testPurgeKeepsDataSharedWithUnexpiredEntry(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	input := "hello, world!"
+	for _, m := range []picoshare.UploadMetadata{
+		{
+			ID:      picoshare.EntryID("expired-entry"),
+			Expires: mustParseExpirationTime("2024-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.EntryID("current-entry"),
+			Expires: mustParseExpirationTime("2040-01-01T00:00:00Z"),
+		},
+	} {
+		mustInsertEntry(t, dataStore, input, m)
+	}
+
+	if err := dataStore.Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge store: %v", err)
+	}
+
+	_, err := dataStore.GetEntryMetadata(context.Background(), picoshare.EntryID("expired-entry"))
+	if _, ok := errors.AsType[store.EntryNotFoundError](err); !ok {
+		t.Errorf("err=%v, want=%v", err, store.EntryNotFoundError{ID: "expired-entry"})
+	}
+
+	if got, want := mustReadEntry(t, dataStore, picoshare.EntryID("current-entry")), input; got != want {
+		t.Errorf("contents=%s, want=%s", got, want)
+	}
+
+	if got, want := mustGetStoredDataSize(t, dataStore), uint64(len(input)); got != want {
+		t.Errorf("stored size after purge=%d, want=%d", got, want)
+	}
+}
+
+func makeRelativeExpirationTime(delta time.Duration) picoshare.ExpirationTime {
+	return picoshare.ExpirationTime(time.Now().UTC().Add(delta).Truncate(time.Second))
+}
@@ -0,0 +1,289 @@
+package storetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+func testSettings(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	settings, err := dataStore.ReadSettings(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+
+	if got, want := settings.DefaultFileLifetime, picoshare.NewFileLifetimeInDays(30); !got.Equal(want) {
+		t.Errorf("default lifetime in new store=%v, want=%v", got, want)
+	}
+	if got, want := settings.Notifications, (picoshare.NotificationSettings{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications in new store=%+v, want=%+v", got, want)
+	}
+
+	for _, lifetime := range []picoshare.FileLifetime{
+		picoshare.NewFileLifetimeInDays(7),
+		picoshare.NewFileLifetimeInYears(2),
+		picoshare.FileLifetimeInfinite,
+	} {
+		if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+			DefaultFileLifetime: lifetime,
+		}); err != nil {
+			t.Fatalf("failed to update settings: %v", err)
+		}
+
+		settings, err := dataStore.ReadSettings(context.Background())
+		if err != nil {
+			t.Fatalf("failed to read settings: %v", err)
+		}
+
+		if got, want := settings.DefaultFileLifetime, lifetime; !got.Equal(want) {
+			t.Errorf("default lifetime=%v, want=%v", got, want)
+		}
+	}
+}
+
+func testNotificationSettings(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, tt := range []struct {
+		description   string
+		notifications picoshare.NotificationSettings
+	}{
+		{
+			description: "stores every notification setting",
+			notifications: picoshare.NotificationSettings{
+				OnGuestUpload:   true,
+				OnFirstDownload: true,
+				Email: picoshare.EmailNotificationSettings{
+					Host:     "smtp.example.com",
+					Port:     587,
+					Username: "picoshare",
+					Password: "hunter2",
+					From:     "picoshare@example.com",
+					To:       "owner@example.com",
+				},
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "https://hooks.example.com/picoshare",
+				},
+				Ntfy: picoshare.NtfyNotificationSettings{
+					TopicURL:    "https://ntfy.sh/picoshare-uploads",
+					AccessToken: "tk_dummy",
+				},
+			},
+		},
+		{
+			description: "stores a single channel",
+			notifications: picoshare.NotificationSettings{
+				OnGuestUpload: true,
+				Webhook: picoshare.WebhookNotificationSettings{
+					URL: "http://localhost:8080/hook",
+				},
+			},
+		},
+		{
+			description:   "clears notification settings",
+			notifications: picoshare.NotificationSettings{},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				Notifications:       tt.notifications,
+			}); err != nil {
+				t.Fatalf("failed to update settings: %v", err)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read settings: %v", err)
+			}
+
+			if got, want := settings.Notifications, tt.notifications; !reflect.DeepEqual(got, want) {
+				t.Errorf("notifications=%+v, want=%+v", got, want)
+			}
+			if got, want := settings.DefaultFileLifetime, picoshare.NewFileLifetimeInDays(7); !got.Equal(want) {
+				t.Errorf("default lifetime=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func testEventWebhookSettings(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, tt := range []struct {
+		description  string
+		eventWebhook picoshare.EventWebhookSettings
+	}{
+		{
+			description: "stores URL and secret",
+			eventWebhook: picoshare.EventWebhookSettings{
+				URL:    "https://hooks.example.com/picoshare-events",
+				Secret: "s3cr3t",
+			},
+		},
+		{
+			description: "stores URL without a secret",
+			eventWebhook: picoshare.EventWebhookSettings{
+				URL: "https://hooks.example.com/picoshare-events",
+			},
+		},
+		{
+			description:  "clears event webhook",
+			eventWebhook: picoshare.EventWebhookSettings{},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				EventWebhook:        tt.eventWebhook,
+			}); err != nil {
+				t.Fatalf("failed to update settings: %v", err)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read settings: %v", err)
+			}
+
+			if got, want := settings.EventWebhook, tt.eventWebhook; got != want {
+				t.Errorf("event webhook=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func testBrandingSettings(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, tt := range []struct {
+		description string
+		branding    picoshare.BrandingSettings
+	}{
+		{
+			description: "stores every branding setting",
+			branding: picoshare.BrandingSettings{
+				Name: "Acme Files",
+				Logo: picoshare.BrandingLogo{
+					ContentType: picoshare.ContentType("image/png"),
+					Data:        []byte("\x89PNG\r\n\x1a\n"),
+				},
+				AccentColor: "#1a73e8",
+			},
+		},
+		{
+			description: "stores name without a logo",
+			branding: picoshare.BrandingSettings{
+				Name: "Acme Files",
+			},
+		},
+		{
+			description: "clears branding",
+			branding:    picoshare.BrandingSettings{},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				Branding:            tt.branding,
+			}); err != nil {
+				t.Fatalf("failed to update settings: %v", err)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read settings: %v", err)
+			}
+
+			if got, want := settings.Branding, tt.branding; !reflect.DeepEqual(got, want) {
+				t.Errorf("branding=%+v, want=%+v", got, want)
+			}
+
+			// Reading only the branding settings skips the logo's data.
+			branding, err := dataStore.ReadBrandingSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read branding settings: %v", err)
+			}
+			wantBranding := tt.branding
+			wantBranding.Logo.Data = nil
+			if got, want := branding, wantBranding; !reflect.DeepEqual(got, want) {
+				t.Errorf("branding without logo data=%+v, want=%+v", got, want)
+			}
+			if got, want := branding.HasLogo(), !tt.branding.Logo.Empty(); got != want {
+				t.Errorf("HasLogo=%v, want=%v", got, want)
+			}
+
+			logo, err := dataStore.ReadBrandingLogo(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read branding logo: %v", err)
+			}
+			if got, want := logo, tt.branding.Logo; !reflect.DeepEqual(got, want) {
+				t.Errorf("logo=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
+
+func testGuestLinkPresetSettings(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	maxFileBytes := uint64(20 * 1024 * 1024)
+	maxFileUploads := 3
+	maxTotalBytes := uint64(100 * 1024 * 1024)
+
+	for _, tt := range []struct {
+		description string
+		presets     []picoshare.GuestLinkPreset
+	}{
+		{
+			description: "stores presets in order",
+			presets: []picoshare.GuestLinkPreset{
+				{
+					Name:            "Photos",
+					MaxFileLifetime: picoshare.FileLifetimeInfinite,
+					MaxTotalBytes:   picoshare.GuestUploadMaxTotalBytes(&maxTotalBytes),
+				},
+				{
+					Name:            "Client documents",
+					MaxFileLifetime: picoshare.NewFileLifetimeInDays(30),
+					MaxFileBytes:    picoshare.GuestUploadMaxFileBytes(&maxFileBytes),
+					MaxFileUploads:  picoshare.GuestUploadCountLimit(&maxFileUploads),
+				},
+			},
+		},
+		{
+			description: "replaces existing presets",
+			presets: []picoshare.GuestLinkPreset{
+				{
+					Name:            "Client documents",
+					MaxFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				},
+			},
+		},
+		{
+			description: "clears presets",
+			presets:     nil,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+				DefaultFileLifetime: picoshare.NewFileLifetimeInDays(7),
+				GuestLinkPresets:    tt.presets,
+			}); err != nil {
+				t.Fatalf("failed to update settings: %v", err)
+			}
+
+			settings, err := dataStore.ReadSettings(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read settings: %v", err)
+			}
+
+			if got, want := settings.GuestLinkPresets, tt.presets; !reflect.DeepEqual(got, want) {
+				t.Errorf("guest link presets=%+v, want=%+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Package storetest checks that an implementation of PicoShare's datastore
+// behaves the way the rest of PicoShare expects. Each storage backend runs the
+// same suite against its own implementation, so a new backend can prove that
+// it's a drop-in replacement for the existing ones.
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/handlers"
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/scrub"
+	"github.com/mtlynch/picoshare/space/checkers"
+	"github.com/mtlynch/picoshare/store/encryption"
+	"github.com/mtlynch/picoshare/webhook"
+)
+
+type (
+	// Store is the set of datastore methods that the suite exercises.
+	Store interface {
+		handlers.Store
+		garbagecollect.DatabasePurger
+		garbagecollect.EvictionStore
+		scrub.EntryVerifier
+		checkers.DatabaseMetadataReader
+		webhook.DeliveryStore
+	}
+
+	// Options controls how the suite's datastores store file data.
+	Options struct {
+		// ChunkSize is the number of bytes of file data per chunk.
+		ChunkSize uint64
+		// MasterKey is the key that protects the data keys of new entries. If
+		// it's nil, the store doesn't encrypt file data.
+		MasterKey *encryption.MasterKey
+	}
+
+	// NewStoreFn creates an empty datastore with the given options.
+	NewStoreFn func(t *testing.T, opts Options) Store
+)
+
+// defaultChunkSize is small so that the suite's short test files span several
+// chunks.
+const defaultChunkSize = uint64(5)
+
+// Run runs the full conformance suite against the datastores that newStore
+// creates. Each test gets a fresh, empty datastore.
+func Run(t *testing.T, newStore NewStoreFn) {
+	for _, tt := range []struct {
+		name string
+		fn   func(*testing.T, NewStoreFn)
+	}{
+		{"InsertDeleteSingleEntry", testInsertDeleteSingleEntry},
+		{"EntryMetadataRoundTrip", testEntryMetadataRoundTrip},
+		{"GetEntriesMetadata", testGetEntriesMetadata},
+		{"UpdateEntryMetadata", testUpdateEntryMetadata},
+		{"EntrySlugs", testEntrySlugs},
+		{"MissingEntry", testMissingEntry},
+		{"InsertEmptyEntry", testInsertEmptyEntry},
+		{"InsertEntryChecksum", testInsertEntryChecksum},
+		{"RangeReads", testRangeReads},
+		{"ReadCompressedEntry", testReadCompressedEntry},
+		{"ReadEncryptedEntry", testReadEncryptedEntry},
+		{"IdenticalEntriesShareData", testIdenticalEntriesShareData},
+		{"DataSizes", testDataSizes},
+		{"GuestLinkRoundTrip", testGuestLinkRoundTrip},
+		{"GuestLinkFileCounts", testGuestLinkFileCounts},
+		{"EnableDisableGuestLink", testEnableDisableGuestLink},
+		{"UpdateGuestLink", testUpdateGuestLink},
+		{"DeleteGuestLinkKeepsEntries", testDeleteGuestLinkKeepsEntries},
+		{"GetGuestLinkEntriesMetadata", testGetGuestLinkEntriesMetadata},
+		{"GuestLinkFirstUpload", testGuestLinkFirstUpload},
+		{"MissingGuestLink", testMissingGuestLink},
+		{"DownloadRecords", testDownloadRecords},
+		{"DeleteEntryDeletesDownloads", testDeleteEntryDeletesDownloads},
+		{"MarkEntryDownloaded", testMarkEntryDownloaded},
+		{"Settings", testSettings},
+		{"NotificationSettings", testNotificationSettings},
+		{"EventWebhookSettings", testEventWebhookSettings},
+		{"BrandingSettings", testBrandingSettings},
+		{"GuestLinkPresetSettings", testGuestLinkPresetSettings},
+		{"PurgeEmptyStore", testPurgeEmptyStore},
+		{"PurgeExpiredEntries", testPurgeExpiredEntries},
+		{"PurgeKeepsUnexpiredEntries", testPurgeKeepsUnexpiredEntries},
+		{"PurgeKeepsDataSharedWithUnexpiredEntry", testPurgeKeepsDataSharedWithUnexpiredEntry},
+		{"EvictedEntries", testEvictedEntries},
+		{"LatestDownloadTimes", testLatestDownloadTimes},
+		{"WebhookDeliveryRoundTrip", testWebhookDeliveryRoundTrip},
+		{"ClaimDueWebhookDeliveries", testClaimDueWebhookDeliveries},
+		{"ConcurrentWebhookDeliveryClaims", testConcurrentWebhookDeliveryClaims},
+		{"PruneWebhookDeliveries", testPruneWebhookDeliveries},
+		{"MissingWebhookDelivery", testMissingWebhookDelivery},
+		{"CorruptEntries", testCorruptEntries},
+		{"VerifyEntryData", testVerifyEntryData},
+		{"ConcurrentUploads", testConcurrentUploads},
+		{"ConcurrentIdenticalUploads", testConcurrentIdenticalUploads},
+		{"ConcurrentReads", testConcurrentReads},
+		{"ConcurrentFirstDownloads", testConcurrentFirstDownloads},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore)
+		})
+	}
+}
+
+func newDefaultStore(t *testing.T, newStore NewStoreFn) Store {
+	return newStore(t, Options{ChunkSize: defaultChunkSize})
+}
+
+// mustInsertEntry inserts an entry with the given contents, filling in any
+// metadata fields that the store requires but the test doesn't care about.
+func mustInsertEntry(t *testing.T, dataStore Store, contents string, meta picoshare.UploadMetadata) picoshare.UploadMetadata {
+	t.Helper()
+	if meta.Filename == "" {
+		meta.Filename = picoshare.Filename("dummy-file.txt")
+	}
+	if meta.Uploaded.IsZero() {
+		meta.Uploaded = mustParseTime("2025-05-25T00:00:00Z")
+	}
+	if time.Time(meta.Expires).IsZero() {
+		meta.Expires = mustParseExpirationTime("2040-01-01T00:00:00Z")
+	}
+	meta.Size = mustParseFileSize(len(contents))
+
+	if err := dataStore.InsertEntry(context.Background(), bytes.NewBufferString(contents), meta); err != nil {
+		t.Fatalf("failed to insert entry %v: %v", meta.ID, err)
+	}
+
+	return meta
+}
+
+func mustReadEntry(t *testing.T, dataStore Store, id picoshare.EntryID) string {
+	t.Helper()
+	entryFile, err := dataStore.ReadEntryFile(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to read entry %v: %v", id, err)
+	}
+
+	contents, err := io.ReadAll(entryFile)
+	if err != nil {
+		t.Fatalf("failed to read contents of entry %v: %v", id, err)
+	}
+
+	return string(contents)
+}
+
+func mustGetStoredDataSize(t *testing.T, dataStore Store) uint64 {
+	t.Helper()
+	size, err := dataStore.GetStoredDataSize(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get stored data size: %v", err)
+	}
+	return size
+}
+
+// entryIDs returns the sorted IDs of the given entries. Stores don't promise
+// any particular order for lists of entries.
+func entryIDs(entries []picoshare.UploadMetadata) []picoshare.EntryID {
+	ids := []picoshare.EntryID{}
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func assertMetadataEqual(t *testing.T, got, want picoshare.UploadMetadata) {
+	t.Helper()
+	if got.ID != want.ID {
+		t.Errorf("id=%v, want=%v", got.ID, want.ID)
+	}
+	if got.Slug != want.Slug {
+		t.Errorf("slug=%v, want=%v", got.Slug, want.Slug)
+	}
+	if got.Filename != want.Filename {
+		t.Errorf("filename=%v, want=%v", got.Filename, want.Filename)
+	}
+	if got.Note.String() != want.Note.String() {
+		t.Errorf("note=%v, want=%v", got.Note, want.Note)
+	}
+	if got.Sender != want.Sender {
+		t.Errorf("sender=%+v, want=%+v", got.Sender, want.Sender)
+	}
+	if got.ContentType != want.ContentType {
+		t.Errorf("content type=%v, want=%v", got.ContentType, want.ContentType)
+	}
+	if !got.Uploaded.Equal(want.Uploaded) {
+		t.Errorf("uploaded=%v, want=%v", got.Uploaded, want.Uploaded)
+	}
+	if !time.Time(got.Expires).Equal(time.Time(want.Expires)) {
+		t.Errorf("expires=%v, want=%v", got.Expires, want.Expires)
+	}
+	if !got.Size.Equal(want.Size) {
+		t.Errorf("size=%v, want=%v", got.Size, want.Size)
+	}
+	if !want.SHA256.Empty() && got.SHA256 != want.SHA256 {
+		t.Errorf("sha256=%v, want=%v", got.SHA256, want.SHA256)
+	}
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustParseExpirationTime(s string) picoshare.ExpirationTime {
+	return picoshare.ExpirationTime(mustParseTime(s))
+}
+
+func mustParseFileSize(val int) picoshare.FileSize {
+	fileSize, err := picoshare.FileSizeFromInt(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return fileSize
+}
+
+func mustParseMasterKey(s string) encryption.MasterKey {
+	mk, err := encryption.ParseMasterKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return mk
+}
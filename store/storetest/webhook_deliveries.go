@@ -0,0 +1,272 @@
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store"
+)
+
+func testWebhookDeliveryRoundTrip(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	deliveries, err := dataStore.GetWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+	if got, want := len(deliveries), 0; got != want {
+		t.Fatalf("webhook delivery count in new store=%d, want=%d", got, want)
+	}
+
+	first := picoshare.WebhookDelivery{
+		ID:          picoshare.WebhookDeliveryID("delivery-first"),
+		EventType:   picoshare.WebhookEventEntryCreated,
+		URL:         "https://hooks.example.com/picoshare-events",
+		Payload:     []byte(`{"id":"delivery-first"}`),
+		State:       picoshare.WebhookDeliveryPending,
+		Created:     mustParseTime("2025-03-01T00:00:00Z"),
+		NextAttempt: mustParseTime("2025-03-01T00:00:00Z"),
+	}
+	second := picoshare.WebhookDelivery{
+		ID:          picoshare.WebhookDeliveryID("delivery-second"),
+		EventType:   picoshare.WebhookEventGuestLinkDisabled,
+		URL:         "https://hooks.example.com/picoshare-events",
+		Payload:     []byte(`{"id":"delivery-second"}`),
+		State:       picoshare.WebhookDeliveryPending,
+		Created:     mustParseTime("2025-03-02T00:00:00Z"),
+		NextAttempt: mustParseTime("2025-03-02T00:00:00Z"),
+	}
+	for _, d := range []picoshare.WebhookDelivery{first, second} {
+		if err := dataStore.InsertWebhookDelivery(context.Background(), d); err != nil {
+			t.Fatalf("failed to insert webhook delivery: %v", err)
+		}
+	}
+
+	// Record a failed attempt followed by a retry.
+	first.Attempts = 1
+	first.LastAttempt = mustParseTime("2025-03-01T00:00:05Z")
+	first.NextAttempt = mustParseTime("2025-03-01T00:01:05Z")
+	first.LastStatusCode = 503
+	first.LastError = "webhook responded with 503 Service Unavailable"
+	if err := dataStore.UpdateWebhookDelivery(context.Background(), first); err != nil {
+		t.Fatalf("failed to update webhook delivery: %v", err)
+	}
+
+	deliveries, err = dataStore.GetWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+
+	// The store returns the most recent deliveries first.
+	want := []picoshare.WebhookDelivery{second, first}
+	if got, want := len(deliveries), len(want); got != want {
+		t.Fatalf("webhook delivery count=%d, want=%d", got, want)
+	}
+	for i := range want {
+		assertWebhookDeliveryEqual(t, deliveries[i], want[i])
+	}
+
+	deliveries, err = dataStore.GetWebhookDeliveries(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+	if got, want := len(deliveries), 1; got != want {
+		t.Fatalf("webhook delivery count with limit=%d, want=%d", got, want)
+	}
+	assertWebhookDeliveryEqual(t, deliveries[0], second)
+}
+
+func testClaimDueWebhookDeliveries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	for _, d := range []picoshare.WebhookDelivery{
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-due-later"),
+			State:       picoshare.WebhookDeliveryPending,
+			Created:     mustParseTime("2025-03-01T11:00:00Z"),
+			NextAttempt: mustParseTime("2025-03-01T11:59:00Z"),
+		},
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-due-earlier"),
+			State:       picoshare.WebhookDeliveryPending,
+			Created:     mustParseTime("2025-03-01T10:00:00Z"),
+			NextAttempt: mustParseTime("2025-03-01T12:00:00Z"),
+		},
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-not-yet-due"),
+			State:       picoshare.WebhookDeliveryPending,
+			Created:     mustParseTime("2025-03-01T09:00:00Z"),
+			NextAttempt: mustParseTime("2025-03-01T12:04:00Z"),
+		},
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-delivered"),
+			State:       picoshare.WebhookDeliveryDelivered,
+			Attempts:    1,
+			Created:     mustParseTime("2025-03-01T08:00:00Z"),
+			LastAttempt: mustParseTime("2025-03-01T08:00:00Z"),
+		},
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-failed"),
+			State:       picoshare.WebhookDeliveryFailed,
+			Attempts:    10,
+			Created:     mustParseTime("2025-03-01T07:00:00Z"),
+			LastAttempt: mustParseTime("2025-03-01T11:00:00Z"),
+		},
+	} {
+		d.EventType = picoshare.WebhookEventEntryDeleted
+		d.URL = "https://hooks.example.com/picoshare-events"
+		d.Payload = []byte(`{}`)
+		if err := dataStore.InsertWebhookDelivery(context.Background(), d); err != nil {
+			t.Fatalf("failed to insert webhook delivery: %v", err)
+		}
+	}
+
+	claimedUntil := now.Add(2 * time.Minute)
+	due, err := dataStore.ClaimDueWebhookDeliveries(context.Background(), now, claimedUntil, 10)
+	if err != nil {
+		t.Fatalf("failed to claim due webhook deliveries: %v", err)
+	}
+
+	// The store returns the oldest deliveries first so that receivers see events
+	// in roughly the order they happened.
+	wantIDs := []picoshare.WebhookDeliveryID{"delivery-due-earlier", "delivery-due-later"}
+	if got, want := len(due), len(wantIDs); got != want {
+		t.Fatalf("due webhook delivery count=%d, want=%d", got, want)
+	}
+	for i := range wantIDs {
+		if got, want := due[i].ID, wantIDs[i]; got != want {
+			t.Errorf("due delivery %d=%v, want=%v", i, got, want)
+		}
+	}
+
+	// Another sender can't claim the deliveries while the claim lasts.
+	due, err = dataStore.ClaimDueWebhookDeliveries(context.Background(), now.Add(time.Minute), claimedUntil, 10)
+	if err != nil {
+		t.Fatalf("failed to claim due webhook deliveries: %v", err)
+	}
+	if got, want := len(due), 0; got != want {
+		t.Errorf("claimed webhook delivery count during claim=%d, want=%d", got, want)
+	}
+
+	// Once the claim expires, another sender can claim the deliveries.
+	due, err = dataStore.ClaimDueWebhookDeliveries(context.Background(), claimedUntil, claimedUntil.Add(10*time.Minute), 1)
+	if err != nil {
+		t.Fatalf("failed to claim due webhook deliveries: %v", err)
+	}
+	if got, want := len(due), 1; got != want {
+		t.Fatalf("claimed webhook delivery count after claim expired=%d, want=%d", got, want)
+	}
+	if got, want := due[0].ID, wantIDs[0]; got != want {
+		t.Errorf("claimed delivery after claim expired=%v, want=%v", got, want)
+	}
+}
+
+func testPruneWebhookDeliveries(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	for _, d := range []picoshare.WebhookDelivery{
+		{
+			ID:      picoshare.WebhookDeliveryID("delivery-old-delivered"),
+			State:   picoshare.WebhookDeliveryDelivered,
+			Created: mustParseTime("2025-01-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.WebhookDeliveryID("delivery-old-failed"),
+			State:   picoshare.WebhookDeliveryFailed,
+			Created: mustParseTime("2025-01-02T00:00:00Z"),
+		},
+		{
+			ID:          picoshare.WebhookDeliveryID("delivery-old-pending"),
+			State:       picoshare.WebhookDeliveryPending,
+			Created:     mustParseTime("2025-01-03T00:00:00Z"),
+			NextAttempt: mustParseTime("2025-03-01T00:00:00Z"),
+		},
+		{
+			ID:      picoshare.WebhookDeliveryID("delivery-recent-delivered"),
+			State:   picoshare.WebhookDeliveryDelivered,
+			Created: mustParseTime("2025-02-15T00:00:00Z"),
+		},
+	} {
+		d.EventType = picoshare.WebhookEventEntryCreated
+		d.URL = "https://hooks.example.com/picoshare-events"
+		d.Payload = []byte(`{}`)
+		if err := dataStore.InsertWebhookDelivery(context.Background(), d); err != nil {
+			t.Fatalf("failed to insert webhook delivery: %v", err)
+		}
+	}
+
+	if err := dataStore.DeleteWebhookDeliveriesBefore(context.Background(), mustParseTime("2025-02-01T00:00:00Z")); err != nil {
+		t.Fatalf("failed to delete webhook deliveries: %v", err)
+	}
+
+	deliveries, err := dataStore.GetWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+
+	// The store keeps pending deliveries regardless of age because PicoShare is
+	// still trying to deliver them.
+	wantIDs := []picoshare.WebhookDeliveryID{"delivery-recent-delivered", "delivery-old-pending"}
+	if got, want := len(deliveries), len(wantIDs); got != want {
+		t.Fatalf("webhook delivery count after pruning=%d, want=%d", got, want)
+	}
+	for i := range wantIDs {
+		if got, want := deliveries[i].ID, wantIDs[i]; got != want {
+			t.Errorf("delivery %d=%v, want=%v", i, got, want)
+		}
+	}
+}
+
+func testMissingWebhookDelivery(t *testing.T, newStore NewStoreFn) {
+	dataStore := newDefaultStore(t, newStore)
+
+	err := dataStore.UpdateWebhookDelivery(context.Background(), picoshare.WebhookDelivery{
+		ID:    picoshare.WebhookDeliveryID("delivery-missing"),
+		State: picoshare.WebhookDeliveryDelivered,
+	})
+	if _, ok := errors.AsType[store.WebhookDeliveryNotFoundError](err); !ok {
+		t.Errorf("error updating missing webhook delivery=%v, want=%T", err, store.WebhookDeliveryNotFoundError{})
+	}
+}
+
+func assertWebhookDeliveryEqual(t *testing.T, got, want picoshare.WebhookDelivery) {
+	t.Helper()
+	if got.ID != want.ID {
+		t.Errorf("id=%v, want=%v", got.ID, want.ID)
+	}
+	if got.EventType != want.EventType {
+		t.Errorf("event type=%v, want=%v", got.EventType, want.EventType)
+	}
+	if got.URL != want.URL {
+		t.Errorf("url=%v, want=%v", got.URL, want.URL)
+	}
+	if !bytes.Equal(got.Payload, want.Payload) {
+		t.Errorf("payload=%s, want=%s", got.Payload, want.Payload)
+	}
+	if got.State != want.State {
+		t.Errorf("state=%v, want=%v", got.State, want.State)
+	}
+	if got.Attempts != want.Attempts {
+		t.Errorf("attempts=%d, want=%d", got.Attempts, want.Attempts)
+	}
+	if !got.Created.Equal(want.Created) {
+		t.Errorf("created=%v, want=%v", got.Created, want.Created)
+	}
+	if !got.NextAttempt.Equal(want.NextAttempt) {
+		t.Errorf("next attempt=%v, want=%v", got.NextAttempt, want.NextAttempt)
+	}
+	if !got.LastAttempt.Equal(want.LastAttempt) {
+		t.Errorf("last attempt=%v, want=%v", got.LastAttempt, want.LastAttempt)
+	}
+	if got.LastStatusCode != want.LastStatusCode {
+		t.Errorf("last status code=%d, want=%d", got.LastStatusCode, want.LastStatusCode)
+	}
+	if got.LastError != want.LastError {
+		t.Errorf("last error=%v, want=%v", got.LastError, want.LastError)
+	}
+}
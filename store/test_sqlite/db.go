@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/mtlynch/picoshare/random"
+	"github.com/mtlynch/picoshare/store/encryption"
 	"github.com/mtlynch/picoshare/store/sqlite"
 )
 
@@ -17,6 +18,10 @@ func NewWithChunkSize(chunkSize uint64) sqlite.Store {
 	return sqlite.NewWithChunkSize(ephemeralDbURI(), chunkSize, optimizeForLitestream)
 }
 
+func NewWithEncryption(masterKey encryption.MasterKey) sqlite.Store {
+	return sqlite.NewWithEncryption(ephemeralDbURI(), masterKey, optimizeForLitestream)
+}
+
 func ephemeralDbURI() string {
 	name := random.String(
 		10,
@@ -0,0 +1,22 @@
+package wrapped
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Interface to represent a sql.DB object that can be mocked for testing.
+type SqlDB interface {
+	Exec(string, ...any) (sql.Result, error)
+}
+
+// Interface to represent a sql.DB object that file readers can query.
+type SqlQuerier interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// Create a new SqlDB instance by wrapping a sql.DB instance.
+func New(db *sql.DB) SqlDB {
+	return db
+}
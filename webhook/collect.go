@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mtlynch/picoshare/garbagecollect"
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type (
+	PurgeStore interface {
+		QueueStore
+		garbagecollect.DatabasePurger
+		GetEntriesMetadata(context.Context) ([]picoshare.UploadMetadata, error)
+	}
+
+	// ReportingPurger purges expired entries from the store and reports each
+	// entry it deletes to the event webhook.
+	ReportingPurger struct {
+		store PurgeStore
+	}
+
+	EvictionStore interface {
+		QueueStore
+		garbagecollect.EvictionStore
+	}
+
+	// ReportingEvictionStore reports each entry that PicoShare evicts to the
+	// event webhook.
+	ReportingEvictionStore struct {
+		EvictionStore
+	}
+)
+
+func NewReportingPurger(store PurgeStore) ReportingPurger {
+	return ReportingPurger{store: store}
+}
+
+func NewReportingEvictionStore(store EvictionStore) ReportingEvictionStore {
+	return ReportingEvictionStore{store}
+}
+
+// Purge deletes expired entries and orphaned data from the store. If the owner
+// has configured an event webhook, Purge compares the entries before and after
+// the purge to find out which entries it deleted.
+func (p ReportingPurger) Purge(ctx context.Context) error {
+	settings, err := p.store.ReadSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if !settings.EventWebhook.IsConfigured() {
+		return p.store.Purge(ctx)
+	}
+
+	before, err := p.store.GetEntriesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := p.store.Purge(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+
+	after, err := p.store.GetEntriesMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	remaining := map[picoshare.EntryID]bool{}
+	for _, entry := range after {
+		remaining[entry.ID] = true
+	}
+
+	for _, entry := range before {
+		// Skip entries that disappeared for some other reason, such as the owner
+		// deleting them while the purge ran.
+		if remaining[entry.ID] || !entry.Expires.Time().Before(now) {
+			continue
+		}
+		if err := Enqueue(ctx, p.store, EntryEvent(picoshare.WebhookEventEntryExpired, entry, now)); err != nil {
+			log.Printf("failed to queue webhook for expired entry %v: %v", entry.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// InsertEvictedEntry records the eviction in the store and queues a webhook
+// about it.
+func (s ReportingEvictionStore) InsertEvictedEntry(ctx context.Context, evicted picoshare.EvictedEntry) error {
+	if err := s.EvictionStore.InsertEvictedEntry(ctx, evicted); err != nil {
+		return err
+	}
+
+	if err := Enqueue(ctx, s.EvictionStore, EvictionEvent(evicted)); err != nil {
+		log.Printf("failed to queue webhook for evicted entry %v: %v", evicted.ID, err)
+	}
+
+	return nil
+}
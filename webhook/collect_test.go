@@ -0,0 +1,149 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/webhook"
+)
+
+func TestReportingPurgerReportsExpiredEntries(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL: "https://hooks.example.com/picoshare-events",
+	})
+
+	for _, entry := range []picoshare.UploadMetadata{
+		{
+			ID:       picoshare.EntryID("EXPIRED000"),
+			Filename: picoshare.Filename("expired.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.ExpirationTime(mustParseTime("2024-02-01T00:00:00Z")),
+		},
+		{
+			ID:       picoshare.EntryID("KEEPFOREVR"),
+			Filename: picoshare.Filename("forever.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.NeverExpire,
+		},
+		{
+			ID:       picoshare.EntryID("NOTEXPIRED"),
+			Filename: picoshare.Filename("future.txt"),
+			Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+			Expires:  picoshare.ExpirationTime(time.Now().Add(24 * time.Hour)),
+		},
+	} {
+		contents := "dummy data"
+		entry.Size = mustParseFileSize(len(contents))
+		if err := dataStore.InsertEntry(context.Background(), strings.NewReader(contents), entry); err != nil {
+			t.Fatalf("failed to insert entry: %v", err)
+		}
+	}
+
+	if err := webhook.NewReportingPurger(&dataStore).Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+
+	deliveries := mustGetDeliveries(t, dataStore)
+	if got, want := len(deliveries), 1; got != want {
+		t.Fatalf("deliveries=%d, want=%d", got, want)
+	}
+	if got, want := deliveries[0].EventType, picoshare.WebhookEventEntryExpired; got != want {
+		t.Errorf("event type=%v, want=%v", got, want)
+	}
+	var payload webhook.Payload
+	if err := json.Unmarshal(deliveries[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if got, want := payload.Data.Entry.ID, "EXPIRED000"; got != want {
+		t.Errorf("expired entry ID=%v, want=%v", got, want)
+	}
+
+	remaining, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries: %v", err)
+	}
+	if got, want := len(remaining), 2; got != want {
+		t.Errorf("entries after purge=%d, want=%d", got, want)
+	}
+}
+
+func TestReportingPurgerWithoutEventWebhook(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{})
+
+	contents := "dummy data"
+	if err := dataStore.InsertEntry(context.Background(), strings.NewReader(contents), picoshare.UploadMetadata{
+		ID:       picoshare.EntryID("EXPIRED000"),
+		Filename: picoshare.Filename("expired.txt"),
+		Size:     mustParseFileSize(len(contents)),
+		Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+		Expires:  picoshare.ExpirationTime(mustParseTime("2024-02-01T00:00:00Z")),
+	}); err != nil {
+		t.Fatalf("failed to insert entry: %v", err)
+	}
+
+	if err := webhook.NewReportingPurger(&dataStore).Purge(context.Background()); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+
+	if got, want := len(mustGetDeliveries(t, dataStore)), 0; got != want {
+		t.Errorf("deliveries=%d, want=%d", got, want)
+	}
+	remaining, err := dataStore.GetEntriesMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get entries: %v", err)
+	}
+	if got, want := len(remaining), 0; got != want {
+		t.Errorf("entries after purge=%d, want=%d", got, want)
+	}
+}
+
+func TestReportingEvictionStoreReportsEvictions(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL: "https://hooks.example.com/picoshare-events",
+	})
+
+	evicted := picoshare.EvictedEntry{
+		ID:       picoshare.EntryID("EVICTED000"),
+		Filename: picoshare.Filename("big.iso"),
+		Size:     mustParseFileSize(4096),
+		Uploaded: mustParseTime("2024-01-01T00:00:00Z"),
+		Evicted:  mustParseTime("2024-06-01T00:00:00Z"),
+		Policy:   "largest",
+	}
+	if err := webhook.NewReportingEvictionStore(&dataStore).InsertEvictedEntry(context.Background(), evicted); err != nil {
+		t.Fatalf("failed to insert evicted entry: %v", err)
+	}
+
+	recorded, err := dataStore.GetEvictedEntries(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get evicted entries: %v", err)
+	}
+	if got, want := len(recorded), 1; got != want {
+		t.Errorf("evicted entries=%d, want=%d", got, want)
+	}
+
+	deliveries := mustGetDeliveries(t, dataStore)
+	if got, want := len(deliveries), 1; got != want {
+		t.Fatalf("deliveries=%d, want=%d", got, want)
+	}
+	var payload webhook.Payload
+	if err := json.Unmarshal(deliveries[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if got, want := payload.Type, picoshare.WebhookEventEntryEvicted; got != want {
+		t.Errorf("event type=%v, want=%v", got, want)
+	}
+	if payload.Data.Eviction == nil {
+		t.Fatalf("payload has no eviction details")
+	}
+	if got, want := payload.Data.Eviction.Policy, "largest"; got != want {
+		t.Errorf("eviction policy=%v, want=%v", got, want)
+	}
+	if got, want := payload.Data.Entry.Size, uint64(4096); got != want {
+		t.Errorf("evicted entry size=%d, want=%d", got, want)
+	}
+}
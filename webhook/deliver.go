@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+)
+
+type (
+	// DeliveryStore is the storage that a Sender needs to work through the
+	// delivery queue.
+	DeliveryStore interface {
+		ReadSettings(context.Context) (picoshare.Settings, error)
+		ClaimDueWebhookDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]picoshare.WebhookDelivery, error)
+		UpdateWebhookDelivery(context.Context, picoshare.WebhookDelivery) error
+		DeleteWebhookDeliveriesBefore(context.Context, time.Time) error
+	}
+
+	// Sender delivers queued events to the event webhook.
+	Sender struct {
+		store  DeliveryStore
+		client *http.Client
+	}
+)
+
+const (
+	// MaxAttempts is the number of times PicoShare tries to deliver an event
+	// before it gives up.
+	MaxAttempts = 10
+
+	// initialRetryDelay is how long PicoShare waits before it retries a failed
+	// delivery for the first time. The delay doubles after each failure.
+	initialRetryDelay = time.Minute
+
+	// retentionPeriod is how long PicoShare keeps finished deliveries so that
+	// the owner can review them.
+	retentionPeriod = 30 * 24 * time.Hour
+
+	requestTimeout = 15 * time.Second
+
+	// claimBatchSize is the number of deliveries that a Sender claims at a time.
+	claimBatchSize = 20
+
+	// claimDuration is how long a Sender's claim on a batch of deliveries lasts.
+	// It's long enough for every request in a batch to time out, so a claim
+	// expires only if the Sender stops before it records the outcome, such as
+	// when PicoShare restarts.
+	claimDuration = 2 * claimBatchSize * requestTimeout
+)
+
+// Headers that PicoShare includes in event webhook requests.
+const (
+	HeaderEvent     = "X-PicoShare-Event"
+	HeaderDelivery  = "X-PicoShare-Delivery"
+	HeaderTimestamp = "X-PicoShare-Timestamp"
+	HeaderSignature = "X-PicoShare-Signature"
+)
+
+func NewSender(store DeliveryStore) Sender {
+	return NewSenderWithClient(store, &http.Client{Timeout: requestTimeout})
+}
+
+// NewSenderWithClient creates a Sender that makes webhook requests through the
+// given client.
+func NewSenderWithClient(store DeliveryStore, client *http.Client) Sender {
+	return Sender{
+		store:  store,
+		client: client,
+	}
+}
+
+// DeliverDue tries to deliver every queued event that's due at the given time.
+// It records the outcome of each attempt in the queue and schedules retries
+// for failed attempts. DeliverDue claims deliveries before it attempts them,
+// so concurrent senders never deliver the same event twice.
+func (s Sender) DeliverDue(ctx context.Context, now time.Time) error {
+	var secret *string
+	for {
+		due, err := s.store.ClaimDueWebhookDeliveries(ctx, now, now.Add(claimDuration), claimBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		// Read the secret at delivery time rather than when PicoShare queued the
+		// event so that retries use the owner's current secret.
+		if secret == nil {
+			settings, err := s.store.ReadSettings(ctx)
+			if err != nil {
+				return err
+			}
+			secret = &settings.EventWebhook.Secret
+		}
+
+		for _, d := range due {
+			d = s.attempt(ctx, d, *secret, now)
+			if err := s.store.UpdateWebhookDelivery(ctx, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Prune deletes finished deliveries that are older than PicoShare's retention
+// period.
+func (s Sender) Prune(ctx context.Context, now time.Time) error {
+	return s.store.DeleteWebhookDeliveriesBefore(ctx, now.Add(-retentionPeriod))
+}
+
+func (s Sender) attempt(ctx context.Context, d picoshare.WebhookDelivery, secret string, now time.Time) picoshare.WebhookDelivery {
+	d.Attempts++
+	d.LastAttempt = now
+
+	statusCode, err := s.post(ctx, d, secret, now)
+	d.LastStatusCode = statusCode
+	if err == nil {
+		d.State = picoshare.WebhookDeliveryDelivered
+		d.NextAttempt = time.Time{}
+		d.LastError = ""
+		return d
+	}
+
+	d.LastError = err.Error()
+	if d.Attempts >= MaxAttempts {
+		log.Printf("giving up on webhook delivery %v after %d attempts: %v", d.ID, d.Attempts, err)
+		d.State = picoshare.WebhookDeliveryFailed
+		d.NextAttempt = time.Time{}
+		return d
+	}
+
+	log.Printf("webhook delivery %v failed on attempt %d: %v", d.ID, d.Attempts, err)
+	d.NextAttempt = now.Add(RetryDelay(d.Attempts))
+	return d
+}
+
+func (s Sender) post(ctx context.Context, d picoshare.WebhookDelivery, secret string, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PicoShare")
+	req.Header.Set(HeaderEvent, d.EventType.String())
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, now, d.Payload))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return res.StatusCode, fmt.Errorf("webhook responded with %s: %s", res.Status, bytes.TrimSpace(detail))
+	}
+
+	return res.StatusCode, nil
+}
+
+// Sign calculates the signature of a webhook request. The signature is the
+// hex-encoded HMAC-SHA256 of the request's Unix timestamp, a period, and the
+// request body, keyed with the owner's secret. Including the timestamp lets
+// receivers reject replays of old requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp.Unix())
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay returns how long PicoShare waits to retry a delivery after the
+// given number of failed attempts.
+func RetryDelay(failedAttempts uint) time.Duration {
+	if failedAttempts == 0 {
+		return 0
+	}
+	return initialRetryDelay << (failedAttempts - 1)
+}
@@ -0,0 +1,453 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/store/sqlite"
+	"github.com/mtlynch/picoshare/store/test_sqlite"
+	"github.com/mtlynch/picoshare/webhook"
+)
+
+type (
+	capturedRequest struct {
+		header http.Header
+		body   []byte
+	}
+
+	// captureServer is a webhook receiver that records each request and
+	// responds with a configurable status code.
+	captureServer struct {
+		*httptest.Server
+		mu         sync.Mutex
+		requests   []capturedRequest
+		statusCode int
+	}
+)
+
+func newCaptureServer(t *testing.T, statusCode int) *captureServer {
+	cs := &captureServer{statusCode: statusCode}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		cs.requests = append(cs.requests, capturedRequest{header: r.Header.Clone(), body: body})
+		w.WriteHeader(cs.statusCode)
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+func (cs *captureServer) Requests() []capturedRequest {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return append([]capturedRequest{}, cs.requests...)
+}
+
+func (cs *captureServer) SetStatusCode(statusCode int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.statusCode = statusCode
+}
+
+func newStoreWithEventWebhook(t *testing.T, settings picoshare.EventWebhookSettings) sqlite.Store {
+	dataStore := test_sqlite.New()
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		EventWebhook:        settings,
+	}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	return dataStore
+}
+
+func mustGetDeliveries(t *testing.T, dataStore sqlite.Store) []picoshare.WebhookDelivery {
+	deliveries, err := dataStore.GetWebhookDeliveries(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("failed to get webhook deliveries: %v", err)
+	}
+	return deliveries
+}
+
+func TestSenderDeliversSignedEvent(t *testing.T) {
+	receiver := newCaptureServer(t, http.StatusNoContent)
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL:    receiver.URL,
+		Secret: "dummy-secret",
+	})
+
+	queued := mustParseTime("2025-03-01T12:00:00Z")
+	entry := picoshare.UploadMetadata{
+		ID:          picoshare.EntryID("AAAAAAAAAA"),
+		Filename:    picoshare.Filename("report.pdf"),
+		ContentType: picoshare.ContentType("application/pdf"),
+		Size:        mustParseFileSize(1024),
+		Uploaded:    queued,
+		Expires:     picoshare.NeverExpire,
+	}
+	if err := webhook.Enqueue(context.Background(), &dataStore, webhook.EntryEvent(picoshare.WebhookEventEntryCreated, entry, queued)); err != nil {
+		t.Fatalf("failed to queue event: %v", err)
+	}
+
+	now := queued.Add(3 * time.Second)
+	sender := webhook.NewSenderWithClient(&dataStore, receiver.Client())
+	if err := sender.DeliverDue(context.Background(), now); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+
+	requests := receiver.Requests()
+	if got, want := len(requests), 1; got != want {
+		t.Fatalf("requests=%d, want=%d", got, want)
+	}
+	req := requests[0]
+
+	var payload webhook.Payload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("failed to decode payload %s: %v", req.body, err)
+	}
+	if got, want := payload.Type, picoshare.WebhookEventEntryCreated; got != want {
+		t.Errorf("payload type=%v, want=%v", got, want)
+	}
+	if got, want := payload.Time, queued; !got.Equal(want) {
+		t.Errorf("payload time=%v, want=%v", got, want)
+	}
+	if payload.Data.Entry == nil {
+		t.Fatalf("payload has no entry: %s", req.body)
+	}
+	if got, want := payload.Data.Entry.Filename, "report.pdf"; got != want {
+		t.Errorf("payload filename=%v, want=%v", got, want)
+	}
+	if payload.Data.Entry.Expires != nil {
+		t.Errorf("payload expiration=%v, want=nil", payload.Data.Entry.Expires)
+	}
+
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"Content-Type", "application/json"},
+		{webhook.HeaderEvent, "entry.created"},
+		{webhook.HeaderDelivery, payload.ID},
+		{webhook.HeaderTimestamp, "1740830403"},
+	} {
+		if got := req.header.Get(tt.header); got != tt.want {
+			t.Errorf("%s=%q, want=%q", tt.header, got, tt.want)
+		}
+	}
+
+	// Check the signature the way a receiver would.
+	mac := hmac.New(sha256.New, []byte("dummy-secret"))
+	mac.Write([]byte(req.header.Get(webhook.HeaderTimestamp) + "."))
+	mac.Write(req.body)
+	if got, want := req.header.Get(webhook.HeaderSignature), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature=%q, want=%q", got, want)
+	}
+
+	deliveries := mustGetDeliveries(t, dataStore)
+	if got, want := len(deliveries), 1; got != want {
+		t.Fatalf("deliveries=%d, want=%d", got, want)
+	}
+	d := deliveries[0]
+	if got, want := d.ID.String(), payload.ID; got != want {
+		t.Errorf("delivery ID=%v, want=%v", got, want)
+	}
+	if got, want := d.State, picoshare.WebhookDeliveryDelivered; got != want {
+		t.Errorf("delivery state=%v, want=%v", got, want)
+	}
+	if got, want := d.Attempts, uint(1); got != want {
+		t.Errorf("delivery attempts=%d, want=%d", got, want)
+	}
+	if got, want := d.LastStatusCode, http.StatusNoContent; got != want {
+		t.Errorf("delivery status code=%d, want=%d", got, want)
+	}
+	if got, want := d.LastAttempt, now; !got.Equal(want) {
+		t.Errorf("delivery last attempt=%v, want=%v", got, want)
+	}
+}
+
+func TestSenderOmitsSignatureWithoutSecret(t *testing.T) {
+	receiver := newCaptureServer(t, http.StatusOK)
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL: receiver.URL,
+	})
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	if err := webhook.Enqueue(context.Background(), &dataStore, webhook.GuestLinkEvent(picoshare.WebhookEventGuestLinkCreated, picoshare.GuestLink{
+		ID:         picoshare.GuestLinkID("abcdefgh23456789"),
+		Created:    now,
+		UrlExpires: picoshare.NeverExpire,
+	}, now)); err != nil {
+		t.Fatalf("failed to queue event: %v", err)
+	}
+
+	sender := webhook.NewSenderWithClient(&dataStore, receiver.Client())
+	if err := sender.DeliverDue(context.Background(), now); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+
+	requests := receiver.Requests()
+	if got, want := len(requests), 1; got != want {
+		t.Fatalf("requests=%d, want=%d", got, want)
+	}
+	if got, want := requests[0].header.Get(webhook.HeaderSignature), ""; got != want {
+		t.Errorf("signature=%q, want=%q", got, want)
+	}
+	if got, want := requests[0].header.Get(webhook.HeaderEvent), "guest_link.created"; got != want {
+		t.Errorf("event header=%q, want=%q", got, want)
+	}
+}
+
+func TestEnqueueDoesNothingWithoutEventWebhook(t *testing.T) {
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{})
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	if err := webhook.Enqueue(context.Background(), &dataStore, webhook.EntryEvent(picoshare.WebhookEventEntryDeleted, picoshare.UploadMetadata{
+		ID:      picoshare.EntryID("AAAAAAAAAA"),
+		Expires: picoshare.NeverExpire,
+	}, now)); err != nil {
+		t.Fatalf("failed to queue event: %v", err)
+	}
+
+	if got, want := len(mustGetDeliveries(t, dataStore)), 0; got != want {
+		t.Errorf("deliveries=%d, want=%d", got, want)
+	}
+}
+
+func TestSenderRetriesFailedDeliveries(t *testing.T) {
+	receiver := newCaptureServer(t, http.StatusServiceUnavailable)
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL:    receiver.URL,
+		Secret: "dummy-secret",
+	})
+
+	queued := mustParseTime("2025-03-01T12:00:00Z")
+	if err := webhook.Enqueue(context.Background(), &dataStore, webhook.EntryEvent(picoshare.WebhookEventEntryDeleted, picoshare.UploadMetadata{
+		ID:      picoshare.EntryID("AAAAAAAAAA"),
+		Expires: picoshare.NeverExpire,
+	}, queued)); err != nil {
+		t.Fatalf("failed to queue event: %v", err)
+	}
+	sender := webhook.NewSenderWithClient(&dataStore, receiver.Client())
+
+	if err := sender.DeliverDue(context.Background(), queued); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+	d := mustGetDeliveries(t, dataStore)[0]
+	if got, want := d.State, picoshare.WebhookDeliveryPending; got != want {
+		t.Errorf("state after failure=%v, want=%v", got, want)
+	}
+	if got, want := d.LastStatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status code after failure=%d, want=%d", got, want)
+	}
+	if d.LastError == "" {
+		t.Errorf("delivery has no error after failure")
+	}
+	if got, want := d.NextAttempt, queued.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("next attempt after failure=%v, want=%v", got, want)
+	}
+
+	// Before the retry is due, the sender leaves the delivery alone.
+	if err := sender.DeliverDue(context.Background(), queued.Add(30*time.Second)); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+	if got, want := len(receiver.Requests()), 1; got != want {
+		t.Errorf("requests before retry is due=%d, want=%d", got, want)
+	}
+
+	receiver.SetStatusCode(http.StatusOK)
+	if err := sender.DeliverDue(context.Background(), d.NextAttempt); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+
+	requests := receiver.Requests()
+	if got, want := len(requests), 2; got != want {
+		t.Fatalf("requests after retry=%d, want=%d", got, want)
+	}
+	// The retry carries the same delivery ID so that receivers can deduplicate.
+	if got, want := requests[1].header.Get(webhook.HeaderDelivery), requests[0].header.Get(webhook.HeaderDelivery); got != want {
+		t.Errorf("retry delivery ID=%v, want=%v", got, want)
+	}
+
+	d = mustGetDeliveries(t, dataStore)[0]
+	if got, want := d.State, picoshare.WebhookDeliveryDelivered; got != want {
+		t.Errorf("state after retry=%v, want=%v", got, want)
+	}
+	if got, want := d.Attempts, uint(2); got != want {
+		t.Errorf("attempts after retry=%d, want=%d", got, want)
+	}
+	if got, want := d.LastError, ""; got != want {
+		t.Errorf("error after retry=%q, want=%q", got, want)
+	}
+}
+
+func TestSenderGivesUpAfterMaxAttempts(t *testing.T) {
+	receiver := newCaptureServer(t, http.StatusInternalServerError)
+	dataStore := newStoreWithEventWebhook(t, picoshare.EventWebhookSettings{
+		URL: receiver.URL,
+	})
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	if err := webhook.Enqueue(context.Background(), &dataStore, webhook.EntryEvent(picoshare.WebhookEventEntryUpdated, picoshare.UploadMetadata{
+		ID:      picoshare.EntryID("AAAAAAAAAA"),
+		Expires: picoshare.NeverExpire,
+	}, now)); err != nil {
+		t.Fatalf("failed to queue event: %v", err)
+	}
+	sender := webhook.NewSenderWithClient(&dataStore, receiver.Client())
+
+	for range webhook.MaxAttempts {
+		if err := sender.DeliverDue(context.Background(), now); err != nil {
+			t.Fatalf("failed to deliver events: %v", err)
+		}
+		now = now.Add(365 * 24 * time.Hour)
+	}
+
+	d := mustGetDeliveries(t, dataStore)[0]
+	if got, want := d.State, picoshare.WebhookDeliveryFailed; got != want {
+		t.Errorf("state=%v, want=%v", got, want)
+	}
+	if got, want := d.Attempts, uint(webhook.MaxAttempts); got != want {
+		t.Errorf("attempts=%d, want=%d", got, want)
+	}
+
+	// A failed delivery is no longer due, so the sender doesn't try again.
+	if err := sender.DeliverDue(context.Background(), now); err != nil {
+		t.Fatalf("failed to deliver events: %v", err)
+	}
+	if got, want := len(receiver.Requests()), webhook.MaxAttempts; got != want {
+		t.Errorf("requests=%d, want=%d", got, want)
+	}
+}
+
+func TestConcurrentSendersDeliverEachEventOnce(t *testing.T) {
+	receiver := newCaptureServer(t, http.StatusOK)
+	// Use a database file rather than a shared in-memory database so that
+	// concurrent senders wait for each other the way they do in production
+	// instead of failing with a locked table.
+	dataStore := sqlite.New(filepath.Join(t.TempDir(), "store.db"), false)
+	if err := dataStore.UpdateSettings(context.Background(), picoshare.Settings{
+		DefaultFileLifetime: picoshare.NewFileLifetimeInDays(30),
+		EventWebhook: picoshare.EventWebhookSettings{
+			URL: receiver.URL,
+		},
+	}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+
+	now := mustParseTime("2025-03-01T12:00:00Z")
+	const eventCount = 30
+	for i := range eventCount {
+		if err := webhook.Enqueue(context.Background(), &dataStore, webhook.EntryEvent(picoshare.WebhookEventEntryCreated, picoshare.UploadMetadata{
+			ID:      picoshare.EntryID(fmt.Sprintf("AAAAAAAA%02d", i)),
+			Expires: picoshare.NeverExpire,
+		}, now)); err != nil {
+			t.Fatalf("failed to queue event: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		sender := webhook.NewSenderWithClient(&dataStore, receiver.Client())
+		wg.Go(func() {
+			if err := sender.DeliverDue(context.Background(), now); err != nil {
+				t.Errorf("failed to deliver events: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	deliveryIDs := map[string]int{}
+	for _, req := range receiver.Requests() {
+		deliveryIDs[req.header.Get(webhook.HeaderDelivery)]++
+	}
+	if got, want := len(deliveryIDs), eventCount; got != want {
+		t.Errorf("delivered events=%d, want=%d", got, want)
+	}
+	for id, n := range deliveryIDs {
+		if n != 1 {
+			t.Errorf("delivery %s sent %d times, want once", id, n)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for _, tt := range []struct {
+		failedAttempts uint
+		want           time.Duration
+	}{
+		{0, 0},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{9, 256 * time.Minute},
+	} {
+		if got := webhook.RetryDelay(tt.failedAttempts); got != tt.want {
+			t.Errorf("RetryDelay(%d)=%v, want=%v", tt.failedAttempts, got, tt.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		secret      string
+		timestamp   time.Time
+		body        string
+		want        string
+	}{
+		{
+			description: "signs timestamp and body",
+			secret:      "dummy-secret",
+			timestamp:   time.Unix(1700000000, 0),
+			body:        `{"id":"abc"}`,
+			want:        "sha256=" + hmacHex("dummy-secret", `1700000000.{"id":"abc"}`),
+		},
+		{
+			description: "signature depends on timestamp",
+			secret:      "dummy-secret",
+			timestamp:   time.Unix(1700000001, 0),
+			body:        `{"id":"abc"}`,
+			want:        "sha256=" + hmacHex("dummy-secret", `1700000001.{"id":"abc"}`),
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			if got, want := webhook.Sign(tt.secret, tt.timestamp, []byte(tt.body)), tt.want; got != want {
+				t.Errorf("signature=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func hmacHex(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func mustParseFileSize(val int) picoshare.FileSize {
+	fileSize, err := picoshare.FileSizeFromInt(val)
+	if err != nil {
+		panic(err)
+	}
+	return fileSize
+}
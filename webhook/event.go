@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mtlynch/picoshare/picoshare"
+	"github.com/mtlynch/picoshare/random"
+)
+
+type (
+	// QueueStore is the storage that Enqueue needs to queue events.
+	QueueStore interface {
+		ReadSettings(context.Context) (picoshare.Settings, error)
+		InsertWebhookDelivery(context.Context, picoshare.WebhookDelivery) error
+	}
+
+	// Event is a change in PicoShare that the event webhook hears about.
+	Event struct {
+		Type picoshare.WebhookEventType
+		Time time.Time
+		Data PayloadData
+	}
+
+	// Payload is the JSON body of an event webhook request.
+	Payload struct {
+		// ID identifies the delivery. If PicoShare retries a delivery, the ID
+		// stays the same, so receivers can use it to ignore duplicates.
+		ID   string                     `json:"id"`
+		Type picoshare.WebhookEventType `json:"type"`
+		Time time.Time                  `json:"time"`
+		Data PayloadData                `json:"data"`
+	}
+
+	PayloadData struct {
+		Entry     *Entry     `json:"entry,omitempty"`
+		Download  *Download  `json:"download,omitempty"`
+		GuestLink *GuestLink `json:"guestLink,omitempty"`
+		Eviction  *Eviction  `json:"eviction,omitempty"`
+	}
+
+	Entry struct {
+		ID          string     `json:"id"`
+		Filename    string     `json:"filename"`
+		ContentType string     `json:"contentType,omitempty"`
+		Size        uint64     `json:"size"`
+		SHA256      string     `json:"sha256,omitempty"`
+		Note        *string    `json:"note,omitempty"`
+		Uploaded    time.Time  `json:"uploaded"`
+		Expires     *time.Time `json:"expires"`
+		GuestLinkID string     `json:"guestLinkId,omitempty"`
+	}
+
+	Download struct {
+		Time      time.Time `json:"time"`
+		ClientIP  string    `json:"clientIp"`
+		UserAgent string    `json:"userAgent"`
+	}
+
+	GuestLink struct {
+		ID         string     `json:"id"`
+		Label      string     `json:"label,omitempty"`
+		Created    time.Time  `json:"created"`
+		Expires    *time.Time `json:"expires"`
+		IsDisabled bool       `json:"isDisabled"`
+	}
+
+	Eviction struct {
+		Policy string `json:"policy"`
+	}
+)
+
+// deliveryIDLength is long enough that receivers can treat delivery IDs as
+// globally unique.
+const deliveryIDLength = 20
+
+var deliveryIDCharacters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+
+// EntryEvent creates an event about a change to an entry.
+func EntryEvent(eventType picoshare.WebhookEventType, entry picoshare.UploadMetadata, t time.Time) Event {
+	return Event{
+		Type: eventType,
+		Time: t,
+		Data: PayloadData{Entry: newEntry(entry)},
+	}
+}
+
+// DownloadEvent creates an event about a client downloading an entry.
+func DownloadEvent(entry picoshare.UploadMetadata, download picoshare.DownloadRecord) Event {
+	return Event{
+		Type: picoshare.WebhookEventEntryDownloaded,
+		Time: download.Time,
+		Data: PayloadData{
+			Entry: newEntry(entry),
+			Download: &Download{
+				Time:      download.Time.UTC(),
+				ClientIP:  download.ClientIP,
+				UserAgent: download.UserAgent,
+			},
+		},
+	}
+}
+
+// EvictionEvent creates an event about PicoShare evicting an entry to stay
+// under its storage limit.
+func EvictionEvent(evicted picoshare.EvictedEntry) Event {
+	return Event{
+		Type: picoshare.WebhookEventEntryEvicted,
+		Time: evicted.Evicted,
+		Data: PayloadData{
+			Entry: &Entry{
+				ID:       evicted.ID.String(),
+				Filename: evicted.Filename.String(),
+				Size:     evicted.Size.UInt64(),
+				Uploaded: evicted.Uploaded.UTC(),
+			},
+			Eviction: &Eviction{Policy: evicted.Policy},
+		},
+	}
+}
+
+// GuestLinkEvent creates an event about a change to a guest link.
+func GuestLinkEvent(eventType picoshare.WebhookEventType, gl picoshare.GuestLink, t time.Time) Event {
+	return Event{
+		Type: eventType,
+		Time: t,
+		Data: PayloadData{
+			GuestLink: &GuestLink{
+				ID:         gl.ID.String(),
+				Label:      gl.Label.String(),
+				Created:    gl.Created.UTC(),
+				Expires:    expirationTimeOrNil(gl.UrlExpires),
+				IsDisabled: gl.IsDisabled,
+			},
+		},
+	}
+}
+
+// Enqueue adds an event to the queue for the owner's event webhook. It does
+// nothing if the owner hasn't configured an event webhook.
+func Enqueue(ctx context.Context, store QueueStore, ev Event) error {
+	settings, err := store.ReadSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if !settings.EventWebhook.IsConfigured() {
+		return nil
+	}
+
+	id := picoshare.WebhookDeliveryID(random.String(deliveryIDLength, deliveryIDCharacters))
+	payload, err := json.Marshal(Payload{
+		ID:   id.String(),
+		Type: ev.Type,
+		Time: ev.Time.UTC(),
+		Data: ev.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.InsertWebhookDelivery(ctx, picoshare.WebhookDelivery{
+		ID:          id,
+		EventType:   ev.Type,
+		URL:         settings.EventWebhook.URL,
+		Payload:     payload,
+		State:       picoshare.WebhookDeliveryPending,
+		Created:     ev.Time,
+		NextAttempt: ev.Time,
+	})
+}
+
+func newEntry(entry picoshare.UploadMetadata) *Entry {
+	return &Entry{
+		ID:          entry.ID.String(),
+		Filename:    entry.Filename.String(),
+		ContentType: entry.ContentType.String(),
+		Size:        entry.Size.UInt64(),
+		SHA256:      entry.SHA256.String(),
+		Note:        entry.Note.Value,
+		Uploaded:    entry.Uploaded.UTC(),
+		Expires:     expirationTimeOrNil(entry.Expires),
+		GuestLinkID: entry.GuestLink.ID.String(),
+	}
+}
+
+// expirationTimeOrNil converts an expiration time to JSON, where null means
+// that the item never expires.
+func expirationTimeOrNil(et picoshare.ExpirationTime) *time.Time {
+	if et == picoshare.NeverExpire {
+		return nil
+	}
+	t := et.Time().UTC()
+	return &t
+}
@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// pruneInterval is how often the scheduler deletes old deliveries.
+const pruneInterval = 24 * time.Hour
+
+type Scheduler struct {
+	sender *Sender
+	ticker *time.Ticker
+}
+
+func NewScheduler(sender *Sender, interval time.Duration) Scheduler {
+	return Scheduler{
+		sender: sender,
+		ticker: time.NewTicker(interval),
+	}
+}
+
+func (s *Scheduler) StartAsync() {
+	go func() {
+		var lastPrune time.Time
+		for range s.ticker.C {
+			now := time.Now()
+			if err := s.sender.DeliverDue(context.Background(), now); err != nil {
+				log.Printf("failed to deliver webhooks: %v", err)
+			}
+			if now.Sub(lastPrune) >= pruneInterval {
+				if err := s.sender.Prune(context.Background(), now); err != nil {
+					log.Printf("failed to delete old webhook deliveries: %v", err)
+				}
+				lastPrune = now
+			}
+		}
+	}()
+}